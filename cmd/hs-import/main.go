@@ -0,0 +1,63 @@
+// Command hs-import loads an HS nomenclature table into the "hs_codes" collection.
+//
+// Usage:
+//
+//	go run ./cmd/hs-import                            # import the bundled table
+//	go run ./cmd/hs-import -file hs2022.csv -complete # import a full "code,description" CSV
+//
+// Without -complete the imported table is treated as a subset: codes it does
+// not know are accepted with a warning. With -complete, stored codes that are
+// not in the file are removed. A running server picks up the import on its
+// next HS code lookup.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+
+	"fs-backend/config"
+	"fs-backend/connections"
+	"fs-backend/modules/hscodes"
+	"fs-backend/repository"
+	"fs-backend/services"
+)
+
+func main() {
+	file := flag.String("file", "", "path to a code,description CSV (defaults to the bundled table)")
+	complete := flag.Bool("complete", false, "the CSV is the full nomenclature (requires -file)")
+	flag.Parse()
+	if *complete && *file == "" {
+		log.Fatal("-complete requires -file, the bundled table is a subset")
+	}
+
+	var (
+		entries []hscodes.Entry
+		err     error
+	)
+	if *file == "" {
+		entries, err = hscodes.Bundled()
+	} else {
+		f, openErr := os.Open(*file)
+		if openErr != nil {
+			log.Fatal(openErr)
+		}
+		defer f.Close()
+		entries, err = hscodes.Parse(f)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	config.Init()
+	db := connections.ConnectMongo(config.GetString("mongo.uri"), config.GetString("mongo.database"))
+
+	service := services.NewHSCodeService(repository.NewHSCodeRepository(db))
+	count, err := service.Import(context.Background(), entries, *complete)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("Imported %d of %d HS codes", count, len(entries))
+}
@@ -0,0 +1,43 @@
+// Command migrate-dates converts booking ETD/ETA and shipment desired delivery
+// dates stored as strings into proper dates. The server runs the same
+// conversion on startup; use this command to preview it with -dry-run or to
+// list the values it could not parse. It is safe to run again.
+//
+// Usage:
+//
+//	go run ./cmd/migrate-dates           # convert
+//	go run ./cmd/migrate-dates -dry-run  # only report what would change
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+
+	"fs-backend/config"
+	"fs-backend/connections"
+	"fs-backend/repository"
+	"fs-backend/services"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "report what would change without writing")
+	flag.Parse()
+
+	config.Init()
+	db := connections.ConnectMongo(config.GetString("mongo.uri"), config.GetString("mongo.database"))
+
+	results, err := services.MigrateScheduleDates(context.Background(), repository.NewDateMigrationRepository(db), *dryRun)
+	for _, result := range results {
+		log.Printf("%s: %d converted, %d empty cleared, %d unparsed", result.Collection, result.Converted, result.Cleared, len(result.Unparsed))
+		for _, unparsed := range result.Unparsed {
+			log.Printf("  unparsed %s", unparsed)
+		}
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *dryRun {
+		log.Println("Dry run, nothing was written")
+	}
+}
@@ -0,0 +1,41 @@
+// Command resolve-carriers replaces the carrier names stored on existing
+// bookings with the names of the carrier master, so bookings synced before
+// the master existed group with the new ones. Run it once after loading the
+// carrier master; it is safe to run again.
+//
+// Usage:
+//
+//	go run ./cmd/resolve-carriers           # rename
+//	go run ./cmd/resolve-carriers -dry-run  # only report what would change
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+
+	"fs-backend/config"
+	"fs-backend/connections"
+	"fs-backend/repository"
+	"fs-backend/services"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "report what would change without writing")
+	flag.Parse()
+
+	config.Init()
+	db := connections.ConnectMongo(config.GetString("mongo.uri"), config.GetString("mongo.database"))
+
+	resolutions, err := services.ResolveBookingCarriers(context.Background(), repository.NewBookingRepository(db), repository.NewCarrierRepository(db), *dryRun)
+	for _, resolution := range resolutions {
+		log.Printf("%q -> %q: %d bookings", resolution.From, resolution.To, resolution.Bookings)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("%d carrier names resolved", len(resolutions))
+	if *dryRun {
+		log.Println("Dry run, nothing was written")
+	}
+}
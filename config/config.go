@@ -38,3 +38,19 @@ func GetConfig() *viper.Viper {
 func GetString(key string) string {
 	return GetConfig().GetString(key)
 }
+
+func GetBool(key string) bool {
+	return GetConfig().GetBool(key)
+}
+
+func GetFloat64(key string) float64 {
+	return GetConfig().GetFloat64(key)
+}
+
+func GetInt(key string) int {
+	return GetConfig().GetInt(key)
+}
+
+func GetStringMapString(key string) map[string]string {
+	return GetConfig().GetStringMapString(key)
+}
@@ -1,12 +1,15 @@
 package controllers
 
 import (
+	"errors"
+	"fs-backend/models"
 	"fs-backend/repository"
 	"fs-backend/services"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type BookingController struct {
@@ -27,6 +30,10 @@ func (c *BookingController) AddShipper(ctx *gin.Context) {
 	}
 
 	id, err := c.bookingService.AddShipper(ctx.Request.Context(), input)
+	if errors.Is(err, services.ErrShipperIDTaken) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add shipper"})
 		return
@@ -56,18 +63,35 @@ func (c *BookingController) UpdateShipper(ctx *gin.Context) {
 		return
 	}
 
+	revision, ok := requireIfMatch(ctx)
+	if !ok {
+		return
+	}
+
 	var updates map[string]interface{}
 	if err := ctx.ShouldBindJSON(&updates); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	err = c.bookingService.UpdateShipper(ctx.Request.Context(), objID, updates)
+	err = c.bookingService.UpdateShipper(ctx.Request.Context(), objID, updates, revision)
 	if err != nil {
+		if respondRevisionConflict(ctx, err) {
+			return
+		}
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Shipper not found"})
+			return
+		}
+		if errors.Is(err, services.ErrShipperIDTaken) {
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update shipper"})
 		return
 	}
 
+	setETag(ctx, revision+1)
 	ctx.JSON(http.StatusOK, gin.H{"message": "Shipper updated successfully"})
 }
 
@@ -88,6 +112,9 @@ func (c *BookingController) DeleteShipper(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, gin.H{"message": "Shipper deleted successfully"})
 }
 
+// SyncBooking handles POST /api/booking/syncBooking
+// Shipments that cannot join the booking answer 422 with one entry per
+// shipment in "shipment_errors"; nothing is synced in that case.
 func (c *BookingController) SyncBooking(ctx *gin.Context) {
 	var input struct {
 		MBLNumber          string   `json:"mbl_number" binding:"required"`
@@ -96,6 +123,7 @@ func (c *BookingController) SyncBooking(ctx *gin.Context) {
 		CarrierName        string   `json:"carrier_name"`
 		EstimatedDeparture string   `json:"estimated_departure"`
 		EstimatedArrival   string   `json:"estimated_arrival"`
+		ForwarderID        string   `json:"forwarder_id"`
 	}
 
 	if err := ctx.ShouldBindJSON(&input); err != nil {
@@ -111,17 +139,39 @@ func (c *BookingController) SyncBooking(ctx *gin.Context) {
 		input.CarrierName,
 		input.EstimatedDeparture,
 		input.EstimatedArrival,
+		input.ForwarderID,
 	)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		var syncErr *services.BookingSyncError
+		switch {
+		case errors.As(err, &syncErr):
+			ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "shipment_errors": syncErr.Errors})
+		case errors.Is(err, services.ErrShipmentAlreadyBooked), errors.Is(err, services.ErrBookingAlreadyExists):
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
 		return
 	}
 
 	ctx.JSON(http.StatusOK, gin.H{"message": "Booking synced successfully"})
 }
 
+// GetStatusDetails handles GET /api/booking/statusdetails?etd_from=&etd_to=&eta_from=&eta_to=
+// The ranges filter on the estimated departure and arrival (YYYY-MM-DD or RFC 3339).
 func (c *BookingController) GetStatusDetails(ctx *gin.Context) {
-	statuses, err := c.bookingService.GetStatusDetails(ctx.Request.Context())
+	var (
+		filter repository.BookingFilter
+		ok     bool
+	)
+	if filter.Departure, ok = bindDateRange(ctx, "etd_from", "etd_to"); !ok {
+		return
+	}
+	if filter.Arrival, ok = bindDateRange(ctx, "eta_from", "eta_to"); !ok {
+		return
+	}
+
+	statuses, err := c.bookingService.GetStatusDetails(ctx.Request.Context(), filter)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch status details"})
 		return
@@ -129,6 +179,10 @@ func (c *BookingController) GetStatusDetails(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, statuses)
 }
 
+// UpdateStatus handles PUT /api/booking/updatestatus/:id
+// Body: {"status": "Cancelled", "reason_code": "CUSTOMER_REQUEST", "reason": "..."}
+// Requires If-Match; the X-User header is recorded in the status history.
+// Transitions the booking state machine does not allow answer 409.
 func (c *BookingController) UpdateStatus(ctx *gin.Context) {
 	idParam := ctx.Param("id")
 	objID, err := primitive.ObjectIDFromHex(idParam)
@@ -137,20 +191,172 @@ func (c *BookingController) UpdateStatus(ctx *gin.Context) {
 		return
 	}
 
-	var input struct {
-		Status string `json:"status" binding:"required"`
+	revision, ok := requireIfMatch(ctx)
+	if !ok {
+		return
+	}
+
+	var input models.BookingStatusRequest
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	booking, err := c.bookingService.UpdateStatus(ctx.Request.Context(), objID, input, ctx.GetHeader("X-User"), revision)
+	if err != nil {
+		if respondRevisionConflict(ctx, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, mongo.ErrNoDocuments):
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+		case errors.Is(err, services.ErrInvalidBookingStatus),
+			errors.Is(err, services.ErrInvalidCancelReason):
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrIllegalBookingTransition):
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update status"})
+		}
+		return
+	}
+
+	setETag(ctx, booking.Revision)
+	ctx.JSON(http.StatusOK, gin.H{"message": "Status updated successfully", "booking": booking})
+}
+
+// MoveShipments handles POST /api/booking/moveshipments/:id
+// Body: {"shipment_ids": ["SHP-001"], "target_mbl_number": "..."}
+// Requires If-Match with the ETag of the booking the shipments leave.
+func (c *BookingController) MoveShipments(ctx *gin.Context) {
+	objID, revision, ok := bookingOperationTarget(ctx)
+	if !ok {
+		return
+	}
+	var input models.MoveShipmentsRequest
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := c.bookingService.MoveShipments(ctx.Request.Context(), objID, input, ctx.GetHeader("X-User"), revision)
+	if err != nil {
+		respondBookingOperationError(ctx, err)
+		return
+	}
+
+	setETag(ctx, result.Booking.Revision)
+	ctx.JSON(http.StatusOK, gin.H{"message": "Shipments moved successfully", "booking": result.Booking, "related_booking": result.Related})
+}
+
+// SplitBooking handles POST /api/booking/splitbooking/:id
+// Body: {"shipment_ids": [...], "new_mbl_number": "...", "carrier_name": "...", "estimated_departure": "...", "estimated_arrival": "..."}
+// At least one shipment stays on the booking; requires If-Match.
+func (c *BookingController) SplitBooking(ctx *gin.Context) {
+	objID, revision, ok := bookingOperationTarget(ctx)
+	if !ok {
+		return
+	}
+	var input models.SplitBookingRequest
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := c.bookingService.SplitBooking(ctx.Request.Context(), objID, input, ctx.GetHeader("X-User"), revision)
+	if err != nil {
+		respondBookingOperationError(ctx, err)
+		return
 	}
 
+	setETag(ctx, result.Booking.Revision)
+	ctx.JSON(http.StatusOK, gin.H{"message": "Booking split successfully", "booking": result.Booking, "related_booking": result.Related})
+}
+
+// MergeBookings handles POST /api/booking/mergebooking/:id
+// Body: {"source_mbl_number": "..."}
+// The source booking's shipments join this booking and the source is
+// cancelled; requires If-Match with the ETag of this booking.
+func (c *BookingController) MergeBookings(ctx *gin.Context) {
+	objID, revision, ok := bookingOperationTarget(ctx)
+	if !ok {
+		return
+	}
+	var input models.MergeBookingRequest
 	if err := ctx.ShouldBindJSON(&input); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	err = c.bookingService.UpdateStatus(ctx.Request.Context(), objID, input.Status)
+	result, err := c.bookingService.MergeBookings(ctx.Request.Context(), objID, input, ctx.GetHeader("X-User"), revision)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update status"})
+		respondBookingOperationError(ctx, err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"message": "Status updated successfully"})
+	setETag(ctx, result.Booking.Revision)
+	ctx.JSON(http.StatusOK, gin.H{"message": "Bookings merged successfully", "booking": result.Booking, "related_booking": result.Related})
+}
+
+// CancelBooking handles POST /api/booking/cancelbooking/:id
+// Body: {"reason_code": "CUSTOMER_REQUEST", "reason": "..."}
+// The booking's shipments are released and can be synced again; requires If-Match.
+func (c *BookingController) CancelBooking(ctx *gin.Context) {
+	objID, revision, ok := bookingOperationTarget(ctx)
+	if !ok {
+		return
+	}
+	var input models.CancelBookingRequest
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	booking, err := c.bookingService.CancelBooking(ctx.Request.Context(), objID, input, ctx.GetHeader("X-User"), revision)
+	if err != nil {
+		respondBookingOperationError(ctx, err)
+		return
+	}
+
+	setETag(ctx, booking.Revision)
+	ctx.JSON(http.StatusOK, gin.H{"message": "Booking cancelled successfully", "booking": booking})
+}
+
+// bookingOperationTarget reads the booking ID and If-Match revision of a
+// booking operation, answering 400/428 when either is missing or malformed
+func bookingOperationTarget(ctx *gin.Context) (primitive.ObjectID, int64, bool) {
+	objID, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return primitive.NilObjectID, 0, false
+	}
+	revision, ok := requireIfMatch(ctx)
+	if !ok {
+		return primitive.NilObjectID, 0, false
+	}
+	return objID, revision, true
+}
+
+func respondBookingOperationError(ctx *gin.Context, err error) {
+	if respondRevisionConflict(ctx, err) {
+		return
+	}
+	var syncErr *services.BookingSyncError
+	switch {
+	case errors.As(err, &syncErr):
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "shipment_errors": syncErr.Errors})
+	case errors.Is(err, mongo.ErrNoDocuments):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+	case errors.Is(err, services.ErrInvalidBookingOperation),
+		errors.Is(err, services.ErrInvalidBookingMode),
+		errors.Is(err, services.ErrInvalidSchedule),
+		errors.Is(err, services.ErrInvalidCancelReason):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrBookingLocked),
+		errors.Is(err, services.ErrIllegalBookingTransition),
+		errors.Is(err, services.ErrBookingAlreadyExists):
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update booking"})
+	}
 }
@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"fs-backend/models"
+	"fs-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BookingModeController manages the per-forwarder booking mode rules
+type BookingModeController struct {
+	service services.BookingModeService
+}
+
+// NewBookingModeController creates a new BookingModeController
+func NewBookingModeController(service services.BookingModeService) *BookingModeController {
+	return &BookingModeController{service: service}
+}
+
+// GetRules handles GET /api/v1/booking-mode-rules?forwarder_id=
+// Forwarders without rules of their own get the defaults.
+func (ctrl *BookingModeController) GetRules(ctx *gin.Context) {
+	rules, err := ctrl.service.GetRules(ctx.Request.Context(), ctx.Query("forwarder_id"))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch booking mode rules"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, rules)
+}
+
+// SaveRules handles PUT /api/v1/booking-mode-rules
+// Body: {"forwarder_id": "FWD001", "rules": [{"mode": "LCL", "max_shipments": 5, "max_volume_cbm": 58, ...}]}
+// The rules replace the forwarder's whole set.
+func (ctrl *BookingModeController) SaveRules(ctx *gin.Context) {
+	var rules models.BookingModeRules
+	if err := ctx.ShouldBindJSON(&rules); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	saved, err := ctrl.service.SaveRules(ctx.Request.Context(), rules)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidBookingModeRules) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save booking mode rules"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Booking mode rules saved successfully", "rules": saved})
+}
+
+// ResetRules handles DELETE /api/v1/booking-mode-rules?forwarder_id=
+// Drops the forwarder's rules so the defaults apply again.
+func (ctrl *BookingModeController) ResetRules(ctx *gin.Context) {
+	forwarderID := ctx.Query("forwarder_id")
+	if forwarderID == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "forwarder_id is required"})
+		return
+	}
+
+	rules, err := ctrl.service.ResetRules(ctx.Request.Context(), forwarderID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset booking mode rules"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Booking mode rules reset to the defaults", "rules": rules})
+}
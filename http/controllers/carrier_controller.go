@@ -0,0 +1,142 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"fs-backend/models"
+	"fs-backend/services"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CarrierController manages the carrier master
+type CarrierController struct {
+	service services.CarrierService
+}
+
+// NewCarrierController creates a new CarrierController
+func NewCarrierController(service services.CarrierService) *CarrierController {
+	return &CarrierController{service: service}
+}
+
+// ListCarriers handles GET /api/v1/carriers
+func (ctrl *CarrierController) ListCarriers(ctx *gin.Context) {
+	carriers, err := ctrl.service.ListCarriers(ctx.Request.Context())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch carriers"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, carriers)
+}
+
+// GetCarrier handles GET /api/v1/carriers/:id
+func (ctrl *CarrierController) GetCarrier(ctx *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	carrier, err := ctrl.service.GetCarrier(ctx.Request.Context(), objID)
+	if err != nil {
+		respondCarrierError(ctx, err, "Failed to fetch carrier")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, carrier)
+}
+
+// ResolveCarrier handles GET /api/v1/carriers/resolve?name=&scac_code=
+// Returns the carrier a free text name, alias or SCAC code stands for.
+func (ctrl *CarrierController) ResolveCarrier(ctx *gin.Context) {
+	name := strings.TrimSpace(ctx.Query("name"))
+	scacCode := strings.TrimSpace(ctx.Query("scac_code"))
+	if name == "" && scacCode == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "name or scac_code is required"})
+		return
+	}
+
+	carrier, err := ctrl.service.ResolveCarrier(ctx.Request.Context(), name, scacCode)
+	if err != nil {
+		respondCarrierError(ctx, err, "Failed to resolve carrier")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, carrier)
+}
+
+// CreateCarrier handles POST /api/v1/carriers
+// Body: {"name": "Maersk", "aliases": ["Maersk Line", "MSK"], "scac_code": "MAEU", "contact": {...}, "default_free_time_days": 7}
+func (ctrl *CarrierController) CreateCarrier(ctx *gin.Context) {
+	var input models.Carrier
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	carrier, err := ctrl.service.CreateCarrier(ctx.Request.Context(), input)
+	if err != nil {
+		respondCarrierError(ctx, err, "Failed to create carrier")
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"message": "Carrier created successfully", "carrier": carrier})
+}
+
+// UpdateCarrier handles PUT /api/v1/carriers/:id
+// The body replaces the carrier's name, aliases, SCAC code, contact and free time.
+func (ctrl *CarrierController) UpdateCarrier(ctx *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	var input models.Carrier
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	carrier, err := ctrl.service.UpdateCarrier(ctx.Request.Context(), objID, input)
+	if err != nil {
+		respondCarrierError(ctx, err, "Failed to update carrier")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Carrier updated successfully", "carrier": carrier})
+}
+
+// DeleteCarrier handles DELETE /api/v1/carriers/:id
+func (ctrl *CarrierController) DeleteCarrier(ctx *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	if err := ctrl.service.DeleteCarrier(ctx.Request.Context(), objID); err != nil {
+		respondCarrierError(ctx, err, "Failed to delete carrier")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Carrier deleted successfully"})
+}
+
+// respondCarrierError maps carrier master errors to their status codes
+func respondCarrierError(ctx *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrCarrierNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidCarrier):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrDuplicateCarrier):
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -0,0 +1,72 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"fs-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ContainerStatusController handles container status ingestion and timelines
+type ContainerStatusController struct {
+	service services.ContainerStatusService
+}
+
+// NewContainerStatusController creates a new ContainerStatusController
+func NewContainerStatusController(service services.ContainerStatusService) *ContainerStatusController {
+	return &ContainerStatusController{service: service}
+}
+
+// Ingest handles POST /api/v1/container-status
+// Accepts a multipart form with file (X12 315 or EDIFACT IFTSTA interchange);
+// answers 422 with segment_errors pointing at the offending segments.
+func (ctrl *ContainerStatusController) Ingest(ctx *gin.Context) {
+	fileHeader, err := ctx.FormFile("file")
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to open uploaded file"})
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to read file contents"})
+		return
+	}
+
+	result, err := ctrl.service.Ingest(ctx.Request.Context(), data)
+	if err != nil {
+		var messageErr *services.StatusMessageError
+		switch {
+		case errors.As(err, &messageErr):
+			ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "segment_errors": messageErr.Errors})
+		case errors.Is(err, services.ErrUnknownStatusFormat):
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to ingest container status message"})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// Timeline handles GET /api/v1/mbl/:mbl_number/container-events
+func (ctrl *ContainerStatusController) Timeline(ctx *gin.Context) {
+	events, err := ctrl.service.Timeline(ctx.Request.Context(), ctx.Param("mbl_number"))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load container events"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, events)
+}
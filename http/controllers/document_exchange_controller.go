@@ -0,0 +1,155 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"fs-backend/models/dcsa"
+	"fs-backend/services"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Values of the format query parameter
+const (
+	exchangeFormatDCSA    = "dcsa"
+	exchangeFormatEDIFACT = "edifact" // import only
+)
+
+// DocumentExchangeController handles the electronic bill exchange endpoints
+type DocumentExchangeController struct {
+	service services.DocumentExchangeService
+}
+
+// NewDocumentExchangeController creates a new DocumentExchangeController
+func NewDocumentExchangeController(service services.DocumentExchangeService) *DocumentExchangeController {
+	return &DocumentExchangeController{service: service}
+}
+
+// ExportHBL handles GET /api/v1/hbl/:hbl_number/export?format=dcsa
+func (ctrl *DocumentExchangeController) ExportHBL(ctx *gin.Context) {
+	if !requireExchangeFormat(ctx) {
+		return
+	}
+
+	td, err := ctrl.service.ExportHBL(ctx.Request.Context(), ctx.Param("hbl_number"))
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "HBL not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export HBL"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, td)
+}
+
+// ExportMBL handles GET /api/v1/mbl/:mbl_number/export?format=dcsa
+func (ctrl *DocumentExchangeController) ExportMBL(ctx *gin.Context) {
+	if !requireExchangeFormat(ctx) {
+		return
+	}
+
+	td, err := ctrl.service.ExportMBL(ctx.Request.Context(), ctx.Param("mbl_number"))
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "MBL not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export MBL"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, td)
+}
+
+// ImportMBL handles POST /api/v1/mbl/import?format=dcsa|edifact
+func (ctrl *DocumentExchangeController) ImportMBL(ctx *gin.Context) {
+	switch strings.ToLower(ctx.Query("format")) {
+	case exchangeFormatDCSA:
+		ctrl.importDCSA(ctx)
+	case exchangeFormatEDIFACT:
+		ctrl.importEDIFACT(ctx)
+	default:
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "format query parameter must be 'dcsa' or 'edifact'"})
+	}
+}
+
+// importDCSA accepts a DCSA transport document as JSON body; answers 422 with
+// field_errors when the document is incomplete.
+func (ctrl *DocumentExchangeController) importDCSA(ctx *gin.Context) {
+	var td dcsa.TransportDocument
+	if err := ctx.ShouldBindJSON(&td); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := ctrl.service.ImportMBL(ctx.Request.Context(), td)
+	if err != nil {
+		var validationErr *services.DCSAValidationError
+		switch {
+		case errors.As(err, &validationErr):
+			ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "field_errors": validationErr.Errors})
+		case errors.Is(err, services.ErrMBLAlreadyExists):
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import MBL"})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, result)
+}
+
+// importEDIFACT accepts a multipart form with: file (IFTMCS/IFTMIN
+// interchange) and optional forwarder_id and mode (one of the forwarder's
+// booking modes, FCL by default) for new bookings; answers 422 with
+// segment_errors pointing at the offending segments.
+func (ctrl *DocumentExchangeController) importEDIFACT(ctx *gin.Context) {
+	fileHeader, err := ctx.FormFile("file")
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to open uploaded file"})
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to read file contents"})
+		return
+	}
+
+	result, err := ctrl.service.ImportEDIFACT(ctx.Request.Context(), data, ctx.PostForm("forwarder_id"), ctx.PostForm("mode"))
+	if err != nil {
+		var edifactErr *services.EDIFACTError
+		switch {
+		case errors.As(err, &edifactErr):
+			ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "segment_errors": edifactErr.Errors})
+		case errors.Is(err, services.ErrInvalidBookingMode):
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import EDIFACT messages"})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, result)
+}
+
+func requireExchangeFormat(ctx *gin.Context) bool {
+	if strings.ToLower(ctx.Query("format")) != exchangeFormatDCSA {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "format query parameter must be 'dcsa'"})
+		return false
+	}
+	return true
+}
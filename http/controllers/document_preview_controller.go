@@ -1,12 +1,15 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 
+	"fs-backend/models"
 	"fs-backend/models/hbl_schema"
 	"fs-backend/services"
 
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 // DocumentPreviewController handles document preview endpoints
@@ -39,6 +42,10 @@ func (ctrl *DocumentPreviewController) PreviewHBL(ctx *gin.Context) {
 
 	result, err := ctrl.service.PreviewHBL(ctx.Request.Context(), req)
 	if err != nil {
+		if errors.Is(err, services.ErrMBLNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -46,13 +53,60 @@ func (ctrl *DocumentPreviewController) PreviewHBL(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, result)
 }
 
+// CreateHBLs handles POST /api/v1/hbl
+// Same body as the preview; stores one HBL per shipment that does not have one yet.
+// An optional Idempotency-Key header makes retries return the first response.
+func (ctrl *DocumentPreviewController) CreateHBLs(ctx *gin.Context) {
+	var req hbl_schema.PreviewHBLRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.MBLNumber == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "mbl_number is required"})
+		return
+	}
+	if len(req.ShipmentList) == 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "shipment_list must contain at least one shipment_id"})
+		return
+	}
+
+	result, err := ctrl.service.CreateHBLs(ctx.Request.Context(), req, ctx.GetHeader("Idempotency-Key"))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrMBLNotFound):
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrIdempotencyKeyReused):
+			ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrIdempotencyKeyInProgress):
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	status := http.StatusOK
+	if result.CreatedCount > 0 {
+		status = http.StatusCreated
+	}
+	ctx.JSON(status, result)
+}
+
 // UpdateHBL handles PUT /api/v1/hbl/:hbl_number
+// The X-User and X-Change-Reason headers are stored on the new HBL version.
+// Requires If-Match with the HBL's ETag; answers 412 with the current HBL when it is outdated.
 func (ctrl *DocumentPreviewController) UpdateHBL(ctx *gin.Context) {
 	hblNumber := ctx.Param("hbl_number")
 	if hblNumber == "" {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "hbl_number is required"})
 		return
 	}
+	revision, ok := requireIfMatch(ctx)
+	if !ok {
+		return
+	}
 
 	var data hbl_schema.HBLData
 	if err := ctx.ShouldBindJSON(&data); err != nil {
@@ -60,10 +114,152 @@ func (ctrl *DocumentPreviewController) UpdateHBL(ctx *gin.Context) {
 		return
 	}
 
-	if err := ctrl.service.UpdateHBL(ctx.Request.Context(), hblNumber, data); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	change := hbl_schema.HBLChangeInfo{
+		Author: ctx.GetHeader("X-User"),
+		Reason: ctx.GetHeader("X-Change-Reason"),
+	}
+	hsCodeChecks, err := ctrl.service.UpdateHBL(ctx.Request.Context(), hblNumber, data, change, revision)
+	if err != nil {
+		respondHBLUpdateError(ctx, err, hsCodeChecks)
+		return
+	}
+
+	setETag(ctx, revision+1)
+	ctx.JSON(http.StatusOK, gin.H{"message": "HBL updated successfully", "hs_code_checks": hsCodeChecks})
+}
+
+// PatchHBL handles PATCH /api/v1/hbl/:hbl_number
+// Content-Type application/merge-patch+json (or application/json) applies an
+// RFC 7396 merge patch, application/json-patch+json an RFC 6902 JSON Patch.
+// Requires If-Match with the HBL's ETag, like PUT.
+func (ctrl *DocumentPreviewController) PatchHBL(ctx *gin.Context) {
+	hblNumber := ctx.Param("hbl_number")
+	revision, ok := requireIfMatch(ctx)
+	if !ok {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"message": "HBL updated successfully"})
+	contentType := ctx.ContentType()
+	switch contentType {
+	case services.MergePatchContentType, services.JSONPatchContentType, "application/json":
+	default:
+		ctx.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Content-Type must be " + services.MergePatchContentType + " or " + services.JSONPatchContentType})
+		return
+	}
+
+	patch, err := ctx.GetRawData()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	change := hbl_schema.HBLChangeInfo{
+		Author: ctx.GetHeader("X-User"),
+		Reason: ctx.GetHeader("X-Change-Reason"),
+	}
+	doc, hsCodeChecks, err := ctrl.service.PatchHBL(ctx.Request.Context(), hblNumber, contentType, patch, change, revision)
+	if err != nil {
+		respondHBLUpdateError(ctx, err, hsCodeChecks)
+		return
+	}
+
+	setETag(ctx, doc.Revision)
+	ctx.JSON(http.StatusOK, gin.H{"hbl": doc, "hs_code_checks": hsCodeChecks})
+}
+
+// CreateStandaloneHBL handles POST /api/v1/hbl/standalone
+// Creates an HBL for a shipment from manually entered routing and vessel data,
+// before the MBL is available.
+func (ctrl *DocumentPreviewController) CreateStandaloneHBL(ctx *gin.Context) {
+	var req hbl_schema.StandaloneHBLRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	doc, err := ctrl.service.CreateStandaloneHBL(ctx.Request.Context(), req)
+	if err != nil {
+		var validationErr *services.HBLValidationError
+		switch {
+		case errors.As(err, &validationErr):
+			ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "field_errors": validationErr.Errors})
+		case errors.Is(err, mongo.ErrNoDocuments):
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Shipment or shipper not found"})
+		case errors.Is(err, services.ErrHBLAlreadyExists):
+			ctx.JSON(http.StatusConflict, gin.H{"error": "A standalone HBL already exists for this shipment"})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	setETag(ctx, doc.Revision)
+	ctx.JSON(http.StatusCreated, doc)
+}
+
+// LinkMBL handles POST /api/v1/hbl/:hbl_number/link-mbl
+// Links a standalone HBL to its MBL, merging the MBL-derived fields and
+// reporting the ones that conflict. Requires If-Match with the HBL's ETag.
+func (ctrl *DocumentPreviewController) LinkMBL(ctx *gin.Context) {
+	revision, ok := requireIfMatch(ctx)
+	if !ok {
+		return
+	}
+
+	var req hbl_schema.LinkMBLRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	change := hbl_schema.HBLChangeInfo{
+		Author: ctx.GetHeader("X-User"),
+		Reason: ctx.GetHeader("X-Change-Reason"),
+	}
+	result, err := ctrl.service.LinkMBL(ctx.Request.Context(), ctx.Param("hbl_number"), req, change, revision)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrMBLNotFound):
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrHBLAlreadyLinked), errors.Is(err, services.ErrHBLNotStandalone):
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrHBLAlreadyExists):
+			ctx.JSON(http.StatusConflict, gin.H{"error": "The shipment already has an HBL under this MBL"})
+		default:
+			respondHBLUpdateError(ctx, err, nil)
+		}
+		return
+	}
+
+	setETag(ctx, result.HBL.Revision)
+	ctx.JSON(http.StatusOK, result)
+}
+
+func respondHBLUpdateError(ctx *gin.Context, err error, hsCodeChecks []models.HSCodeValidation) {
+	if respondRevisionConflict(ctx, err) {
+		return
+	}
+	var hsErr *services.HSCodeValidationError
+	if errors.As(err, &hsErr) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "hs_code_checks": hsCodeChecks})
+		return
+	}
+	var validationErr *services.HBLValidationError
+	if errors.As(err, &validationErr) {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "field_errors": validationErr.Errors})
+		return
+	}
+
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "HBL not found"})
+	case errors.Is(err, services.ErrInvalidPatch):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrPatchFailed):
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrHBLLocked), errors.Is(err, services.ErrHBLVersionConflict):
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
 }
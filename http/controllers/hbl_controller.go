@@ -0,0 +1,150 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"fs-backend/models/hbl_schema"
+	"fs-backend/repository"
+	"fs-backend/services"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// HBLController serves stored HBLs
+type HBLController struct {
+	service services.HBLService
+}
+
+// NewHBLController creates a new HBLController
+func NewHBLController(service services.HBLService) *HBLController {
+	return &HBLController{service: service}
+}
+
+// GetHBL handles GET /api/v1/hbl/:hbl_number
+func (ctrl *HBLController) GetHBL(ctx *gin.Context) {
+	doc, err := ctrl.service.GetHBL(ctx.Request.Context(), ctx.Param("hbl_number"))
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "HBL not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch HBL"})
+		return
+	}
+
+	setETag(ctx, doc.Revision)
+	ctx.JSON(http.StatusOK, doc)
+}
+
+// ListHBLs handles GET /api/v1/hbl?mbl_number=&shipment_id=&status=&consignee=&from=&to=&page=&page_size=&sort=
+// from/to filter on the creation date (YYYY-MM-DD or RFC 3339); sort is a field
+// name, prefixed with "-" for descending order.
+func (ctrl *HBLController) ListHBLs(ctx *gin.Context) {
+	query := hbl_schema.HBLSearchQuery{
+		MBLNumber:  ctx.Query("mbl_number"),
+		ShipmentID: ctx.Query("shipment_id"),
+		Status:     strings.ToLower(ctx.Query("status")),
+		Consignee:  strings.TrimSpace(ctx.Query("consignee")),
+	}
+
+	var err error
+	if query.From, err = parseDateQuery(ctx.Query("from"), false); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "from must be a date (YYYY-MM-DD) or RFC 3339 timestamp"})
+		return
+	}
+	if query.To, err = parseDateQuery(ctx.Query("to"), true); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "to must be a date (YYYY-MM-DD) or RFC 3339 timestamp"})
+		return
+	}
+	if value := ctx.Query("page"); value != "" {
+		if query.Page, err = strconv.ParseInt(value, 10, 64); err != nil || query.Page < 1 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "page must be a positive number"})
+			return
+		}
+	}
+	if value := ctx.Query("page_size"); value != "" {
+		if query.PageSize, err = strconv.ParseInt(value, 10, 64); err != nil || query.PageSize < 1 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "page_size must be a positive number"})
+			return
+		}
+	}
+	if sort := ctx.Query("sort"); sort != "" {
+		query.SortDesc = strings.HasPrefix(sort, "-")
+		query.SortBy = strings.TrimPrefix(sort, "-")
+		if _, ok := repository.HBLSortFields[query.SortBy]; !ok {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "unsupported sort field " + query.SortBy})
+			return
+		}
+	}
+
+	result, err := ctrl.service.ListHBLs(ctx.Request.Context(), query)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list HBLs"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// DeleteHBL handles DELETE /api/v1/hbl/:hbl_number
+// Only draft and void HBLs can be deleted; requires If-Match with the HBL's ETag.
+func (ctrl *HBLController) DeleteHBL(ctx *gin.Context) {
+	revision, ok := requireIfMatch(ctx)
+	if !ok {
+		return
+	}
+
+	deletedDocs, err := ctrl.service.DeleteHBL(ctx.Request.Context(), ctx.Param("hbl_number"), revision)
+	if err != nil {
+		if respondRevisionConflict(ctx, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, mongo.ErrNoDocuments):
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "HBL not found"})
+		case errors.Is(err, repository.ErrHBLNotDeletable):
+			ctx.JSON(http.StatusConflict, gin.H{"error": "only draft or void HBLs can be deleted"})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete HBL"})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "HBL deleted successfully", "deleted_documents": deletedDocs})
+}
+
+// parseDateQuery accepts a date or an RFC 3339 timestamp. A bare date used as
+// an upper bound covers the whole day.
+func parseDateQuery(value string, endOfDay bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return t, nil
+}
+
+// bindDateRange reads a from/to pair of query parameters into a list filter,
+// answering 400 when either is not a date or the range is empty
+func bindDateRange(ctx *gin.Context, fromKey, toKey string) (repository.DateRange, bool) {
+	dateRange, err := services.ParseDateRange(ctx.Query(fromKey), ctx.Query(toKey))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s/%s: %v", fromKey, toKey, err)})
+		return dateRange, false
+	}
+	return dateRange, true
+}
@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"fs-backend/models/hbl_schema"
+	"fs-backend/services"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// HBLLifecycleController exposes HBL status transitions and amendments
+type HBLLifecycleController struct {
+	service services.HBLLifecycleService
+}
+
+// NewHBLLifecycleController creates a new HBLLifecycleController
+func NewHBLLifecycleController(service services.HBLLifecycleService) *HBLLifecycleController {
+	return &HBLLifecycleController{service: service}
+}
+
+// GetStatus handles GET /api/v1/hbl/:hbl_number/status
+func (ctrl *HBLLifecycleController) GetStatus(ctx *gin.Context) {
+	result, err := ctrl.service.GetStatus(ctx.Request.Context(), ctx.Param("hbl_number"))
+	if err != nil {
+		respondLifecycleError(ctx, err)
+		return
+	}
+
+	setETag(ctx, result.Revision)
+	ctx.JSON(http.StatusOK, result)
+}
+
+// Transition handles POST /api/v1/hbl/:hbl_number/transitions
+// Body: {"status": "approved", "actor": "...", "reason": "..."}
+func (ctrl *HBLLifecycleController) Transition(ctx *gin.Context) {
+	var req hbl_schema.HBLTransitionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := ctrl.service.Transition(ctx.Request.Context(), ctx.Param("hbl_number"), req)
+	if err != nil {
+		respondLifecycleError(ctx, err)
+		return
+	}
+
+	setETag(ctx, result.Revision)
+	ctx.JSON(http.StatusOK, result)
+}
+
+// Amend handles POST /api/v1/hbl/:hbl_number/amendments
+// Replaces the data of an issued HBL and records who changed it and why.
+// Requires If-Match with the HBL's ETag.
+func (ctrl *HBLLifecycleController) Amend(ctx *gin.Context) {
+	revision, ok := requireIfMatch(ctx)
+	if !ok {
+		return
+	}
+
+	var req hbl_schema.HBLAmendmentRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, hsCodeChecks, err := ctrl.service.Amend(ctx.Request.Context(), ctx.Param("hbl_number"), req, revision)
+	if err != nil {
+		var hsErr *services.HSCodeValidationError
+		if errors.As(err, &hsErr) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "hs_code_checks": hsCodeChecks})
+			return
+		}
+		respondLifecycleError(ctx, err)
+		return
+	}
+
+	setETag(ctx, result.Revision)
+	ctx.JSON(http.StatusOK, gin.H{"status": result, "hs_code_checks": hsCodeChecks})
+}
+
+func respondLifecycleError(ctx *gin.Context, err error) {
+	if respondRevisionConflict(ctx, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "HBL not found"})
+	case errors.Is(err, services.ErrInvalidHBLStatus):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrIllegalHBLTransition),
+		errors.Is(err, services.ErrHBLNotIssued),
+		errors.Is(err, services.ErrHBLStatusConflict),
+		errors.Is(err, services.ErrHBLVersionConflict):
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
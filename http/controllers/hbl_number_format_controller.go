@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"fs-backend/models"
+	"fs-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxFormatPreviewCount caps the number of sample numbers a preview returns
+const maxFormatPreviewCount = 20
+
+// HBLNumberFormatController manages per-forwarder HBL numbering formats
+type HBLNumberFormatController struct {
+	service services.HBLNumberingService
+}
+
+// NewHBLNumberFormatController creates a new HBLNumberFormatController
+func NewHBLNumberFormatController(service services.HBLNumberingService) *HBLNumberFormatController {
+	return &HBLNumberFormatController{service: service}
+}
+
+// GetFormat handles GET /api/v1/hbl-number-format?forwarder_id=
+func (ctrl *HBLNumberFormatController) GetFormat(ctx *gin.Context) {
+	format, err := ctrl.service.GetFormat(ctx.Request.Context(), ctx.Query("forwarder_id"))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch HBL number format"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, format)
+}
+
+// SaveFormat handles PUT /api/v1/hbl-number-format
+func (ctrl *HBLNumberFormatController) SaveFormat(ctx *gin.Context) {
+	var format models.HBLNumberFormat
+	if err := ctx.ShouldBindJSON(&format); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := ctrl.service.SaveFormat(ctx.Request.Context(), format); err != nil {
+		if errors.Is(err, services.ErrInvalidHBLNumberFormat) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save HBL number format"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "HBL number format saved successfully"})
+}
+
+// PreviewFormat handles POST /api/v1/hbl-number-format/preview
+// Renders sample numbers for a format without saving it or consuming a sequence.
+func (ctrl *HBLNumberFormatController) PreviewFormat(ctx *gin.Context) {
+	var input struct {
+		Format    models.HBLNumberFormat `json:"format" binding:"required"`
+		MBLNumber string                 `json:"mbl_number"`
+		Count     int                    `json:"count"`
+	}
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if input.Count <= 0 {
+		input.Count = 3
+	}
+	if input.Count > maxFormatPreviewCount {
+		input.Count = maxFormatPreviewCount
+	}
+	if input.MBLNumber == "" {
+		input.MBLNumber = "MBLNUMBER"
+	}
+
+	numbers, err := ctrl.service.PreviewFormat(input.Format, input.MBLNumber, input.Count)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"numbers": numbers})
+}
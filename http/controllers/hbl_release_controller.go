@@ -0,0 +1,161 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"fs-backend/models/hbl_schema"
+	"fs-backend/services"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// HBLReleaseController exposes original bills, surrender, telex release and
+// sea waybill conversion
+type HBLReleaseController struct {
+	service       services.HBLReleaseService
+	pdfController *PdfGeneratorController
+}
+
+// NewHBLReleaseController creates a new HBLReleaseController
+func NewHBLReleaseController(service services.HBLReleaseService, pdfController *PdfGeneratorController) *HBLReleaseController {
+	return &HBLReleaseController{service: service, pdfController: pdfController}
+}
+
+// GetRelease handles GET /api/v1/hbl/:hbl_number/release
+func (ctrl *HBLReleaseController) GetRelease(ctx *gin.Context) {
+	result, err := ctrl.service.GetRelease(ctx.Request.Context(), ctx.Param("hbl_number"))
+	if err != nil {
+		respondReleaseError(ctx, err)
+		return
+	}
+
+	setETag(ctx, result.Revision)
+	ctx.JSON(http.StatusOK, result)
+}
+
+// IssueOriginals handles POST /api/v1/hbl/:hbl_number/originals
+// Body: {"count": 3, "actor": "..."}. Requires If-Match with the HBL's ETag.
+func (ctrl *HBLReleaseController) IssueOriginals(ctx *gin.Context) {
+	revision, ok := requireIfMatch(ctx)
+	if !ok {
+		return
+	}
+	var req hbl_schema.IssueOriginalsRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := ctrl.service.IssueOriginals(ctx.Request.Context(), ctx.Param("hbl_number"), req, revision)
+	ctrl.respond(ctx, result, err)
+}
+
+// SurrenderOriginals handles POST /api/v1/hbl/:hbl_number/originals/surrender
+// Body: {"serials": [...], "actor": "...", "place": "..."}. Requires If-Match.
+func (ctrl *HBLReleaseController) SurrenderOriginals(ctx *gin.Context) {
+	revision, ok := requireIfMatch(ctx)
+	if !ok {
+		return
+	}
+	var req hbl_schema.SurrenderOriginalsRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := ctrl.service.SurrenderOriginals(ctx.Request.Context(), ctx.Param("hbl_number"), req, revision)
+	ctrl.respond(ctx, result, err)
+}
+
+// PrintOriginals handles POST /api/v1/hbl/:hbl_number/originals/pdf?documentTo=
+// Generates one PDF per outstanding original, marked ORIGINAL with its serial.
+func (ctrl *HBLReleaseController) PrintOriginals(ctx *gin.Context) {
+	documentTo := strings.TrimSpace(ctx.Query("documentTo"))
+	if documentTo == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "documentTo query parameter is required"})
+		return
+	}
+
+	req, err := ctrl.service.OriginalsPdfRequest(ctx.Request.Context(), ctx.Param("hbl_number"))
+	if err != nil {
+		respondReleaseError(ctx, err)
+		return
+	}
+
+	ctrl.pdfController.generateAndSave(ctx, *req, documentTo)
+}
+
+// TelexRelease handles POST /api/v1/hbl/:hbl_number/telex-release
+// Body: {"actor": "...", "remarks": "..."}. Requires If-Match.
+func (ctrl *HBLReleaseController) TelexRelease(ctx *gin.Context) {
+	revision, ok := requireIfMatch(ctx)
+	if !ok {
+		return
+	}
+	var req hbl_schema.TelexReleaseRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := ctrl.service.TelexRelease(ctx.Request.Context(), ctx.Param("hbl_number"), req, revision)
+	ctrl.respond(ctx, result, err)
+}
+
+// GetTelexReleaseMessage handles GET /api/v1/hbl/:hbl_number/telex-release
+// Returns the telex release message as plain text.
+func (ctrl *HBLReleaseController) GetTelexReleaseMessage(ctx *gin.Context) {
+	message, err := ctrl.service.TelexReleaseMessage(ctx.Request.Context(), ctx.Param("hbl_number"))
+	if err != nil {
+		respondReleaseError(ctx, err)
+		return
+	}
+
+	ctx.String(http.StatusOK, message)
+}
+
+// ConvertToSeaWaybill handles POST /api/v1/hbl/:hbl_number/sea-waybill
+// Body: {"actor": "...", "reason": "..."}. Requires If-Match.
+func (ctrl *HBLReleaseController) ConvertToSeaWaybill(ctx *gin.Context) {
+	revision, ok := requireIfMatch(ctx)
+	if !ok {
+		return
+	}
+	var req hbl_schema.SeaWaybillRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := ctrl.service.ConvertToSeaWaybill(ctx.Request.Context(), ctx.Param("hbl_number"), req, revision)
+	ctrl.respond(ctx, result, err)
+}
+
+func (ctrl *HBLReleaseController) respond(ctx *gin.Context, result *hbl_schema.HBLReleaseResponse, err error) {
+	if err != nil {
+		respondReleaseError(ctx, err)
+		return
+	}
+
+	setETag(ctx, result.Revision)
+	ctx.JSON(http.StatusOK, result)
+}
+
+func respondReleaseError(ctx *gin.Context, err error) {
+	if respondRevisionConflict(ctx, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "HBL not found"})
+	case errors.Is(err, services.ErrInvalidOriginalsCount), errors.Is(err, services.ErrUnknownOriginal):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrHBLReleaseState), errors.Is(err, services.ErrHBLVersionConflict):
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,36 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"fs-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HBLVerificationController serves the public verification of signed HBL PDFs
+type HBLVerificationController struct {
+	service services.HBLVerificationService
+}
+
+// NewHBLVerificationController creates a new HBLVerificationController
+func NewHBLVerificationController(service services.HBLVerificationService) *HBLVerificationController {
+	return &HBLVerificationController{service: service}
+}
+
+// Verify handles GET /verify/:token. It is unauthenticated so that anyone
+// holding a printed HBL can check it.
+func (ctrl *HBLVerificationController) Verify(ctx *gin.Context) {
+	result, err := ctrl.service.Verify(ctx.Request.Context(), ctx.Param("token"))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidVerificationToken) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Verification token is invalid or unknown", "valid": false})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify HBL"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,114 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"fs-backend/services"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// HBLVersionController exposes the version history of HBLs
+type HBLVersionController struct {
+	service       services.HBLVersionService
+	pdfController *PdfGeneratorController
+}
+
+// NewHBLVersionController creates a new HBLVersionController
+func NewHBLVersionController(service services.HBLVersionService, pdfController *PdfGeneratorController) *HBLVersionController {
+	return &HBLVersionController{service: service, pdfController: pdfController}
+}
+
+// ListVersions handles GET /api/v1/hbl/:hbl_number/versions
+func (ctrl *HBLVersionController) ListVersions(ctx *gin.Context) {
+	hblNumber := ctx.Param("hbl_number")
+	versions, err := ctrl.service.ListVersions(ctx.Request.Context(), hblNumber)
+	if err != nil {
+		respondVersionError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"hbl_number": hblNumber, "versions": versions})
+}
+
+// GetVersion handles GET /api/v1/hbl/:hbl_number/versions/:version
+func (ctrl *HBLVersionController) GetVersion(ctx *gin.Context) {
+	version, ok := parseVersionParam(ctx, ctx.Param("version"), "version")
+	if !ok {
+		return
+	}
+
+	result, err := ctrl.service.GetVersion(ctx.Request.Context(), ctx.Param("hbl_number"), version)
+	if err != nil {
+		respondVersionError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// Diff handles GET /api/v1/hbl/:hbl_number/diff?from=1&to=2
+func (ctrl *HBLVersionController) Diff(ctx *gin.Context) {
+	from, ok := parseVersionParam(ctx, ctx.Query("from"), "from")
+	if !ok {
+		return
+	}
+	to, ok := parseVersionParam(ctx, ctx.Query("to"), "to")
+	if !ok {
+		return
+	}
+
+	result, err := ctrl.service.Diff(ctx.Request.Context(), ctx.Param("hbl_number"), from, to)
+	if err != nil {
+		respondVersionError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// RegeneratePDF handles POST /api/v1/hbl/:hbl_number/versions/:version/pdf?documentTo=
+// Generates the PDF of a historical version through the pdf-generator.
+func (ctrl *HBLVersionController) RegeneratePDF(ctx *gin.Context) {
+	documentTo := strings.TrimSpace(ctx.Query("documentTo"))
+	if documentTo == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "documentTo query parameter is required"})
+		return
+	}
+	version, ok := parseVersionParam(ctx, ctx.Param("version"), "version")
+	if !ok {
+		return
+	}
+
+	req, err := ctrl.service.PdfRequest(ctx.Request.Context(), ctx.Param("hbl_number"), version)
+	if err != nil {
+		respondVersionError(ctx, err)
+		return
+	}
+
+	ctrl.pdfController.generateAndSave(ctx, *req, documentTo)
+}
+
+func parseVersionParam(ctx *gin.Context, value, name string) (int, bool) {
+	version, err := strconv.Atoi(value)
+	if err != nil || version < 1 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": name + " must be a positive version number"})
+		return 0, false
+	}
+	return version, true
+}
+
+func respondVersionError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "HBL not found"})
+	case errors.Is(err, services.ErrHBLVersionNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
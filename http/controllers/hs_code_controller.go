@@ -0,0 +1,59 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"fs-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HSCodeController handles HS code validation and suggestion endpoints
+type HSCodeController struct {
+	service services.HSCodeService
+}
+
+// NewHSCodeController creates a new HSCodeController
+func NewHSCodeController(service services.HSCodeService) *HSCodeController {
+	return &HSCodeController{service: service}
+}
+
+// Validate handles GET /api/v1/hs-codes/validate?code=&description=
+func (ctrl *HSCodeController) Validate(ctx *gin.Context) {
+	code := strings.TrimSpace(ctx.Query("code"))
+	if code == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "code is required"})
+		return
+	}
+
+	result := ctrl.service.Validate(ctx.Request.Context(), code, ctx.Query("description"))
+	ctx.JSON(http.StatusOK, result)
+}
+
+// Suggest handles GET /api/v1/hs-codes/suggest?description=&limit=
+// Proposes HS codes for a goods description by keyword matching.
+func (ctrl *HSCodeController) Suggest(ctx *gin.Context) {
+	description := strings.TrimSpace(ctx.Query("description"))
+	if description == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "description is required"})
+		return
+	}
+
+	limit := 5
+	if raw := ctx.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return
+		}
+		limit = parsed
+	}
+
+	suggestions := ctrl.service.Suggest(ctx.Request.Context(), description, limit)
+	ctx.JSON(http.StatusOK, gin.H{
+		"description": description,
+		"suggestions": suggestions,
+	})
+}
@@ -0,0 +1,45 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"fs-backend/models"
+	"fs-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LoadPlanController proposes container load plans for consolidations
+type LoadPlanController struct {
+	service services.LoadPlanningService
+}
+
+// NewLoadPlanController creates a new LoadPlanController
+func NewLoadPlanController(service services.LoadPlanningService) *LoadPlanController {
+	return &LoadPlanController{service: service}
+}
+
+// PlanLoad handles POST /api/booking/loadplan
+// Body: {"shipment_ids": [...], "container_types": [{"type": "40HC"}, {"type": "20GP", "max_volume_cbm": 30}], "forwarder_id": "FWD001"}
+// Returns the proposed containers with their shipments and utilisation; the
+// shipment_ids of each container can be sent to /api/booking/syncBooking.
+func (ctrl *LoadPlanController) PlanLoad(ctx *gin.Context) {
+	var input models.LoadPlanRequest
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	plan, err := ctrl.service.PlanLoad(ctx.Request.Context(), input)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidLoadPlan) || errors.Is(err, services.ErrInvalidBookingMode) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to plan load"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, plan)
+}
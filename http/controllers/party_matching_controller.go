@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"fs-backend/services"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// PartyMatchingController handles shipper matching endpoints for MBL parties
+type PartyMatchingController struct {
+	service services.PartyMatchingService
+}
+
+// NewPartyMatchingController creates a new PartyMatchingController
+func NewPartyMatchingController(service services.PartyMatchingService) *PartyMatchingController {
+	return &PartyMatchingController{service: service}
+}
+
+// GetPartyMatches handles GET /api/v1/mbl/:mbl_number/party-matches
+func (ctrl *PartyMatchingController) GetPartyMatches(ctx *gin.Context) {
+	mblNumber := ctx.Param("mbl_number")
+
+	result, err := ctrl.service.MatchMBLParties(ctx.Request.Context(), mblNumber)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// CreateShipperFromParty handles POST /api/v1/mbl/:mbl_number/parties/:party/shipper
+// Pass ?force=true to create the shipper even when a near-identical one exists.
+func (ctrl *PartyMatchingController) CreateShipperFromParty(ctx *gin.Context) {
+	mblNumber := ctx.Param("mbl_number")
+	party := ctx.Param("party")
+	force := ctx.Query("force") == "true"
+
+	shipper, existing, err := ctrl.service.CreateShipperFromParty(ctx.Request.Context(), mblNumber, party, force)
+	if err != nil {
+		switch {
+		case errors.Is(err, mongo.ErrNoDocuments):
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrUnknownParty), errors.Is(err, services.ErrPartyNameMissing):
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrShipperAlreadyExists):
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error(), "existing": existing})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create shipper"})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{
+		"message": "Shipper created successfully",
+		"shipper": shipper,
+	})
+}
@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"fs-backend/models"
 	"fs-backend/services"
@@ -13,12 +14,15 @@ import (
 )
 
 type PdfGeneratorController struct {
-	service        services.PdfGeneratorService
-	saveController *PdfSaveController
+	service               services.PdfGeneratorService
+	saveController        *PdfSaveController
+	reconciliationService services.ReconciliationService
+	releaseService        services.HBLReleaseService
+	verificationService   services.HBLVerificationService
 }
 
-func NewPdfGeneratorController(service services.PdfGeneratorService, saveController *PdfSaveController) *PdfGeneratorController {
-	return &PdfGeneratorController{service: service, saveController: saveController}
+func NewPdfGeneratorController(service services.PdfGeneratorService, saveController *PdfSaveController, reconciliationService services.ReconciliationService, releaseService services.HBLReleaseService, verificationService services.HBLVerificationService) *PdfGeneratorController {
+	return &PdfGeneratorController{service: service, saveController: saveController, reconciliationService: reconciliationService, releaseService: releaseService, verificationService: verificationService}
 }
 
 func (c *PdfGeneratorController) Generate(ctx *gin.Context) {
@@ -36,6 +40,41 @@ func (c *PdfGeneratorController) Generate(ctx *gin.Context) {
 
 	log.Printf("pdf-generator request received: mbl_number=%s total_count=%d hbl_count=%d documentTo=%s", req.MBLNumber, req.TotalCount, len(req.HBLList), documentTo)
 
+	// ORIGINAL / COPY / TELEX RELEASED markings come from the stored HBLs, not the client
+	if err := c.releaseService.MarkPdfRequest(ctx.Request.Context(), &req); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.generateAndSave(ctx, req, documentTo)
+}
+
+// generateAndSave forwards the request to the pdf-generator, stores the uploaded
+// files and writes the response
+func (c *PdfGeneratorController) generateAndSave(ctx *gin.Context, req models.PdfGenerationRequest, documentTo string) {
+	if req.MBLNumber != "" {
+		report, err := c.reconciliationService.CheckPDFGeneration(ctx.Request.Context(), req.MBLNumber)
+		if errors.Is(err, services.ErrReconciliationBlocked) {
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error(), "reconciliation": report})
+			return
+		}
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reconcile MBL"})
+			return
+		}
+	}
+
+	// Embeds the verification URLs in the payload before the PDFs are rendered
+	verifications, err := c.verificationService.Prepare(ctx.Request.Context(), &req)
+	if errors.Is(err, services.ErrHBLPayloadMismatch) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
 	result, err := c.service.Generate(ctx.Request.Context(), req, documentTo)
 	if err != nil {
 		ctx.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
@@ -57,14 +96,24 @@ func (c *PdfGeneratorController) Generate(ctx *gin.Context) {
 		return
 	}
 
+	hblNumbers := make([]string, 0, len(req.HBLList))
+	for _, hbl := range req.HBLList {
+		hblNumbers = append(hblNumbers, hbl.SeaWaybillNo)
+	}
 	saveResult, err := c.saveController.Save(ctx.Request.Context(), models.PdfSaveRequest{
 		UploadedFiles: uploadResponse.UploadedFiles,
+		HBLNumbers:    hblNumbers,
 	})
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	// The PDFs are already stored, so a failed verification record is only logged
+	if err := c.verificationService.Record(ctx.Request.Context(), verifications, uploadResponse.UploadedFiles); err != nil {
+		log.Printf("Warning: failed to record HBL verifications: %v", err)
+	}
+
 	ctx.JSON(http.StatusCreated, gin.H{
 		"success":       uploadResponse.Success,
 		"message":       uploadResponse.Message,
@@ -0,0 +1,36 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"fs-backend/services"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ReconciliationController serves MBL/HBL reconciliation reports
+type ReconciliationController struct {
+	service services.ReconciliationService
+}
+
+// NewReconciliationController creates a new ReconciliationController
+func NewReconciliationController(service services.ReconciliationService) *ReconciliationController {
+	return &ReconciliationController{service: service}
+}
+
+// GetReport handles GET /api/v1/mbl/:mbl_number/reconciliation
+func (ctrl *ReconciliationController) GetReport(ctx *gin.Context) {
+	report, err := ctrl.service.Reconcile(ctx.Request.Context(), ctx.Param("mbl_number"))
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "MBL not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reconcile MBL"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,49 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"fs-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setETag exposes a document revision as a strong ETag
+func setETag(ctx *gin.Context, revision int64) {
+	ctx.Header("ETag", fmt.Sprintf("%q", strconv.FormatInt(revision, 10)))
+}
+
+// requireIfMatch reads the revision an update is based on from the If-Match
+// header. It responds 428 when the header is missing and 400 when it does not
+// hold a revision ETag.
+func requireIfMatch(ctx *gin.Context) (int64, bool) {
+	value := strings.TrimSpace(ctx.GetHeader("If-Match"))
+	if value == "" {
+		ctx.JSON(http.StatusPreconditionRequired, gin.H{"error": "If-Match header with the current ETag is required"})
+		return 0, false
+	}
+
+	value = strings.Trim(strings.TrimPrefix(value, "W/"), `"`)
+	revision, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || revision < 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "If-Match must be an ETag returned by the API"})
+		return 0, false
+	}
+	return revision, true
+}
+
+// respondRevisionConflict answers 412 with the current document when err is a
+// revision conflict, and reports whether it did
+func respondRevisionConflict(ctx *gin.Context, err error) bool {
+	var conflict *services.RevisionConflictError
+	if !errors.As(err, &conflict) {
+		return false
+	}
+	setETag(ctx, conflict.Revision)
+	ctx.JSON(http.StatusPreconditionFailed, gin.H{"error": conflict.Error(), "current": conflict.Current})
+	return true
+}
@@ -1,11 +1,14 @@
 package controllers
 
 import (
+	"errors"
+	"fmt"
 	"fs-backend/repository"
 	"fs-backend/services"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type ShipmentController struct {
@@ -18,19 +21,45 @@ func NewShipmentController(shipmentService services.ShipmentService) *ShipmentCo
 	}
 }
 
+// shipmentInput is the request body of a shipment. The desired delivery date
+// is read as text so plain dates as well as timestamps are accepted.
+type shipmentInput struct {
+	repository.ShipmentDocument
+	DesiredDeliveryDate string `json:"desired_delivery_date"`
+}
+
+func (in *shipmentInput) document() (*repository.ShipmentDocument, error) {
+	date, err := services.ParseScheduleDate(in.DesiredDeliveryDate)
+	if err != nil {
+		return nil, fmt.Errorf("desired_delivery_date: %v", err)
+	}
+	doc := in.ShipmentDocument
+	doc.DesiredDeliveryDate = date
+	return &doc, nil
+}
+
 func (c *ShipmentController) CreateShipment(ctx *gin.Context) {
-	var input repository.ShipmentDocument
+	var input shipmentInput
 	if err := ctx.ShouldBindJSON(&input); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	doc, err := input.document()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	id, err := c.shipmentService.InsertShipment(ctx.Request.Context(), &input)
+	id, err := c.shipmentService.InsertShipment(ctx.Request.Context(), doc)
 	if err != nil {
 		if err.Error() == "Invalid shipper id" || err.Error() == "shipper ID is required" {
 			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Enter valid shipper id"})
 			return
 		}
+		if errors.Is(err, services.ErrInvalidBookingMode) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create shipment"})
 		return
 	}
@@ -41,8 +70,15 @@ func (c *ShipmentController) CreateShipment(ctx *gin.Context) {
 	})
 }
 
+// GetShipmentList handles GET /api/booking/shipments?delivery_from=&delivery_to=
+// The range filters on the desired delivery date (YYYY-MM-DD or RFC 3339).
 func (c *ShipmentController) GetShipmentList(ctx *gin.Context) {
-	shipments, err := c.shipmentService.GetAllShipments(ctx.Request.Context())
+	delivery, ok := bindDateRange(ctx, "delivery_from", "delivery_to")
+	if !ok {
+		return
+	}
+
+	shipments, err := c.shipmentService.GetAllShipments(ctx.Request.Context(), repository.ShipmentFilter{DesiredDelivery: delivery})
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch shipments"})
 		return
@@ -53,19 +89,40 @@ func (c *ShipmentController) GetShipmentList(ctx *gin.Context) {
 
 func (c *ShipmentController) UpdateShipment(ctx *gin.Context) {
 	id := ctx.Param("id")
+	revision, ok := requireIfMatch(ctx)
+	if !ok {
+		return
+	}
 
-	var updates repository.ShipmentDocument
-	if err := ctx.ShouldBindJSON(&updates); err != nil {
+	var input shipmentInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	updates, err := input.document()
+	if err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	err := c.shipmentService.UpdateShipment(ctx.Request.Context(), id, &updates)
+	err = c.shipmentService.UpdateShipment(ctx.Request.Context(), id, updates, revision)
 	if err != nil {
+		if respondRevisionConflict(ctx, err) {
+			return
+		}
+		if errors.Is(err, services.ErrInvalidSchedule) || errors.Is(err, services.ErrInvalidBookingMode) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Shipment not found"})
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update shipment"})
 		return
 	}
 
+	setETag(ctx, revision+1)
 	ctx.JSON(http.StatusOK, gin.H{"message": "Shipment updated successfully"})
 }
 
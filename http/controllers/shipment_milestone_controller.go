@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"fs-backend/models"
+	"fs-backend/services"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ShipmentMilestoneController handles the milestone timeline of shipments
+type ShipmentMilestoneController struct {
+	service services.ShipmentMilestoneService
+}
+
+// NewShipmentMilestoneController creates a new ShipmentMilestoneController
+func NewShipmentMilestoneController(service services.ShipmentMilestoneService) *ShipmentMilestoneController {
+	return &ShipmentMilestoneController{service: service}
+}
+
+// RecordMilestone handles POST /api/booking/shipments/:id/milestones
+// The X-User header is stored as the milestone's recorder; answers 422 with
+// field_errors when the milestone is invalid.
+func (ctrl *ShipmentMilestoneController) RecordMilestone(ctx *gin.Context) {
+	var req models.RecordMilestoneRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	milestone, err := ctrl.service.RecordMilestone(ctx.Request.Context(), ctx.Param("id"), req, ctx.GetHeader("X-User"))
+	if err != nil {
+		var validationErr *services.MilestoneValidationError
+		switch {
+		case errors.As(err, &validationErr):
+			ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "field_errors": validationErr.Errors})
+		case errors.Is(err, mongo.ErrNoDocuments):
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Shipment not found"})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record milestone"})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, milestone)
+}
+
+// ListMilestones handles GET /api/booking/shipments/:id/milestones
+func (ctrl *ShipmentMilestoneController) ListMilestones(ctx *gin.Context) {
+	timeline, err := ctrl.service.GetTimeline(ctx.Request.Context(), ctx.Param("id"))
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Shipment not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch milestones"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, timeline)
+}
@@ -0,0 +1,93 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+
+	"fs-backend/models"
+	"fs-backend/services"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// StuffingPlanController manages the stuffing plans of LCL consolidations
+type StuffingPlanController struct {
+	service services.StuffingPlanService
+}
+
+// NewStuffingPlanController creates a new StuffingPlanController
+func NewStuffingPlanController(service services.StuffingPlanService) *StuffingPlanController {
+	return &StuffingPlanController{service: service}
+}
+
+// GetPlan handles GET /api/v1/mbl/:mbl_number/stuffing-plan
+func (ctrl *StuffingPlanController) GetPlan(ctx *gin.Context) {
+	result, err := ctrl.service.GetPlan(ctx.Request.Context(), ctx.Param("mbl_number"))
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Stuffing plan not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch stuffing plan"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// SavePlan handles PUT /api/v1/mbl/:mbl_number/stuffing-plan
+// Replaces the plan; answers 422 with field_errors when a container would be
+// overloaded or an allocation does not add up.
+func (ctrl *StuffingPlanController) SavePlan(ctx *gin.Context) {
+	var plan models.StuffingPlan
+	if err := ctx.ShouldBindJSON(&plan); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	plan.MBLNumber = ctx.Param("mbl_number")
+
+	result, err := ctrl.service.SavePlan(ctx.Request.Context(), plan)
+	if err != nil {
+		var validationErr *services.StuffingPlanValidationError
+		switch {
+		case errors.As(err, &validationErr):
+			ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "field_errors": validationErr.Errors})
+		case errors.Is(err, services.ErrMBLNotFound):
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save stuffing plan"})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// DeletePlan handles DELETE /api/v1/mbl/:mbl_number/stuffing-plan
+func (ctrl *StuffingPlanController) DeletePlan(ctx *gin.Context) {
+	err := ctrl.service.DeletePlan(ctx.Request.Context(), ctx.Param("mbl_number"))
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Stuffing plan not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete stuffing plan"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Stuffing plan deleted successfully"})
+}
+
+// ListContainerTypes handles GET /api/v1/container-types
+// Lists the ISO container types and the capacities plans are checked against.
+func (ctrl *StuffingPlanController) ListContainerTypes(ctx *gin.Context) {
+	types := make([]models.ContainerCapacity, 0, len(models.ContainerCapacities))
+	for _, capacity := range models.ContainerCapacities {
+		types = append(types, capacity)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i].ISOType < types[j].ISOType })
+
+	ctx.JSON(http.StatusOK, types)
+}
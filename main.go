@@ -1,13 +1,22 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fs-backend/config"
 	"fs-backend/connections"
 	"fs-backend/http/controllers"
+	"fs-backend/models"
 	"fs-backend/repository"
 	"fs-backend/routes"
 	"fs-backend/services"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -21,6 +30,24 @@ func main() {
 	mongoURI := config.GetString("mongo.uri")
 	mongoDBName := config.GetString("mongo.database")
 	extractionBaseURL := config.GetString("extraction_service.base_url")
+	reconciliationTolerances := models.ReconciliationTolerances{
+		Packages:             config.GetInt("reconciliation.package_tolerance"),
+		WeightPercent:        config.GetFloat64("reconciliation.weight_tolerance_percent"),
+		VolumePercent:        config.GetFloat64("reconciliation.volume_tolerance_percent"),
+		BlockPDFOnMismatches: config.GetBool("reconciliation.block_pdf_generation"),
+	}
+	verificationBaseURL := config.GetString("verification.base_url")
+	verificationIssuer := config.GetString("verification.issuer")
+	verificationKey, err := services.ParseEd25519SigningKey(config.GetString("verification.signing_key"))
+	if err != nil {
+		log.Printf("Warning: HBL PDF verification disabled: %v", err)
+	}
+	statusWatchDir := config.GetString("status_ingestion.watch_dir")
+	statusPollInterval := time.Duration(config.GetInt("status_ingestion.poll_interval_seconds")) * time.Second
+	if statusPollInterval <= 0 {
+		statusPollInterval = 30 * time.Second
+	}
+	statusEventMapping := config.GetStringMapString("status_ingestion.event_status")
 
 	// 2. Initialize MongoDB
 	db := connections.ConnectMongo(mongoURI, mongoDBName)
@@ -34,20 +61,122 @@ func main() {
 	shipmentRepo := repository.NewShipmentRepository(db)
 	shipperRepo := repository.NewShipperRepository(db)
 	forwarderRepo := repository.NewForwarderRepository(db)
+	hsCodeRepo := repository.NewHSCodeRepository(db)
+	counterRepo := repository.NewCounterRepository(db)
+	hblNumberFormatRepo := repository.NewHBLNumberFormatRepository(db)
+	idempotencyRepo := repository.NewIdempotencyRepository(db)
+	hblVersionRepo := repository.NewHBLVersionRepository(db)
+	stuffingPlanRepo := repository.NewStuffingPlanRepository(db)
+	hblVerificationRepo := repository.NewHBLVerificationRepository(db)
+	containerEventRepo := repository.NewContainerEventRepository(db)
+	shipmentMilestoneRepo := repository.NewShipmentMilestoneRepository(db)
+	bookingModeRuleRepo := repository.NewBookingModeRuleRepository(db)
+	carrierRepo := repository.NewCarrierRepository(db)
+	txRunner := repository.NewTxRunner(db)
+
+	// Bookings and shipments with string schedule dates cannot be decoded, so
+	// they are converted before anything reads them
+	results, err := services.MigrateScheduleDates(context.Background(), repository.NewDateMigrationRepository(db), false)
+	for _, result := range results {
+		if result.Converted+result.Cleared+len(result.Unparsed) > 0 {
+			log.Printf("Migrated %s schedule dates: %d converted, %d empty cleared, %d unparsed", result.Collection, result.Converted, result.Cleared, len(result.Unparsed))
+		}
+	}
+	if err != nil {
+		log.Fatalf("Failed to migrate schedule dates: %v", err)
+	}
+	if n, err := hblRepo.BackfillMBLNumbers(context.Background()); err != nil {
+		log.Fatalf("Failed to backfill HBL MBL numbers: %v", err)
+	} else if n > 0 {
+		log.Printf("Backfilled the MBL number of %d legacy HBLs", n)
+	}
+	if n, err := hblRepo.BackfillStandalone(context.Background()); err != nil {
+		log.Fatalf("Failed to flag standalone HBLs: %v", err)
+	} else if n > 0 {
+		log.Printf("Flagged %d standalone HBLs", n)
+	}
+	if n, err := hblRepo.RestoreSeaWaybillNumbers(context.Background()); err != nil {
+		log.Fatalf("Failed to restore HBL sea waybill numbers: %v", err)
+	} else if n > 0 {
+		log.Printf("Restored the sea waybill number of %d HBLs", n)
+	}
+	// The unique HBL indexes back HBL numbering and idempotent HBL creation,
+	// so the server does not start without them
+	if err := hblRepo.EnsureIndexes(context.Background()); err != nil {
+		log.Fatalf("Failed to create HBL indexes (resolve duplicate HBL numbers or MBL/shipment pairs and restart): %v", err)
+	}
+	if n, err := mblRepo.BackfillContainerNumbers(context.Background()); err != nil {
+		log.Fatalf("Failed to backfill MBL container numbers: %v", err)
+	} else if n > 0 {
+		log.Printf("Backfilled the container numbers of %d MBLs", n)
+	}
+	if err := mblRepo.EnsureIndexes(context.Background()); err != nil {
+		log.Printf("Warning: failed to create MBL indexes: %v", err)
+	}
+	if err := hblDocRepo.EnsureIndexes(context.Background()); err != nil {
+		log.Printf("Warning: failed to create HBL_Doc indexes: %v", err)
+	}
+	if err := idempotencyRepo.EnsureIndexes(context.Background()); err != nil {
+		log.Printf("Warning: failed to create idempotency key indexes: %v", err)
+	}
+	if err := hblVersionRepo.EnsureIndexes(context.Background()); err != nil {
+		log.Printf("Warning: failed to create HBL version indexes: %v", err)
+	}
+	if err := stuffingPlanRepo.EnsureIndexes(context.Background()); err != nil {
+		log.Printf("Warning: failed to create stuffing plan indexes: %v", err)
+	}
+	if err := hblVerificationRepo.EnsureIndexes(context.Background()); err != nil {
+		log.Printf("Warning: failed to create HBL verification indexes: %v", err)
+	}
+	if err := containerEventRepo.EnsureIndexes(context.Background()); err != nil {
+		log.Printf("Warning: failed to create container event indexes: %v", err)
+	}
+	// Shipper IDs drawn from the sequence rely on the unique index to detect
+	// IDs already taken
+	if err := shipperRepo.EnsureIndexes(context.Background()); err != nil {
+		log.Fatalf("Failed to create shipper indexes (resolve duplicate shipper IDs and restart): %v", err)
+	}
+	// Booking sync relies on the unique booking indexes to keep shipments and
+	// MBLs on a single booking
+	if err := bookingRepo.EnsureIndexes(context.Background()); err != nil {
+		log.Fatalf("Failed to create booking indexes (resolve shipments or MBLs on several bookings and restart): %v", err)
+	}
+	if err := shipmentMilestoneRepo.EnsureIndexes(context.Background()); err != nil {
+		log.Printf("Warning: failed to create shipment milestone indexes: %v", err)
+	}
+	if err := carrierRepo.EnsureIndexes(context.Background()); err != nil {
+		log.Printf("Warning: failed to create carrier indexes: %v", err)
+	}
 
 	// 4. Initialize Services (Manual DI)
 	pdfService := services.NewPdfGeneratorService(pdfBaseURL)
 	pdfSaveService := services.NewPdfSaveService(hblDocRepo)
+	partyMatchingService := services.NewPartyMatchingService(mblRepo, shipperRepo, counterRepo)
+	hsCodeService := services.NewHSCodeService(hsCodeRepo)
+	hblNumberingService := services.NewHBLNumberingService(hblNumberFormatRepo, counterRepo, hblRepo)
+	hblVersionService := services.NewHBLVersionService(hblRepo, hblVersionRepo, txRunner)
 	docConvertService := services.NewDocumentConvertService(
-		extractionBaseURL, mblRepo, mblCacheRepo, bookingRepo, shipmentRepo, shipperRepo,
+		extractionBaseURL, mblRepo, mblCacheRepo, bookingRepo, shipmentRepo, shipperRepo, partyMatchingService, hsCodeService, carrierRepo,
 	)
 	docPreviewService := services.NewDocumentPreviewService(
-		mblRepo, hblRepo, shipmentRepo, shipperRepo, mblCacheRepo,
+		mblRepo, hblRepo, shipmentRepo, shipperRepo, mblCacheRepo, hsCodeService, hblNumberingService, idempotencyRepo, hblVersionService, stuffingPlanRepo,
 	)
-	bookingService := services.NewBookingService(shipperRepo, bookingRepo, shipmentRepo)
-	shipmentService := services.NewShipmentService(shipmentRepo, bookingRepo, shipperRepo)
+	hblService := services.NewHBLService(hblRepo, hblDocRepo, hblVersionRepo, hblVerificationRepo, txRunner)
+	documentExchangeService := services.NewDocumentExchangeService(mblRepo, hblRepo, bookingRepo, shipmentRepo, partyMatchingService, hsCodeService, carrierRepo, bookingModeRuleRepo, txRunner)
+	stuffingPlanService := services.NewStuffingPlanService(stuffingPlanRepo, mblRepo, shipmentRepo, bookingRepo, reconciliationTolerances)
+	reconciliationService := services.NewReconciliationService(mblRepo, hblRepo, hblDocRepo, bookingRepo, reconciliationTolerances)
+	hblLifecycleService := services.NewHBLLifecycleService(hblRepo, hsCodeService, hblVersionService)
+	hblReleaseService := services.NewHBLReleaseService(hblRepo, hblVersionService)
+	hblVerificationService := services.NewHBLVerificationService(hblVerificationRepo, hblRepo, hblVersionRepo, verificationKey, verificationBaseURL, verificationIssuer)
+	bookingService := services.NewBookingService(shipperRepo, bookingRepo, shipmentRepo, bookingModeRuleRepo, carrierRepo, txRunner, counterRepo)
+	shipmentService := services.NewShipmentService(shipmentRepo, bookingRepo, shipperRepo, shipmentMilestoneRepo, bookingModeRuleRepo)
 	dashboardService := services.NewDashboardService(hblDocRepo, hblRepo)
 	forwarderService := services.NewForwarderService(forwarderRepo)
+	shipmentMilestoneService := services.NewShipmentMilestoneService(shipmentMilestoneRepo, shipmentRepo, bookingRepo)
+	bookingModeService := services.NewBookingModeService(bookingModeRuleRepo)
+	loadPlanningService := services.NewLoadPlanningService(shipmentRepo, bookingRepo, bookingModeRuleRepo)
+	carrierService := services.NewCarrierService(carrierRepo)
+	containerStatusService := services.NewContainerStatusService(containerEventRepo, bookingRepo, mblRepo, statusEventMapping)
 
 	// Initialize Controllers
 	bookingController := controllers.NewBookingController(bookingService)
@@ -57,6 +186,18 @@ func main() {
 	infoToDocRepo := repository.NewInfoToDocRepository(db)
 	infoToDocService := services.NewInfoToDocService(infoToDocRepo, hblDocRepo, pdfService)
 	infoToDocController := controllers.NewInfoToDocController(infoToDocService)
+	partyMatchingController := controllers.NewPartyMatchingController(partyMatchingService)
+	hsCodeController := controllers.NewHSCodeController(hsCodeService)
+	hblNumberFormatController := controllers.NewHBLNumberFormatController(hblNumberingService)
+	hblLifecycleController := controllers.NewHBLLifecycleController(hblLifecycleService)
+	hblController := controllers.NewHBLController(hblService)
+	stuffingPlanController := controllers.NewStuffingPlanController(stuffingPlanService)
+	documentExchangeController := controllers.NewDocumentExchangeController(documentExchangeService)
+	containerStatusController := controllers.NewContainerStatusController(containerStatusService)
+	shipmentMilestoneController := controllers.NewShipmentMilestoneController(shipmentMilestoneService)
+	bookingModeController := controllers.NewBookingModeController(bookingModeService)
+	loadPlanController := controllers.NewLoadPlanController(loadPlanningService)
+	carrierController := controllers.NewCarrierController(carrierService)
 
 	// 5. Initialize Router
 	r := gin.Default()
@@ -67,11 +208,26 @@ func main() {
 		"https://freightdocs-one.vercel.app",
 	}
 	corsConfig.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"}
-	corsConfig.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type"}
+	corsConfig.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Idempotency-Key", "X-User", "X-Change-Reason", "If-Match"}
+	corsConfig.ExposeHeaders = []string{"ETag"}
 	r.Use(cors.New(corsConfig))
 
 	// 6. Register Routes
-	routes.RegisterRoutes(r, pdfService, pdfSaveService, docConvertService, docPreviewService, bookingController, shipmentController, dashboardController, authController, infoToDocController)
+	routes.RegisterRoutes(r, pdfService, pdfSaveService, docConvertService, docPreviewService, bookingController, shipmentController, dashboardController, authController, infoToDocController, partyMatchingController, hsCodeController, hblNumberFormatController, hblLifecycleController, hblVersionService, hblController, reconciliationService, stuffingPlanController, hblReleaseService, hblVerificationService, documentExchangeController, containerStatusController, shipmentMilestoneController, bookingModeController, loadPlanController, carrierController)
+
+	// Background work stops on SIGINT/SIGTERM, before the server shuts down
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Container status messages dropped into a local directory
+	var watchers sync.WaitGroup
+	if statusWatchDir != "" {
+		watchers.Add(1)
+		go func() {
+			defer watchers.Done()
+			services.WatchStatusDirectory(ctx, containerStatusService, statusWatchDir, statusPollInterval)
+		}()
+	}
 
 	// Health check endpoint
 	r.GET("/health", func(c *gin.Context) {
@@ -79,8 +235,21 @@ func main() {
 	})
 
 	// 7. Start Server
-	log.Println("Server starting on " + port)
-	if err := r.Run(port); err != nil {
-		log.Fatal(err)
+	server := &http.Server{Addr: port, Handler: r}
+	go func() {
+		log.Println("Server starting on " + port)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+
+	<-ctx.Done()
+	stop()
+	log.Println("Shutting down")
+	watchers.Wait()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Warning: server shutdown: %v", err)
 	}
 }
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Booking modes every forwarder starts with
+const (
+	BookingModeFCL       = "FCL"
+	BookingModeLCL       = "LCL"
+	BookingModeBreakbulk = "BREAKBULK"
+	BookingModeRoRo      = "RORO"
+	BookingModeAir       = "AIR"
+)
+
+// BookingModeRule limits what one booking of a mode can hold. Zero limits
+// are unlimited and an empty AllowedCargoTypes accepts any cargo type.
+type BookingModeRule struct {
+	Mode              string   `bson:"mode" json:"mode"`
+	Description       string   `bson:"description,omitempty" json:"description,omitempty"`
+	MaxShipments      int      `bson:"max_shipments" json:"max_shipments"`
+	MaxWeightKg       float64  `bson:"max_weight_kg" json:"max_weight_kg"`   // total gross weight
+	MaxVolumeCbm      float64  `bson:"max_volume_cbm" json:"max_volume_cbm"` // total volume
+	AllowedCargoTypes []string `bson:"allowed_cargo_types,omitempty" json:"allowed_cargo_types,omitempty"`
+}
+
+// BookingModeRules is the set of modes a forwarder books in
+// ("booking_mode_rules" collection). Shipments and bookings may only use
+// the modes listed.
+type BookingModeRules struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	ForwarderID string             `bson:"forwarder_id" json:"forwarder_id"`
+	Rules       []BookingModeRule  `bson:"rules" json:"rules"`
+	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// DefaultBookingModeRules keeps the historical FCL (one shipment) and LCL (at
+// most five shipments) limits and adds breakbulk, Ro-Ro and air freight
+func DefaultBookingModeRules() BookingModeRules {
+	return BookingModeRules{
+		Rules: []BookingModeRule{
+			{Mode: BookingModeFCL, Description: "Full container load", MaxShipments: 1},
+			{Mode: BookingModeLCL, Description: "Less than container load", MaxShipments: 5},
+			{Mode: BookingModeBreakbulk, Description: "Break bulk cargo"},
+			{Mode: BookingModeRoRo, Description: "Roll-on/roll-off"},
+			{Mode: BookingModeAir, Description: "Air freight"},
+		},
+	}
+}
@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// Booking shipment changes, recorded on each booking involved
+const (
+	BookingShipmentsMovedIn   = "moved_in"
+	BookingShipmentsMovedOut  = "moved_out"
+	BookingShipmentsSplitFrom = "split_from" // on the new booking of a split
+	BookingShipmentsSplitOut  = "split_out"
+	BookingShipmentsMergedIn  = "merged_in"
+	BookingShipmentsMergedOut = "merged_out"
+	BookingShipmentsReleased  = "released" // cancellation, back to "yet to sync"
+)
+
+// CancelReasonMerged is set on a booking whose shipments were merged into another
+const CancelReasonMerged = "MERGED"
+
+// BookingShipmentChange is one entry of a booking's shipment history
+type BookingShipmentChange struct {
+	Action      string    `bson:"action" json:"action"` // see BookingShipments*
+	ShipmentIDs []string  `bson:"shipment_ids" json:"shipment_ids"`
+	MBLNumber   string    `bson:"mbl_number,omitempty" json:"mbl_number,omitempty"` // the other booking of a move, split or merge
+	Actor       string    `bson:"actor,omitempty" json:"actor,omitempty"`
+	At          time.Time `bson:"at" json:"at"`
+}
+
+// MoveShipmentsRequest is the JSON payload for POST /api/booking/moveshipments/:id
+type MoveShipmentsRequest struct {
+	ShipmentIDs     []string `json:"shipment_ids" binding:"required"`
+	TargetMBLNumber string   `json:"target_mbl_number" binding:"required"`
+}
+
+// SplitBookingRequest is the JSON payload for POST /api/booking/splitbooking/:id.
+// Carrier and dates default to those of the booking being split.
+type SplitBookingRequest struct {
+	ShipmentIDs        []string `json:"shipment_ids" binding:"required"`
+	NewMBLNumber       string   `json:"new_mbl_number" binding:"required"`
+	CarrierName        string   `json:"carrier_name"`
+	EstimatedDeparture string   `json:"estimated_departure"`
+	EstimatedArrival   string   `json:"estimated_arrival"`
+}
+
+// MergeBookingRequest is the JSON payload for POST /api/booking/mergebooking/:id
+type MergeBookingRequest struct {
+	SourceMBLNumber string `json:"source_mbl_number" binding:"required"`
+}
+
+// CancelBookingRequest is the JSON payload for POST /api/booking/cancelbooking/:id
+type CancelBookingRequest struct {
+	ReasonCode string `json:"reason_code" binding:"required"`
+	Reason     string `json:"reason"`
+}
@@ -0,0 +1,72 @@
+package models
+
+import "time"
+
+// Booking statuses
+const (
+	BookingStatusBooked    = "Booked"
+	BookingStatusConfirmed = "Confirmed"
+	BookingStatusGatedIn   = "Gated-In"
+	BookingStatusLoaded    = "Loaded" // on board the vessel, not sailed yet
+	BookingStatusSailed    = "Sailed"
+	BookingStatusArrived   = "Arrived"
+	BookingStatusDelivered = "Delivered"
+	BookingStatusCancelled = "Cancelled"
+)
+
+// BookingStatusFlow lists the regular booking statuses in the order a booking
+// moves through them; Cancelled is outside the flow
+var BookingStatusFlow = []string{
+	BookingStatusBooked,
+	BookingStatusConfirmed,
+	BookingStatusGatedIn,
+	BookingStatusLoaded,
+	BookingStatusSailed,
+	BookingStatusArrived,
+	BookingStatusDelivered,
+}
+
+// Reason codes for cancelling a booking
+const (
+	CancelReasonCustomerRequest  = "CUSTOMER_REQUEST"
+	CancelReasonSpaceUnavailable = "SPACE_UNAVAILABLE"
+	CancelReasonCargoNotReady    = "CARGO_NOT_READY"
+	CancelReasonDocumentation    = "DOCUMENTATION"
+	CancelReasonDuplicate        = "DUPLICATE"
+	CancelReasonOther            = "OTHER" // requires a free-text reason
+)
+
+// CancelReasons describes the cancellation reason codes
+var CancelReasons = map[string]string{
+	CancelReasonCustomerRequest:  "Cancelled at the customer's request",
+	CancelReasonSpaceUnavailable: "Carrier could not provide space or equipment",
+	CancelReasonCargoNotReady:    "Cargo not ready in time",
+	CancelReasonDocumentation:    "Documents or customs clearance incomplete",
+	CancelReasonDuplicate:        "Booking was entered twice",
+	CancelReasonOther:            "Other, see reason",
+	CancelReasonMerged:           "Shipments merged into another booking",
+}
+
+// Sources of booking status changes
+const (
+	BookingStatusSourceUser    = "user"
+	BookingStatusSourceCarrier = "carrier" // container status messages
+)
+
+// BookingStatusChange is one entry of a booking's status history
+type BookingStatusChange struct {
+	From       string    `bson:"from" json:"from"` // empty for the initial status
+	To         string    `bson:"to" json:"to"`
+	ReasonCode string    `bson:"reason_code,omitempty" json:"reason_code,omitempty"` // cancellations only, see CancelReason*
+	Reason     string    `bson:"reason,omitempty" json:"reason,omitempty"`
+	Actor      string    `bson:"actor,omitempty" json:"actor,omitempty"`
+	Source     string    `bson:"source,omitempty" json:"source,omitempty"` // see BookingStatusSource*
+	At         time.Time `bson:"at" json:"at"`
+}
+
+// BookingStatusRequest is the JSON payload for PUT /api/booking/updatestatus/:id
+type BookingStatusRequest struct {
+	Status     string `json:"status" binding:"required"`
+	ReasonCode string `json:"reason_code"` // required when cancelling
+	Reason     string `json:"reason"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CarrierContact is who to reach at a carrier
+type CarrierContact struct {
+	Name  string `bson:"name" json:"name"`
+	Email string `bson:"email" json:"email"`
+	Phone string `bson:"phone" json:"phone"`
+}
+
+// Carrier is a shipping line of the carrier master ("carriers" collection).
+// Free text carrier names on MBLs and bookings are resolved to its Name by
+// the name itself, any alias or the SCAC code.
+type Carrier struct {
+	ID                  primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Name                string             `bson:"name" json:"name" binding:"required"`
+	Aliases             []string           `bson:"aliases" json:"aliases"`
+	SCACCode            string             `bson:"scac_code,omitempty" json:"scac_code"` // NMFTA Standard Carrier Alpha Code, 2 to 4 letters
+	Contact             CarrierContact     `bson:"contact" json:"contact"`
+	DefaultFreeTimeDays int                `bson:"default_free_time_days" json:"default_free_time_days"` // days at destination before demurrage
+	MatchKeys           []string           `bson:"match_keys" json:"-"`                                  // normalized name, aliases and SCAC, unique across carriers
+	CreatedAt           time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt           time.Time          `bson:"updated_at" json:"updated_at"`
+}
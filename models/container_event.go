@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Sources of container events
+const (
+	ContainerEventSourceX12315 = "X12_315"
+	ContainerEventSourceIFTSTA = "IFTSTA"
+)
+
+// ContainerEvent is one entry of a container status timeline, stored in the
+// "container_events" collection
+type ContainerEvent struct {
+	ID               primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	MBLNumber        string             `bson:"mbl_number" json:"mbl_number"`
+	ContainerNo      string             `bson:"container_no" json:"container_no"`
+	EventCode        string             `bson:"event_code" json:"event_code"` // e.g. VD (vessel departure)
+	EventTime        time.Time          `bson:"event_time" json:"event_time"`
+	Location         string             `bson:"location,omitempty" json:"location,omitempty"`
+	Vessel           string             `bson:"vessel,omitempty" json:"vessel,omitempty"`
+	Source           string             `bson:"source" json:"source"` // see ContainerEventSource*
+	MessageReference string             `bson:"message_reference,omitempty" json:"message_reference,omitempty"`
+	BookingStatus    string             `bson:"booking_status,omitempty" json:"booking_status,omitempty"` // status the event advanced the booking to
+	ReceivedAt       time.Time          `bson:"received_at" json:"received_at"`
+}
+
+// UnmatchedStatusEvent is a status event that could not be matched to an MBL
+type UnmatchedStatusEvent struct {
+	EventCode   string `json:"event_code"`
+	ContainerNo string `json:"container_no,omitempty"`
+	Reference   string `json:"reference,omitempty"`
+	Segment     int    `json:"segment"` // position of the event's B4/STS segment
+}
+
+// BookingStatusUpdate is a booking status advanced by an ingested event
+type BookingStatusUpdate struct {
+	MBLNumber string `json:"mbl_number"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+	EventCode string `json:"event_code"`
+}
+
+// StatusIngestionResponse is the response from POST /api/v1/container-status
+type StatusIngestionResponse struct {
+	Source         string                 `json:"source"`
+	Received       int                    `json:"received"`
+	Stored         int                    `json:"stored"` // events already on the timeline are not stored twice
+	Unmatched      []UnmatchedStatusEvent `json:"unmatched"`
+	BookingUpdates []BookingStatusUpdate  `json:"booking_updates"`
+}
@@ -0,0 +1,206 @@
+// Package dcsa holds the subset of the DCSA eBL Transport Document JSON
+// structure exchanged with partners.
+package dcsa
+
+// Transport document type codes
+const (
+	TypeBillOfLading = "BOL"
+	TypeSeaWaybill   = "SWB"
+)
+
+// Transport document status codes
+const (
+	StatusDraft       = "DRAFT"
+	StatusApproved    = "APPROVED"
+	StatusIssued      = "ISSUED"
+	StatusSurrendered = "SURRENDERED"
+	StatusVoided      = "VOIDED"
+)
+
+// Freight payment term codes
+const (
+	PaymentTermPrepaid = "PRE"
+	PaymentTermCollect = "COL"
+)
+
+// Carrier code list providers
+const (
+	CodeListSMDG  = "SMDG"
+	CodeListNMFTA = "NMFTA" // SCAC codes
+)
+
+// Weight and volume units (UN/ECE Recommendation 20)
+const (
+	UnitKilogram   = "KGM"
+	UnitPound      = "LBR"
+	UnitCubicMetre = "MTQ"
+	UnitCubicFoot  = "FTQ"
+)
+
+// Party function, reference type and charge basis codes used by the mapper
+const (
+	PartyFunctionForwarder  = "DDR" // consignor's freight forwarder
+	ReferenceForwarder      = "FF"  // freight forwarder's reference
+	ReferenceConsignee      = "AAO" // consignee's reference
+	ReferencePackingList    = "PK"  // packing list number
+	CalculationBasisPerBill = "PER_BL"
+)
+
+// DateFormat is the layout of DCSA dates
+const DateFormat = "2006-01-02"
+
+// TransportDocument is a DCSA Transport Document (bill of lading or sea waybill)
+type TransportDocument struct {
+	TransportDocumentReference      string                       `json:"transportDocumentReference"`
+	TransportDocumentTypeCode       string                       `json:"transportDocumentTypeCode"` // BOL or SWB
+	TransportDocumentStatus         string                       `json:"transportDocumentStatus,omitempty"`
+	IsShippedOnBoardType            bool                         `json:"isShippedOnBoardType"`
+	IsToOrder                       bool                         `json:"isToOrder"`
+	FreightPaymentTermCode          string                       `json:"freightPaymentTermCode,omitempty"` // PRE or COL
+	NumberOfOriginalsWithCharges    int                          `json:"numberOfOriginalsWithCharges,omitempty"`
+	NumberOfOriginalsWithoutCharges int                          `json:"numberOfOriginalsWithoutCharges,omitempty"`
+	IssueDate                       string                       `json:"issueDate,omitempty"`          // YYYY-MM-DD
+	ShippedOnBoardDate              string                       `json:"shippedOnBoardDate,omitempty"` // YYYY-MM-DD
+	PlaceOfIssue                    *Location                    `json:"placeOfIssue,omitempty"`
+	InvoicePayableAt                *Location                    `json:"invoicePayableAt,omitempty"`
+	CarrierCode                     string                       `json:"carrierCode,omitempty"`
+	CarrierCodeListProvider         string                       `json:"carrierCodeListProvider,omitempty"` // SMDG or NMFTA
+	CarrierBookingReference         string                       `json:"carrierBookingReference,omitempty"`
+	TermsOfSale                     string                       `json:"termsOfSale,omitempty"`                    // Incoterms
+	CargoMovementTypeAtOrigin       string                       `json:"cargoMovementTypeAtOrigin,omitempty"`      // FCL or LCL
+	CargoMovementTypeAtDestination  string                       `json:"cargoMovementTypeAtDestination,omitempty"` // FCL or LCL
+	Transports                      Transports                   `json:"transports"`
+	DocumentParties                 DocumentParties              `json:"documentParties"`
+	ConsignmentItems                []ConsignmentItem            `json:"consignmentItems"`
+	UtilizedTransportEquipments     []UtilizedTransportEquipment `json:"utilizedTransportEquipments"`
+	References                      []Reference                  `json:"references,omitempty"`
+	Charges                         []Charge                     `json:"charges,omitempty"`
+}
+
+// Transports holds the routing and the vessel voyages of a transport document
+type Transports struct {
+	PlaceOfReceipt  *Location      `json:"placeOfReceipt,omitempty"`
+	PortOfLoading   Location       `json:"portOfLoading"`
+	PortOfDischarge Location       `json:"portOfDischarge"`
+	PlaceOfDelivery *Location      `json:"placeOfDelivery,omitempty"`
+	VesselVoyages   []VesselVoyage `json:"vesselVoyages"`
+}
+
+// Location is identified by a UN/LOCODE, a name or both
+type Location struct {
+	LocationName   string `json:"locationName,omitempty"`
+	UNLocationCode string `json:"UNLocationCode,omitempty"`
+}
+
+// VesselVoyage is one vessel leg
+type VesselVoyage struct {
+	VesselName                string `json:"vesselName"`
+	CarrierExportVoyageNumber string `json:"carrierExportVoyageNumber,omitempty"`
+}
+
+// DocumentParties lists the parties printed on a transport document
+type DocumentParties struct {
+	Shipper       Party           `json:"shipper"`
+	Consignee     *Party          `json:"consignee,omitempty"` // absent on to-order bills
+	NotifyParties []Party         `json:"notifyParties,omitempty"`
+	IssuingParty  *Party          `json:"issuingParty,omitempty"`
+	Other         []OtherDocParty `json:"other,omitempty"`
+}
+
+// Party is a document party. Free-text addresses are carried in Address.Street.
+type Party struct {
+	PartyName           string         `json:"partyName"`
+	Address             *Address       `json:"address,omitempty"`
+	PartyContactDetails []PartyContact `json:"partyContactDetails,omitempty"`
+}
+
+// Address is a DCSA structured address
+type Address struct {
+	Street       string `json:"street,omitempty"`
+	StreetNumber string `json:"streetNumber,omitempty"`
+	Floor        string `json:"floor,omitempty"`
+	PostCode     string `json:"postCode,omitempty"`
+	City         string `json:"city,omitempty"`
+	StateRegion  string `json:"stateRegion,omitempty"`
+	CountryCode  string `json:"countryCode,omitempty"`
+}
+
+// PartyContact is a contact person or channel of a party
+type PartyContact struct {
+	Name  string `json:"name,omitempty"`
+	Phone string `json:"phone,omitempty"`
+	Email string `json:"email,omitempty"`
+	Fax   string `json:"fax,omitempty"`
+}
+
+// OtherDocParty is an additional party identified by its party function code
+type OtherDocParty struct {
+	Party         Party  `json:"party"`
+	PartyFunction string `json:"partyFunction"`
+}
+
+// ConsignmentItem is one commodity of a transport document
+type ConsignmentItem struct {
+	CarrierBookingReference string      `json:"carrierBookingReference,omitempty"`
+	DescriptionOfGoods      []string    `json:"descriptionOfGoods"`
+	HSCodes                 []string    `json:"HSCodes,omitempty"`
+	ShippingMarks           []string    `json:"shippingMarks,omitempty"`
+	CargoItems              []CargoItem `json:"cargoItems"`
+}
+
+// CargoItem is the part of a consignment item stuffed in one piece of equipment
+type CargoItem struct {
+	EquipmentReference string         `json:"equipmentReference"`
+	CargoGrossWeight   Measure        `json:"cargoGrossWeight"`
+	CargoNetWeight     *Measure       `json:"cargoNetWeight,omitempty"`
+	CargoGrossVolume   *Measure       `json:"cargoGrossVolume,omitempty"`
+	OuterPackaging     OuterPackaging `json:"outerPackaging"`
+}
+
+// Measure is a weight or volume with its UN/ECE unit code
+type Measure struct {
+	Value float64 `json:"value"`
+	Unit  string  `json:"unit"`
+}
+
+// OuterPackaging describes the packages of a cargo item
+type OuterPackaging struct {
+	NumberOfPackages int    `json:"numberOfPackages"`
+	PackageCode      string `json:"packageCode,omitempty"` // UN/ECE Recommendation 21
+	Description      string `json:"description,omitempty"`
+}
+
+// UtilizedTransportEquipment is a container used by a transport document
+type UtilizedTransportEquipment struct {
+	Equipment      Equipment `json:"equipment"`
+	IsShipperOwned bool      `json:"isShipperOwned"`
+	Seals          []Seal    `json:"seals,omitempty"`
+}
+
+// Equipment identifies a container
+type Equipment struct {
+	EquipmentReference string `json:"equipmentReference"`         // container number
+	ISOEquipmentCode   string `json:"ISOEquipmentCode,omitempty"` // ISO 6346 size/type
+}
+
+// Seal is a seal applied to a container
+type Seal struct {
+	Number string `json:"number"`
+}
+
+// Reference is an additional reference of a transport document
+type Reference struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// Charge is a freight charge
+type Charge struct {
+	ChargeName       string  `json:"chargeName"`
+	CurrencyAmount   float64 `json:"currencyAmount"`
+	CurrencyCode     string  `json:"currencyCode"`
+	PaymentTermCode  string  `json:"paymentTermCode"` // PRE or COL
+	CalculationBasis string  `json:"calculationBasis"`
+	UnitPrice        float64 `json:"unitPrice"`
+	Quantity         float64 `json:"quantity"`
+}
@@ -0,0 +1,17 @@
+package models
+
+// EDIFACTMessageResult is the outcome of one message of an uploaded EDIFACT interchange
+type EDIFACTMessageResult struct {
+	MessageType      string `json:"message_type"` // IFTMCS or IFTMIN
+	MessageReference string `json:"message_reference"`
+	MBLNumber        string `json:"mbl_number"`
+	MBLCreated       bool   `json:"mbl_created"` // false when the MBL was already stored
+	BookingCreated   bool   `json:"booking_created"`
+	BookingUpdated   bool   `json:"booking_updated"` // carrier and schedule of an existing booking refreshed
+}
+
+// EDIFACTImportResponse is the response from POST /api/v1/mbl/import?format=edifact
+type EDIFACTImportResponse struct {
+	Sender   string                 `json:"sender"`
+	Messages []EDIFACTMessageResult `json:"messages"`
+}
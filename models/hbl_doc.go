@@ -8,6 +8,7 @@ import (
 
 type HBLDoc struct {
 	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	HBLNumber string             `bson:"hbl_number,omitempty" json:"hbl_number,omitempty"` // HBL the file was generated for, when known
 	Filename  string             `bson:"filename" json:"filename"`
 	Type      string             `bson:"type" json:"type"` // "hbl" or "any other document type"
 	URL       string             `bson:"url" json:"url"`
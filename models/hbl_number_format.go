@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// HBL numbering scopes: one sequence per MBL, or one per forwarder
+const (
+	HBLNumberScopeMBL       = "mbl"
+	HBLNumberScopeForwarder = "forwarder"
+)
+
+// HBLNumberFormat describes how a forwarder's HBL numbers are built
+// ("hbl_number_formats" collection). A number is assembled as
+// {Prefix}{MBL number}{YYYY}{zero-padded sequence}{check character},
+// each part except the sequence being optional.
+type HBLNumberFormat struct {
+	ID               primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	ForwarderID      string             `bson:"forwarder_id" json:"forwarder_id"`
+	Prefix           string             `bson:"prefix" json:"prefix"`
+	IncludeMBLNumber bool               `bson:"include_mbl_number" json:"include_mbl_number"`
+	IncludeYear      bool               `bson:"include_year" json:"include_year"`
+	Padding          int                `bson:"padding" json:"padding"`
+	CheckCharacter   bool               `bson:"check_character" json:"check_character"`
+	Scope            string             `bson:"scope" json:"scope"` // "mbl" or "forwarder"
+	UpdatedAt        time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// DefaultHBLNumberFormat reproduces the historical HBL{MBL_NUMBER}001 numbering
+func DefaultHBLNumberFormat() HBLNumberFormat {
+	return HBLNumberFormat{
+		Prefix:           "HBL",
+		IncludeMBLNumber: true,
+		Padding:          3,
+		Scope:            HBLNumberScopeMBL,
+	}
+}
@@ -1,14 +1,65 @@
 package hbl_schema
 
-// PreviewHBLRequest is the JSON payload for POST /api/v1/preview/hbl
+import "time"
+
+// PreviewHBLRequest is the JSON payload for POST /api/v1/preview/hbl and POST /api/v1/hbl
 type PreviewHBLRequest struct {
 	MBLNumber   string   `json:"mbl_number" binding:"required"`
 	ShipmentList []string `json:"shipment_list" binding:"required"` // array of shipment_ids
+	ForwarderID  string   `json:"forwarder_id"`                      // selects the HBL numbering format
 }
 
 // PreviewHBLResponse is the response from POST /api/v1/preview/hbl
 type PreviewHBLResponse struct {
-	MBLNumber  string    `json:"mbl_number"`
-	TotalCount int       `json:"total_count"`
-	HBLList    []HBLData `json:"hbl_list"`
+	MBLNumber  string           `json:"mbl_number"`
+	TotalCount int              `json:"total_count"`
+	HBLList    []HBLData        `json:"hbl_list"`
+	Items      []HBLPreviewItem `json:"items"`
+}
+
+// HBL preview item states
+const (
+	HBLPreviewExisting = "existing" // an HBL was already created for the shipment
+	HBLPreviewDraft    = "draft"    // not persisted; the HBL number is only indicative
+	HBLPreviewCreated  = "created"  // created by this request
+)
+
+// HBLPreviewItem tells, per shipment, which HBL number the entry of HBLList has
+// and whether it is stored.
+type HBLPreviewItem struct {
+	ShipmentID string `json:"shipment_id"`
+	HBLNumber  string `json:"hbl_number"`
+	State      string `json:"state"`
+	Revision   int64  `json:"revision"`
+}
+
+// CreateHBLResponse is the response from POST /api/v1/hbl
+type CreateHBLResponse struct {
+	MBLNumber    string           `json:"mbl_number"`
+	TotalCount   int              `json:"total_count"`
+	CreatedCount int              `json:"created_count"`
+	HBLList      []HBLData        `json:"hbl_list"`
+	Items        []HBLPreviewItem `json:"items"`
+}
+
+// HBLSearchQuery holds the filters, paging and sorting of GET /api/v1/hbl
+type HBLSearchQuery struct {
+	MBLNumber  string
+	ShipmentID string
+	Status     string
+	Consignee  string    // case-insensitive substring of the consignee name
+	From       time.Time // created_at lower bound, zero when unset
+	To         time.Time // created_at upper bound, zero when unset
+	Page       int64
+	PageSize   int64
+	SortBy     string
+	SortDesc   bool
+}
+
+// HBLListResponse is the response from GET /api/v1/hbl
+type HBLListResponse struct {
+	Items    []HBLDocument `json:"items"`
+	Total    int64         `json:"total"`
+	Page     int64         `json:"page"`
+	PageSize int64         `json:"page_size"`
 }
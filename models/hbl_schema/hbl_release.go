@@ -0,0 +1,86 @@
+package hbl_schema
+
+import "time"
+
+// Bill types
+const (
+	HBLBillTypeHBL        = "HBL"
+	HBLBillTypeSeaWaybill = "SEA WAYBILL"
+)
+
+// Markings printed on generated HBL PDFs
+const (
+	HBLMarkingOriginal      = "ORIGINAL"
+	HBLMarkingCopy          = "COPY NON-NEGOTIABLE"
+	HBLMarkingTelexReleased = "TELEX RELEASED"
+	HBLMarkingSeaWaybill    = "NON-NEGOTIABLE"
+)
+
+// HBLRelease tracks how the cargo of an HBL is released: against surrendered
+// original bills, by telex release, or without originals as a sea waybill
+type HBLRelease struct {
+	Originals    []HBLOriginal    `bson:"originals,omitempty" json:"originals,omitempty"`
+	TelexRelease *HBLTelexRelease `bson:"telex_release,omitempty" json:"telex_release,omitempty"`
+	SeaWaybill   *HBLReleaseEvent `bson:"sea_waybill,omitempty" json:"sea_waybill,omitempty"` // set when converted to a sea waybill
+}
+
+// HBLOriginal is one original bill of a set, identified by its serial number
+type HBLOriginal struct {
+	Number        int        `bson:"number" json:"number"` // 1..N within the set
+	Serial        string     `bson:"serial" json:"serial"`
+	IssuedBy      string     `bson:"issued_by" json:"issued_by"`
+	IssuedAt      time.Time  `bson:"issued_at" json:"issued_at"`
+	SurrenderedBy string     `bson:"surrendered_by,omitempty" json:"surrendered_by,omitempty"`
+	SurrenderedAt *time.Time `bson:"surrendered_at,omitempty" json:"surrendered_at,omitempty"`
+	SurrenderedIn string     `bson:"surrendered_in,omitempty" json:"surrendered_in,omitempty"` // place of surrender
+}
+
+// HBLTelexRelease records the telex release sent to the destination agent
+type HBLTelexRelease struct {
+	Reference string    `bson:"reference" json:"reference"`
+	Actor     string    `bson:"actor" json:"actor"`
+	Remarks   string    `bson:"remarks,omitempty" json:"remarks,omitempty"`
+	At        time.Time `bson:"at" json:"at"`
+}
+
+// HBLReleaseEvent records who performed a release operation and when
+type HBLReleaseEvent struct {
+	Actor  string    `bson:"actor" json:"actor"`
+	Reason string    `bson:"reason,omitempty" json:"reason,omitempty"`
+	At     time.Time `bson:"at" json:"at"`
+}
+
+// IssueOriginalsRequest is the JSON payload for POST /api/v1/hbl/:hbl_number/originals
+type IssueOriginalsRequest struct {
+	Count int    `json:"count" binding:"required"`
+	Actor string `json:"actor" binding:"required"`
+}
+
+// SurrenderOriginalsRequest is the JSON payload for POST /api/v1/hbl/:hbl_number/originals/surrender
+type SurrenderOriginalsRequest struct {
+	Serials []string `json:"serials" binding:"required"`
+	Actor   string   `json:"actor" binding:"required"`
+	Place   string   `json:"place"`
+}
+
+// TelexReleaseRequest is the JSON payload for POST /api/v1/hbl/:hbl_number/telex-release
+type TelexReleaseRequest struct {
+	Actor   string `json:"actor" binding:"required"`
+	Remarks string `json:"remarks"`
+}
+
+// SeaWaybillRequest is the JSON payload for POST /api/v1/hbl/:hbl_number/sea-waybill
+type SeaWaybillRequest struct {
+	Actor  string `json:"actor" binding:"required"`
+	Reason string `json:"reason"`
+}
+
+// HBLReleaseResponse is the response from the HBL release endpoints
+type HBLReleaseResponse struct {
+	HBLNumber string     `json:"hbl_number"`
+	Status    string     `json:"status"`
+	BillType  string     `json:"bill_type"`
+	Marking   string     `json:"marking"` // marking the HBL's PDFs currently get
+	Release   HBLRelease `json:"release"`
+	Revision  int64      `json:"revision"`
+}
@@ -0,0 +1,41 @@
+package hbl_schema
+
+// StandaloneHBLRequest is the JSON payload for POST /api/v1/hbl/standalone.
+// It carries the data that normally comes from the MBL, entered by hand so an
+// HBL can be issued before the carrier's master bill arrives.
+type StandaloneHBLRequest struct {
+	ShipmentID      string           `json:"shipment_id" binding:"required"`
+	ForwarderID     string           `json:"forwarder_id"` // selects the HBL numbering format
+	Carrier         HBLCarrier       `json:"carrier"`
+	Consignee       HBLParty         `json:"consignee"`
+	NotifyParty     HBLParty         `json:"notify_party"`
+	ForwardingAgent HBLParty         `json:"forwarding_agent"`
+	Routing         HBLRouting       `json:"routing"`
+	VesselDetails   []HBLVessel      `json:"vessel_details"`
+	ShipmentDates   HBLShipmentDates `json:"shipment_dates"`
+	ContainerNo     string           `json:"container_no"`
+	ContainerSize   string           `json:"container_size"`
+	SealNo          string           `json:"seal_no"`
+	FreightStatus   string           `json:"freight_status"`
+}
+
+// LinkMBLRequest is the JSON payload for POST /api/v1/hbl/:hbl_number/link-mbl
+type LinkMBLRequest struct {
+	MBLNumber string `json:"mbl_number" binding:"required"`
+	PreferMBL bool   `json:"prefer_mbl"` // resolve conflicts with the MBL value instead of keeping the HBL value
+}
+
+// HBLFieldConflict is a field the HBL and the MBL both fill in, differently
+type HBLFieldConflict struct {
+	Path     string `json:"path"`
+	HBLValue string `json:"hbl_value"`
+	MBLValue string `json:"mbl_value"`
+	Kept     string `json:"kept"` // "hbl" or "mbl"
+}
+
+// LinkMBLResponse is the response from POST /api/v1/hbl/:hbl_number/link-mbl
+type LinkMBLResponse struct {
+	HBL       *HBLDocument       `json:"hbl"`
+	Merged    []string           `json:"merged"` // fields that were empty on the HBL and taken from the MBL
+	Conflicts []HBLFieldConflict `json:"conflicts"`
+}
@@ -8,11 +8,18 @@ import (
 
 // HBLDocument is the top-level struct stored in MongoDB "HBL" collection
 type HBLDocument struct {
-	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
-	ShipmentID string             `bson:"shipment_id" json:"shipment_id"`
-	HBLNumber  string             `bson:"hbl_number" json:"hbl_number"`
-	HBL        HBLData            `bson:"hbl" json:"hbl"`
-	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	ShipmentID    string             `bson:"shipment_id" json:"shipment_id"`
+	MBLNumber     string             `bson:"mbl_number" json:"mbl_number"`
+	Standalone    bool               `bson:"standalone,omitempty" json:"standalone,omitempty"` // created before its MBL, see CreateStandaloneHBL
+	HBLNumber     string             `bson:"hbl_number" json:"hbl_number"`
+	HBL           HBLData            `bson:"hbl" json:"hbl"`
+	Status        string             `bson:"status" json:"status"` // see HBLStatus*; empty on legacy documents means draft
+	StatusHistory []HBLStatusChange  `bson:"status_history,omitempty" json:"status_history,omitempty"`
+	Amendments    []HBLAmendment     `bson:"amendments,omitempty" json:"amendments,omitempty"`
+	Release       HBLRelease         `bson:"release,omitempty" json:"release"`
+	Revision      int64              `bson:"revision,omitempty" json:"revision"` // incremented on every write, exposed as ETag
+	CreatedAt     time.Time          `bson:"created_at" json:"created_at"`
 }
 
 // HBLData contains all the fields of a House Bill of Lading
@@ -37,6 +44,12 @@ type HBLData struct {
 	FreightDetails     HBLFreightDetails  `bson:"freight_details" json:"freight_details"`
 	ValidationScore    float64            `bson:"validation_score" json:"validation_score"`
 	AccuracyScore      float64            `bson:"accuracy_score" json:"accuracy_score"`
+
+	// Set on PDF generation payloads only, never stored
+	Marking           string `bson:"-" json:"marking,omitempty"`             // see HBLMarking*
+	OriginalSerial    string `bson:"-" json:"original_serial,omitempty"`     // serial of the original being printed
+	NumberOfOriginals int    `bson:"-" json:"number_of_originals,omitempty"` // size of the set of originals
+	VerificationURL   string `bson:"-" json:"verification_url,omitempty"`    // also the QR code payload
 }
 
 // HBLCarrier holds carrier name
@@ -0,0 +1,54 @@
+package hbl_schema
+
+import "time"
+
+// HBL lifecycle statuses
+const (
+	HBLStatusDraft       = "draft"
+	HBLStatusApproved    = "approved"
+	HBLStatusIssued      = "issued"
+	HBLStatusReleased    = "released"
+	HBLStatusSurrendered = "surrendered"
+	HBLStatusVoid        = "void"
+)
+
+// HBLStatusChange is one entry of an HBL's transition history
+type HBLStatusChange struct {
+	From   string    `bson:"from" json:"from"`
+	To     string    `bson:"to" json:"to"`
+	Actor  string    `bson:"actor" json:"actor"`
+	Reason string    `bson:"reason" json:"reason"`
+	At     time.Time `bson:"at" json:"at"`
+}
+
+// HBLAmendment records a change made to an HBL after it was issued
+type HBLAmendment struct {
+	Number  int       `bson:"number" json:"number"`
+	Version int       `bson:"version" json:"version"` // HBL version holding the amended content
+	Actor   string    `bson:"actor" json:"actor"`
+	Reason  string    `bson:"reason" json:"reason"`
+	At      time.Time `bson:"at" json:"at"`
+}
+
+// HBLTransitionRequest is the JSON payload for POST /api/v1/hbl/:hbl_number/transitions
+type HBLTransitionRequest struct {
+	Status string `json:"status" binding:"required"`
+	Actor  string `json:"actor" binding:"required"`
+	Reason string `json:"reason"`
+}
+
+// HBLAmendmentRequest is the JSON payload for POST /api/v1/hbl/:hbl_number/amendments
+type HBLAmendmentRequest struct {
+	Actor  string  `json:"actor" binding:"required"`
+	Reason string  `json:"reason" binding:"required"`
+	HBL    HBLData `json:"hbl"`
+}
+
+// HBLStatusResponse is the response from the HBL status endpoints
+type HBLStatusResponse struct {
+	HBLNumber     string            `json:"hbl_number"`
+	Status        string            `json:"status"`
+	StatusHistory []HBLStatusChange `json:"status_history"`
+	Amendments    []HBLAmendment    `json:"amendments"`
+	Revision      int64             `json:"revision"`
+}
@@ -0,0 +1,42 @@
+package hbl_schema
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// HBLVersion is an immutable snapshot stored in the "HBL_Versions" collection
+// each time an HBL's data changes
+type HBLVersion struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	HBLNumber string             `bson:"hbl_number" json:"hbl_number"`
+	Version   int                `bson:"version" json:"version"`
+	Author    string             `bson:"author" json:"author"`
+	Reason    string             `bson:"reason" json:"reason"`
+	Changes   []HBLFieldChange   `bson:"changes" json:"changes"` // diff against the previous version
+	HBL       HBLData            `bson:"hbl" json:"hbl"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// HBLFieldChange is a single changed field, addressed by its JSON path
+// (e.g. "routing.port_of_loading" or "container_details[0].hs_code")
+type HBLFieldChange struct {
+	Path     string      `bson:"path" json:"path"`
+	OldValue interface{} `bson:"old_value" json:"old_value"`
+	NewValue interface{} `bson:"new_value" json:"new_value"`
+}
+
+// HBLChangeInfo identifies who changes an HBL and why
+type HBLChangeInfo struct {
+	Author string
+	Reason string
+}
+
+// HBLVersionDiff is the response from GET /api/v1/hbl/:hbl_number/diff
+type HBLVersionDiff struct {
+	HBLNumber   string           `json:"hbl_number"`
+	FromVersion int              `json:"from_version"`
+	ToVersion   int              `json:"to_version"`
+	Changes     []HBLFieldChange `json:"changes"`
+}
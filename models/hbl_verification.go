@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// HBLVerification is a generated HBL PDF that can be checked through its
+// signed verification token ("HBL_Verifications" collection)
+type HBLVerification struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	TokenID        string             `bson:"token_id" json:"token_id"`
+	HBLNumber      string             `bson:"hbl_number" json:"hbl_number"`
+	Issuer         string             `bson:"issuer" json:"issuer"`
+	IssuedAt       time.Time          `bson:"issued_at" json:"issued_at"`
+	DataHash       string             `bson:"data_hash" json:"-"` // SHA-256 of the HBL data printed on the document
+	Marking        string             `bson:"marking,omitempty" json:"marking,omitempty"`
+	OriginalSerial string             `bson:"original_serial,omitempty" json:"original_serial,omitempty"`
+	Filename       string             `bson:"filename,omitempty" json:"filename,omitempty"`
+	SHA256         string             `bson:"sha256,omitempty" json:"sha256,omitempty"` // of the PDF file
+	URL            string             `bson:"url" json:"-"`                             // verification URL embedded in the document
+	HBLDeletedAt   *time.Time         `bson:"hbl_deleted_at,omitempty" json:"-"`        // set when the HBL was deleted
+}
+
+// HBLVerificationResult is the response from GET /verify/:token
+type HBLVerificationResult struct {
+	Valid          bool      `json:"valid"`
+	HBLNumber      string    `json:"hbl_number"`
+	Issuer         string    `json:"issuer"`
+	IssuedAt       time.Time `json:"issued_at"`
+	Marking        string    `json:"marking,omitempty"`
+	OriginalSerial string    `json:"original_serial,omitempty"`
+	SHA256         string    `json:"sha256,omitempty"`
+	Status         string    `json:"status"`
+	Amended        bool      `json:"amended"` // the HBL on record no longer matches the document
+	Voided         bool      `json:"voided"`  // the HBL was voided or deleted
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// HSCode is one entry of the Harmonized System nomenclature ("hs_codes" collection)
+type HSCode struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Code        string             `bson:"code" json:"code"`   // digits only: 2 (chapter), 4 (heading) or 6 (subheading)
+	Level       string             `bson:"level" json:"level"` // "chapter", "heading" or "subheading"
+	Chapter     string             `bson:"chapter" json:"chapter"`
+	Description string             `bson:"description" json:"description"`
+	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// HSCodeValidation is the outcome of checking an HS code (and optionally the
+// goods description it was declared for) against the nomenclature.
+type HSCodeValidation struct {
+	Code           string   `json:"code"`
+	NormalizedCode string   `json:"normalized_code"`
+	Valid          bool     `json:"valid"`
+	Level          string   `json:"level,omitempty"`
+	Description    string   `json:"description,omitempty"`
+	Errors         []string `json:"errors,omitempty"`
+	Warnings       []string `json:"warnings,omitempty"`
+}
+
+// HSCodeSuggestion is a nomenclature entry proposed for a goods description
+type HSCodeSuggestion struct {
+	Code        string  `json:"code"`
+	Level       string  `json:"level"`
+	Description string  `json:"description"`
+	Score       float64 `json:"score"`
+}
@@ -0,0 +1,53 @@
+package models
+
+// LoadPlanContainerType is a container type a load plan may use. Zero limits
+// take the capacity of the ISO type; set them to plan with less than the
+// nominal capacity, e.g. to leave room for dunnage.
+type LoadPlanContainerType struct {
+	Type         string  `json:"type" binding:"required"` // ISO 6346 code or alias such as 40HC
+	MaxPayloadKg float64 `json:"max_payload_kg"`
+	MaxVolumeCbm float64 `json:"max_volume_cbm"`
+	MaxPackages  int     `json:"max_packages"` // 0 is unlimited
+}
+
+// LoadPlanRequest is the JSON payload for POST /api/booking/loadplan. Without
+// container types the plan uses 20' and 40' general purpose and 40' high cube.
+// The mode rule of the forwarder (LCL by default) also limits each container,
+// so every planned container can be synced as one booking.
+type LoadPlanRequest struct {
+	ShipmentIDs    []string                `json:"shipment_ids" binding:"required"`
+	ContainerTypes []LoadPlanContainerType `json:"container_types"`
+	ForwarderID    string                  `json:"forwarder_id"`
+	Mode           string                  `json:"mode"`
+}
+
+// PlannedContainer is one container of a load plan and how full it is
+type PlannedContainer struct {
+	Sequence          int      `json:"sequence"`
+	ISOType           string   `json:"iso_type"`
+	Description       string   `json:"description"`
+	ShipmentIDs       []string `json:"shipment_ids"`
+	Packages          int      `json:"packages"`
+	GrossWeight       float64  `json:"gross_weight"` // kg
+	Volume            float64  `json:"volume"`       // cbm
+	MaxPayloadKg      float64  `json:"max_payload_kg"`
+	MaxVolumeCbm      float64  `json:"max_volume_cbm"`
+	WeightUtilisation float64  `json:"weight_utilisation"` // percent
+	VolumeUtilisation float64  `json:"volume_utilisation"` // percent
+}
+
+// UnplannedShipment is a candidate shipment the plan could not place
+type UnplannedShipment struct {
+	ShipmentID string `json:"shipment_id"`
+	Reason     string `json:"reason"`
+}
+
+// LoadPlan is a proposed packing of shipments into containers, fewest
+// containers first. Shipments are not split across containers.
+type LoadPlan struct {
+	Mode        string              `json:"mode"`
+	Containers  []PlannedContainer  `json:"containers"`
+	Unplanned   []UnplannedShipment `json:"unplanned"`
+	GrossWeight float64             `json:"gross_weight"` // kg, planned shipments
+	Volume      float64             `json:"volume"`       // cbm, planned shipments
+}
@@ -1,9 +1,14 @@
 package mbl_schema
 
+import "fs-backend/models"
+
 // ConvertMBLResponse is the API response for POST /api/v1/convert/mbl
 type ConvertMBLResponse struct {
 	MBLNumber       string             `json:"mbl_number"`
 	ShipmentsList []ShipmentListItem `json:"shipments_list"`
+	PartyMatches  *PartyMatchResult  `json:"party_matches,omitempty"`
+	HSCodeCheck   *models.HSCodeValidation `json:"hs_code_check,omitempty"`
+	Warnings      []string                 `json:"warnings,omitempty"` // fields dropped while storing the MBL
 }
 
 // ShipmentListItem holds individual shipment information returned in the response.
@@ -18,3 +23,21 @@ type ShipmentListItem struct {
 	MarksAndNumbers  string  `json:"marks_and_numbers"`
 	Measurement      string  `json:"measurement"`
 }
+
+// PartyMatchResult holds the shipper master suggestions for each MBL party,
+// best match first.
+type PartyMatchResult struct {
+	Shipper     []ShipperCandidate `json:"shipper"`
+	Consignee   []ShipperCandidate `json:"consignee"`
+	NotifyParty []ShipperCandidate `json:"notify_party"`
+}
+
+// ShipperCandidate is an existing shipper record that resembles an extracted party.
+type ShipperCandidate struct {
+	ShipperID      string  `json:"shipper_id"`
+	ShipperName    string  `json:"shipper_name"`
+	ShipperAddress string  `json:"shipper_address"`
+	Score          float64 `json:"score"`
+	NameScore      float64 `json:"name_score"`
+	AddressScore   float64 `json:"address_score"`
+}
@@ -8,10 +8,11 @@ import (
 
 // MBLDocument is the top-level struct stored in MongoDB "MBL" collection
 type MBLDocument struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
-	Mode      string             `bson:"mode" json:"mode"` // "FCL" or "LCL"
-	MBL       MBLData            `bson:"mbl" json:"mbl"`
-	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Mode         string             `bson:"mode" json:"mode"` // "FCL" or "LCL"
+	MBL          MBLData            `bson:"mbl" json:"mbl"`
+	ContainerNos []string           `bson:"container_nos,omitempty" json:"-"` // normalised MBL.Cargo.ContainerNo, set by the repository
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
 }
 
 // MBLData contains all the fields of a Master Bill of Lading
@@ -90,9 +90,10 @@ type PdfGeneratorService struct {
 }
 
 type PdfGeneratorUploadedFile struct {
-	Filename string `json:"filename"`
-	Type     string `json:"type"`
-	URL      string `json:"url"`
+	Filename  string `json:"filename"`
+	Type      string `json:"type"`
+	URL       string `json:"url"`
+	HBLNumber string `json:"hblNumber,omitempty"` // HBL the file was generated for; else matched by filename
 }
 
 type PdfGeneratorUploadResponse struct {
@@ -103,6 +104,7 @@ type PdfGeneratorUploadResponse struct {
 
 type PdfSaveRequest struct {
 	UploadedFiles []PdfGeneratorUploadedFile `json:"uploadedFiles" binding:"required"`
+	HBLNumbers    []string                   `json:"hblNumbers,omitempty"` // used to link each file to its HBL by filename
 }
 
 type PdfSaveResponse struct {
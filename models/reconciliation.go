@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// Reconciliation mismatch severities; errors count towards blocking PDF generation
+const (
+	ReconciliationError   = "error"
+	ReconciliationWarning = "warning"
+)
+
+// ReconciliationTolerances are the allowed differences between the MBL cargo
+// and the sum of its HBLs
+type ReconciliationTolerances struct {
+	Packages             int     `json:"packages"`
+	WeightPercent        float64 `json:"weight_percent"`
+	VolumePercent        float64 `json:"volume_percent"`
+	BlockPDFOnMismatches bool    `json:"block_pdf_on_mismatches"`
+}
+
+// ReconciliationTotals are cargo totals in kilograms and cubic metres
+type ReconciliationTotals struct {
+	Packages    int     `json:"packages"`
+	GrossWeight float64 `json:"gross_weight_kg"`
+	Volume      float64 `json:"volume_cbm"`
+}
+
+// ReconciliationMismatch is one disagreement between the MBL and its HBLs
+type ReconciliationMismatch struct {
+	Field     string  `json:"field"`
+	HBLNumber string  `json:"hbl_number,omitempty"`
+	Expected  string  `json:"expected"`
+	Actual    string  `json:"actual"`
+	Tolerance float64 `json:"tolerance,omitempty"`
+	Severity  string  `json:"severity"`
+}
+
+// ReconciliationReport is the response from GET /api/v1/mbl/:mbl_number/reconciliation
+type ReconciliationReport struct {
+	MBLNumber        string                   `json:"mbl_number"`
+	GeneratedAt      time.Time                `json:"generated_at"`
+	HBLCount         int                      `json:"hbl_count"`
+	MBLTotals        ReconciliationTotals     `json:"mbl_totals"`
+	HBLTotals        ReconciliationTotals     `json:"hbl_totals"`
+	Tolerances       ReconciliationTolerances `json:"tolerances"`
+	Mismatches       []ReconciliationMismatch `json:"mismatches"`
+	MissingShipments []string                 `json:"missing_shipments"` // booked shipments without an HBL
+	UnbookedHBLs     []string                 `json:"unbooked_hbls"`     // HBLs whose shipment is not in the booking
+	HBLsWithoutPDF   []string                 `json:"hbls_without_pdf"`
+	Consistent       bool                     `json:"consistent"`
+	BlocksPDF        bool                     `json:"blocks_pdf"`
+}
@@ -0,0 +1,96 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Shipment milestones, in the order a shipment normally reaches them
+const (
+	MilestoneCargoReceived = "cargo_received"
+	MilestoneStuffed       = "stuffed"
+	MilestoneGatedIn       = "gated_in"
+	MilestoneLoaded        = "loaded"
+	MilestoneDeparted      = "departed"
+	MilestoneTranshipped   = "transhipped"
+	MilestoneArrived       = "arrived"
+	MilestoneDischarged    = "discharged"
+	MilestoneDelivered     = "delivered"
+)
+
+// MilestoneOrder lists the milestones in shipment order
+var MilestoneOrder = []string{
+	MilestoneCargoReceived,
+	MilestoneStuffed,
+	MilestoneGatedIn,
+	MilestoneLoaded,
+	MilestoneDeparted,
+	MilestoneTranshipped,
+	MilestoneArrived,
+	MilestoneDischarged,
+	MilestoneDelivered,
+}
+
+// MilestoneStatus is the shipment status shown once a milestone is reached
+var MilestoneStatus = map[string]string{
+	MilestoneCargoReceived: "Cargo Received",
+	MilestoneStuffed:       "Stuffed",
+	MilestoneGatedIn:       "Gated In",
+	MilestoneLoaded:        "Loaded",
+	MilestoneDeparted:      "Departed",
+	MilestoneTranshipped:   "Transhipped",
+	MilestoneArrived:       "Arrived",
+	MilestoneDischarged:    "Discharged",
+	MilestoneDelivered:     "Delivered",
+}
+
+// ShipmentMilestone is a planned and/or reached milestone of a shipment,
+// stored in the "shipment_milestones" collection. A shipment has one
+// milestone of each kind, except transhipments which are numbered by Sequence.
+type ShipmentMilestone struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	ShipmentID      string             `bson:"shipment_id" json:"shipment_id"`
+	Milestone       string             `bson:"milestone" json:"milestone"` // see Milestone*
+	Sequence        int                `bson:"sequence" json:"sequence"`   // 1, or the n-th transhipment
+	PlannedAt       *time.Time         `bson:"planned_at,omitempty" json:"planned_at,omitempty"`
+	ActualAt        *time.Time         `bson:"actual_at,omitempty" json:"actual_at,omitempty"`
+	PlannedLocation string             `bson:"planned_location,omitempty" json:"planned_location,omitempty"`
+	ActualLocation  string             `bson:"actual_location,omitempty" json:"actual_location,omitempty"`
+	Remarks         string             `bson:"remarks,omitempty" json:"remarks,omitempty"`
+	RecordedBy      string             `bson:"recorded_by,omitempty" json:"recorded_by,omitempty"`
+	UpdatedAt       time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// RecordMilestoneRequest is the body of POST /api/booking/shipments/:id/milestones.
+// Only the given fields are changed, so the plan can be recorded first and the
+// actual time and place once the milestone is reached.
+type RecordMilestoneRequest struct {
+	Milestone       string     `json:"milestone"`
+	Sequence        int        `json:"sequence"` // defaults to 1
+	PlannedAt       *time.Time `json:"planned_at"`
+	ActualAt        *time.Time `json:"actual_at"`
+	PlannedLocation string     `json:"planned_location"`
+	ActualLocation  string     `json:"actual_location"`
+	Remarks         string     `json:"remarks"`
+}
+
+// MilestoneDelay flags a milestone reached (or still pending) later than expected
+type MilestoneDelay struct {
+	Milestone  string     `json:"milestone"`
+	Sequence   int        `json:"sequence"`
+	Basis      string     `json:"basis"` // planned, booking_etd or booking_eta
+	Expected   time.Time  `json:"expected"`
+	ActualAt   *time.Time `json:"actual_at,omitempty"`
+	DelayHours float64    `json:"delay_hours"`
+	Overdue    bool       `json:"overdue"` // not reached yet and already late
+}
+
+// ShipmentTimeline is the response from GET /api/booking/shipments/:id/milestones
+type ShipmentTimeline struct {
+	ShipmentID string              `json:"shipment_id"`
+	MBLNumber  string              `json:"mbl_number,omitempty"`
+	Status     string              `json:"status"`
+	Milestones []ShipmentMilestone `json:"milestones"`
+	Delays     []MilestoneDelay    `json:"delays"`
+}
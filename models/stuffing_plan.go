@@ -0,0 +1,97 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// StuffingPlan describes how the shipments of an LCL consolidation are loaded
+// into the containers of an MBL ("stuffing_plans" collection, one per MBL).
+// A shipment may be split over several containers and a container holds parts
+// of several shipments.
+type StuffingPlan struct {
+	ID          primitive.ObjectID   `bson:"_id,omitempty" json:"id,omitempty"`
+	MBLNumber   string               `bson:"mbl_number" json:"mbl_number"`
+	Containers  []StuffedContainer   `bson:"containers" json:"containers"`
+	Allocations []StuffingAllocation `bson:"allocations" json:"allocations"`
+	UpdatedAt   time.Time            `bson:"updated_at" json:"updated_at"`
+}
+
+// StuffedContainer is a container of the consolidation
+type StuffedContainer struct {
+	ContainerNo string `bson:"container_no" json:"container_no"`
+	ISOType     string `bson:"iso_type" json:"iso_type"` // ISO 6346 size/type code, e.g. 22G1, 45G1
+	SealNo      string `bson:"seal_no" json:"seal_no"`
+}
+
+// StuffingAllocation is the share of a shipment loaded into one container
+type StuffingAllocation struct {
+	ShipmentID  string  `bson:"shipment_id" json:"shipment_id"`
+	ContainerNo string  `bson:"container_no" json:"container_no"`
+	Packages    int     `bson:"packages" json:"packages"`
+	GrossWeight float64 `bson:"gross_weight" json:"gross_weight"` // kg
+	Volume      float64 `bson:"volume" json:"volume"`             // cbm
+}
+
+// ContainerCapacity is the usable payload and volume of an ISO container type
+type ContainerCapacity struct {
+	ISOType      string  `json:"iso_type"`
+	Description  string  `json:"description"`
+	MaxPayloadKg float64 `json:"max_payload_kg"`
+	MaxVolumeCbm float64 `json:"max_volume_cbm"`
+}
+
+// ContainerCapacities lists typical capacities by ISO 6346 size/type code
+var ContainerCapacities = map[string]ContainerCapacity{
+	"22G1": {ISOType: "22G1", Description: "20' general purpose", MaxPayloadKg: 28200, MaxVolumeCbm: 33.2},
+	"22R1": {ISOType: "22R1", Description: "20' reefer", MaxPayloadKg: 27400, MaxVolumeCbm: 28.3},
+	"22U1": {ISOType: "22U1", Description: "20' open top", MaxPayloadKg: 28100, MaxVolumeCbm: 32.5},
+	"42G1": {ISOType: "42G1", Description: "40' general purpose", MaxPayloadKg: 26700, MaxVolumeCbm: 67.7},
+	"42R1": {ISOType: "42R1", Description: "40' reefer", MaxPayloadKg: 27700, MaxVolumeCbm: 59.3},
+	"42U1": {ISOType: "42U1", Description: "40' open top", MaxPayloadKg: 26500, MaxVolumeCbm: 65.9},
+	"45G1": {ISOType: "45G1", Description: "40' high cube", MaxPayloadKg: 26500, MaxVolumeCbm: 76.3},
+	"45R1": {ISOType: "45R1", Description: "40' high cube reefer", MaxPayloadKg: 29000, MaxVolumeCbm: 67.3},
+	"L5G1": {ISOType: "L5G1", Description: "45' high cube", MaxPayloadKg: 27700, MaxVolumeCbm: 86.0},
+}
+
+// containerTypeAliases maps common trade names to ISO 6346 size/type codes
+var containerTypeAliases = map[string]string{
+	"20GP": "22G1", "20DV": "22G1", "20DC": "22G1", "20ST": "22G1",
+	"20RF": "22R1", "20RE": "22R1", "20OT": "22U1",
+	"40GP": "42G1", "40DV": "42G1", "40DC": "42G1", "40ST": "42G1",
+	"40RF": "42R1", "40OT": "42U1",
+	"40HC": "45G1", "40HQ": "45G1", "40RH": "45R1", "40HR": "45R1",
+	"45HC": "L5G1", "45HQ": "L5G1",
+}
+
+// LookupContainerCapacity returns the capacity of an ISO size/type code or a
+// common alias such as 40HC
+func LookupContainerCapacity(containerType string) (ContainerCapacity, bool) {
+	code := strings.ToUpper(strings.NewReplacer(" ", "", "'", "", "-", "").Replace(containerType))
+	if iso, ok := containerTypeAliases[code]; ok {
+		code = iso
+	}
+	capacity, ok := ContainerCapacities[code]
+	return capacity, ok
+}
+
+// ContainerUtilisation is how full a container of a stuffing plan is
+type ContainerUtilisation struct {
+	ContainerNo       string  `json:"container_no"`
+	ISOType           string  `json:"iso_type"`
+	Packages          int     `json:"packages"`
+	GrossWeight       float64 `json:"gross_weight"`
+	Volume            float64 `json:"volume"`
+	MaxPayloadKg      float64 `json:"max_payload_kg"`
+	MaxVolumeCbm      float64 `json:"max_volume_cbm"`
+	WeightUtilisation float64 `json:"weight_utilisation"` // percent
+	VolumeUtilisation float64 `json:"volume_utilisation"` // percent
+}
+
+// StuffingPlanResponse is the response from GET/PUT /api/v1/mbl/:mbl_number/stuffing-plan
+type StuffingPlanResponse struct {
+	Plan        *StuffingPlan          `json:"plan"`
+	Utilisation []ContainerUtilisation `json:"utilisation"`
+}
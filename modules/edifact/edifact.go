@@ -0,0 +1,258 @@
+// Package edifact reads UN/EDIFACT interchanges. It only deals with syntax
+// (service characters, segments, UNH/UNT envelopes); mapping message content
+// is left to the caller.
+package edifact
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Delimiters are the service characters of an interchange, set by its UNA segment
+type Delimiters struct {
+	Component byte
+	Element   byte
+	Decimal   byte
+	Release   byte
+	Segment   byte
+}
+
+// DefaultDelimiters apply when an interchange has no UNA segment
+var DefaultDelimiters = Delimiters{Component: ':', Element: '+', Decimal: '.', Release: '?', Segment: '\''}
+
+// Segment is one segment of an interchange. Elements hold the data elements
+// after the tag, each split into its components.
+type Segment struct {
+	Tag      string
+	Elements [][]string
+	Position int // 1-based position in the interchange, used in error messages
+}
+
+// Component returns component j of data element i (both 0-based), or "" when absent
+func (s Segment) Component(i, j int) string {
+	if i >= len(s.Elements) || j >= len(s.Elements[i]) {
+		return ""
+	}
+	return s.Elements[i][j]
+}
+
+// Element returns data element i (0-based) with its components, or nil when absent
+func (s Segment) Element(i int) []string {
+	if i >= len(s.Elements) {
+		return nil
+	}
+	return s.Elements[i]
+}
+
+// Message is one UNH…UNT message; Segments excludes the envelope
+type Message struct {
+	Type      string // e.g. IFTMIN
+	Version   string // e.g. D:99B
+	Reference string
+	Position  int // position of the UNH segment
+	Segments  []Segment
+}
+
+// Interchange is a parsed UNB…UNZ interchange
+type Interchange struct {
+	Sender     string
+	Recipient  string
+	ControlRef string
+	Messages   []Message
+}
+
+// SegmentError points at the segment (and optionally the data element) a
+// problem was found in
+type SegmentError struct {
+	Position int    `json:"segment"`
+	Tag      string `json:"tag"`
+	Element  int    `json:"element,omitempty"` // 1-based, 0 when the whole segment is at fault
+	Message  string `json:"message"`
+}
+
+func (e SegmentError) Error() string {
+	if e.Element > 0 {
+		return fmt.Sprintf("segment %d (%s) element %d: %s", e.Position, e.Tag, e.Element, e.Message)
+	}
+	return fmt.Sprintf("segment %d (%s): %s", e.Position, e.Tag, e.Message)
+}
+
+// Errors lists every problem found in an interchange
+type Errors []SegmentError
+
+func (e Errors) Error() string {
+	parts := make([]string, len(e))
+	for i, segmentErr := range e {
+		parts[i] = segmentErr.Error()
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Parse splits an interchange into its messages. Envelope problems such as a
+// missing UNT or a wrong segment count are returned as Errors.
+func Parse(data []byte) (*Interchange, error) {
+	segments, err := Tokenize(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		interchange Interchange
+		errs        Errors
+		current     *Message
+	)
+	for _, segment := range segments {
+		switch segment.Tag {
+		case "UNB":
+			interchange.Sender = segment.Component(1, 0)
+			interchange.Recipient = segment.Component(2, 0)
+			interchange.ControlRef = segment.Component(4, 0)
+		case "UNZ":
+			if current != nil {
+				errs = append(errs, SegmentError{Position: current.Position, Tag: "UNH", Message: "message has no UNT segment"})
+				current = nil
+			}
+			if count := segment.Component(0, 0); count != "" && count != fmt.Sprint(len(interchange.Messages)) {
+				errs = append(errs, SegmentError{Position: segment.Position, Tag: segment.Tag, Element: 1,
+					Message: fmt.Sprintf("interchange declares %s messages but contains %d", count, len(interchange.Messages))})
+			}
+		case "UNH":
+			if current != nil {
+				errs = append(errs, SegmentError{Position: current.Position, Tag: "UNH", Message: "message has no UNT segment"})
+			}
+			current = &Message{
+				Type:      segment.Component(1, 0),
+				Reference: segment.Component(0, 0),
+				Position:  segment.Position,
+			}
+			if version, release := segment.Component(1, 1), segment.Component(1, 2); version != "" {
+				current.Version = version + ":" + release
+			}
+			if current.Type == "" {
+				errs = append(errs, SegmentError{Position: segment.Position, Tag: segment.Tag, Element: 2, Message: "message type is missing"})
+			}
+		case "UNT":
+			if current == nil {
+				errs = append(errs, SegmentError{Position: segment.Position, Tag: segment.Tag, Message: "UNT without UNH"})
+				continue
+			}
+			// The count includes UNH and UNT
+			if count := segment.Component(0, 0); count != fmt.Sprint(len(current.Segments)+2) {
+				errs = append(errs, SegmentError{Position: segment.Position, Tag: segment.Tag, Element: 1,
+					Message: fmt.Sprintf("message declares %s segments but contains %d", count, len(current.Segments)+2)})
+			}
+			if ref := segment.Component(1, 0); ref != current.Reference {
+				errs = append(errs, SegmentError{Position: segment.Position, Tag: segment.Tag, Element: 2,
+					Message: fmt.Sprintf("reference %q does not match UNH reference %q", ref, current.Reference)})
+			}
+			interchange.Messages = append(interchange.Messages, *current)
+			current = nil
+		default:
+			if current == nil {
+				errs = append(errs, SegmentError{Position: segment.Position, Tag: segment.Tag, Message: "segment outside of a UNH/UNT message"})
+				continue
+			}
+			current.Segments = append(current.Segments, segment)
+		}
+	}
+	if current != nil {
+		errs = append(errs, SegmentError{Position: current.Position, Tag: "UNH", Message: "message has no UNT segment"})
+	}
+	if len(interchange.Messages) == 0 && len(errs) == 0 {
+		errs = append(errs, SegmentError{Position: 1, Tag: firstTag(segments), Message: "interchange contains no messages"})
+	}
+	if len(errs) > 0 {
+		return &interchange, errs
+	}
+	return &interchange, nil
+}
+
+// Tokenize splits raw EDIFACT data into segments, honouring the UNA service
+// string advice and release characters. Line breaks between segments are ignored.
+func Tokenize(data []byte) ([]Segment, error) {
+	delimiters := DefaultDelimiters
+	text := string(data)
+	if strings.HasPrefix(text, "UNA") {
+		if len(text) < 9 {
+			return nil, Errors{{Position: 1, Tag: "UNA", Message: "service string advice must be 6 characters"}}
+		}
+		delimiters = Delimiters{Component: text[3], Element: text[4], Decimal: text[5], Release: text[6], Segment: text[8]}
+		text = text[9:]
+	}
+
+	var (
+		segments []Segment
+		raw      []string // data elements of the current segment, components not split yet
+		element  strings.Builder
+		escaped  bool
+	)
+	flushElement := func() {
+		raw = append(raw, element.String())
+		element.Reset()
+	}
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case escaped:
+			element.WriteByte(delimiters.Release)
+			element.WriteByte(c)
+			escaped = false
+		case c == delimiters.Release:
+			escaped = true
+		case c == delimiters.Element:
+			flushElement()
+		case c == delimiters.Segment:
+			flushElement()
+			segments = append(segments, buildSegment(raw, delimiters, len(segments)+1))
+			raw = nil
+		case (c == '\n' || c == '\r') && len(raw) == 0 && element.Len() == 0:
+			// line breaks between segments
+		default:
+			element.WriteByte(c)
+		}
+	}
+	if rest := strings.TrimSpace(element.String()); rest != "" || len(raw) > 0 {
+		flushElement()
+		segment := buildSegment(raw, delimiters, len(segments)+1)
+		return nil, Errors{{Position: segment.Position, Tag: segment.Tag, Message: "segment is not terminated"}}
+	}
+	return segments, nil
+}
+
+// buildSegment splits the raw data elements into components and removes the
+// release characters
+func buildSegment(raw []string, delimiters Delimiters, position int) Segment {
+	segment := Segment{Tag: strings.TrimSpace(raw[0]), Position: position}
+	for _, element := range raw[1:] {
+		segment.Elements = append(segment.Elements, splitComponents(element, delimiters))
+	}
+	return segment
+}
+
+func splitComponents(element string, delimiters Delimiters) []string {
+	var (
+		components []string
+		component  strings.Builder
+	)
+	for i := 0; i < len(element); i++ {
+		c := element[i]
+		switch {
+		case c == delimiters.Release && i+1 < len(element):
+			i++
+			component.WriteByte(element[i])
+		case c == delimiters.Component:
+			components = append(components, component.String())
+			component.Reset()
+		default:
+			component.WriteByte(c)
+		}
+	}
+	return append(components, component.String())
+}
+
+func firstTag(segments []Segment) string {
+	if len(segments) == 0 {
+		return ""
+	}
+	return segments[0].Tag
+}
@@ -0,0 +1,89 @@
+package hscodes
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+//go:embed nomenclature.csv
+var bundledCSV []byte
+
+// Entry is a single row of an HS nomenclature table
+type Entry struct {
+	Code        string
+	Description string
+}
+
+// Bundled returns the nomenclature table shipped with the binary. It covers all
+// chapters and the headings/subheadings most common in our trade lanes; the
+// full table can be loaded with the hs-import command.
+func Bundled() ([]Entry, error) {
+	return Parse(bytes.NewReader(bundledCSV))
+}
+
+// Parse reads a "code,description" CSV (with header row). Dots and spaces in
+// codes are ignored, so "8471.30" and "847130" are equivalent.
+func Parse(r io.Reader) ([]Entry, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read nomenclature csv: %w", err)
+	}
+
+	entries := make([]Entry, 0, len(rows))
+	for i, row := range rows {
+		if i == 0 && len(row) > 0 && strings.EqualFold(strings.TrimSpace(row[0]), "code") {
+			continue
+		}
+		if len(row) < 2 {
+			return nil, fmt.Errorf("line %d: expected code and description", i+1)
+		}
+
+		code := NormalizeCode(row[0])
+		if Level(code) == "" {
+			return nil, fmt.Errorf("line %d: invalid code %q", i+1, row[0])
+		}
+		entries = append(entries, Entry{
+			Code:        code,
+			Description: strings.TrimSpace(row[1]),
+		})
+	}
+	return entries, nil
+}
+
+// NormalizeCode strips separators commonly used when writing HS codes
+func NormalizeCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '.', ' ', '-', '\t':
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(code))
+}
+
+// Level returns "chapter", "heading" or "subheading" for a normalized code of
+// 2, 4 or 6 digits, and "" for anything else.
+func Level(code string) string {
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return ""
+		}
+	}
+	switch len(code) {
+	case 2:
+		return "chapter"
+	case 4:
+		return "heading"
+	case 6:
+		return "subheading"
+	default:
+		return ""
+	}
+}
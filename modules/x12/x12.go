@@ -0,0 +1,126 @@
+// Package x12 reads ANSI ASC X12 interchanges. Segments and errors share the
+// representation of the edifact package so callers can handle both alike.
+package x12
+
+import (
+	"fmt"
+	"strings"
+
+	"fs-backend/modules/edifact"
+)
+
+// isaLength is the fixed length of the ISA segment including its terminator
+const isaLength = 106
+
+type (
+	Segment      = edifact.Segment
+	SegmentError = edifact.SegmentError
+	Errors       = edifact.Errors
+)
+
+// TransactionSet is one ST…SE transaction set; Segments excludes the envelope
+type TransactionSet struct {
+	Code          string // e.g. 315
+	ControlNumber string
+	Position      int // position of the ST segment
+	Segments      []Segment
+}
+
+// Interchange is a parsed ISA…IEA interchange
+type Interchange struct {
+	Sender          string
+	Receiver        string
+	ControlNumber   string
+	TransactionSets []TransactionSet
+}
+
+// Parse splits an interchange into its transaction sets. The delimiters are
+// taken from the fixed-length ISA segment. Envelope problems such as a
+// missing SE or a wrong segment count are returned as Errors.
+func Parse(data []byte) (*Interchange, error) {
+	text := strings.TrimLeft(string(data), " \r\n\t")
+	if len(text) < isaLength || !strings.HasPrefix(text, "ISA") {
+		return nil, Errors{{Position: 1, Tag: "ISA", Message: "interchange must start with a 106 character ISA segment"}}
+	}
+	elementSep, componentSep, segmentTerm := text[3], text[104], text[105]
+
+	var (
+		interchange Interchange
+		errs        Errors
+		current     *TransactionSet
+	)
+	position := 0
+	for _, raw := range strings.Split(text, string(segmentTerm)) {
+		raw = strings.Trim(raw, "\r\n")
+		if strings.TrimSpace(raw) == "" {
+			continue
+		}
+		position++
+		segment := buildSegment(raw, elementSep, componentSep, position)
+
+		switch segment.Tag {
+		case "ISA":
+			interchange.Sender = strings.TrimSpace(segment.Component(5, 0))
+			interchange.Receiver = strings.TrimSpace(segment.Component(7, 0))
+			interchange.ControlNumber = segment.Component(12, 0)
+		case "GS", "GE":
+			// functional groups carry nothing we need
+		case "IEA":
+			if current != nil {
+				errs = append(errs, SegmentError{Position: current.Position, Tag: "ST", Message: "transaction set has no SE segment"})
+				current = nil
+			}
+		case "ST":
+			if current != nil {
+				errs = append(errs, SegmentError{Position: current.Position, Tag: "ST", Message: "transaction set has no SE segment"})
+			}
+			current = &TransactionSet{Code: segment.Component(0, 0), ControlNumber: segment.Component(1, 0), Position: position}
+		case "SE":
+			if current == nil {
+				errs = append(errs, SegmentError{Position: position, Tag: segment.Tag, Message: "SE without ST"})
+				continue
+			}
+			// The count includes ST and SE
+			if count := segment.Component(0, 0); count != fmt.Sprint(len(current.Segments)+2) {
+				errs = append(errs, SegmentError{Position: position, Tag: segment.Tag, Element: 1,
+					Message: fmt.Sprintf("transaction set declares %s segments but contains %d", count, len(current.Segments)+2)})
+			}
+			if control := segment.Component(1, 0); control != current.ControlNumber {
+				errs = append(errs, SegmentError{Position: position, Tag: segment.Tag, Element: 2,
+					Message: fmt.Sprintf("control number %q does not match ST control number %q", control, current.ControlNumber)})
+			}
+			interchange.TransactionSets = append(interchange.TransactionSets, *current)
+			current = nil
+		default:
+			if current == nil {
+				errs = append(errs, SegmentError{Position: position, Tag: segment.Tag, Message: "segment outside of an ST/SE transaction set"})
+				continue
+			}
+			current.Segments = append(current.Segments, segment)
+		}
+	}
+	if current != nil {
+		errs = append(errs, SegmentError{Position: current.Position, Tag: "ST", Message: "transaction set has no SE segment"})
+	}
+	if len(interchange.TransactionSets) == 0 && len(errs) == 0 {
+		errs = append(errs, SegmentError{Position: 1, Tag: "ISA", Message: "interchange contains no transaction sets"})
+	}
+	if len(errs) > 0 {
+		return &interchange, errs
+	}
+	return &interchange, nil
+}
+
+func buildSegment(raw string, elementSep, componentSep byte, position int) Segment {
+	elements := strings.Split(raw, string(elementSep))
+	segment := Segment{Tag: strings.TrimSpace(elements[0]), Position: position}
+	for i, element := range elements[1:] {
+		// ISA16 is the component separator itself
+		if segment.Tag == "ISA" && i == 15 {
+			segment.Elements = append(segment.Elements, []string{element})
+			continue
+		}
+		segment.Elements = append(segment.Elements, strings.Split(element, string(componentSep)))
+	}
+	return segment
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"fs-backend/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// BookingModeRuleRepository defines operations on the "booking_mode_rules" collection
+type BookingModeRuleRepository interface {
+	FindByForwarderID(ctx context.Context, forwarderID string) (*models.BookingModeRules, error)
+	Upsert(ctx context.Context, rules *models.BookingModeRules) error
+	DeleteByForwarderID(ctx context.Context, forwarderID string) error
+}
+
+type bookingModeRuleRepository struct {
+	collection *mongo.Collection
+}
+
+// NewBookingModeRuleRepository creates a new BookingModeRuleRepository
+func NewBookingModeRuleRepository(db *mongo.Database) BookingModeRuleRepository {
+	return &bookingModeRuleRepository{
+		collection: db.Collection("booking_mode_rules"),
+	}
+}
+
+func (r *bookingModeRuleRepository) FindByForwarderID(ctx context.Context, forwarderID string) (*models.BookingModeRules, error) {
+	var rules models.BookingModeRules
+	err := r.collection.FindOne(ctx, bson.M{"forwarder_id": forwarderID}).Decode(&rules)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil // No custom rules, caller falls back to the defaults
+		}
+		return nil, err
+	}
+	return &rules, nil
+}
+
+func (r *bookingModeRuleRepository) Upsert(ctx context.Context, rules *models.BookingModeRules) error {
+	rules.UpdatedAt = time.Now()
+	update := bson.M{"$set": bson.M{
+		"forwarder_id": rules.ForwarderID,
+		"rules":        rules.Rules,
+		"updated_at":   rules.UpdatedAt,
+	}}
+	opts := options.Update().SetUpsert(true)
+	_, err := r.collection.UpdateOne(ctx, bson.M{"forwarder_id": rules.ForwarderID}, update, opts)
+	return err
+}
+
+// DeleteByForwarderID drops a forwarder's rules, restoring the defaults
+func (r *bookingModeRuleRepository) DeleteByForwarderID(ctx context.Context, forwarderID string) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"forwarder_id": forwarderID})
+	return err
+}
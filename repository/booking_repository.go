@@ -2,35 +2,62 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"fs-backend/models"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Names of the unique booking indexes, see IsDuplicateKeyOnIndex
+const (
+	BookingShipmentIndex = "uniq_booking_shipment"
+	BookingMBLIndex      = "uniq_booking_mbl"
 )
 
 // BookingDocument represents a document in the "Booking" collection
 type BookingDocument struct {
-	ID                 primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	MBLNumber          string             `bson:"mbl_number" json:"mbl_number"`
-	ShipmentIDs        []string           `bson:"shipment_ids" json:"shipment_ids"`
-	Mode               string             `bson:"mode" json:"mode"` // FCL or LCL
-	CarrierName        string             `bson:"carrier_name" json:"carrier_name"`
-	EstimatedDeparture string             `bson:"estimated_departure" json:"estimated_departure"`
-	EstimatedArrival   string             `bson:"estimated_arrival" json:"estimated_arrival"`
-	Status             string             `bson:"status" json:"status"`
-	CreatedAt          time.Time          `bson:"created_at" json:"created_at"`
+	ID                 primitive.ObjectID             `bson:"_id,omitempty" json:"id"`
+	MBLNumber          string                         `bson:"mbl_number" json:"mbl_number"`
+	ShipmentIDs        []string                       `bson:"shipment_ids" json:"shipment_ids"`
+	Mode               string                         `bson:"mode" json:"mode"` // see models.BookingModeRules
+	ForwarderID        string                         `bson:"forwarder_id,omitempty" json:"forwarder_id,omitempty"`
+	CarrierName        string                         `bson:"carrier_name" json:"carrier_name"`
+	EstimatedDeparture *time.Time                     `bson:"estimated_departure" json:"estimated_departure"`
+	EstimatedArrival   *time.Time                     `bson:"estimated_arrival" json:"estimated_arrival"`
+	Status             string                         `bson:"status" json:"status"`
+	StatusHistory      []models.BookingStatusChange   `bson:"status_history,omitempty" json:"status_history,omitempty"`
+	ShipmentHistory    []models.BookingShipmentChange `bson:"shipment_history,omitempty" json:"shipment_history,omitempty"`
+	Revision           int64                          `bson:"revision,omitempty" json:"revision"` // incremented on every update, exposed as ETag
+	CreatedAt          time.Time                      `bson:"created_at" json:"created_at"`
+}
+
+// BookingFilter narrows the booking list by estimated departure and arrival
+type BookingFilter struct {
+	Departure DateRange
+	Arrival   DateRange
 }
 
 // BookingRepository defines read operations on the "Booking" collection
 type BookingRepository interface {
 	FindByMBLNumber(ctx context.Context, mblNumber string) (*BookingDocument, error)
+	FindByID(ctx context.Context, id primitive.ObjectID) (*BookingDocument, error)
 	CreateBooking(ctx context.Context, doc *BookingDocument) error
-	AddShipmentToBooking(ctx context.Context, mblNumber, shipmentID string) error
+	AddShipmentsToBooking(ctx context.Context, mblNumber string, shipmentIDs []string) error
 	FindByShipmentID(ctx context.Context, shipmentID string) (*BookingDocument, error)
-	GetAllBookings(ctx context.Context) ([]BookingDocument, error)
-	UpdateBookingStatus(ctx context.Context, id primitive.ObjectID, status string) error
+	FindByShipmentIDs(ctx context.Context, shipmentIDs []string) ([]BookingDocument, error)
+	GetAllBookings(ctx context.Context, filter BookingFilter) ([]BookingDocument, error)
+	UpdateBookingStatus(ctx context.Context, id primitive.ObjectID, change models.BookingStatusChange, revision int64) error
+	ReplaceShipments(ctx context.Context, id primitive.ObjectID, shipmentIDs []string, change models.BookingShipmentChange, revision int64) error
 	RemoveShipmentFromBooking(ctx context.Context, shipmentID string) error
+	UpdateSchedule(ctx context.Context, mblNumber, carrierName string, estimatedDeparture, estimatedArrival *time.Time) error
+	CarrierNameCounts(ctx context.Context) (map[string]int64, error)
+	RenameCarrier(ctx context.Context, from, to string) (int64, error)
+	EnsureIndexes(ctx context.Context) error
 }
 
 type bookingRepository struct {
@@ -53,15 +80,24 @@ func (r *bookingRepository) FindByMBLNumber(ctx context.Context, mblNumber strin
 	return &doc, nil
 }
 
+func (r *bookingRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*BookingDocument, error) {
+	var doc BookingDocument
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&doc)
+	if err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
 func (r *bookingRepository) CreateBooking(ctx context.Context, doc *BookingDocument) error {
 	doc.CreatedAt = time.Now()
 	_, err := r.collection.InsertOne(ctx, doc)
 	return err
 }
 
-func (r *bookingRepository) AddShipmentToBooking(ctx context.Context, mblNumber, shipmentID string) error {
+func (r *bookingRepository) AddShipmentsToBooking(ctx context.Context, mblNumber string, shipmentIDs []string) error {
 	filter := bson.M{"mbl_number": mblNumber}
-	update := bson.M{"$addToSet": bson.M{"shipment_ids": shipmentID}}
+	update := bson.M{"$addToSet": bson.M{"shipment_ids": bson.M{"$each": shipmentIDs}}, "$inc": bson.M{"revision": 1}}
 	_, err := r.collection.UpdateOne(ctx, filter, update)
 	return err
 }
@@ -78,9 +114,28 @@ func (r *bookingRepository) FindByShipmentID(ctx context.Context, shipmentID str
 	return &doc, nil
 }
 
-func (r *bookingRepository) GetAllBookings(ctx context.Context) ([]BookingDocument, error) {
+// FindByShipmentIDs returns the bookings holding any of the shipments
+func (r *bookingRepository) FindByShipmentIDs(ctx context.Context, shipmentIDs []string) ([]BookingDocument, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"shipment_ids": bson.M{"$in": shipmentIDs}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var docs []BookingDocument
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+func (r *bookingRepository) GetAllBookings(ctx context.Context, filter BookingFilter) ([]BookingDocument, error) {
+	query := bson.M{}
+	filter.Departure.apply(query, "estimated_departure")
+	filter.Arrival.apply(query, "estimated_arrival")
+
 	var bookings []BookingDocument
-	cursor, err := r.collection.Find(ctx, bson.M{})
+	cursor, err := r.collection.Find(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -96,16 +151,119 @@ func (r *bookingRepository) GetAllBookings(ctx context.Context) ([]BookingDocume
 	return bookings, nil
 }
 
-func (r *bookingRepository) UpdateBookingStatus(ctx context.Context, id primitive.ObjectID, status string) error {
+// UpdateBookingStatus moves the booking to change.To and appends change to its
+// history if the booking is still at revision
+func (r *bookingRepository) UpdateBookingStatus(ctx context.Context, id primitive.ObjectID, change models.BookingStatusChange, revision int64) error {
 	filter := bson.M{"_id": id}
-	update := bson.M{"$set": bson.M{"status": status}}
-	_, err := r.collection.UpdateOne(ctx, filter, update)
-	return err
+	update := bson.M{
+		"$set":  bson.M{"status": change.To},
+		"$push": bson.M{"status_history": change},
+	}
+	return updateRevision(ctx, r.collection, filter, revision, update)
+}
+
+// ReplaceShipments sets the shipments of a booking, recording the change in its
+// shipment history, if the booking is still at revision
+func (r *bookingRepository) ReplaceShipments(ctx context.Context, id primitive.ObjectID, shipmentIDs []string, change models.BookingShipmentChange, revision int64) error {
+	if shipmentIDs == nil {
+		shipmentIDs = []string{}
+	}
+	filter := bson.M{"_id": id}
+	update := bson.M{
+		"$set":  bson.M{"shipment_ids": shipmentIDs},
+		"$push": bson.M{"shipment_history": change},
+	}
+	return updateRevision(ctx, r.collection, filter, revision, update)
 }
 
 func (r *bookingRepository) RemoveShipmentFromBooking(ctx context.Context, shipmentID string) error {
 	filter := bson.M{"shipment_ids": shipmentID}
-	update := bson.M{"$pull": bson.M{"shipment_ids": shipmentID}}
+	update := bson.M{"$pull": bson.M{"shipment_ids": shipmentID}, "$inc": bson.M{"revision": 1}}
 	_, err := r.collection.UpdateMany(ctx, filter, update)
 	return err
 }
+
+// UpdateSchedule refreshes the carrier and estimated dates of a booking; empty
+// values leave the stored ones untouched
+func (r *bookingRepository) UpdateSchedule(ctx context.Context, mblNumber, carrierName string, estimatedDeparture, estimatedArrival *time.Time) error {
+	set := bson.M{}
+	if carrierName != "" {
+		set["carrier_name"] = carrierName
+	}
+	if estimatedDeparture != nil {
+		set["estimated_departure"] = estimatedDeparture
+	}
+	if estimatedArrival != nil {
+		set["estimated_arrival"] = estimatedArrival
+	}
+	if len(set) == 0 {
+		return nil
+	}
+	update := bson.M{"$set": set, "$inc": bson.M{"revision": 1}}
+	_, err := r.collection.UpdateOne(ctx, bson.M{"mbl_number": mblNumber}, update)
+	return err
+}
+
+// CarrierNameCounts returns the number of bookings per carrier name
+func (r *bookingRepository) CarrierNameCounts(ctx context.Context) (map[string]int64, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"carrier_name": bson.M{"$gt": ""}}}},
+		{{Key: "$group", Value: bson.M{"_id": "$carrier_name", "count": bson.M{"$sum": 1}}}},
+	}
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var groups []struct {
+		Name  string `bson:"_id"`
+		Count int64  `bson:"count"`
+	}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int64, len(groups))
+	for _, group := range groups {
+		counts[group.Name] = group.Count
+	}
+	return counts, nil
+}
+
+// RenameCarrier replaces a carrier name on every booking carrying it
+func (r *bookingRepository) RenameCarrier(ctx context.Context, from, to string) (int64, error) {
+	update := bson.M{"$set": bson.M{"carrier_name": to}, "$inc": bson.M{"revision": 1}}
+	res, err := r.collection.UpdateMany(ctx, bson.M{"carrier_name": from}, update)
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
+
+// EnsureIndexes creates the unique indexes that keep a shipment on a single
+// booking and an MBL to a single booking. Each index is created on its own so
+// duplicates blocking one do not keep the other from being built.
+func (r *bookingRepository) EnsureIndexes(ctx context.Context) error {
+	var errs []error
+	for _, index := range []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "shipment_ids", Value: 1}},
+			Options: options.Index().
+				SetUnique(true).
+				SetName(BookingShipmentIndex).
+				SetPartialFilterExpression(bson.M{"shipment_ids.0": bson.M{"$exists": true}}),
+		},
+		{
+			Keys: bson.D{{Key: "mbl_number", Value: 1}},
+			Options: options.Index().
+				SetUnique(true).
+				SetName(BookingMBLIndex).
+				SetPartialFilterExpression(bson.M{"mbl_number": bson.M{"$gt": ""}}),
+		},
+	} {
+		if _, err := r.collection.Indexes().CreateOne(ctx, index); err != nil {
+			errs = append(errs, fmt.Errorf("index %s: %w", *index.Options.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"fs-backend/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CarrierRepository defines operations on the "carriers" collection
+type CarrierRepository interface {
+	FindAll(ctx context.Context) ([]models.Carrier, error)
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.Carrier, error)
+	FindByMatchKey(ctx context.Context, key string) (*models.Carrier, error)
+	Insert(ctx context.Context, carrier *models.Carrier) error
+	Update(ctx context.Context, carrier *models.Carrier) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+	EnsureIndexes(ctx context.Context) error
+}
+
+type carrierRepository struct {
+	collection *mongo.Collection
+}
+
+// NewCarrierRepository creates a new CarrierRepository backed by the "carriers" collection
+func NewCarrierRepository(db *mongo.Database) CarrierRepository {
+	return &carrierRepository{
+		collection: db.Collection("carriers"),
+	}
+}
+
+func (r *carrierRepository) FindAll(ctx context.Context) ([]models.Carrier, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	carriers := []models.Carrier{}
+	if err = cursor.All(ctx, &carriers); err != nil {
+		return nil, err
+	}
+	return carriers, nil
+}
+
+func (r *carrierRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Carrier, error) {
+	var carrier models.Carrier
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&carrier)
+	if err != nil {
+		return nil, err
+	}
+	return &carrier, nil
+}
+
+// FindByMatchKey returns the carrier known by a normalized name, alias or
+// SCAC code, or nil when no carrier is
+func (r *carrierRepository) FindByMatchKey(ctx context.Context, key string) (*models.Carrier, error) {
+	var carrier models.Carrier
+	err := r.collection.FindOne(ctx, bson.M{"match_keys": key}).Decode(&carrier)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &carrier, nil
+}
+
+func (r *carrierRepository) Insert(ctx context.Context, carrier *models.Carrier) error {
+	carrier.CreatedAt = time.Now()
+	carrier.UpdatedAt = carrier.CreatedAt
+	res, err := r.collection.InsertOne(ctx, carrier)
+	if err != nil {
+		return err
+	}
+	if oid, ok := res.InsertedID.(primitive.ObjectID); ok {
+		carrier.ID = oid
+	}
+	return nil
+}
+
+// Update replaces the carrier's master data, keeping its creation time
+func (r *carrierRepository) Update(ctx context.Context, carrier *models.Carrier) error {
+	carrier.UpdatedAt = time.Now()
+	update := bson.M{"$set": bson.M{
+		"name":                   carrier.Name,
+		"aliases":                carrier.Aliases,
+		"scac_code":              carrier.SCACCode,
+		"contact":                carrier.Contact,
+		"default_free_time_days": carrier.DefaultFreeTimeDays,
+		"match_keys":             carrier.MatchKeys,
+		"updated_at":             carrier.UpdatedAt,
+	}}
+	res, err := r.collection.UpdateOne(ctx, bson.M{"_id": carrier.ID}, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (r *carrierRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	res, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// EnsureIndexes keeps a name, alias or SCAC code from resolving to two
+// carriers
+func (r *carrierRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "match_keys", Value: 1}},
+		Options: options.Index().SetUnique(true).SetName("uniq_carrier_match_key"),
+	})
+	return err
+}
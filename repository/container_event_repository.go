@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"fs-backend/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ContainerEventRepository defines operations on the "container_events" collection
+type ContainerEventRepository interface {
+	Insert(ctx context.Context, event *models.ContainerEvent) (bool, error)
+	FindByMBLNumber(ctx context.Context, mblNumber string) ([]models.ContainerEvent, error)
+	EnsureIndexes(ctx context.Context) error
+}
+
+type containerEventRepository struct {
+	collection *mongo.Collection
+}
+
+// NewContainerEventRepository creates a new ContainerEventRepository
+func NewContainerEventRepository(db *mongo.Database) ContainerEventRepository {
+	return &containerEventRepository{
+		collection: db.Collection("container_events"),
+	}
+}
+
+// Insert stores an event and reports false when the same event is already on
+// the timeline, so re-sent messages are harmless
+func (r *containerEventRepository) Insert(ctx context.Context, event *models.ContainerEvent) (bool, error) {
+	event.ReceivedAt = time.Now()
+	_, err := r.collection.InsertOne(ctx, event)
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// FindByMBLNumber returns the timeline of an MBL, oldest event first
+func (r *containerEventRepository) FindByMBLNumber(ctx context.Context, mblNumber string) ([]models.ContainerEvent, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "event_time", Value: 1}, {Key: "received_at", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"mbl_number": mblNumber}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	events := []models.ContainerEvent{}
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// EnsureIndexes makes an event unique per MBL, container, code and time
+func (r *containerEventRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "mbl_number", Value: 1},
+			{Key: "container_no", Value: 1},
+			{Key: "event_code", Value: 1},
+			{Key: "event_time", Value: 1},
+		},
+		Options: options.Index().SetUnique(true).SetName("uniq_container_event"),
+	})
+	return err
+}
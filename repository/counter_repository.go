@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// counterDocument is a named sequence in the "counters" collection
+type counterDocument struct {
+	Key string `bson:"_id"`
+	Seq int64  `bson:"seq"`
+}
+
+// CounterRepository hands out atomic, monotonically increasing sequence values
+type CounterRepository interface {
+	Next(ctx context.Context, key string) (int64, error)
+	Current(ctx context.Context, key string) (int64, error)
+	SeedAtLeast(ctx context.Context, key string, value int64) error
+}
+
+type counterRepository struct {
+	collection *mongo.Collection
+}
+
+// NewCounterRepository creates a new CounterRepository backed by the "counters" collection
+func NewCounterRepository(db *mongo.Database) CounterRepository {
+	return &counterRepository{
+		collection: db.Collection("counters"),
+	}
+}
+
+// Next increments the sequence (creating it at 1) and returns the new value
+func (r *counterRepository) Next(ctx context.Context, key string) (int64, error) {
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	var doc counterDocument
+	err := r.collection.FindOneAndUpdate(ctx, bson.M{"_id": key}, bson.M{"$inc": bson.M{"seq": 1}}, opts).Decode(&doc)
+	if err != nil {
+		return 0, err
+	}
+	return doc.Seq, nil
+}
+
+// Current returns the last value handed out, or 0 for an unused sequence
+func (r *counterRepository) Current(ctx context.Context, key string) (int64, error) {
+	var doc counterDocument
+	err := r.collection.FindOne(ctx, bson.M{"_id": key}).Decode(&doc)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return doc.Seq, nil
+}
+
+// SeedAtLeast raises the sequence to value unless it is already past it, so a
+// sequence taking over from existing IDs never hands one of them out again
+func (r *counterRepository) SeedAtLeast(ctx context.Context, key string, value int64) error {
+	opts := options.Update().SetUpsert(true)
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": key}, bson.M{"$max": bson.M{"seq": value}}, opts)
+	return err
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ScheduleDateFields are the date fields of each collection that used to be
+// stored as free-form strings
+var ScheduleDateFields = map[string][]string{
+	"Booking":   {"estimated_departure", "estimated_arrival"},
+	"shipments": {"desired_delivery_date"},
+}
+
+// StringDate is a date field of a document that is still stored as a string
+type StringDate struct {
+	ID    interface{}
+	Field string
+	Value string
+}
+
+// DateMigrationRepository converts date fields stored as strings into dates
+type DateMigrationRepository interface {
+	FindStringDates(ctx context.Context, collection string, fields []string) ([]StringDate, error)
+	SetDate(ctx context.Context, collection string, date StringDate, value *time.Time) error
+	SetUnparsed(ctx context.Context, collection string, date StringDate) error
+}
+
+type dateMigrationRepository struct {
+	db *mongo.Database
+}
+
+// NewDateMigrationRepository creates a new DateMigrationRepository
+func NewDateMigrationRepository(db *mongo.Database) DateMigrationRepository {
+	return &dateMigrationRepository{db: db}
+}
+
+// FindStringDates lists every listed field that holds a string
+func (r *dateMigrationRepository) FindStringDates(ctx context.Context, collection string, fields []string) ([]StringDate, error) {
+	var or bson.A
+	for _, field := range fields {
+		or = append(or, bson.M{field: bson.M{"$type": "string"}})
+	}
+	cursor, err := r.db.Collection(collection).Find(ctx, bson.M{"$or": or})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var dates []StringDate
+	for cursor.Next(ctx) {
+		var doc bson.M
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, err
+		}
+		for _, field := range fields {
+			if value, ok := doc[field].(string); ok {
+				dates = append(dates, StringDate{ID: doc["_id"], Field: field, Value: value})
+			}
+		}
+	}
+	return dates, cursor.Err()
+}
+
+// SetDate replaces the string with value; nil clears the field. The update
+// only applies while the field still holds the string that was read.
+func (r *dateMigrationRepository) SetDate(ctx context.Context, collection string, date StringDate, value *time.Time) error {
+	filter := bson.M{"_id": date.ID, date.Field: date.Value}
+	_, err := r.db.Collection(collection).UpdateOne(ctx, filter, bson.M{"$set": bson.M{date.Field: value}})
+	return err
+}
+
+// SetUnparsed moves a string that is not a date to <field>_unparsed and clears
+// the field, so the document can be read again and the value fixed by hand
+func (r *dateMigrationRepository) SetUnparsed(ctx context.Context, collection string, date StringDate) error {
+	filter := bson.M{"_id": date.ID, date.Field: date.Value}
+	update := bson.M{"$set": bson.M{date.Field: nil, date.Field + "_unparsed": date.Value}}
+	_, err := r.db.Collection(collection).UpdateOne(ctx, filter, update)
+	return err
+}
@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// DateRange limits a date field of a list; From is inclusive, To exclusive and
+// either may be nil for an open end
+type DateRange struct {
+	From *time.Time
+	To   *time.Time
+}
+
+// apply adds the range on field to filter
+func (r DateRange) apply(filter bson.M, field string) {
+	condition := bson.M{}
+	if r.From != nil {
+		condition["$gte"] = *r.From
+	}
+	if r.To != nil {
+		condition["$lt"] = *r.To
+	}
+	if len(condition) > 0 {
+		filter[field] = condition
+	}
+}
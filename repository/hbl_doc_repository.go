@@ -16,6 +16,9 @@ type HBLDocRepository interface {
 	CountTotal(ctx context.Context) (int64, error)
 	GetRecent(ctx context.Context, limit int64) ([]models.HBLDoc, error)
 	DeleteByID(ctx context.Context, id string) error
+	DeleteByHBLNumber(ctx context.Context, hblNumber string) (int64, error)
+	HBLNumbersWithDocs(ctx context.Context, hblNumbers []string) ([]string, error)
+	EnsureIndexes(ctx context.Context) error
 }
 
 type hblDocRepository struct {
@@ -72,3 +75,35 @@ func (r *hblDocRepository) DeleteByID(ctx context.Context, id string) error {
 	_, err = r.collection.DeleteOne(ctx, bson.M{"_id": objID})
 	return err
 }
+
+func (r *hblDocRepository) DeleteByHBLNumber(ctx context.Context, hblNumber string) (int64, error) {
+	result, err := r.collection.DeleteMany(ctx, bson.M{"hbl_number": hblNumber})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+// HBLNumbersWithDocs returns which of the given HBL numbers have at least one stored document
+func (r *hblDocRepository) HBLNumbersWithDocs(ctx context.Context, hblNumbers []string) ([]string, error) {
+	values, err := r.collection.Distinct(ctx, "hbl_number", bson.M{"hbl_number": bson.M{"$in": hblNumbers}})
+	if err != nil {
+		return nil, err
+	}
+
+	found := make([]string, 0, len(values))
+	for _, value := range values {
+		if hblNumber, ok := value.(string); ok {
+			found = append(found, hblNumber)
+		}
+	}
+	return found, nil
+}
+
+func (r *hblDocRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "hbl_number", Value: 1}},
+		Options: options.Index().SetName("hbl_number"),
+	})
+	return err
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"fs-backend/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// HBLNumberFormatRepository defines operations on the "hbl_number_formats" collection
+type HBLNumberFormatRepository interface {
+	FindByForwarderID(ctx context.Context, forwarderID string) (*models.HBLNumberFormat, error)
+	Upsert(ctx context.Context, format *models.HBLNumberFormat) error
+}
+
+type hblNumberFormatRepository struct {
+	collection *mongo.Collection
+}
+
+// NewHBLNumberFormatRepository creates a new HBLNumberFormatRepository
+func NewHBLNumberFormatRepository(db *mongo.Database) HBLNumberFormatRepository {
+	return &hblNumberFormatRepository{
+		collection: db.Collection("hbl_number_formats"),
+	}
+}
+
+func (r *hblNumberFormatRepository) FindByForwarderID(ctx context.Context, forwarderID string) (*models.HBLNumberFormat, error) {
+	var format models.HBLNumberFormat
+	err := r.collection.FindOne(ctx, bson.M{"forwarder_id": forwarderID}).Decode(&format)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil // No custom format, caller falls back to the default
+		}
+		return nil, err
+	}
+	return &format, nil
+}
+
+func (r *hblNumberFormatRepository) Upsert(ctx context.Context, format *models.HBLNumberFormat) error {
+	format.UpdatedAt = time.Now()
+	update := bson.M{"$set": bson.M{
+		"forwarder_id":       format.ForwarderID,
+		"prefix":             format.Prefix,
+		"include_mbl_number": format.IncludeMBLNumber,
+		"include_year":       format.IncludeYear,
+		"padding":            format.Padding,
+		"check_character":    format.CheckCharacter,
+		"scope":              format.Scope,
+		"updated_at":         format.UpdatedAt,
+	}}
+	opts := options.Update().SetUpsert(true)
+	_, err := r.collection.UpdateOne(ctx, bson.M{"forwarder_id": format.ForwarderID}, update, opts)
+	return err
+}
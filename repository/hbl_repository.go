@@ -2,19 +2,55 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"fs-backend/models/hbl_schema"
+	"regexp"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// Index names on the "HBL" collection
+const (
+	HBLNumberIndex      = "uniq_hbl_number"
+	HBLMBLShipmentIndex = "uniq_mbl_shipment"
+	HBLStandaloneIndex  = "uniq_standalone_shipment"
+)
+
+// ErrHBLNotDeletable is returned when an HBL's status does not allow deleting it
+var ErrHBLNotDeletable = errors.New("HBL status does not allow deletion")
+
+// HBLSortFields maps the sort keys accepted by Search to document fields
+var HBLSortFields = map[string]string{
+	"created_at":  "created_at",
+	"hbl_number":  "hbl_number",
+	"mbl_number":  "mbl_number",
+	"shipment_id": "shipment_id",
+	"status":      "status",
+}
+
 // HBLRepository defines operations on the "HBL" collection
 type HBLRepository interface {
 	InsertHBL(ctx context.Context, doc *hbl_schema.HBLDocument) error
-	UpdateHBL(ctx context.Context, hblNumber string, data hbl_schema.HBLData) error
+	UpdateHBL(ctx context.Context, hblNumber string, data hbl_schema.HBLData, revision int64) error
 	FindByHBLNumber(ctx context.Context, hblNumber string) (*hbl_schema.HBLDocument, error)
+	FindByMBLAndShipment(ctx context.Context, mblNumber, shipmentID string) (*hbl_schema.HBLDocument, error)
+	FindByMBLNumber(ctx context.Context, mblNumber string) ([]hbl_schema.HBLDocument, error)
+	LinkMBL(ctx context.Context, hblNumber, mblNumber string, data hbl_schema.HBLData, revision int64) error
+	UpdateRelease(ctx context.Context, hblNumber string, release hbl_schema.HBLRelease, change *hbl_schema.HBLStatusChange, revision int64) error
+	TransitionStatus(ctx context.Context, hblNumber, from string, change hbl_schema.HBLStatusChange) (bool, error)
+	AmendHBL(ctx context.Context, hblNumber string, data hbl_schema.HBLData, amendment hbl_schema.HBLAmendment, revision int64) (bool, error)
+	Search(ctx context.Context, query hbl_schema.HBLSearchQuery) ([]hbl_schema.HBLDocument, int64, error)
+	DeleteHBL(ctx context.Context, hblNumber string, statuses []string, revision int64) error
 	CountTotal(ctx context.Context) (int64, error)
+	BackfillMBLNumbers(ctx context.Context) (int64, error)
+	BackfillStandalone(ctx context.Context) (int64, error)
+	RestoreSeaWaybillNumbers(ctx context.Context) (int64, error)
+	EnsureIndexes(ctx context.Context) error
 }
 
 type hblRepository struct {
@@ -30,15 +66,18 @@ func NewHBLRepository(db *mongo.Database) HBLRepository {
 
 func (r *hblRepository) InsertHBL(ctx context.Context, doc *hbl_schema.HBLDocument) error {
 	doc.CreatedAt = time.Now()
+	if doc.Status == "" {
+		doc.Status = hbl_schema.HBLStatusDraft
+	}
 	_, err := r.collection.InsertOne(ctx, doc)
 	return err
 }
 
-func (r *hblRepository) UpdateHBL(ctx context.Context, hblNumber string, data hbl_schema.HBLData) error {
+// UpdateHBL replaces the HBL data if the document is still at revision
+func (r *hblRepository) UpdateHBL(ctx context.Context, hblNumber string, data hbl_schema.HBLData, revision int64) error {
 	filter := bson.M{"hbl_number": hblNumber}
 	update := bson.M{"$set": bson.M{"hbl": data}}
-	_, err := r.collection.UpdateOne(ctx, filter, update)
-	return err
+	return updateRevision(ctx, r.collection, filter, revision, update)
 }
 
 func (r *hblRepository) FindByHBLNumber(ctx context.Context, hblNumber string) (*hbl_schema.HBLDocument, error) {
@@ -48,6 +87,290 @@ func (r *hblRepository) FindByHBLNumber(ctx context.Context, hblNumber string) (
 	return &doc, err
 }
 
+func (r *hblRepository) FindByMBLAndShipment(ctx context.Context, mblNumber, shipmentID string) (*hbl_schema.HBLDocument, error) {
+	filter := bson.M{"mbl_number": mblNumber, "shipment_id": shipmentID}
+	var doc hbl_schema.HBLDocument
+	err := r.collection.FindOne(ctx, filter).Decode(&doc)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil // No HBL created yet for this shipment
+		}
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// FindByMBLNumber returns all HBLs issued under an MBL, oldest first. Legacy
+// HBLs not yet given their MBL number (see BackfillMBLNumbers) are matched by
+// the MBL number they carry as carrier reference.
+func (r *hblRepository) FindByMBLNumber(ctx context.Context, mblNumber string) ([]hbl_schema.HBLDocument, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	filter := bson.M{"$or": bson.A{
+		bson.M{"mbl_number": mblNumber},
+		bson.M{"mbl_number": bson.M{"$exists": false}, "hbl.carrier_reference": mblNumber},
+	}}
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	docs := []hbl_schema.HBLDocument{}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+// LinkMBL attaches a standalone HBL at revision to an MBL and replaces its data
+// with the merged one. Only HBLs created as standalone and not linked yet are
+// matched.
+func (r *hblRepository) LinkMBL(ctx context.Context, hblNumber, mblNumber string, data hbl_schema.HBLData, revision int64) error {
+	filter := bson.M{"hbl_number": hblNumber, "standalone": true, "mbl_number": ""}
+	update := bson.M{"$set": bson.M{"mbl_number": mblNumber, "hbl": data}}
+	return updateRevision(ctx, r.collection, filter, revision, update)
+}
+
+// UpdateRelease replaces the release state of an HBL at revision. A status
+// change, when given, is applied and appended to the history in the same write;
+// a sea waybill conversion also sets the bill type.
+func (r *hblRepository) UpdateRelease(ctx context.Context, hblNumber string, release hbl_schema.HBLRelease, change *hbl_schema.HBLStatusChange, revision int64) error {
+	filter := bson.M{"hbl_number": hblNumber}
+	set := bson.M{"release": release}
+	if release.SeaWaybill != nil {
+		set["hbl.bill_type"] = hbl_schema.HBLBillTypeSeaWaybill
+	}
+	update := bson.M{"$set": set}
+	if change != nil {
+		set["status"] = change.To
+		update["$push"] = bson.M{"status_history": change}
+	}
+	return updateRevision(ctx, r.collection, filter, revision, update)
+}
+
+// hblStatusFilter matches a status; HBLs stored before statuses existed count as drafts
+func hblStatusFilter(status string) interface{} {
+	if status == hbl_schema.HBLStatusDraft {
+		return bson.M{"$in": bson.A{hbl_schema.HBLStatusDraft, "", nil}}
+	}
+	return status
+}
+
+// TransitionStatus moves the HBL to change.To and appends change to its history,
+// provided it is still in status from. It returns false when it is not.
+func (r *hblRepository) TransitionStatus(ctx context.Context, hblNumber, from string, change hbl_schema.HBLStatusChange) (bool, error) {
+	filter := bson.M{"hbl_number": hblNumber, "status": hblStatusFilter(from)}
+	update := bson.M{
+		"$set":  bson.M{"status": change.To},
+		"$push": bson.M{"status_history": change},
+		"$inc":  bson.M{"revision": 1},
+	}
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// AmendHBL replaces the data of an issued HBL at revision and records the
+// amendment. It returns false when the HBL is no longer issued or its revision
+// has moved on.
+func (r *hblRepository) AmendHBL(ctx context.Context, hblNumber string, data hbl_schema.HBLData, amendment hbl_schema.HBLAmendment, revision int64) (bool, error) {
+	filter := bson.M{"hbl_number": hblNumber, "status": hbl_schema.HBLStatusIssued, "revision": revisionFilter(revision)}
+	update := bson.M{
+		"$set":  bson.M{"hbl": data},
+		"$push": bson.M{"amendments": amendment},
+		"$inc":  bson.M{"revision": 1},
+	}
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// Search returns one page of HBLs matching the query and the total number of matches
+func (r *hblRepository) Search(ctx context.Context, query hbl_schema.HBLSearchQuery) ([]hbl_schema.HBLDocument, int64, error) {
+	filter := bson.M{}
+	if query.MBLNumber != "" {
+		filter["mbl_number"] = query.MBLNumber
+	}
+	if query.ShipmentID != "" {
+		filter["shipment_id"] = query.ShipmentID
+	}
+	if query.Status != "" {
+		filter["status"] = hblStatusFilter(query.Status)
+	}
+	if query.Consignee != "" {
+		filter["hbl.consignee.name"] = bson.M{"$regex": regexp.QuoteMeta(query.Consignee), "$options": "i"}
+	}
+	createdAt := bson.M{}
+	if !query.From.IsZero() {
+		createdAt["$gte"] = query.From
+	}
+	if !query.To.IsZero() {
+		createdAt["$lte"] = query.To
+	}
+	if len(createdAt) > 0 {
+		filter["created_at"] = createdAt
+	}
+
+	total, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	sortField, ok := HBLSortFields[query.SortBy]
+	if !ok {
+		sortField = "created_at"
+	}
+	order := 1
+	if query.SortDesc {
+		order = -1
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: sortField, Value: order}, {Key: "_id", Value: order}}).
+		SetSkip((query.Page - 1) * query.PageSize).
+		SetLimit(query.PageSize)
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	docs := []hbl_schema.HBLDocument{}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, 0, err
+	}
+	return docs, total, nil
+}
+
+// DeleteHBL removes the HBL if it is in one of statuses and still at revision.
+// Like UpdateHBL it returns mongo.ErrNoDocuments or ErrRevisionMismatch, and
+// ErrHBLNotDeletable when the status does not allow deletion.
+func (r *hblRepository) DeleteHBL(ctx context.Context, hblNumber string, statuses []string, revision int64) error {
+	allowed := bson.A{}
+	for _, status := range statuses {
+		allowed = append(allowed, status)
+		if status == hbl_schema.HBLStatusDraft {
+			allowed = append(allowed, "", nil)
+		}
+	}
+	filter := bson.M{
+		"hbl_number": hblNumber,
+		"status":     bson.M{"$in": allowed},
+		"revision":   revisionFilter(revision),
+	}
+	result, err := r.collection.DeleteOne(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount > 0 {
+		return nil
+	}
+
+	doc, err := r.FindByHBLNumber(ctx, hblNumber)
+	if err != nil {
+		return err
+	}
+	if doc.Revision != revision {
+		return ErrRevisionMismatch
+	}
+	return ErrHBLNotDeletable
+}
+
 func (r *hblRepository) CountTotal(ctx context.Context) (int64, error) {
 	return r.collection.CountDocuments(ctx, bson.M{})
 }
+
+// BackfillMBLNumbers sets mbl_number on HBLs stored before the field existed.
+// Those were all generated from an MBL, whose number they carry as the
+// carrier reference. Without it they are invisible to per-MBL lookups and a
+// second HBL would be generated for their shipment.
+func (r *hblRepository) BackfillMBLNumbers(ctx context.Context) (int64, error) {
+	filter := bson.M{
+		"mbl_number":            bson.M{"$exists": false},
+		"hbl.carrier_reference": bson.M{"$gt": ""},
+	}
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.M{"mbl_number": "$hbl.carrier_reference"}}}}
+	res, err := r.collection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
+
+// BackfillStandalone flags the standalone HBLs created before the flag
+// existed: they are the only ones stored with an empty mbl_number, legacy HBLs
+// have no such field at all
+func (r *hblRepository) BackfillStandalone(ctx context.Context) (int64, error) {
+	filter := bson.M{"mbl_number": "", "standalone": bson.M{"$exists": false}}
+	res, err := r.collection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"standalone": true}})
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
+
+// RestoreSeaWaybillNumbers resets the sea waybill number of HBLs whose number
+// was edited before the field became read-only. PDF payloads find their HBL
+// by it, so a diverged number keeps the PDFs of those HBLs from being signed,
+// marked and linked to them.
+func (r *hblRepository) RestoreSeaWaybillNumbers(ctx context.Context) (int64, error) {
+	filter := bson.M{"$expr": bson.M{"$ne": bson.A{"$hbl.sea_waybill_no", "$hbl_number"}}}
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.M{"hbl.sea_waybill_no": "$hbl_number"}}}}
+	res, err := r.collection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
+
+// EnsureIndexes creates the unique indexes that guarantee HBL numbers are never
+// reused and that a shipment gets at most one HBL per MBL (or one standalone
+// HBL while it has no MBL), plus the lookup
+// indexes. Each index is created on its own, so one that cannot be built (e.g.
+// over legacy duplicates) does not keep the others from being created; the
+// failures are returned together.
+func (r *hblRepository) EnsureIndexes(ctx context.Context) error {
+	var errs []error
+	for _, model := range []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "hbl_number", Value: 1}},
+			Options: options.Index().SetUnique(true).SetName(HBLNumberIndex),
+		},
+		{
+			Keys:    bson.D{{Key: "shipment_id", Value: 1}},
+			Options: options.Index().SetName("shipment_id"),
+		},
+		{
+			Keys:    bson.D{{Key: "hbl.carrier_reference", Value: 1}},
+			Options: options.Index().SetName("carrier_reference"),
+		},
+		{
+			Keys:    bson.D{{Key: "created_at", Value: -1}},
+			Options: options.Index().SetName("created_at"),
+		},
+		{
+			Keys: bson.D{{Key: "mbl_number", Value: 1}, {Key: "shipment_id", Value: 1}},
+			Options: options.Index().SetUnique(true).SetName(HBLMBLShipmentIndex).
+				SetPartialFilterExpression(bson.M{"mbl_number": bson.M{"$gt": ""}}),
+		},
+		{
+			Keys: bson.D{{Key: "shipment_id", Value: 1}},
+			Options: options.Index().SetUnique(true).SetName(HBLStandaloneIndex).
+				SetPartialFilterExpression(bson.M{"standalone": true, "mbl_number": ""}),
+		},
+	} {
+		if _, err := r.collection.Indexes().CreateOne(ctx, model); err != nil {
+			errs = append(errs, fmt.Errorf("index %s: %w", *model.Options.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// IsDuplicateKeyOnIndex reports whether err is a duplicate key error raised by the named index
+func IsDuplicateKeyOnIndex(err error, indexName string) bool {
+	return mongo.IsDuplicateKeyError(err) && strings.Contains(err.Error(), indexName)
+}
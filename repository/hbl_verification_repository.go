@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"fs-backend/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// HBLVerificationRepository defines operations on the "HBL_Verifications" collection
+type HBLVerificationRepository interface {
+	InsertMany(ctx context.Context, verifications []models.HBLVerification) error
+	FindByTokenID(ctx context.Context, tokenID string) (*models.HBLVerification, error)
+	MarkHBLDeleted(ctx context.Context, hblNumber string, at time.Time) (int64, error)
+	EnsureIndexes(ctx context.Context) error
+}
+
+type hblVerificationRepository struct {
+	collection *mongo.Collection
+}
+
+// NewHBLVerificationRepository creates a new HBLVerificationRepository
+func NewHBLVerificationRepository(db *mongo.Database) HBLVerificationRepository {
+	return &hblVerificationRepository{
+		collection: db.Collection("HBL_Verifications"),
+	}
+}
+
+func (r *hblVerificationRepository) InsertMany(ctx context.Context, verifications []models.HBLVerification) error {
+	if len(verifications) == 0 {
+		return nil
+	}
+	docs := make([]interface{}, 0, len(verifications))
+	for _, verification := range verifications {
+		docs = append(docs, verification)
+	}
+	_, err := r.collection.InsertMany(ctx, docs)
+	return err
+}
+
+func (r *hblVerificationRepository) FindByTokenID(ctx context.Context, tokenID string) (*models.HBLVerification, error) {
+	var verification models.HBLVerification
+	err := r.collection.FindOne(ctx, bson.M{"token_id": tokenID}).Decode(&verification)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &verification, nil
+}
+
+// MarkHBLDeleted tombstones the verifications of a deleted HBL. They are
+// kept so the documents already handed out verify as deleted, even if the
+// HBL number is used again.
+func (r *hblVerificationRepository) MarkHBLDeleted(ctx context.Context, hblNumber string, at time.Time) (int64, error) {
+	filter := bson.M{"hbl_number": hblNumber, "hbl_deleted_at": bson.M{"$exists": false}}
+	res, err := r.collection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"hbl_deleted_at": at}})
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
+
+func (r *hblVerificationRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "token_id", Value: 1}},
+			Options: options.Index().SetUnique(true).SetName("uniq_token_id"),
+		},
+		{
+			Keys:    bson.D{{Key: "hbl_number", Value: 1}},
+			Options: options.Index().SetName("hbl_number"),
+		},
+	})
+	return err
+}
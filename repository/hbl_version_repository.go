@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"fs-backend/models/hbl_schema"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// HBLVersionRepository defines operations on the "HBL_Versions" collection
+type HBLVersionRepository interface {
+	Insert(ctx context.Context, version *hbl_schema.HBLVersion) error
+	FindByHBLNumber(ctx context.Context, hblNumber string) ([]hbl_schema.HBLVersion, error)
+	FindVersion(ctx context.Context, hblNumber string, version int) (*hbl_schema.HBLVersion, error)
+	DeleteByHBLNumber(ctx context.Context, hblNumber string) (int64, error)
+	EnsureIndexes(ctx context.Context) error
+}
+
+type hblVersionRepository struct {
+	collection *mongo.Collection
+}
+
+// NewHBLVersionRepository creates a new HBLVersionRepository backed by the "HBL_Versions" collection
+func NewHBLVersionRepository(db *mongo.Database) HBLVersionRepository {
+	return &hblVersionRepository{
+		collection: db.Collection("HBL_Versions"),
+	}
+}
+
+// Insert stores a new version. Versions are never updated; a duplicate
+// (hbl_number, version) means another update won the race.
+func (r *hblVersionRepository) Insert(ctx context.Context, version *hbl_schema.HBLVersion) error {
+	_, err := r.collection.InsertOne(ctx, version)
+	return err
+}
+
+// FindByHBLNumber returns all versions of an HBL, oldest first
+func (r *hblVersionRepository) FindByHBLNumber(ctx context.Context, hblNumber string) ([]hbl_schema.HBLVersion, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "version", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"hbl_number": hblNumber}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	versions := []hbl_schema.HBLVersion{}
+	if err := cursor.All(ctx, &versions); err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+func (r *hblVersionRepository) FindVersion(ctx context.Context, hblNumber string, version int) (*hbl_schema.HBLVersion, error) {
+	filter := bson.M{"hbl_number": hblNumber, "version": version}
+	var doc hbl_schema.HBLVersion
+	err := r.collection.FindOne(ctx, filter).Decode(&doc)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// DeleteByHBLNumber drops the history of a deleted HBL
+func (r *hblVersionRepository) DeleteByHBLNumber(ctx context.Context, hblNumber string) (int64, error) {
+	res, err := r.collection.DeleteMany(ctx, bson.M{"hbl_number": hblNumber})
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
+
+// EnsureIndexes makes version numbers unique per HBL
+func (r *hblVersionRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "hbl_number", Value: 1}, {Key: "version", Value: 1}},
+		Options: options.Index().SetUnique(true).SetName("uniq_hbl_version"),
+	})
+	return err
+}
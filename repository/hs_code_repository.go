@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"fs-backend/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// HSCodeRepository defines operations on the "hs_codes" collection
+type HSCodeRepository interface {
+	UpsertMany(ctx context.Context, codes []models.HSCode) (int64, error)
+	FindAll(ctx context.Context) ([]models.HSCode, error)
+	DeleteExcept(ctx context.Context, codes []string) (int64, error)
+	SetComplete(ctx context.Context, complete bool) error
+	LastImport(ctx context.Context) (*HSCodeImport, error)
+}
+
+// HSCodeImport describes the last import of the nomenclature
+type HSCodeImport struct {
+	Complete  bool      `bson:"complete"`
+	UpdatedAt time.Time `bson:"updated_at"`
+}
+
+// hsNomenclatureKey is the "hs_code_imports" document describing the import
+const hsNomenclatureKey = "nomenclature"
+
+type hsCodeRepository struct {
+	collection *mongo.Collection
+	imports    *mongo.Collection
+}
+
+// NewHSCodeRepository creates a new HSCodeRepository backed by the "hs_codes" collection
+func NewHSCodeRepository(db *mongo.Database) HSCodeRepository {
+	return &hsCodeRepository{
+		collection: db.Collection("hs_codes"),
+		imports:    db.Collection("hs_code_imports"),
+	}
+}
+
+func (r *hsCodeRepository) UpsertMany(ctx context.Context, codes []models.HSCode) (int64, error) {
+	if len(codes) == 0 {
+		return 0, nil
+	}
+
+	now := time.Now()
+	writes := make([]mongo.WriteModel, 0, len(codes))
+	for _, code := range codes {
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"code": code.Code}).
+			SetUpdate(bson.M{"$set": bson.M{
+				"code":        code.Code,
+				"level":       code.Level,
+				"chapter":     code.Chapter,
+				"description": code.Description,
+				"updated_at":  now,
+			}}).
+			SetUpsert(true))
+	}
+
+	res, err := r.collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		return 0, err
+	}
+	return res.UpsertedCount + res.ModifiedCount, nil
+}
+
+func (r *hsCodeRepository) FindAll(ctx context.Context) ([]models.HSCode, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var codes []models.HSCode
+	if err = cursor.All(ctx, &codes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// DeleteExcept removes the codes that are not in codes, i.e. those dropped
+// from the nomenclature since an earlier import
+func (r *hsCodeRepository) DeleteExcept(ctx context.Context, codes []string) (int64, error) {
+	res, err := r.collection.DeleteMany(ctx, bson.M{"code": bson.M{"$nin": codes}})
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
+
+// SetComplete records whether the imported table is the full nomenclature
+func (r *hsCodeRepository) SetComplete(ctx context.Context, complete bool) error {
+	update := bson.M{"$set": bson.M{"complete": complete, "updated_at": time.Now()}}
+	_, err := r.imports.UpdateOne(ctx, bson.M{"_id": hsNomenclatureKey}, update, options.Update().SetUpsert(true))
+	return err
+}
+
+// LastImport returns the last import of the nomenclature, or nil for tables
+// imported before imports were recorded (which are not complete)
+func (r *hsCodeRepository) LastImport(ctx context.Context) (*HSCodeImport, error) {
+	var doc HSCodeImport
+	err := r.imports.FindOne(ctx, bson.M{"_id": hsNomenclatureKey}).Decode(&doc)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &doc, nil
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// idempotencyKeyTTL is how long a completed request can be replayed
+const idempotencyKeyTTL = 24 * time.Hour
+
+// IdempotencyRecord remembers the outcome of a request sent with an Idempotency-Key
+type IdempotencyRecord struct {
+	Key          string    `bson:"key"`
+	Scope        string    `bson:"scope"`
+	RequestHash  string    `bson:"request_hash"`
+	Completed    bool      `bson:"completed"`
+	ResponseJSON string    `bson:"response_json"`
+	CreatedAt    time.Time `bson:"created_at"`
+}
+
+// IdempotencyRepository defines operations on the "idempotency_keys" collection
+type IdempotencyRepository interface {
+	Reserve(ctx context.Context, record *IdempotencyRecord) (bool, error)
+	Find(ctx context.Context, scope, key string) (*IdempotencyRecord, error)
+	Complete(ctx context.Context, scope, key, responseJSON string) error
+	Release(ctx context.Context, scope, key string) error
+	EnsureIndexes(ctx context.Context) error
+}
+
+type idempotencyRepository struct {
+	collection *mongo.Collection
+}
+
+// NewIdempotencyRepository creates a new IdempotencyRepository backed by the "idempotency_keys" collection
+func NewIdempotencyRepository(db *mongo.Database) IdempotencyRepository {
+	return &idempotencyRepository{
+		collection: db.Collection("idempotency_keys"),
+	}
+}
+
+// Reserve inserts a pending record. It returns false when the key is already taken.
+func (r *idempotencyRepository) Reserve(ctx context.Context, record *IdempotencyRecord) (bool, error) {
+	record.CreatedAt = time.Now()
+	_, err := r.collection.InsertOne(ctx, record)
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r *idempotencyRepository) Find(ctx context.Context, scope, key string) (*IdempotencyRecord, error) {
+	var record IdempotencyRecord
+	err := r.collection.FindOne(ctx, bson.M{"scope": scope, "key": key}).Decode(&record)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (r *idempotencyRepository) Complete(ctx context.Context, scope, key, responseJSON string) error {
+	filter := bson.M{"scope": scope, "key": key}
+	update := bson.M{"$set": bson.M{"completed": true, "response_json": responseJSON}}
+	_, err := r.collection.UpdateOne(ctx, filter, update)
+	return err
+}
+
+// Release drops a pending record so the request can be retried after a failure
+func (r *idempotencyRepository) Release(ctx context.Context, scope, key string) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"scope": scope, "key": key, "completed": false})
+	return err
+}
+
+// EnsureIndexes makes keys unique per scope and expires them after idempotencyKeyTTL
+func (r *idempotencyRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "scope", Value: 1}, {Key: "key", Value: 1}},
+			Options: options.Index().SetUnique(true).SetName("uniq_scope_key"),
+		},
+		{
+			Keys:    bson.D{{Key: "created_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(idempotencyKeyTTL.Seconds())).SetName("ttl_created_at"),
+		},
+	})
+	return err
+}
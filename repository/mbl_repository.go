@@ -3,16 +3,21 @@ package repository
 import (
 	"context"
 	"fs-backend/models/mbl_schema"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // MBLRepository defines operations on the "MBL" collection
 type MBLRepository interface {
 	InsertMBL(ctx context.Context, doc *mbl_schema.MBLDocument) error
 	FindByMBLNumber(ctx context.Context, mblNumber string) (*mbl_schema.MBLDocument, error)
+	FindByContainerNo(ctx context.Context, containerNo string) (*mbl_schema.MBLDocument, error)
+	BackfillContainerNumbers(ctx context.Context) (int64, error)
+	EnsureIndexes(ctx context.Context) error
 }
 
 type mblRepository struct {
@@ -26,8 +31,22 @@ func NewMBLRepository(db *mongo.Database) MBLRepository {
 	}
 }
 
+// normalizedContainerNos splits a container field that may hold several
+// comma, semicolon or slash separated numbers and strips spaces and dashes
+func normalizedContainerNos(containerNo string) []string {
+	var numbers []string
+	for _, number := range strings.FieldsFunc(containerNo, func(r rune) bool { return r == ',' || r == ';' || r == '/' }) {
+		number = strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(number))
+		if number != "" {
+			numbers = append(numbers, number)
+		}
+	}
+	return numbers
+}
+
 func (r *mblRepository) InsertMBL(ctx context.Context, doc *mbl_schema.MBLDocument) error {
 	doc.CreatedAt = time.Now()
+	doc.ContainerNos = normalizedContainerNos(doc.MBL.Cargo.ContainerNo)
 	_, err := r.collection.InsertOne(ctx, doc)
 	return err
 }
@@ -40,3 +59,62 @@ func (r *mblRepository) FindByMBLNumber(ctx context.Context, mblNumber string) (
 	}
 	return &doc, nil
 }
+
+// FindByContainerNo finds the latest MBL listing the container, ignoring case,
+// spaces and dashes in the number
+func (r *mblRepository) FindByContainerNo(ctx context.Context, containerNo string) (*mbl_schema.MBLDocument, error) {
+	numbers := normalizedContainerNos(containerNo)
+	if len(numbers) != 1 {
+		return nil, mongo.ErrNoDocuments
+	}
+	filter := bson.M{"container_nos": numbers[0]}
+	opts := options.FindOne().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	var doc mbl_schema.MBLDocument
+	if err := r.collection.FindOne(ctx, filter, opts).Decode(&doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// BackfillContainerNumbers sets the normalised container numbers of the MBLs
+// stored before they were kept
+func (r *mblRepository) BackfillContainerNumbers(ctx context.Context) (int64, error) {
+	filter := bson.M{"container_nos": bson.M{"$exists": false}, "mbl.cargo.container_no": bson.M{"$gt": ""}}
+	opts := options.Find().SetProjection(bson.M{"mbl.cargo.container_no": 1})
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var updated int64
+	for cursor.Next(ctx) {
+		var doc mbl_schema.MBLDocument
+		if err := cursor.Decode(&doc); err != nil {
+			return updated, err
+		}
+		numbers := normalizedContainerNos(doc.MBL.Cargo.ContainerNo)
+		if len(numbers) == 0 {
+			continue
+		}
+		if _, err := r.collection.UpdateByID(ctx, doc.ID, bson.M{"$set": bson.M{"container_nos": numbers}}); err != nil {
+			return updated, err
+		}
+		updated++
+	}
+	return updated, cursor.Err()
+}
+
+func (r *mblRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "mbl.bill_of_lading_no", Value: 1}},
+			Options: options.Index().SetName("mbl_number"),
+		},
+		{
+			Keys:    bson.D{{Key: "container_nos", Value: 1}, {Key: "created_at", Value: -1}},
+			Options: options.Index().SetName("container_nos_created_at"),
+		},
+	})
+	return err
+}
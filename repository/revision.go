@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ErrRevisionMismatch is returned when a document exists but no longer has the
+// revision the update was based on
+var ErrRevisionMismatch = errors.New("document revision has changed")
+
+// revisionFilter matches a revision. Documents written before revisions were
+// introduced have no revision field and count as revision 0.
+func revisionFilter(revision int64) interface{} {
+	if revision == 0 {
+		return bson.M{"$in": bson.A{0, nil}}
+	}
+	return revision
+}
+
+// updateRevision applies update to the document matched by filter if it still
+// has the given revision, and increments the revision. It returns
+// mongo.ErrNoDocuments when the document does not exist and ErrRevisionMismatch
+// when its revision has moved on.
+func updateRevision(ctx context.Context, collection *mongo.Collection, filter bson.M, revision int64, update bson.M) error {
+	guarded := bson.M{"revision": revisionFilter(revision)}
+	for key, value := range filter {
+		guarded[key] = value
+	}
+	update["$inc"] = bson.M{"revision": 1}
+
+	result, err := collection.UpdateOne(ctx, guarded, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount > 0 {
+		return nil
+	}
+
+	count, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return ErrRevisionMismatch
+}
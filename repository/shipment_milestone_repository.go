@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"fs-backend/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ShipmentMilestoneRepository defines operations on the "shipment_milestones" collection
+type ShipmentMilestoneRepository interface {
+	Upsert(ctx context.Context, milestone *models.ShipmentMilestone) (*models.ShipmentMilestone, error)
+	FindByShipmentID(ctx context.Context, shipmentID string) ([]models.ShipmentMilestone, error)
+	FindByShipmentIDs(ctx context.Context, shipmentIDs []string) ([]models.ShipmentMilestone, error)
+	EnsureIndexes(ctx context.Context) error
+}
+
+type shipmentMilestoneRepository struct {
+	collection *mongo.Collection
+}
+
+// NewShipmentMilestoneRepository creates a new ShipmentMilestoneRepository
+func NewShipmentMilestoneRepository(db *mongo.Database) ShipmentMilestoneRepository {
+	return &shipmentMilestoneRepository{
+		collection: db.Collection("shipment_milestones"),
+	}
+}
+
+// Upsert records a milestone of a shipment, creating it if needed. Empty
+// fields keep their stored values. Returns the milestone as stored.
+func (r *shipmentMilestoneRepository) Upsert(ctx context.Context, milestone *models.ShipmentMilestone) (*models.ShipmentMilestone, error) {
+	set := bson.M{"updated_at": time.Now()}
+	if milestone.PlannedAt != nil {
+		set["planned_at"] = milestone.PlannedAt
+	}
+	if milestone.ActualAt != nil {
+		set["actual_at"] = milestone.ActualAt
+	}
+	if milestone.PlannedLocation != "" {
+		set["planned_location"] = milestone.PlannedLocation
+	}
+	if milestone.ActualLocation != "" {
+		set["actual_location"] = milestone.ActualLocation
+	}
+	if milestone.Remarks != "" {
+		set["remarks"] = milestone.Remarks
+	}
+	if milestone.RecordedBy != "" {
+		set["recorded_by"] = milestone.RecordedBy
+	}
+
+	filter := bson.M{
+		"shipment_id": milestone.ShipmentID,
+		"milestone":   milestone.Milestone,
+		"sequence":    milestone.Sequence,
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	var stored models.ShipmentMilestone
+	if err := r.collection.FindOneAndUpdate(ctx, filter, bson.M{"$set": set}, opts).Decode(&stored); err != nil {
+		return nil, err
+	}
+	return &stored, nil
+}
+
+func (r *shipmentMilestoneRepository) FindByShipmentID(ctx context.Context, shipmentID string) ([]models.ShipmentMilestone, error) {
+	return r.find(ctx, bson.M{"shipment_id": shipmentID})
+}
+
+func (r *shipmentMilestoneRepository) FindByShipmentIDs(ctx context.Context, shipmentIDs []string) ([]models.ShipmentMilestone, error) {
+	return r.find(ctx, bson.M{"shipment_id": bson.M{"$in": shipmentIDs}})
+}
+
+func (r *shipmentMilestoneRepository) find(ctx context.Context, filter bson.M) ([]models.ShipmentMilestone, error) {
+	cursor, err := r.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	milestones := []models.ShipmentMilestone{}
+	if err := cursor.All(ctx, &milestones); err != nil {
+		return nil, err
+	}
+	return milestones, nil
+}
+
+// EnsureIndexes keeps one milestone of a kind (and transhipment number) per shipment
+func (r *shipmentMilestoneRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "shipment_id", Value: 1},
+			{Key: "milestone", Value: 1},
+			{Key: "sequence", Value: 1},
+		},
+		Options: options.Index().SetUnique(true).SetName("uniq_shipment_milestone"),
+	})
+	return err
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -11,31 +12,39 @@ import (
 
 // ShipmentDocument represents a document in the "shipments" collection
 type ShipmentDocument struct {
-	ShipmentID          string  `bson:"shipment_id" json:"shipment_id"`
-	ShipperID           string  `bson:"shipper_id" json:"shipper_id"`
-	Mode                string  `bson:"mode" json:"mode"`
-	CargoType           string  `bson:"cargo_type" json:"cargo_type"`
-	GoodsDescription    string  `bson:"goods_description" json:"goods_description"`
-	PackagesCount       int     `bson:"packages_count" json:"packages_count"`
-	GrossWeight         float64 `bson:"gross_weight" json:"gross_weight"`
-	NetWeight           float64 `bson:"net_weight" json:"net_weight"`
-	Volume              float64 `bson:"volume" json:"volume"`
-	MarksAndNumbers     string  `bson:"marks_and_numbers" json:"marks_and_numbers"`
-	Measurement         string  `bson:"measurement" json:"measurement"`
-	Origin              string  `bson:"origin" json:"origin"`
-	Destination         string  `bson:"destination" json:"destination"`
-	DesiredDeliveryDate string  `bson:"desired_delivery_date" json:"desired_delivery_date"`
-	SpecialRequirements string  `bson:"special_requirements" json:"special_requirements"`
+	ShipmentID          string     `bson:"shipment_id" json:"shipment_id"`
+	ShipperID           string     `bson:"shipper_id" json:"shipper_id"`
+	ForwarderID         string     `bson:"forwarder_id,omitempty" json:"forwarder_id,omitempty"` // whose booking mode rules apply
+	Mode                string     `bson:"mode" json:"mode"`
+	CargoType           string     `bson:"cargo_type" json:"cargo_type"`
+	GoodsDescription    string     `bson:"goods_description" json:"goods_description"`
+	PackagesCount       int        `bson:"packages_count" json:"packages_count"`
+	GrossWeight         float64    `bson:"gross_weight" json:"gross_weight"`
+	NetWeight           float64    `bson:"net_weight" json:"net_weight"`
+	Volume              float64    `bson:"volume" json:"volume"`
+	MarksAndNumbers     string     `bson:"marks_and_numbers" json:"marks_and_numbers"`
+	Measurement         string     `bson:"measurement" json:"measurement"`
+	Origin              string     `bson:"origin" json:"origin"`
+	Destination         string     `bson:"destination" json:"destination"`
+	DesiredDeliveryDate *time.Time `bson:"desired_delivery_date" json:"desired_delivery_date"`
+	SpecialRequirements string     `bson:"special_requirements" json:"special_requirements"`
+	Revision            int64      `bson:"revision,omitempty" json:"revision"` // incremented on every update, exposed as ETag
+}
+
+// ShipmentFilter narrows the shipment list by desired delivery date
+type ShipmentFilter struct {
+	DesiredDelivery DateRange
 }
 
 // ShipmentRepository defines read operations on the "shipments" collection
 type ShipmentRepository interface {
 	FindByShipmentIDs(ctx context.Context, shipmentIDs []string) ([]ShipmentDocument, error)
 	FindByShipperIDs(ctx context.Context, shipperIDs []string) ([]ShipmentDocument, error)
+	FindByShipmentID(ctx context.Context, shipmentID string) (*ShipmentDocument, error)
 	GetNextShipmentID(ctx context.Context) (string, error)
-	GetAllShipments(ctx context.Context) ([]ShipmentDocument, error)
+	GetAllShipments(ctx context.Context, filter ShipmentFilter) ([]ShipmentDocument, error)
 	InsertShipment(ctx context.Context, doc *ShipmentDocument) error
-	UpdateShipment(ctx context.Context, shipmentID string, doc *ShipmentDocument) error
+	UpdateShipment(ctx context.Context, shipmentID string, doc *ShipmentDocument, revision int64) error
 	DeleteShipment(ctx context.Context, shipmentID string) error
 }
 
@@ -80,6 +89,15 @@ func (r *shipmentRepository) FindByShipperIDs(ctx context.Context, shipperIDs []
 	return docs, nil
 }
 
+func (r *shipmentRepository) FindByShipmentID(ctx context.Context, shipmentID string) (*ShipmentDocument, error) {
+	var doc ShipmentDocument
+	err := r.collection.FindOne(ctx, bson.M{"shipment_id": shipmentID}).Decode(&doc)
+	if err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
 func (r *shipmentRepository) GetNextShipmentID(ctx context.Context) (string, error) {
 	opts := options.FindOne().SetSort(bson.D{{Key: "shipment_id", Value: -1}})
 	var lastShipment ShipmentDocument
@@ -97,8 +115,11 @@ func (r *shipmentRepository) GetNextShipmentID(ctx context.Context) (string, err
 	return newID, nil
 }
 
-func (r *shipmentRepository) GetAllShipments(ctx context.Context) ([]ShipmentDocument, error) {
-	cursor, err := r.collection.Find(ctx, bson.M{})
+func (r *shipmentRepository) GetAllShipments(ctx context.Context, filter ShipmentFilter) ([]ShipmentDocument, error) {
+	query := bson.M{}
+	filter.DesiredDelivery.apply(query, "desired_delivery_date")
+
+	cursor, err := r.collection.Find(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -116,15 +137,16 @@ func (r *shipmentRepository) InsertShipment(ctx context.Context, doc *ShipmentDo
 	return err
 }
 
-func (r *shipmentRepository) UpdateShipment(ctx context.Context, shipmentID string, doc *ShipmentDocument) error {
+// UpdateShipment replaces the shipment if it is still at revision
+func (r *shipmentRepository) UpdateShipment(ctx context.Context, shipmentID string, doc *ShipmentDocument, revision int64) error {
 	filter := bson.M{"shipment_id": shipmentID}
 	
 	updateDoc := *doc
 	updateDoc.ShipmentID = shipmentID
+	updateDoc.Revision = 0 // maintained by updateRevision
 	
 	update := bson.M{"$set": updateDoc}
-	_, err := r.collection.UpdateOne(ctx, filter, update)
-	return err
+	return updateRevision(ctx, r.collection, filter, revision, update)
 }
 
 func (r *shipmentRepository) DeleteShipment(ctx context.Context, shipmentID string) error {
@@ -2,12 +2,17 @@ package repository
 
 import (
 	"context"
+	"fmt"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// ShipperIDIndex is the name of the unique shipper ID index, see IsDuplicateKeyOnIndex
+const ShipperIDIndex = "uniq_shipper_id"
+
 // ShipperDocument represents a document in the "shippers" collection
 type ShipperDocument struct {
 	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
@@ -15,6 +20,7 @@ type ShipperDocument struct {
 	ShipperName    string             `bson:"shipper_name" json:"shipper_name"`
 	ShipperAddress string             `bson:"shipper_address" json:"shipper_address"`
 	ShipperContact string             `bson:"shipper_contact" json:"shipper_contact"`
+	Revision       int64              `bson:"revision,omitempty" json:"revision"` // incremented on every update, exposed as ETag
 }
 
 // ShipperRepository defines read operations on the "shippers" collection
@@ -22,8 +28,11 @@ type ShipperRepository interface {
 	FindByShipperIDs(ctx context.Context, shipperIDs []string) ([]ShipperDocument, error)
 	CreateShipper(ctx context.Context, doc ShipperDocument) (*mongo.InsertOneResult, error)
 	FindAllShippers(ctx context.Context) ([]ShipperDocument, error)
-	UpdateShipper(ctx context.Context, id primitive.ObjectID, doc map[string]interface{}) (*mongo.UpdateResult, error)
+	FindByID(ctx context.Context, id primitive.ObjectID) (*ShipperDocument, error)
+	UpdateShipper(ctx context.Context, id primitive.ObjectID, doc map[string]interface{}, revision int64) error
 	DeleteShipper(ctx context.Context, id primitive.ObjectID) (*mongo.DeleteResult, error)
+	MaxShipperNumber(ctx context.Context) (int64, error)
+	EnsureIndexes(ctx context.Context) error
 }
 
 type shipperRepository struct {
@@ -74,13 +83,61 @@ func (r *shipperRepository) FindAllShippers(ctx context.Context) ([]ShipperDocum
 	return docs, nil
 }
 
-func (r *shipperRepository) UpdateShipper(ctx context.Context, id primitive.ObjectID, doc map[string]interface{}) (*mongo.UpdateResult, error) {
+func (r *shipperRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*ShipperDocument, error) {
+	var doc ShipperDocument
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&doc)
+	if err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// UpdateShipper sets the given fields if the shipper is still at revision
+func (r *shipperRepository) UpdateShipper(ctx context.Context, id primitive.ObjectID, doc map[string]interface{}, revision int64) error {
 	filter := bson.M{"_id": id}
 	update := bson.M{"$set": doc}
-	return r.collection.UpdateOne(ctx, filter, update)
+	return updateRevision(ctx, r.collection, filter, revision, update)
 }
 
 func (r *shipperRepository) DeleteShipper(ctx context.Context, id primitive.ObjectID) (*mongo.DeleteResult, error) {
 	filter := bson.M{"_id": id}
 	return r.collection.DeleteOne(ctx, filter)
 }
+
+// MaxShipperNumber returns the highest number of the SHPR<n> shipper IDs in
+// use, compared as numbers so SHPR1000 sorts after SHPR999
+func (r *shipperRepository) MaxShipperNumber(ctx context.Context) (int64, error) {
+	filter := bson.M{"shipper_id": bson.M{"$regex": "^SHPR[0-9]+$"}}
+	opts := options.Find().SetProjection(bson.M{"shipper_id": 1})
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var max int64
+	for cursor.Next(ctx) {
+		var doc ShipperDocument
+		if err := cursor.Decode(&doc); err != nil {
+			return 0, err
+		}
+		var n int64
+		if _, err := fmt.Sscanf(doc.ShipperID, "SHPR%d", &n); err == nil && n > max {
+			max = n
+		}
+	}
+	return max, cursor.Err()
+}
+
+// EnsureIndexes creates the unique shipper ID index; shippers without an ID
+// are left out of it
+func (r *shipperRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "shipper_id", Value: 1}},
+		Options: options.Index().
+			SetUnique(true).
+			SetName(ShipperIDIndex).
+			SetPartialFilterExpression(bson.M{"shipper_id": bson.M{"$gt": ""}}),
+	})
+	return err
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"fs-backend/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// StuffingPlanRepository defines operations on the "stuffing_plans" collection
+type StuffingPlanRepository interface {
+	FindByMBLNumber(ctx context.Context, mblNumber string) (*models.StuffingPlan, error)
+	Upsert(ctx context.Context, plan *models.StuffingPlan) error
+	DeleteByMBLNumber(ctx context.Context, mblNumber string) error
+	EnsureIndexes(ctx context.Context) error
+}
+
+type stuffingPlanRepository struct {
+	collection *mongo.Collection
+}
+
+// NewStuffingPlanRepository creates a new StuffingPlanRepository
+func NewStuffingPlanRepository(db *mongo.Database) StuffingPlanRepository {
+	return &stuffingPlanRepository{
+		collection: db.Collection("stuffing_plans"),
+	}
+}
+
+func (r *stuffingPlanRepository) FindByMBLNumber(ctx context.Context, mblNumber string) (*models.StuffingPlan, error) {
+	var plan models.StuffingPlan
+	err := r.collection.FindOne(ctx, bson.M{"mbl_number": mblNumber}).Decode(&plan)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil // No plan, HBLs use the MBL container
+		}
+		return nil, err
+	}
+	return &plan, nil
+}
+
+// Upsert replaces the MBL's plan, creating it if needed
+func (r *stuffingPlanRepository) Upsert(ctx context.Context, plan *models.StuffingPlan) error {
+	plan.UpdatedAt = time.Now()
+	update := bson.M{"$set": bson.M{
+		"mbl_number":  plan.MBLNumber,
+		"containers":  plan.Containers,
+		"allocations": plan.Allocations,
+		"updated_at":  plan.UpdatedAt,
+	}}
+	opts := options.Update().SetUpsert(true)
+	_, err := r.collection.UpdateOne(ctx, bson.M{"mbl_number": plan.MBLNumber}, update, opts)
+	return err
+}
+
+func (r *stuffingPlanRepository) DeleteByMBLNumber(ctx context.Context, mblNumber string) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"mbl_number": mblNumber})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// EnsureIndexes makes the MBL number unique so an MBL has a single plan
+func (r *stuffingPlanRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "mbl_number", Value: 1}},
+		Options: options.Index().SetUnique(true).SetName("uniq_mbl_number"),
+	})
+	return err
+}
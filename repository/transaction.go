@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// TxRunner runs a unit of work inside a MongoDB transaction. Repository calls
+// made with the context passed to fn take part in the transaction. MongoDB
+// only supports transactions on a replica set or sharded cluster.
+type TxRunner interface {
+	RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type txRunner struct {
+	client *mongo.Client
+}
+
+// NewTxRunner creates a TxRunner on the client of db
+func NewTxRunner(db *mongo.Database) TxRunner {
+	return &txRunner{client: db.Client()}
+}
+
+// RunInTransaction commits when fn returns nil and aborts otherwise. fn is run
+// again when the transaction hits a transient error such as a write conflict,
+// so it must not have side effects outside the database.
+func (t *txRunner) RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	session, err := t.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sessCtx)
+	})
+	return err
+}
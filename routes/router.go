@@ -7,19 +7,97 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func RegisterRoutes(router *gin.Engine, pdfService services.PdfGeneratorService, pdfSaveService services.PdfSaveService, docConvertService services.DocumentConvertService, docPreviewService services.DocumentPreviewService, bookingController *controllers.BookingController, shipmentController *controllers.ShipmentController, dashboardController *controllers.DashboardController, authController controllers.AuthController, infoToDocController *controllers.InfoToDocController) {
+func RegisterRoutes(router *gin.Engine, pdfService services.PdfGeneratorService, pdfSaveService services.PdfSaveService, docConvertService services.DocumentConvertService, docPreviewService services.DocumentPreviewService, bookingController *controllers.BookingController, shipmentController *controllers.ShipmentController, dashboardController *controllers.DashboardController, authController controllers.AuthController, infoToDocController *controllers.InfoToDocController, partyMatchingController *controllers.PartyMatchingController, hsCodeController *controllers.HSCodeController, hblNumberFormatController *controllers.HBLNumberFormatController, hblLifecycleController *controllers.HBLLifecycleController, hblVersionService services.HBLVersionService, hblController *controllers.HBLController, reconciliationService services.ReconciliationService, stuffingPlanController *controllers.StuffingPlanController, hblReleaseService services.HBLReleaseService, hblVerificationService services.HBLVerificationService, documentExchangeController *controllers.DocumentExchangeController, containerStatusController *controllers.ContainerStatusController, shipmentMilestoneController *controllers.ShipmentMilestoneController, bookingModeController *controllers.BookingModeController, loadPlanController *controllers.LoadPlanController, carrierController *controllers.CarrierController) {
 	pdfSaveController := controllers.NewPdfSaveController(pdfSaveService)
-	pdfController := controllers.NewPdfGeneratorController(pdfService, pdfSaveController)
+	pdfController := controllers.NewPdfGeneratorController(pdfService, pdfSaveController, reconciliationService, hblReleaseService, hblVerificationService)
 	docConvertController := controllers.NewDocumentConvertController(docConvertService)
 	docPreviewController := controllers.NewDocumentPreviewController(docPreviewService)
+	hblVersionController := controllers.NewHBLVersionController(hblVersionService, pdfController)
+	reconciliationController := controllers.NewReconciliationController(reconciliationService)
+	hblReleaseController := controllers.NewHBLReleaseController(hblReleaseService, pdfController)
+	hblVerificationController := controllers.NewHBLVerificationController(hblVerificationService)
+
+	//Public HBL verification (unauthenticated)
+	router.GET("/verify/:token", hblVerificationController.Verify)
 
 	api := router.Group("/api/v1")
 	{
 		api.POST("/pdf-generator", pdfController.Generate)
 		api.POST("/convert/mbl", docConvertController.ConvertMBL)
 		api.POST("/preview/hbl", docPreviewController.PreviewHBL)
+		api.POST("/hbl", docPreviewController.CreateHBLs)
+		api.POST("/hbl/standalone", docPreviewController.CreateStandaloneHBL)
+		api.GET("/hbl", hblController.ListHBLs)
+		api.GET("/hbl/:hbl_number", hblController.GetHBL)
 		api.PUT("/hbl/:hbl_number", docPreviewController.UpdateHBL)
+		api.PATCH("/hbl/:hbl_number", docPreviewController.PatchHBL)
+		api.DELETE("/hbl/:hbl_number", hblController.DeleteHBL)
+		api.POST("/hbl/:hbl_number/link-mbl", docPreviewController.LinkMBL)
 		api.POST("/hbl-docs/download-archive", controllers.DownloadHBLDocsArchive)
+
+		//Party matching
+		api.GET("/mbl/:mbl_number/party-matches", partyMatchingController.GetPartyMatches)
+		api.POST("/mbl/:mbl_number/parties/:party/shipper", partyMatchingController.CreateShipperFromParty)
+
+		//Reconciliation
+		api.GET("/mbl/:mbl_number/reconciliation", reconciliationController.GetReport)
+
+		//LCL stuffing plans
+		api.GET("/mbl/:mbl_number/stuffing-plan", stuffingPlanController.GetPlan)
+		api.PUT("/mbl/:mbl_number/stuffing-plan", stuffingPlanController.SavePlan)
+		api.DELETE("/mbl/:mbl_number/stuffing-plan", stuffingPlanController.DeletePlan)
+		api.GET("/container-types", stuffingPlanController.ListContainerTypes)
+
+		//DCSA eBL exchange
+		api.GET("/hbl/:hbl_number/export", documentExchangeController.ExportHBL)
+		api.GET("/mbl/:mbl_number/export", documentExchangeController.ExportMBL)
+		api.POST("/mbl/import", documentExchangeController.ImportMBL)
+
+		//Container status
+		api.POST("/container-status", containerStatusController.Ingest)
+		api.GET("/mbl/:mbl_number/container-events", containerStatusController.Timeline)
+
+		//HS codes
+		api.GET("/hs-codes/validate", hsCodeController.Validate)
+		api.GET("/hs-codes/suggest", hsCodeController.Suggest)
+
+		//HBL numbering
+		api.GET("/hbl-number-format", hblNumberFormatController.GetFormat)
+		api.PUT("/hbl-number-format", hblNumberFormatController.SaveFormat)
+		api.POST("/hbl-number-format/preview", hblNumberFormatController.PreviewFormat)
+
+		//Booking mode rules
+		api.GET("/booking-mode-rules", bookingModeController.GetRules)
+		api.PUT("/booking-mode-rules", bookingModeController.SaveRules)
+		api.DELETE("/booking-mode-rules", bookingModeController.ResetRules)
+
+		//Carrier master
+		api.GET("/carriers", carrierController.ListCarriers)
+		api.GET("/carriers/resolve", carrierController.ResolveCarrier)
+		api.GET("/carriers/:id", carrierController.GetCarrier)
+		api.POST("/carriers", carrierController.CreateCarrier)
+		api.PUT("/carriers/:id", carrierController.UpdateCarrier)
+		api.DELETE("/carriers/:id", carrierController.DeleteCarrier)
+
+		//HBL lifecycle
+		api.GET("/hbl/:hbl_number/status", hblLifecycleController.GetStatus)
+		api.POST("/hbl/:hbl_number/transitions", hblLifecycleController.Transition)
+		api.POST("/hbl/:hbl_number/amendments", hblLifecycleController.Amend)
+
+		//HBL versions
+		api.GET("/hbl/:hbl_number/versions", hblVersionController.ListVersions)
+		api.GET("/hbl/:hbl_number/versions/:version", hblVersionController.GetVersion)
+		api.POST("/hbl/:hbl_number/versions/:version/pdf", hblVersionController.RegeneratePDF)
+		api.GET("/hbl/:hbl_number/diff", hblVersionController.Diff)
+
+		//HBL originals and release
+		api.GET("/hbl/:hbl_number/release", hblReleaseController.GetRelease)
+		api.POST("/hbl/:hbl_number/originals", hblReleaseController.IssueOriginals)
+		api.POST("/hbl/:hbl_number/originals/surrender", hblReleaseController.SurrenderOriginals)
+		api.POST("/hbl/:hbl_number/originals/pdf", hblReleaseController.PrintOriginals)
+		api.GET("/hbl/:hbl_number/telex-release", hblReleaseController.GetTelexReleaseMessage)
+		api.POST("/hbl/:hbl_number/telex-release", hblReleaseController.TelexRelease)
+		api.POST("/hbl/:hbl_number/sea-waybill", hblReleaseController.ConvertToSeaWaybill)
 	}
 
 	usersAPI := router.Group("/api/users")
@@ -49,8 +127,21 @@ func RegisterRoutes(router *gin.Engine, pdfService services.PdfGeneratorService,
 		bookingApi.PUT("/shipments/:id", shipmentController.UpdateShipment)
 		bookingApi.DELETE("/shipments/:id", shipmentController.DeleteShipment)
 
+		//Shipment milestones
+		bookingApi.GET("/shipments/:id/milestones", shipmentMilestoneController.ListMilestones)
+		bookingApi.POST("/shipments/:id/milestones", shipmentMilestoneController.RecordMilestone)
+
 		//Sync MBL Number
 		bookingApi.POST("/syncBooking", bookingController.SyncBooking)
+
+		//Load planning
+		bookingApi.POST("/loadplan", loadPlanController.PlanLoad)
+
+		//Booking operations
+		bookingApi.POST("/moveshipments/:id", bookingController.MoveShipments)
+		bookingApi.POST("/splitbooking/:id", bookingController.SplitBooking)
+		bookingApi.POST("/mergebooking/:id", bookingController.MergeBookings)
+		bookingApi.POST("/cancelbooking/:id", bookingController.CancelBooking)
 	}
 
 	dashboardApi := router.Group("/api/dashboard")
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"fs-backend/models"
+	"fs-backend/repository"
+)
+
+// bookingModePattern is the shape of a mode code such as FCL or RORO
+var bookingModePattern = regexp.MustCompile(`^[A-Z][A-Z0-9_-]{1,19}$`)
+
+func normalizeBookingMode(mode string) string {
+	return strings.ToUpper(strings.TrimSpace(mode))
+}
+
+// loadBookingModeRules returns the forwarder's mode rules, or the defaults
+func loadBookingModeRules(ctx context.Context, repo repository.BookingModeRuleRepository, forwarderID string) (models.BookingModeRules, error) {
+	rules, err := repo.FindByForwarderID(ctx, forwarderID)
+	if err != nil {
+		return models.BookingModeRules{}, err
+	}
+	if rules == nil {
+		defaults := models.DefaultBookingModeRules()
+		defaults.ForwarderID = forwarderID
+		return defaults, nil
+	}
+	return *rules, nil
+}
+
+// findBookingModeRule looks a mode up in a rule set, answering
+// ErrInvalidBookingMode with the modes available when it is not there
+func findBookingModeRule(rules models.BookingModeRules, mode string) (models.BookingModeRule, error) {
+	mode = normalizeBookingMode(mode)
+	modes := make([]string, len(rules.Rules))
+	for i, rule := range rules.Rules {
+		if rule.Mode == mode {
+			return rule, nil
+		}
+		modes[i] = rule.Mode
+	}
+	return models.BookingModeRule{}, fmt.Errorf("%w: %q, please select one of %s", ErrInvalidBookingMode, mode, strings.Join(modes, ", "))
+}
+
+// validateBookingModeRules checks a rule set before it is saved and
+// normalizes its mode codes and cargo types
+func validateBookingModeRules(rules *models.BookingModeRules) error {
+	if strings.TrimSpace(rules.ForwarderID) == "" {
+		return errors.New("forwarder_id is required")
+	}
+	if len(rules.Rules) == 0 {
+		return errors.New("at least one mode is required")
+	}
+	seen := make(map[string]bool, len(rules.Rules))
+	for i := range rules.Rules {
+		rule := &rules.Rules[i]
+		rule.Mode = normalizeBookingMode(rule.Mode)
+		switch {
+		case !bookingModePattern.MatchString(rule.Mode):
+			return fmt.Errorf("mode %q must be 2 to 20 letters, digits, '-' or '_', starting with a letter", rule.Mode)
+		case seen[rule.Mode]:
+			return fmt.Errorf("mode %s is listed twice", rule.Mode)
+		case rule.MaxShipments < 0 || rule.MaxWeightKg < 0 || rule.MaxVolumeCbm < 0:
+			return fmt.Errorf("mode %s: limits cannot be negative", rule.Mode)
+		}
+		seen[rule.Mode] = true
+		rule.AllowedCargoTypes = nonEmptyStrings(rule.AllowedCargoTypes)
+	}
+	return nil
+}
+
+// cargoTypeAllowed tells whether a rule accepts a shipment's cargo type
+func cargoTypeAllowed(rule models.BookingModeRule, cargoType string) bool {
+	if len(rule.AllowedCargoTypes) == 0 {
+		return true
+	}
+	for _, allowed := range rule.AllowedCargoTypes {
+		if strings.EqualFold(allowed, strings.TrimSpace(cargoType)) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateBookingLoad applies the limits of a mode to the shipments a booking
+// will hold, including those already on it. shipments are the documents of
+// shipmentIDs; weights are taken as kg and volumes as cbm.
+func validateBookingLoad(rule models.BookingModeRule, shipmentIDs []string, shipments []repository.ShipmentDocument) error {
+	if rule.MaxShipments > 0 && len(shipmentIDs) > rule.MaxShipments {
+		return fmt.Errorf("%s booking can have at most %d shipment(s), it would hold %d", rule.Mode, rule.MaxShipments, len(shipmentIDs))
+	}
+	var weight, volume float64
+	for _, shipment := range shipments {
+		if containsString(shipmentIDs, shipment.ShipmentID) {
+			weight += shipment.GrossWeight
+			volume += shipment.Volume
+		}
+	}
+	if rule.MaxWeightKg > 0 && weight > rule.MaxWeightKg {
+		return fmt.Errorf("%s booking can carry at most %.0f kg, the shipments weigh %.0f kg", rule.Mode, rule.MaxWeightKg, weight)
+	}
+	if rule.MaxVolumeCbm > 0 && volume > rule.MaxVolumeCbm {
+		return fmt.Errorf("%s booking can carry at most %.2f cbm, the shipments measure %.2f cbm", rule.Mode, rule.MaxVolumeCbm, volume)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"fs-backend/models"
+	"fs-backend/repository"
+)
+
+var (
+	ErrInvalidBookingModeRules = errors.New("invalid booking mode rules")
+	ErrInvalidBookingMode      = errors.New("invalid mode")
+)
+
+// BookingModeService manages the booking modes of each forwarder and the
+// limits a booking of each mode has to respect
+type BookingModeService interface {
+	GetRules(ctx context.Context, forwarderID string) (models.BookingModeRules, error)
+	SaveRules(ctx context.Context, rules models.BookingModeRules) (models.BookingModeRules, error)
+	ResetRules(ctx context.Context, forwarderID string) (models.BookingModeRules, error)
+}
+
+type bookingModeService struct {
+	repo repository.BookingModeRuleRepository
+}
+
+// NewBookingModeService creates a new BookingModeService
+func NewBookingModeService(repo repository.BookingModeRuleRepository) BookingModeService {
+	return &bookingModeService{repo: repo}
+}
+
+// GetRules returns the forwarder's mode rules, or the default ones
+func (s *bookingModeService) GetRules(ctx context.Context, forwarderID string) (models.BookingModeRules, error) {
+	return loadBookingModeRules(ctx, s.repo, forwarderID)
+}
+
+// SaveRules replaces the forwarder's mode rules. Existing bookings keep their
+// mode; the new limits apply to the next shipments added to them.
+func (s *bookingModeService) SaveRules(ctx context.Context, rules models.BookingModeRules) (models.BookingModeRules, error) {
+	if err := validateBookingModeRules(&rules); err != nil {
+		return rules, fmt.Errorf("%w: %v", ErrInvalidBookingModeRules, err)
+	}
+	if err := s.repo.Upsert(ctx, &rules); err != nil {
+		return rules, err
+	}
+	return rules, nil
+}
+
+// ResetRules drops the forwarder's own rules and returns the defaults in force
+func (s *bookingModeService) ResetRules(ctx context.Context, forwarderID string) (models.BookingModeRules, error) {
+	if err := s.repo.DeleteByForwarderID(ctx, forwarderID); err != nil {
+		return models.BookingModeRules{}, err
+	}
+	return loadBookingModeRules(ctx, s.repo, forwarderID)
+}
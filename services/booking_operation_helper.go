@@ -0,0 +1,47 @@
+package services
+
+import (
+	"fmt"
+
+	"fs-backend/models"
+	"fs-backend/repository"
+)
+
+// BookingOperationResult is the outcome of a move, split or merge: the booking
+// operated on and the other booking involved, both as stored afterwards
+type BookingOperationResult struct {
+	Booking *repository.BookingDocument `json:"booking"`
+	Related *repository.BookingDocument `json:"related_booking"`
+}
+
+// checkBookingAmendable allows shipments to be moved on or off a booking for
+// as long as the booking could still be cancelled
+func checkBookingAmendable(booking *repository.BookingDocument) error {
+	status := normalizeBookingStatus(booking.Status)
+	if !canTransitionBooking(status, models.BookingStatusCancelled) {
+		return fmt.Errorf("%w: booking %s is %s", ErrBookingLocked, booking.MBLNumber, status)
+	}
+	return nil
+}
+
+// shipmentsNotOnBooking reports the shipments that are not on the booking
+func shipmentsNotOnBooking(booking *repository.BookingDocument, shipmentIDs []string) []ShipmentSyncError {
+	var problems []ShipmentSyncError
+	for _, id := range shipmentIDs {
+		if !containsString(booking.ShipmentIDs, id) {
+			problems = append(problems, ShipmentSyncError{ShipmentID: id, Message: "not on booking " + booking.MBLNumber})
+		}
+	}
+	return problems
+}
+
+// remainingShipments returns the shipments of a booking without the removed ones
+func remainingShipments(shipmentIDs, removed []string) []string {
+	remaining := []string{}
+	for _, id := range shipmentIDs {
+		if !containsString(removed, id) {
+			remaining = append(remaining, id)
+		}
+	}
+	return remaining
+}
@@ -0,0 +1,317 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"fs-backend/models"
+	"fs-backend/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// MoveShipments moves shipments from a booking to the booking of another MBL
+// of the same mode. Both bookings record the move in their shipment history.
+func (s *bookingService) MoveShipments(ctx context.Context, id primitive.ObjectID, req models.MoveShipmentsRequest, actor string, revision int64) (*BookingOperationResult, error) {
+	shipmentIDs := uniqueShipmentIDs(req.ShipmentIDs)
+	if len(shipmentIDs) == 0 {
+		return nil, fmt.Errorf("%w: no shipments selected", ErrInvalidBookingOperation)
+	}
+	targetMBL := strings.TrimSpace(req.TargetMBLNumber)
+
+	err := s.txRunner.RunInTransaction(ctx, func(ctx context.Context) error {
+		source, err := s.amendableBooking(ctx, id, revision)
+		if err != nil {
+			return err
+		}
+		target, err := s.bookingRepo.FindByMBLNumber(ctx, targetMBL)
+		if err != nil {
+			return err
+		}
+		if target.ID == source.ID {
+			return fmt.Errorf("%w: shipments are already on booking %s", ErrInvalidBookingOperation, targetMBL)
+		}
+		if err := checkBookingAmendable(target); err != nil {
+			return err
+		}
+		if problems := shipmentsNotOnBooking(source, shipmentIDs); len(problems) > 0 {
+			return &BookingSyncError{Errors: problems}
+		}
+
+		rule, err := s.bookingModeRule(ctx, target)
+		if err != nil {
+			return err
+		}
+		merged := uniqueShipmentIDs(append(append([]string{}, target.ShipmentIDs...), shipmentIDs...))
+		shipments, err := s.shipmentRepo.FindByShipmentIDs(ctx, merged)
+		if err != nil {
+			return err
+		}
+		if problems := validateSyncShipments(target.MBLNumber, rule, shipmentIDs, shipments, nil, target.EstimatedArrival); len(problems) > 0 {
+			return &BookingSyncError{Errors: problems}
+		}
+		if err := validateBookingLoad(rule, merged, shipments); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidBookingOperation, err)
+		}
+
+		// The source lets go first, a shipment is never on two bookings
+		now := time.Now()
+		out := models.BookingShipmentChange{Action: models.BookingShipmentsMovedOut, ShipmentIDs: shipmentIDs, MBLNumber: target.MBLNumber, Actor: actor, At: now}
+		if err := s.bookingRepo.ReplaceShipments(ctx, source.ID, remainingShipments(source.ShipmentIDs, shipmentIDs), out, source.Revision); err != nil {
+			return err
+		}
+		in := models.BookingShipmentChange{Action: models.BookingShipmentsMovedIn, ShipmentIDs: shipmentIDs, MBLNumber: source.MBLNumber, Actor: actor, At: now}
+		return s.bookingRepo.ReplaceShipments(ctx, target.ID, merged, in, target.Revision)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.operationResult(ctx, id, targetMBL)
+}
+
+// SplitBooking moves shipments of a consolidated booking, typically LCL, onto
+// a new booking of the same mode under another MBL. At least one shipment
+// stays on the original booking.
+func (s *bookingService) SplitBooking(ctx context.Context, id primitive.ObjectID, req models.SplitBookingRequest, actor string, revision int64) (*BookingOperationResult, error) {
+	shipmentIDs := uniqueShipmentIDs(req.ShipmentIDs)
+	if len(shipmentIDs) == 0 {
+		return nil, fmt.Errorf("%w: no shipments selected", ErrInvalidBookingOperation)
+	}
+	newMBL := strings.TrimSpace(req.NewMBLNumber)
+	departure, err := ParseScheduleDate(req.EstimatedDeparture)
+	if err != nil {
+		return nil, fmt.Errorf("%w: estimated departure %v", ErrInvalidSchedule, err)
+	}
+	arrival, err := ParseScheduleDate(req.EstimatedArrival)
+	if err != nil {
+		return nil, fmt.Errorf("%w: estimated arrival %v", ErrInvalidSchedule, err)
+	}
+	carrierName := canonicalCarrierName(ctx, s.carrierRepo, req.CarrierName)
+
+	err = s.txRunner.RunInTransaction(ctx, func(ctx context.Context) error {
+		source, err := s.amendableBooking(ctx, id, revision)
+		if err != nil {
+			return err
+		}
+		if _, err := s.bookingRepo.FindByMBLNumber(ctx, newMBL); err == nil {
+			return fmt.Errorf("%w: MBL %s already has a booking, move the shipments instead", ErrInvalidBookingOperation, newMBL)
+		} else if !errors.Is(err, mongo.ErrNoDocuments) {
+			return err
+		}
+		if problems := shipmentsNotOnBooking(source, shipmentIDs); len(problems) > 0 {
+			return &BookingSyncError{Errors: problems}
+		}
+		remaining := remainingShipments(source.ShipmentIDs, shipmentIDs)
+		if len(remaining) == 0 {
+			return fmt.Errorf("%w: a split must leave at least one shipment on %s", ErrInvalidBookingOperation, source.MBLNumber)
+		}
+
+		split := &repository.BookingDocument{
+			MBLNumber:          newMBL,
+			ShipmentIDs:        shipmentIDs,
+			Mode:               source.Mode,
+			ForwarderID:        source.ForwarderID,
+			CarrierName:        firstNonEmpty(carrierName, source.CarrierName),
+			EstimatedDeparture: departure,
+			EstimatedArrival:   arrival,
+			Status:             models.BookingStatusBooked,
+			StatusHistory:      initialBookingStatus(models.BookingStatusSourceUser),
+		}
+		if split.EstimatedDeparture == nil {
+			split.EstimatedDeparture = source.EstimatedDeparture
+		}
+		if split.EstimatedArrival == nil {
+			split.EstimatedArrival = source.EstimatedArrival
+		}
+		if err := validateSchedule(split.EstimatedDeparture, split.EstimatedArrival); err != nil {
+			return err
+		}
+		shipments, err := s.shipmentRepo.FindByShipmentIDs(ctx, shipmentIDs)
+		if err != nil {
+			return err
+		}
+		rule, err := s.bookingModeRule(ctx, source)
+		if err != nil {
+			return err
+		}
+		if problems := validateSyncShipments(newMBL, rule, shipmentIDs, shipments, nil, split.EstimatedArrival); len(problems) > 0 {
+			return &BookingSyncError{Errors: problems}
+		}
+
+		now := time.Now()
+		out := models.BookingShipmentChange{Action: models.BookingShipmentsSplitOut, ShipmentIDs: shipmentIDs, MBLNumber: newMBL, Actor: actor, At: now}
+		if err := s.bookingRepo.ReplaceShipments(ctx, source.ID, remaining, out, source.Revision); err != nil {
+			return err
+		}
+		split.ShipmentHistory = []models.BookingShipmentChange{
+			{Action: models.BookingShipmentsSplitFrom, ShipmentIDs: shipmentIDs, MBLNumber: source.MBLNumber, Actor: actor, At: now},
+		}
+		return s.bookingRepo.CreateBooking(ctx, split)
+	})
+	if repository.IsDuplicateKeyOnIndex(err, repository.BookingMBLIndex) {
+		return nil, fmt.Errorf("%w: %s", ErrBookingAlreadyExists, newMBL)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.operationResult(ctx, id, newMBL)
+}
+
+// MergeBookings moves all shipments of the booking of another MBL onto this
+// booking and cancels the emptied booking with reason MERGED
+func (s *bookingService) MergeBookings(ctx context.Context, id primitive.ObjectID, req models.MergeBookingRequest, actor string, revision int64) (*BookingOperationResult, error) {
+	sourceMBL := strings.TrimSpace(req.SourceMBLNumber)
+
+	err := s.txRunner.RunInTransaction(ctx, func(ctx context.Context) error {
+		target, err := s.amendableBooking(ctx, id, revision)
+		if err != nil {
+			return err
+		}
+		source, err := s.bookingRepo.FindByMBLNumber(ctx, sourceMBL)
+		if err != nil {
+			return err
+		}
+		if source.ID == target.ID {
+			return fmt.Errorf("%w: a booking cannot be merged with itself", ErrInvalidBookingOperation)
+		}
+		if err := checkBookingAmendable(source); err != nil {
+			return err
+		}
+		if source.Mode != target.Mode {
+			return fmt.Errorf("%w: %s is %s and %s is %s", ErrInvalidBookingOperation, source.MBLNumber, source.Mode, target.MBLNumber, target.Mode)
+		}
+		if len(source.ShipmentIDs) == 0 {
+			return fmt.Errorf("%w: booking %s has no shipments", ErrInvalidBookingOperation, source.MBLNumber)
+		}
+
+		rule, err := s.bookingModeRule(ctx, target)
+		if err != nil {
+			return err
+		}
+		merged := uniqueShipmentIDs(append(append([]string{}, target.ShipmentIDs...), source.ShipmentIDs...))
+		shipments, err := s.shipmentRepo.FindByShipmentIDs(ctx, merged)
+		if err != nil {
+			return err
+		}
+		if problems := validateSyncShipments(target.MBLNumber, rule, source.ShipmentIDs, shipments, nil, target.EstimatedArrival); len(problems) > 0 {
+			return &BookingSyncError{Errors: problems}
+		}
+		if err := validateBookingLoad(rule, merged, shipments); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidBookingOperation, err)
+		}
+
+		now := time.Now()
+		out := models.BookingShipmentChange{Action: models.BookingShipmentsMergedOut, ShipmentIDs: source.ShipmentIDs, MBLNumber: target.MBLNumber, Actor: actor, At: now}
+		if err := s.bookingRepo.ReplaceShipments(ctx, source.ID, nil, out, source.Revision); err != nil {
+			return err
+		}
+		cancel := models.BookingStatusChange{
+			From:       normalizeBookingStatus(source.Status),
+			To:         models.BookingStatusCancelled,
+			ReasonCode: models.CancelReasonMerged,
+			Reason:     "Merged into " + target.MBLNumber,
+			Actor:      actor,
+			Source:     models.BookingStatusSourceUser,
+			At:         now,
+		}
+		if err := s.bookingRepo.UpdateBookingStatus(ctx, source.ID, cancel, source.Revision+1); err != nil {
+			return err
+		}
+		in := models.BookingShipmentChange{Action: models.BookingShipmentsMergedIn, ShipmentIDs: source.ShipmentIDs, MBLNumber: source.MBLNumber, Actor: actor, At: now}
+		return s.bookingRepo.ReplaceShipments(ctx, target.ID, merged, in, target.Revision)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.operationResult(ctx, id, sourceMBL)
+}
+
+// CancelBooking cancels a booking and releases its shipments, which are "Yet
+// to sync" again and can be booked on another MBL
+func (s *bookingService) CancelBooking(ctx context.Context, id primitive.ObjectID, req models.CancelBookingRequest, actor string, revision int64) (*repository.BookingDocument, error) {
+	reasonCode := strings.ToUpper(strings.TrimSpace(req.ReasonCode))
+	reason := strings.TrimSpace(req.Reason)
+	if problem := validateCancelReason(models.BookingStatusCancelled, reasonCode, reason); problem != "" {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidCancelReason, problem)
+	}
+
+	err := s.txRunner.RunInTransaction(ctx, func(ctx context.Context) error {
+		booking, err := s.bookingRepo.FindByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if booking.Revision != revision {
+			return &RevisionConflictError{Revision: booking.Revision, Current: booking}
+		}
+		from := normalizeBookingStatus(booking.Status)
+		if !canTransitionBooking(from, models.BookingStatusCancelled) {
+			return fmt.Errorf("%w: %s → %s", ErrIllegalBookingTransition, from, models.BookingStatusCancelled)
+		}
+
+		now := time.Now()
+		change := models.BookingStatusChange{
+			From:       from,
+			To:         models.BookingStatusCancelled,
+			ReasonCode: reasonCode,
+			Reason:     reason,
+			Actor:      actor,
+			Source:     models.BookingStatusSourceUser,
+			At:         now,
+		}
+		if err := s.bookingRepo.UpdateBookingStatus(ctx, id, change, revision); err != nil {
+			return err
+		}
+		if len(booking.ShipmentIDs) == 0 {
+			return nil
+		}
+		released := models.BookingShipmentChange{Action: models.BookingShipmentsReleased, ShipmentIDs: booking.ShipmentIDs, Actor: actor, At: now}
+		return s.bookingRepo.ReplaceShipments(ctx, id, nil, released, revision+1)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.bookingRepo.FindByID(ctx, id)
+}
+
+// amendableBooking loads a booking for an operation, checking its revision
+// and that its shipments may still change
+func (s *bookingService) amendableBooking(ctx context.Context, id primitive.ObjectID, revision int64) (*repository.BookingDocument, error) {
+	booking, err := s.bookingRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if booking.Revision != revision {
+		return nil, &RevisionConflictError{Revision: booking.Revision, Current: booking}
+	}
+	if err := checkBookingAmendable(booking); err != nil {
+		return nil, err
+	}
+	return booking, nil
+}
+
+// bookingModeRule returns the rule of a booking's mode from the rules of the
+// forwarder owning it
+func (s *bookingService) bookingModeRule(ctx context.Context, booking *repository.BookingDocument) (models.BookingModeRule, error) {
+	rules, err := loadBookingModeRules(ctx, s.modeRuleRepo, booking.ForwarderID)
+	if err != nil {
+		return models.BookingModeRule{}, err
+	}
+	return findBookingModeRule(rules, booking.Mode)
+}
+
+// operationResult reads both bookings of an operation as committed
+func (s *bookingService) operationResult(ctx context.Context, id primitive.ObjectID, relatedMBL string) (*BookingOperationResult, error) {
+	booking, err := s.bookingRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	related, err := s.bookingRepo.FindByMBLNumber(ctx, relatedMBL)
+	if err != nil {
+		return nil, err
+	}
+	return &BookingOperationResult{Booking: booking, Related: related}, nil
+}
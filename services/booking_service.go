@@ -3,40 +3,81 @@ package services
 import (
 	"context"
 	"errors"
+	"fmt"
+	"fs-backend/models"
 	"fs-backend/repository"
+	"log"
+	"strings"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	ErrInvalidBookingStatus     = errors.New("invalid booking status")
+	ErrIllegalBookingTransition = errors.New("illegal booking status transition")
+	ErrInvalidCancelReason      = errors.New("invalid cancellation reason")
+	ErrShipmentAlreadyBooked    = errors.New("a shipment is already part of another booking")
+	ErrBookingAlreadyExists     = errors.New("the MBL already has a booking")
+	ErrInvalidBookingOperation  = errors.New("invalid booking operation")
+	ErrBookingLocked            = errors.New("booking can no longer be changed")
 )
 
 type BookingService interface {
 	AddShipper(ctx context.Context, doc repository.ShipperDocument) (primitive.ObjectID, error)
 	GetShipperList(ctx context.Context) ([]repository.ShipperDocument, error)
-	UpdateShipper(ctx context.Context, id primitive.ObjectID, updates map[string]interface{}) error
+	UpdateShipper(ctx context.Context, id primitive.ObjectID, updates map[string]interface{}, revision int64) error
 	DeleteShipper(ctx context.Context, id primitive.ObjectID) error
-	SyncBooking(ctx context.Context, mblNumber, mode string, shipmentIDs []string, carrierName, estimatedDeparture, estimatedArrival string) error
-	GetStatusDetails(ctx context.Context) ([]repository.BookingDocument, error)
-	UpdateStatus(ctx context.Context, id primitive.ObjectID, status string) error
+	SyncBooking(ctx context.Context, mblNumber, mode string, shipmentIDs []string, carrierName, estimatedDeparture, estimatedArrival, forwarderID string) error
+	GetStatusDetails(ctx context.Context, filter repository.BookingFilter) ([]repository.BookingDocument, error)
+	UpdateStatus(ctx context.Context, id primitive.ObjectID, req models.BookingStatusRequest, actor string, revision int64) (*repository.BookingDocument, error)
+	MoveShipments(ctx context.Context, id primitive.ObjectID, req models.MoveShipmentsRequest, actor string, revision int64) (*BookingOperationResult, error)
+	SplitBooking(ctx context.Context, id primitive.ObjectID, req models.SplitBookingRequest, actor string, revision int64) (*BookingOperationResult, error)
+	MergeBookings(ctx context.Context, id primitive.ObjectID, req models.MergeBookingRequest, actor string, revision int64) (*BookingOperationResult, error)
+	CancelBooking(ctx context.Context, id primitive.ObjectID, req models.CancelBookingRequest, actor string, revision int64) (*repository.BookingDocument, error)
 }
 
 type bookingService struct {
 	shipperRepo  repository.ShipperRepository
 	bookingRepo  repository.BookingRepository
 	shipmentRepo repository.ShipmentRepository
+	modeRuleRepo repository.BookingModeRuleRepository
+	carrierRepo  repository.CarrierRepository
+	txRunner     repository.TxRunner
+	shipperIDs   *shipperIDAllocator
 }
 
-func NewBookingService(shipperRepo repository.ShipperRepository, bookingRepo repository.BookingRepository, shipmentRepo repository.ShipmentRepository) BookingService {
+func NewBookingService(shipperRepo repository.ShipperRepository, bookingRepo repository.BookingRepository, shipmentRepo repository.ShipmentRepository, modeRuleRepo repository.BookingModeRuleRepository, carrierRepo repository.CarrierRepository, txRunner repository.TxRunner, counterRepo repository.CounterRepository) BookingService {
 	return &bookingService{
 		shipperRepo:  shipperRepo,
+		shipperIDs:   newShipperIDAllocator(shipperRepo, counterRepo),
 		bookingRepo:  bookingRepo,
 		shipmentRepo: shipmentRepo,
+		modeRuleRepo: modeRuleRepo,
+		carrierRepo:  carrierRepo,
+		txRunner:     txRunner,
 	}
 }
 
+// AddShipper stores a shipper. Without a shipper_id it is given the next
+// SHPR<n> ID; a SHPR<n> ID given by the client is kept out of the sequence.
 func (s *bookingService) AddShipper(ctx context.Context, doc repository.ShipperDocument) (primitive.ObjectID, error) {
+	doc.ShipperID = strings.TrimSpace(doc.ShipperID)
+	if doc.ShipperID == "" {
+		return s.shipperIDs.Insert(ctx, &doc)
+	}
+
 	res, err := s.shipperRepo.CreateShipper(ctx, doc)
+	if repository.IsDuplicateKeyOnIndex(err, repository.ShipperIDIndex) {
+		return primitive.NilObjectID, fmt.Errorf("%w: %s", ErrShipperIDTaken, doc.ShipperID)
+	}
 	if err != nil {
 		return primitive.NilObjectID, err
 	}
+	if err := s.shipperIDs.Reserve(ctx, doc.ShipperID); err != nil {
+		log.Printf("Warning: failed to move the shipper ID sequence past %s: %v", doc.ShipperID, err)
+	}
 	return res.InsertedID.(primitive.ObjectID), nil
 }
 
@@ -44,8 +85,27 @@ func (s *bookingService) GetShipperList(ctx context.Context) ([]repository.Shipp
 	return s.shipperRepo.FindAllShippers(ctx)
 }
 
-func (s *bookingService) UpdateShipper(ctx context.Context, id primitive.ObjectID, updates map[string]interface{}) error {
-	_, err := s.shipperRepo.UpdateShipper(ctx, id, updates)
+// UpdateShipper applies the updates only if the shipper is still at revision
+func (s *bookingService) UpdateShipper(ctx context.Context, id primitive.ObjectID, updates map[string]interface{}, revision int64) error {
+	// Identity and revision are not client-editable
+	delete(updates, "_id")
+	delete(updates, "id")
+	delete(updates, "revision")
+
+	err := s.shipperRepo.UpdateShipper(ctx, id, updates, revision)
+	if repository.IsDuplicateKeyOnIndex(err, repository.ShipperIDIndex) {
+		return fmt.Errorf("%w: %v", ErrShipperIDTaken, updates["shipper_id"])
+	}
+	if shipperID, ok := updates["shipper_id"].(string); ok && err == nil {
+		if err := s.shipperIDs.Reserve(ctx, shipperID); err != nil {
+			log.Printf("Warning: failed to move the shipper ID sequence past %s: %v", shipperID, err)
+		}
+	}
+	if errors.Is(err, repository.ErrRevisionMismatch) {
+		if current, findErr := s.shipperRepo.FindByID(ctx, id); findErr == nil {
+			return &RevisionConflictError{Revision: current.Revision, Current: current}
+		}
+	}
 	return err
 }
 
@@ -54,78 +114,164 @@ func (s *bookingService) DeleteShipper(ctx context.Context, id primitive.ObjectI
 	return err
 }
 
-func (s *bookingService) SyncBooking(ctx context.Context, mblNumber, mode string, shipmentIDs []string, carrierName, estimatedDeparture, estimatedArrival string) error {
+// SyncBooking adds shipments to the booking of an MBL, creating the booking
+// when there is none. The mode and its limits come from the booking mode rules
+// of the forwarder owning the booking. Everything is read, validated and
+// written in one transaction, so a concurrent sync cannot put a shipment on
+// two bookings or take a booking past its limits. Shipment problems are
+// returned together as a *BookingSyncError.
+func (s *bookingService) SyncBooking(ctx context.Context, mblNumber, mode string, shipmentIDs []string, carrierName, estimatedDeparture, estimatedArrival, forwarderID string) error {
+	shipmentIDs = uniqueShipmentIDs(shipmentIDs)
 	if len(shipmentIDs) == 0 {
 		return errors.New("No shipments selected for booking")
 	}
+	mode = normalizeBookingMode(mode)
 
-	if mode != "FCL" && mode != "LCL" {
-		return errors.New("Invalid mode. Please select FCL or LCL.")
-	}
-
-	if mode == "FCL" && len(shipmentIDs) > 1 {
-		return errors.New("FCL booking can only have one shipment")
+	departure, err := ParseScheduleDate(estimatedDeparture)
+	if err != nil {
+		return fmt.Errorf("%w: estimated departure %v", ErrInvalidSchedule, err)
 	}
-
-	if mode == "LCL" && len(shipmentIDs) > 5 {
-		return errors.New("LCL booking can have at most 5 shipments")
+	arrival, err := ParseScheduleDate(estimatedArrival)
+	if err != nil {
+		return fmt.Errorf("%w: estimated arrival %v", ErrInvalidSchedule, err)
 	}
-
-	// Fetch shipments to validate them
-	shipments, err := s.shipmentRepo.FindByShipmentIDs(ctx, shipmentIDs)
-	if err != nil || len(shipments) == 0 {
-		return errors.New("Shipment not found")
+	if err := validateSchedule(departure, arrival); err != nil {
+		return err
 	}
+	carrierName = canonicalCarrierName(ctx, s.carrierRepo, carrierName)
 
-	for _, shipment := range shipments {
-		if shipment.Mode == "" {
-			return errors.New("All shipments must have a mode set before syncing")
+	err = s.txRunner.RunInTransaction(ctx, func(ctx context.Context) error {
+		booking, err := s.bookingRepo.FindByMBLNumber(ctx, mblNumber)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			booking = nil
+		} else if err != nil {
+			return err
 		}
-		if shipment.Mode != mode {
-			return errors.New("Selected shipment modes do not match the chosen booking mode")
-		}
-	}
 
-	booking, err := s.bookingRepo.FindByMBLNumber(ctx, mblNumber)
+		merged := shipmentIDs
+		owner := forwarderID
+		deliverBy := arrival
+		if booking != nil {
+			if booking.Mode == "" {
+				return errors.New("MBL exists but mode is not set. Cannot proceed. Please delete and recreate the booking.")
+			}
+
+			if normalizeBookingMode(booking.Mode) != mode {
+				return fmt.Errorf("MBL is synced with %s - cannot add %s shipments", booking.Mode, mode)
+			}
 
-	if err == nil && booking != nil {
-		if booking.Mode == "" {
-			return errors.New("MBL exists but mode is not set. Cannot proceed. Please delete and recreate the booking.")
+			if normalizeBookingStatus(booking.Status) == models.BookingStatusCancelled {
+				return errors.New("Booking is cancelled - cannot add shipments")
+			}
+
+			merged = uniqueShipmentIDs(append(append([]string{}, booking.ShipmentIDs...), shipmentIDs...))
+			owner = firstNonEmpty(booking.ForwarderID, forwarderID)
+			deliverBy = booking.EstimatedArrival
 		}
 
-		if booking.Mode == "FCL" {
-			return errors.New("MBL synced with FCL - cannot add more shipments")
+		rules, err := loadBookingModeRules(ctx, s.modeRuleRepo, owner)
+		if err != nil {
+			return err
+		}
+		rule, err := findBookingModeRule(rules, mode)
+		if err != nil {
+			return err
 		}
 
-		if booking.Mode == "LCL" && mode == "FCL" {
-			return errors.New("MBL is synced with LCL - cannot add FCL shipment")
+		shipments, err := s.shipmentRepo.FindByShipmentIDs(ctx, merged)
+		if err != nil {
+			return err
+		}
+		booked, err := s.bookingRepo.FindByShipmentIDs(ctx, shipmentIDs)
+		if err != nil {
+			return err
+		}
+		if problems := validateSyncShipments(mblNumber, rule, shipmentIDs, shipments, booked, deliverBy); len(problems) > 0 {
+			return &BookingSyncError{Errors: problems}
+		}
+		if err := validateBookingLoad(rule, merged, shipments); err != nil {
+			return err
 		}
 
-		for _, shipmentID := range shipmentIDs {
-			if err := s.bookingRepo.AddShipmentToBooking(ctx, mblNumber, shipmentID); err != nil {
-				return err
-			}
+		if booking != nil {
+			return s.bookingRepo.AddShipmentsToBooking(ctx, mblNumber, shipmentIDs)
 		}
+		return s.bookingRepo.CreateBooking(ctx, &repository.BookingDocument{
+			MBLNumber:          mblNumber,
+			ShipmentIDs:        shipmentIDs,
+			Mode:               mode,
+			ForwarderID:        forwarderID,
+			CarrierName:        carrierName,
+			EstimatedDeparture: departure,
+			EstimatedArrival:   arrival,
+			Status:             models.BookingStatusBooked,
+			StatusHistory:      initialBookingStatus(models.BookingStatusSourceUser),
+		})
+	})
+	switch {
+	case repository.IsDuplicateKeyOnIndex(err, repository.BookingShipmentIndex):
+		return ErrShipmentAlreadyBooked
+	case repository.IsDuplicateKeyOnIndex(err, repository.BookingMBLIndex):
+		// Another sync created the booking first; a retry adds to it
+		return fmt.Errorf("%w, sync again to add the shipments to it", ErrBookingAlreadyExists)
+	}
+	return err
+}
 
-		return nil
+func (s *bookingService) GetStatusDetails(ctx context.Context, filter repository.BookingFilter) ([]repository.BookingDocument, error) {
+	return s.bookingRepo.GetAllBookings(ctx, filter)
+}
+
+// UpdateStatus moves the booking to a new status if the state machine allows
+// it and the booking is still at revision, recording the change in its history.
+// Cancelling also releases the shipments, see CancelBooking.
+func (s *bookingService) UpdateStatus(ctx context.Context, id primitive.ObjectID, req models.BookingStatusRequest, actor string, revision int64) (*repository.BookingDocument, error) {
+	to := normalizeBookingStatus(req.Status)
+	if !isKnownBookingStatus(to) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidBookingStatus, req.Status)
+	}
+	reasonCode := strings.ToUpper(strings.TrimSpace(req.ReasonCode))
+	reason := strings.TrimSpace(req.Reason)
+	if problem := validateCancelReason(to, reasonCode, reason); problem != "" {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidCancelReason, problem)
+	}
+	if to == models.BookingStatusCancelled {
+		return s.CancelBooking(ctx, id, models.CancelBookingRequest{ReasonCode: reasonCode, Reason: reason}, actor, revision)
 	}
 
-	newBooking := &repository.BookingDocument{
-		MBLNumber:          mblNumber,
-		ShipmentIDs:        shipmentIDs,
-		Mode:               mode,
-		CarrierName:        carrierName,
-		EstimatedDeparture: estimatedDeparture,
-		EstimatedArrival:   estimatedArrival,
-		Status:             "Booked",
+	booking, err := s.bookingRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if booking.Revision != revision {
+		return nil, &RevisionConflictError{Revision: booking.Revision, Current: booking}
+	}
+	from := normalizeBookingStatus(booking.Status)
+	if !canTransitionBooking(from, to) {
+		return nil, fmt.Errorf("%w: %s → %s", ErrIllegalBookingTransition, from, to)
 	}
-	return s.bookingRepo.CreateBooking(ctx, newBooking)
-}
 
-func (s *bookingService) GetStatusDetails(ctx context.Context) ([]repository.BookingDocument, error) {
-	return s.bookingRepo.GetAllBookings(ctx)
-}
+	change := models.BookingStatusChange{
+		From:       from,
+		To:         to,
+		ReasonCode: reasonCode,
+		Reason:     reason,
+		Actor:      actor,
+		Source:     models.BookingStatusSourceUser,
+		At:         time.Now(),
+	}
+	err = s.bookingRepo.UpdateBookingStatus(ctx, id, change, revision)
+	if errors.Is(err, repository.ErrRevisionMismatch) {
+		if current, findErr := s.bookingRepo.FindByID(ctx, id); findErr == nil {
+			return nil, &RevisionConflictError{Revision: current.Revision, Current: current}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
 
-func (s *bookingService) UpdateStatus(ctx context.Context, id primitive.ObjectID, status string) error {
-	return s.bookingRepo.UpdateBookingStatus(ctx, id, status)
+	booking.Status = to
+	booking.StatusHistory = append(booking.StatusHistory, change)
+	booking.Revision++
+	return booking, nil
 }
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"strings"
+	"time"
+
+	"fs-backend/models"
+)
+
+// bookingCancellableUntil is the last status a booking can be cancelled in;
+// once the cargo is on board the booking has to run its course
+const bookingCancellableUntil = models.BookingStatusGatedIn
+
+// normalizeBookingStatus maps a status to its canonical spelling, treating the
+// empty status of legacy bookings as booked. Unknown statuses are returned as is.
+func normalizeBookingStatus(status string) string {
+	status = strings.TrimSpace(status)
+	if status == "" {
+		return models.BookingStatusBooked
+	}
+	for _, known := range models.BookingStatusFlow {
+		if strings.EqualFold(known, status) {
+			return known
+		}
+	}
+	if strings.EqualFold(models.BookingStatusCancelled, status) {
+		return models.BookingStatusCancelled
+	}
+	return status
+}
+
+// bookingStatusRank returns the position of a status in models.BookingStatusFlow
+func bookingStatusRank(status string) (int, bool) {
+	status = normalizeBookingStatus(status)
+	for i, known := range models.BookingStatusFlow {
+		if known == status {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+func isKnownBookingStatus(status string) bool {
+	_, inFlow := bookingStatusRank(status)
+	return inFlow || normalizeBookingStatus(status) == models.BookingStatusCancelled
+}
+
+// canTransitionBooking reports whether a booking may move from one status to
+// another. Bookings only move forward through the flow, possibly skipping
+// statuses that were never reported, and can be cancelled until they sail.
+// Delivered and cancelled bookings are final. Bookings still holding a free
+// text status from before the state machine may move to any status.
+func canTransitionBooking(from, to string) bool {
+	from, to = normalizeBookingStatus(from), normalizeBookingStatus(to)
+	if !isKnownBookingStatus(from) {
+		return isKnownBookingStatus(to)
+	}
+
+	fromRank, inFlow := bookingStatusRank(from)
+	if !inFlow {
+		return false // cancelled
+	}
+	if to == models.BookingStatusCancelled {
+		limit, _ := bookingStatusRank(bookingCancellableUntil)
+		return fromRank <= limit
+	}
+	toRank, ok := bookingStatusRank(to)
+	return ok && toRank > fromRank
+}
+
+// validateCancelReason checks the reason given for a status change; reason
+// codes belong to cancellations only and OTHER needs an explanation
+func validateCancelReason(to, reasonCode, reason string) string {
+	if to != models.BookingStatusCancelled {
+		if reasonCode != "" {
+			return "reason codes only apply to cancellations"
+		}
+		return ""
+	}
+	if reasonCode == "" {
+		return "a reason code is required to cancel a booking"
+	}
+	if _, ok := models.CancelReasons[reasonCode]; !ok {
+		return "unknown cancellation reason code " + reasonCode
+	}
+	if reasonCode == models.CancelReasonOther && reason == "" {
+		return "a reason is required with reason code " + models.CancelReasonOther
+	}
+	return ""
+}
+
+// initialBookingStatus is the history entry of a newly created booking
+func initialBookingStatus(source string) []models.BookingStatusChange {
+	return []models.BookingStatusChange{{To: models.BookingStatusBooked, Source: source, At: time.Now()}}
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"fs-backend/models"
+	"fs-backend/repository"
+)
+
+// ShipmentSyncError is the problem of one shipment of a booking sync
+type ShipmentSyncError struct {
+	ShipmentID string `json:"shipment_id"`
+	Message    string `json:"message"`
+}
+
+// BookingSyncError lists the shipments that keep a sync from going through.
+// Nothing is written when it is returned.
+type BookingSyncError struct {
+	Errors []ShipmentSyncError
+}
+
+func (e *BookingSyncError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, shipmentErr := range e.Errors {
+		messages[i] = shipmentErr.ShipmentID + ": " + shipmentErr.Message
+	}
+	return "shipments cannot be synced: " + strings.Join(messages, "; ")
+}
+
+// uniqueShipmentIDs trims the requested IDs and drops blanks and repeats,
+// keeping the order of the request
+func uniqueShipmentIDs(shipmentIDs []string) []string {
+	seen := make(map[string]bool, len(shipmentIDs))
+	var ids []string
+	for _, id := range shipmentIDs {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// validateSyncShipments checks every requested shipment: it must exist, have
+// the booking mode and a cargo type the mode accepts, be on no other booking
+// and be deliverable by its desired date. arrival is the ETA of the booking
+// the shipments join.
+func validateSyncShipments(mblNumber string, rule models.BookingModeRule, shipmentIDs []string, shipments []repository.ShipmentDocument, booked []repository.BookingDocument, arrival *time.Time) []ShipmentSyncError {
+	byID := make(map[string]repository.ShipmentDocument, len(shipments))
+	for _, shipment := range shipments {
+		byID[shipment.ShipmentID] = shipment
+	}
+	bookedOn := make(map[string]string)
+	for _, booking := range booked {
+		if booking.MBLNumber == mblNumber {
+			continue
+		}
+		for _, id := range booking.ShipmentIDs {
+			bookedOn[id] = booking.MBLNumber
+		}
+	}
+
+	var problems []ShipmentSyncError
+	fail := func(shipmentID, format string, args ...interface{}) {
+		problems = append(problems, ShipmentSyncError{ShipmentID: shipmentID, Message: fmt.Sprintf(format, args...)})
+	}
+	for _, id := range shipmentIDs {
+		shipment, ok := byID[id]
+		switch {
+		case !ok:
+			fail(id, "shipment not found")
+			continue
+		case shipment.Mode == "":
+			fail(id, "mode must be set before syncing")
+		case normalizeBookingMode(shipment.Mode) != rule.Mode:
+			fail(id, "mode %s does not match the booking mode %s", shipment.Mode, rule.Mode)
+		case !cargoTypeAllowed(rule, shipment.CargoType):
+			fail(id, "cargo type %q is not accepted for %s, allowed: %s", shipment.CargoType, rule.Mode, strings.Join(rule.AllowedCargoTypes, ", "))
+		}
+		if other, ok := bookedOn[id]; ok {
+			fail(id, "already booked on MBL %s", other)
+		}
+		if err := validateDeliveryDate(id, shipment.DesiredDeliveryDate, arrival); err != nil {
+			fail(id, "%v", err)
+		}
+	}
+	return problems
+}
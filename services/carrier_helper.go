@@ -0,0 +1,134 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"fs-backend/models"
+	"fs-backend/models/mbl_schema"
+	"fs-backend/repository"
+)
+
+// scacCodePattern is the shape of an NMFTA Standard Carrier Alpha Code
+var scacCodePattern = regexp.MustCompile(`^[A-Z]{2,4}$`)
+
+// carrierKeySeparators are the characters that do not tell carriers apart
+var carrierKeySeparators = regexp.MustCompile(`[^A-Z0-9&]+`)
+
+func normalizeSCACCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// validateSCACCode checks a normalized SCAC code; an empty code is allowed
+func validateSCACCode(code string) error {
+	if code != "" && !scacCodePattern.MatchString(code) {
+		return fmt.Errorf("SCAC code %q must be 2 to 4 letters", code)
+	}
+	return nil
+}
+
+// carrierMatchKey reduces a carrier name to what resolution compares, so
+// "Maersk Line", "MAERSK LINE" and "maersk-line" are the same key
+func carrierMatchKey(name string) string {
+	return strings.TrimSpace(carrierKeySeparators.ReplaceAllString(strings.ToUpper(name), " "))
+}
+
+// validateCarrier checks a carrier before it is saved, normalizes its SCAC
+// code and aliases and computes the keys it is resolved by
+func validateCarrier(carrier *models.Carrier) error {
+	carrier.Name = strings.TrimSpace(carrier.Name)
+	if carrierMatchKey(carrier.Name) == "" {
+		return errors.New("name is required")
+	}
+	carrier.SCACCode = normalizeSCACCode(carrier.SCACCode)
+	if err := validateSCACCode(carrier.SCACCode); err != nil {
+		return err
+	}
+	if carrier.DefaultFreeTimeDays < 0 {
+		return errors.New("default free time cannot be negative")
+	}
+	carrier.Contact.Email = strings.TrimSpace(carrier.Contact.Email)
+	if carrier.Contact.Email != "" && !strings.Contains(carrier.Contact.Email, "@") {
+		return fmt.Errorf("contact email %q is not an email address", carrier.Contact.Email)
+	}
+
+	keys := []string{carrierMatchKey(carrier.Name)}
+	aliases := []string{}
+	for _, alias := range nonEmptyStrings(carrier.Aliases) {
+		key := carrierMatchKey(alias)
+		if key == "" || containsString(keys, key) {
+			continue
+		}
+		keys = append(keys, key)
+		aliases = append(aliases, alias)
+	}
+	if carrier.SCACCode != "" && !containsString(keys, carrier.SCACCode) {
+		keys = append(keys, carrier.SCACCode)
+	}
+	carrier.Aliases = aliases
+	carrier.MatchKeys = keys
+	return nil
+}
+
+// resolveCarrier finds the master record of a carrier by its SCAC code, or
+// else by its name or an alias. It returns nil for unknown carriers.
+func resolveCarrier(ctx context.Context, repo repository.CarrierRepository, name, scacCode string) (*models.Carrier, error) {
+	if code := normalizeSCACCode(scacCode); code != "" && validateSCACCode(code) == nil {
+		carrier, err := repo.FindByMatchKey(ctx, code)
+		if carrier != nil || err != nil {
+			return carrier, err
+		}
+	}
+	key := carrierMatchKey(name)
+	if key == "" {
+		return nil, nil
+	}
+	return repo.FindByMatchKey(ctx, key)
+}
+
+// canonicalCarrierName returns the master name of a free text carrier name,
+// or the name itself when the carrier is not in the master
+func canonicalCarrierName(ctx context.Context, repo repository.CarrierRepository, name string) string {
+	name = strings.TrimSpace(name)
+	carrier, err := resolveCarrier(ctx, repo, name, "")
+	if err != nil {
+		log.Printf("Warning: carrier lookup failed for %q: %v", name, err)
+		return name
+	}
+	if carrier == nil {
+		return name
+	}
+	return carrier.Name
+}
+
+// applyCarrierMaster normalizes the carrier of an MBL: an invalid SCAC code
+// is dropped, a known carrier gets its master name and, when the document
+// has none, its SCAC code. It returns a warning for the caller to pass on
+// when the SCAC code was dropped, "" otherwise.
+func applyCarrierMaster(ctx context.Context, repo repository.CarrierRepository, carrier *mbl_schema.Carrier) string {
+	var warning string
+	carrier.SCACCode = normalizeSCACCode(carrier.SCACCode)
+	if err := validateSCACCode(carrier.SCACCode); err != nil {
+		warning = fmt.Sprintf("carrier SCAC code %q was dropped: %v", carrier.SCACCode, err)
+		log.Printf("Warning: dropping carrier %q code: %v", carrier.Name, err)
+		carrier.SCACCode = ""
+	}
+
+	master, err := resolveCarrier(ctx, repo, carrier.Name, carrier.SCACCode)
+	if err != nil {
+		log.Printf("Warning: carrier lookup failed for %q: %v", carrier.Name, err)
+		return warning
+	}
+	if master == nil {
+		return warning
+	}
+	carrier.Name = master.Name
+	if carrier.SCACCode == "" {
+		carrier.SCACCode = master.SCACCode
+	}
+	return warning
+}
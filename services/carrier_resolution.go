@@ -0,0 +1,53 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"fs-backend/repository"
+)
+
+// CarrierResolution is a booking carrier name replaced by its master name
+type CarrierResolution struct {
+	From     string
+	To       string
+	Bookings int64
+}
+
+// ResolveBookingCarriers replaces the free text carrier names stored on
+// bookings before the carrier master existed with the master names, the way
+// new bookings are stored. Names of unknown carriers are kept. With dryRun
+// nothing is written. Running it again only touches names not resolved yet.
+func ResolveBookingCarriers(ctx context.Context, bookingRepo repository.BookingRepository, carrierRepo repository.CarrierRepository, dryRun bool) ([]CarrierResolution, error) {
+	counts, err := bookingRepo.CarrierNameCounts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list booking carriers: %w", err)
+	}
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var resolutions []CarrierResolution
+	for _, name := range names {
+		carrier, err := resolveCarrier(ctx, carrierRepo, name, "")
+		if err != nil {
+			return resolutions, fmt.Errorf("carrier lookup failed for %q: %w", name, err)
+		}
+		if carrier == nil || carrier.Name == name {
+			continue
+		}
+
+		resolution := CarrierResolution{From: name, To: carrier.Name, Bookings: counts[name]}
+		if !dryRun {
+			resolution.Bookings, err = bookingRepo.RenameCarrier(ctx, name, carrier.Name)
+			if err != nil {
+				return resolutions, fmt.Errorf("failed to rename carrier %q: %w", name, err)
+			}
+		}
+		resolutions = append(resolutions, resolution)
+	}
+	return resolutions, nil
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"fs-backend/models"
+	"fs-backend/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	ErrInvalidCarrier   = errors.New("invalid carrier")
+	ErrCarrierNotFound  = errors.New("carrier not found")
+	ErrDuplicateCarrier = errors.New("carrier name, alias or SCAC code is already used by another carrier")
+)
+
+// CarrierService manages the carrier master and resolves free text carrier
+// names to its records
+type CarrierService interface {
+	ListCarriers(ctx context.Context) ([]models.Carrier, error)
+	GetCarrier(ctx context.Context, id primitive.ObjectID) (*models.Carrier, error)
+	CreateCarrier(ctx context.Context, carrier models.Carrier) (*models.Carrier, error)
+	UpdateCarrier(ctx context.Context, id primitive.ObjectID, carrier models.Carrier) (*models.Carrier, error)
+	DeleteCarrier(ctx context.Context, id primitive.ObjectID) error
+	ResolveCarrier(ctx context.Context, name, scacCode string) (*models.Carrier, error)
+}
+
+type carrierService struct {
+	repo repository.CarrierRepository
+}
+
+// NewCarrierService creates a new CarrierService
+func NewCarrierService(repo repository.CarrierRepository) CarrierService {
+	return &carrierService{repo: repo}
+}
+
+// ListCarriers returns the whole carrier master by name
+func (s *carrierService) ListCarriers(ctx context.Context) ([]models.Carrier, error) {
+	return s.repo.FindAll(ctx)
+}
+
+func (s *carrierService) GetCarrier(ctx context.Context, id primitive.ObjectID) (*models.Carrier, error) {
+	carrier, err := s.repo.FindByID(ctx, id)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrCarrierNotFound
+	}
+	return carrier, err
+}
+
+func (s *carrierService) CreateCarrier(ctx context.Context, carrier models.Carrier) (*models.Carrier, error) {
+	if err := validateCarrier(&carrier); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCarrier, err)
+	}
+	carrier.ID = primitive.NilObjectID
+	if err := s.repo.Insert(ctx, &carrier); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrDuplicateCarrier
+		}
+		return nil, err
+	}
+	return &carrier, nil
+}
+
+// UpdateCarrier replaces a carrier's master data. Bookings and MBLs already
+// stored keep the name they were resolved to.
+func (s *carrierService) UpdateCarrier(ctx context.Context, id primitive.ObjectID, carrier models.Carrier) (*models.Carrier, error) {
+	if err := validateCarrier(&carrier); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCarrier, err)
+	}
+	existing, err := s.GetCarrier(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	carrier.ID = id
+	carrier.CreatedAt = existing.CreatedAt
+	if err := s.repo.Update(ctx, &carrier); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrDuplicateCarrier
+		}
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrCarrierNotFound
+		}
+		return nil, err
+	}
+	return &carrier, nil
+}
+
+func (s *carrierService) DeleteCarrier(ctx context.Context, id primitive.ObjectID) error {
+	err := s.repo.Delete(ctx, id)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrCarrierNotFound
+	}
+	return err
+}
+
+// ResolveCarrier finds the carrier known by a SCAC code, name or alias
+func (s *carrierService) ResolveCarrier(ctx context.Context, name, scacCode string) (*models.Carrier, error) {
+	carrier, err := resolveCarrier(ctx, s.repo, name, scacCode)
+	if err != nil {
+		return nil, err
+	}
+	if carrier == nil {
+		return nil, ErrCarrierNotFound
+	}
+	return carrier, nil
+}
@@ -0,0 +1,280 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"fs-backend/models"
+	"fs-backend/modules/edifact"
+	"fs-backend/modules/x12"
+)
+
+// defaultEventStatusMap maps X12 315 / SMDG IFTSTA status codes to booking
+// statuses. It can be extended or overridden with status_ingestion.event_status.
+var defaultEventStatusMap = map[string]string{
+	"I":  models.BookingStatusGatedIn, // in-gate
+	"AE": models.BookingStatusLoaded,  // loaded on vessel
+	"VD": models.BookingStatusSailed,  // vessel departure
+	"VA": models.BookingStatusArrived, // vessel arrival
+	"UV": models.BookingStatusArrived, // unloaded from vessel
+	"X1": models.BookingStatusDelivered,
+	"D":  models.BookingStatusDelivered,
+}
+
+// StatusMessageError lists the segments of a status message that could not be read
+type StatusMessageError struct {
+	Errors edifact.Errors
+}
+
+func (e *StatusMessageError) Error() string {
+	return "invalid status message: " + e.Errors.Error()
+}
+
+// statusEvent is a container status event read from a 315 or an IFTSTA
+type statusEvent struct {
+	code        string
+	containerNo string
+	billRef     string // RFF/N9 BM
+	bookingRef  string // RFF/N9 BN
+	time        time.Time
+	location    string
+	vessel      string
+	reference   string // transaction set / message reference
+	segment     int    // position of the B4/STS segment
+}
+
+// eventStatusMap merges the configured mappings over the defaults; codes are
+// upper-cased since the configuration keys are not case sensitive, and an
+// empty status disables a default mapping
+func eventStatusMap(configured map[string]string) map[string]string {
+	mapping := make(map[string]string, len(defaultEventStatusMap)+len(configured))
+	for code, status := range defaultEventStatusMap {
+		mapping[code] = status
+	}
+	for code, status := range configured {
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if status = strings.TrimSpace(status); status == "" {
+			delete(mapping, code)
+			continue
+		}
+		mapping[code] = status
+	}
+	return mapping
+}
+
+// x12StatusEvents reads the B4, N9 and Q2 segments of each 315 transaction set
+func x12StatusEvents(interchange *x12.Interchange) ([]statusEvent, edifact.Errors) {
+	var (
+		events []statusEvent
+		errs   edifact.Errors
+	)
+	for _, set := range interchange.TransactionSets {
+		if set.Code != "315" {
+			errs = append(errs, edifact.SegmentError{Position: set.Position, Tag: "ST", Element: 1,
+				Message: fmt.Sprintf("unsupported transaction set %q, expected 315", set.Code)})
+			continue
+		}
+
+		event := statusEvent{reference: set.ControlNumber}
+		for _, segment := range set.Segments {
+			switch segment.Tag {
+			case "B4":
+				event.segment = segment.Position
+				event.code = strings.ToUpper(segment.Component(2, 0))
+				event.location = firstNonEmpty(segment.Component(5, 0), segment.Component(10, 0))
+				event.containerNo = normalizeContainerNo(segment.Component(6, 0) + segment.Component(7, 0))
+				t, err := x12DateTime(segment.Component(3, 0), segment.Component(4, 0))
+				if err != nil {
+					errs = append(errs, edifact.SegmentError{Position: segment.Position, Tag: segment.Tag, Element: 4, Message: err.Error()})
+				}
+				event.time = t
+				if event.code == "" {
+					errs = append(errs, edifact.SegmentError{Position: segment.Position, Tag: segment.Tag, Element: 3, Message: "status code is missing"})
+				}
+			case "N9":
+				switch segment.Component(0, 0) {
+				case "BM":
+					event.billRef = strings.TrimSpace(segment.Component(1, 0))
+				case "BN":
+					event.bookingRef = strings.TrimSpace(segment.Component(1, 0))
+				case "EQ":
+					if event.containerNo == "" {
+						event.containerNo = normalizeContainerNo(segment.Component(1, 0))
+					}
+				}
+			case "Q2":
+				event.vessel = strings.TrimSpace(segment.Component(12, 0))
+			}
+		}
+		if event.segment == 0 {
+			errs = append(errs, edifact.SegmentError{Position: set.Position, Tag: "ST", Message: "transaction set has no B4 segment"})
+			continue
+		}
+		events = append(events, event)
+	}
+	return events, errs
+}
+
+// x12DateTime reads a CCYYMMDD (or YYMMDD) date and an optional HHMM time
+func x12DateTime(date, clock string) (time.Time, error) {
+	layout := "20060102"
+	if len(date) == 6 {
+		layout = "060102"
+	}
+	value := date
+	if len(clock) >= 4 {
+		layout += "1504"
+		value += clock[:4]
+	}
+	t, err := time.Parse(layout, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("status date %q is not a CCYYMMDD date", date)
+	}
+	return t, nil
+}
+
+// iftstaStatusEvents reads each STS segment of the IFTSTA messages with the
+// RFF, DTM, LOC, EQD and TDT segments that follow it. References and
+// equipment given before the first STS of a consignment (CNI) apply to all of
+// its events.
+func iftstaStatusEvents(interchange *edifact.Interchange) ([]statusEvent, edifact.Errors) {
+	var (
+		events []statusEvent
+		errs   edifact.Errors
+	)
+	for _, message := range interchange.Messages {
+		if message.Type != "IFTSTA" {
+			errs = append(errs, edifact.SegmentError{Position: message.Position, Tag: "UNH", Element: 2,
+				Message: fmt.Sprintf("unsupported message type %q, expected IFTSTA", message.Type)})
+			continue
+		}
+
+		var (
+			messageLevel  = statusEvent{reference: message.Reference}
+			shared        = messageLevel // references of the current consignment
+			current       *statusEvent
+			inConsignment bool
+		)
+		flush := func() {
+			if current == nil {
+				return
+			}
+			if current.time.IsZero() {
+				errs = append(errs, edifact.SegmentError{Position: current.segment, Tag: "STS", Message: "status has no event date (DTM)"})
+			} else {
+				events = append(events, *current)
+			}
+			current = nil
+		}
+		// targets are the levels a reference, container or vessel applies to
+		targets := func() []*statusEvent {
+			switch {
+			case current != nil:
+				return []*statusEvent{current}
+			case inConsignment:
+				return []*statusEvent{&shared}
+			}
+			return []*statusEvent{&shared, &messageLevel}
+		}
+
+		for _, segment := range message.Segments {
+			switch segment.Tag {
+			case "CNI":
+				flush()
+				shared = messageLevel
+				inConsignment = true
+			case "STS":
+				flush()
+				event := shared
+				event.segment = segment.Position
+				event.code = strings.ToUpper(segment.Component(1, 0))
+				if event.code == "" {
+					errs = append(errs, edifact.SegmentError{Position: segment.Position, Tag: segment.Tag, Element: 2, Message: "status code is missing"})
+				}
+				current = &event
+			case "RFF":
+				reference := strings.TrimSpace(segment.Component(0, 1))
+				for _, event := range targets() {
+					switch segment.Component(0, 0) {
+					case "BM":
+						event.billRef = reference
+					case "BN":
+						event.bookingRef = reference
+					}
+				}
+			case "DTM":
+				if current == nil || !current.time.IsZero() {
+					continue
+				}
+				t, err := edifactDateTime(segment.Component(0, 1), segment.Component(0, 2))
+				if err != nil {
+					errs = append(errs, edifact.SegmentError{Position: segment.Position, Tag: segment.Tag, Element: 1, Message: err.Error()})
+					continue
+				}
+				current.time = t
+			case "LOC":
+				if current != nil && current.location == "" {
+					current.location = firstNonEmpty(segment.Component(1, 3), segment.Component(1, 0))
+				}
+			case "EQD":
+				if segment.Component(0, 0) != "CN" {
+					continue
+				}
+				for _, event := range targets() {
+					event.containerNo = normalizeContainerNo(segment.Component(1, 0))
+				}
+			case "TDT":
+				for _, event := range targets() {
+					event.vessel = firstNonEmpty(segment.Component(7, 3), segment.Component(7, 0))
+				}
+			}
+		}
+		flush()
+	}
+	return events, errs
+}
+
+// edifactDateTime parses a DTM value with its format qualifier
+func edifactDateTime(value, format string) (time.Time, error) {
+	if format == "" {
+		format = "102"
+	}
+	layout, ok := edifactDateFormats[format]
+	if !ok {
+		return time.Time{}, fmt.Errorf("unsupported date format %s", format)
+	}
+	t, err := time.Parse(layout, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("date %q does not match format %s", value, format)
+	}
+	return t, nil
+}
+
+// sortStatusEvents orders events by time so bookings advance in the order
+// things happened, whatever the order of the message
+func sortStatusEvents(events []statusEvent) {
+	sort.SliceStable(events, func(i, j int) bool { return events[i].time.Before(events[j].time) })
+}
+
+func unmatchedStatusEvent(event statusEvent) models.UnmatchedStatusEvent {
+	return models.UnmatchedStatusEvent{
+		EventCode:   event.code,
+		ContainerNo: event.containerNo,
+		Reference:   firstNonEmpty(event.billRef, event.bookingRef),
+		Segment:     event.segment,
+	}
+}
+
+// statusEventReason describes the event behind a booking status change
+func statusEventReason(event statusEvent) string {
+	reason := "Carrier status " + event.code
+	if event.containerNo != "" {
+		reason += " for " + event.containerNo
+	}
+	if event.location != "" {
+		reason += " at " + event.location
+	}
+	return reason
+}
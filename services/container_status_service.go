@@ -0,0 +1,241 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"fs-backend/models"
+	"fs-backend/modules/edifact"
+	"fs-backend/modules/x12"
+	"fs-backend/repository"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var ErrUnknownStatusFormat = errors.New("status message is neither an X12 interchange nor an EDIFACT interchange")
+
+// statusUpdateAttempts bounds the retries when a booking changes while its
+// status is being advanced
+const statusUpdateAttempts = 3
+
+// ContainerStatusService ingests carrier container status messages (X12 315
+// and EDIFACT IFTSTA) into a per-MBL event timeline
+type ContainerStatusService interface {
+	Ingest(ctx context.Context, data []byte) (*models.StatusIngestionResponse, error)
+	Timeline(ctx context.Context, mblNumber string) ([]models.ContainerEvent, error)
+}
+
+type containerStatusService struct {
+	eventRepo   repository.ContainerEventRepository
+	bookingRepo repository.BookingRepository
+	mblRepo     repository.MBLRepository
+	eventStatus map[string]string
+}
+
+// NewContainerStatusService creates a new ContainerStatusService. eventStatus
+// maps status codes to booking statuses on top of the defaults.
+func NewContainerStatusService(
+	eventRepo repository.ContainerEventRepository,
+	bookingRepo repository.BookingRepository,
+	mblRepo repository.MBLRepository,
+	eventStatus map[string]string,
+) ContainerStatusService {
+	mapping := eventStatusMap(eventStatus)
+	for code, status := range mapping {
+		if _, ok := bookingStatusRank(status); !ok {
+			log.Printf("Warning: status_ingestion.event_status maps %s to %q, which is not a booking status events can set, ignoring", code, status)
+			delete(mapping, code)
+			continue
+		}
+		mapping[code] = normalizeBookingStatus(status)
+	}
+	return &containerStatusService{
+		eventRepo:   eventRepo,
+		bookingRepo: bookingRepo,
+		mblRepo:     mblRepo,
+		eventStatus: mapping,
+	}
+}
+
+// Ingest stores the events of a status message on the timeline of the MBL
+// they belong to and advances the booking of that MBL. Events are applied in
+// time order through the booking state machine, so late or re-sent messages
+// cannot roll a status back. Events that match no MBL are reported, not stored.
+func (s *containerStatusService) Ingest(ctx context.Context, data []byte) (*models.StatusIngestionResponse, error) {
+	events, source, err := parseStatusMessage(data)
+	if err != nil {
+		return nil, err
+	}
+	sortStatusEvents(events)
+
+	response := &models.StatusIngestionResponse{
+		Source:         source,
+		Received:       len(events),
+		Unmatched:      []models.UnmatchedStatusEvent{},
+		BookingUpdates: []models.BookingStatusUpdate{},
+	}
+	for _, event := range events {
+		mblNumber, err := s.matchEvent(ctx, event)
+		if err != nil {
+			return nil, err
+		}
+		if mblNumber == "" {
+			response.Unmatched = append(response.Unmatched, unmatchedStatusEvent(event))
+			continue
+		}
+
+		record := &models.ContainerEvent{
+			MBLNumber:        mblNumber,
+			ContainerNo:      event.containerNo,
+			EventCode:        event.code,
+			EventTime:        event.time,
+			Location:         event.location,
+			Vessel:           event.vessel,
+			Source:           source,
+			MessageReference: event.reference,
+		}
+		update, err := s.advanceBooking(ctx, mblNumber, event)
+		if err != nil {
+			return nil, err
+		}
+		if update != nil {
+			record.BookingStatus = update.To
+			response.BookingUpdates = append(response.BookingUpdates, *update)
+		}
+
+		stored, err := s.eventRepo.Insert(ctx, record)
+		if err != nil {
+			return nil, err
+		}
+		if stored {
+			response.Stored++
+		}
+	}
+	return response, nil
+}
+
+func (s *containerStatusService) Timeline(ctx context.Context, mblNumber string) ([]models.ContainerEvent, error) {
+	return s.eventRepo.FindByMBLNumber(ctx, mblNumber)
+}
+
+// parseStatusMessage tells X12 from EDIFACT by the first segment and reads
+// the events of the interchange
+func parseStatusMessage(data []byte) ([]statusEvent, string, error) {
+	text := strings.TrimLeft(string(data), " \r\n\t")
+	var (
+		events      []statusEvent
+		source      string
+		segmentErrs edifact.Errors
+		err         error
+	)
+	switch {
+	case strings.HasPrefix(text, "ISA"):
+		source = models.ContainerEventSourceX12315
+		var interchange *x12.Interchange
+		if interchange, err = x12.Parse([]byte(text)); err == nil {
+			events, segmentErrs = x12StatusEvents(interchange)
+		}
+	case strings.HasPrefix(text, "UNA"), strings.HasPrefix(text, "UNB"), strings.HasPrefix(text, "UNH"):
+		source = models.ContainerEventSourceIFTSTA
+		var interchange *edifact.Interchange
+		if interchange, err = edifact.Parse([]byte(text)); err == nil {
+			events, segmentErrs = iftstaStatusEvents(interchange)
+		}
+	default:
+		return nil, "", ErrUnknownStatusFormat
+	}
+
+	if errors.As(err, &segmentErrs) {
+		return nil, "", &StatusMessageError{Errors: segmentErrs}
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if len(segmentErrs) > 0 {
+		return nil, "", &StatusMessageError{Errors: segmentErrs}
+	}
+	return events, source, nil
+}
+
+// matchEvent resolves the MBL an event belongs to: by its bill or booking
+// reference when a booking or MBL is on file under it, otherwise by the
+// container number. It returns "" when nothing matches.
+func (s *containerStatusService) matchEvent(ctx context.Context, event statusEvent) (string, error) {
+	for _, reference := range nonEmptyStrings([]string{event.billRef, event.bookingRef}) {
+		_, err := s.bookingRepo.FindByMBLNumber(ctx, reference)
+		if err == nil {
+			return reference, nil
+		}
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			return "", err
+		}
+		_, err = s.mblRepo.FindByMBLNumber(ctx, reference)
+		if err == nil {
+			return reference, nil
+		}
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			return "", err
+		}
+	}
+
+	if event.containerNo == "" {
+		return "", nil
+	}
+	doc, err := s.mblRepo.FindByContainerNo(ctx, event.containerNo)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return doc.MBL.BillOfLadingNo, nil
+}
+
+// advanceBooking moves the booking of an MBL to the status mapped to the
+// event code when the booking state machine allows it, which keeps bookings
+// from moving backwards or out of a cancellation
+func (s *containerStatusService) advanceBooking(ctx context.Context, mblNumber string, event statusEvent) (*models.BookingStatusUpdate, error) {
+	to, ok := s.eventStatus[event.code]
+	if !ok {
+		return nil, nil
+	}
+
+	for attempt := 0; attempt < statusUpdateAttempts; attempt++ {
+		booking, err := s.bookingRepo.FindByMBLNumber(ctx, mblNumber)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		from := normalizeBookingStatus(booking.Status)
+		if !canTransitionBooking(from, to) {
+			return nil, nil
+		}
+
+		change := models.BookingStatusChange{
+			From:   from,
+			To:     to,
+			Reason: statusEventReason(event),
+			Source: models.BookingStatusSourceCarrier,
+			At:     time.Now(),
+		}
+		err = s.bookingRepo.UpdateBookingStatus(ctx, booking.ID, change, booking.Revision)
+		if errors.Is(err, repository.ErrRevisionMismatch) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &models.BookingStatusUpdate{
+			MBLNumber: mblNumber,
+			From:      from,
+			To:        to,
+			EventCode: event.code,
+		}, nil
+	}
+	return nil, repository.ErrRevisionMismatch
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Subdirectories of the watched directory that ingested files are moved to
+const (
+	statusProcessedDir = "processed"
+	statusFailedDir    = "failed"
+)
+
+// WatchStatusDirectory ingests the status messages dropped into dir until ctx
+// is cancelled, checking for new files every interval. Ingested files are
+// moved to dir/processed; files that could not be ingested are moved to
+// dir/failed next to a .error file with the reason. Hidden files and files
+// still being written (.tmp, .part) are skipped. A file being ingested when ctx
+// is cancelled stays in dir for the next run.
+func WatchStatusDirectory(ctx context.Context, service ContainerStatusService, dir string, interval time.Duration) {
+	for _, sub := range []string{statusProcessedDir, statusFailedDir} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			log.Printf("Warning: status ingestion watcher disabled: %v", err)
+			return
+		}
+	}
+	log.Printf("Watching %s for container status messages", dir)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		ingestStatusDirectory(ctx, service, dir)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func ingestStatusDirectory(ctx context.Context, service ContainerStatusService, dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		log.Printf("Error reading status directory %s: %v", dir, err)
+		return
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || strings.HasSuffix(name, ".tmp") || strings.HasSuffix(name, ".part") {
+			continue
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		path := filepath.Join(dir, name)
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Printf("Error reading status message %s: %v", path, err)
+			continue
+		}
+		result, err := service.Ingest(ctx, data)
+		if err != nil && ctx.Err() != nil {
+			log.Printf("Status message %s left for the next run: %v", name, err)
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to ingest status message %s: %v", name, err)
+			if writeErr := os.WriteFile(filepath.Join(dir, statusFailedDir, name+".error"), []byte(err.Error()+"\n"), 0o644); writeErr != nil {
+				log.Printf("Error writing %s.error: %v", name, writeErr)
+			}
+			moveStatusFile(dir, statusFailedDir, name)
+			continue
+		}
+		log.Printf("Ingested status message %s: %d events, %d stored, %d unmatched, %d booking updates",
+			name, result.Received, result.Stored, len(result.Unmatched), len(result.BookingUpdates))
+		moveStatusFile(dir, statusProcessedDir, name)
+	}
+}
+
+func moveStatusFile(dir, sub, name string) {
+	if err := os.Rename(filepath.Join(dir, name), filepath.Join(dir, sub, name)); err != nil {
+		log.Printf("Error moving status message %s to %s: %v", name, sub, err)
+	}
+}
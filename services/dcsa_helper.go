@@ -0,0 +1,851 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"fs-backend/models"
+	"fs-backend/models/dcsa"
+	"fs-backend/models/hbl_schema"
+	"fs-backend/models/mbl_schema"
+)
+
+var (
+	unLocationCodePattern = regexp.MustCompile(`^[A-Z]{2}[A-Z2-9]{3}$`)
+	packageCodePattern    = regexp.MustCompile(`^[0-9A-Z]{2}$`)
+)
+
+// documentDateLayouts are the date layouts found on extracted and typed bills
+var documentDateLayouts = []string{
+	dcsa.DateFormat,
+	"02-01-2006",
+	"02/01/2006",
+	"02.01.2006",
+	"2 Jan 2006",
+	"02-Jan-2006",
+	"02-Jan-06",
+	"Jan 2, 2006",
+	"January 2, 2006",
+	"2 January 2006",
+	time.RFC3339,
+}
+
+// DCSAValidationError lists everything wrong with a DCSA transport document
+type DCSAValidationError struct {
+	Errors []HBLFieldError
+}
+
+func (e *DCSAValidationError) Error() string {
+	var parts []string
+	for _, fieldErr := range e.Errors {
+		parts = append(parts, fieldErr.Field+": "+fieldErr.Message)
+	}
+	return "invalid DCSA transport document: " + strings.Join(parts, "; ")
+}
+
+// hblToDCSA maps a stored HBL to a DCSA transport document. The forwarder
+// issuing the HBL is the issuing party.
+func hblToDCSA(doc *hbl_schema.HBLDocument) dcsa.TransportDocument {
+	data := doc.HBL
+	td := dcsa.TransportDocument{
+		TransportDocumentReference: doc.HBLNumber,
+		TransportDocumentTypeCode:  dcsa.TypeBillOfLading,
+		TransportDocumentStatus:    dcsaStatus(doc.Status),
+		FreightPaymentTermCode:     dcsaPaymentTerm(data.FreightDetails.FreightStatus),
+		InvoicePayableAt:           dcsaLocation(data.ShipmentDates.FreightPayableAt),
+		CarrierBookingReference:    data.CarrierReference,
+		Transports: dcsa.Transports{
+			PlaceOfReceipt:  dcsaLocation(data.Routing.PlaceOfReceipt),
+			PortOfLoading:   dcsaLocationValue(data.Routing.PortOfLoading),
+			PortOfDischarge: dcsaLocationValue(data.Routing.PortOfDischarge),
+			PlaceOfDelivery: dcsaLocation(data.Routing.PlaceOfDelivery),
+			VesselVoyages:   []dcsa.VesselVoyage{},
+		},
+		DocumentParties: dcsa.DocumentParties{
+			Shipper:      dcsaParty(data.Shipper.Name, data.Shipper.Address, dcsa.PartyContact{}),
+			IssuingParty: dcsaOptionalParty(data.ForwardingAgent.Name, data.ForwardingAgent.Address, dcsa.PartyContact{}),
+		},
+		ConsignmentItems:            []dcsa.ConsignmentItem{},
+		UtilizedTransportEquipments: []dcsa.UtilizedTransportEquipment{},
+	}
+	if data.BillType == hbl_schema.HBLBillTypeSeaWaybill {
+		td.TransportDocumentTypeCode = dcsa.TypeSeaWaybill
+	} else {
+		td.NumberOfOriginalsWithCharges = len(doc.Release.Originals)
+	}
+	td.CargoMovementTypeAtOrigin, td.CargoMovementTypeAtDestination = dcsaMovementTypes(data.MovementType)
+	td.PlaceOfIssue, td.IssueDate = dcsaPlaceAndDate(data.ShipmentDates.PlaceAndDateOfIssue)
+
+	if consignee := dcsaOptionalParty(data.Consignee.Name, data.Consignee.Address, dcsa.PartyContact{}); consignee != nil {
+		td.DocumentParties.Consignee = consignee
+	} else {
+		td.IsToOrder = true
+	}
+	if notify := dcsaOptionalParty(data.NotifyParty.Name, data.NotifyParty.Address, dcsa.PartyContact{}); notify != nil {
+		td.DocumentParties.NotifyParties = []dcsa.Party{*notify}
+	}
+
+	for _, vessel := range data.VesselDetails {
+		if strings.TrimSpace(vessel.VesselName) == "" {
+			continue
+		}
+		td.Transports.VesselVoyages = append(td.Transports.VesselVoyages, dcsa.VesselVoyage{
+			VesselName:                vessel.VesselName,
+			CarrierExportVoyageNumber: vessel.VoyageNo,
+		})
+	}
+
+	if data.ExportReference != "" {
+		td.References = append(td.References, dcsa.Reference{Type: dcsa.ReferenceForwarder, Value: data.ExportReference})
+	}
+	if data.ConsigneeReference != "" {
+		td.References = append(td.References, dcsa.Reference{Type: dcsa.ReferenceConsignee, Value: data.ConsigneeReference})
+	}
+
+	// One consignment item per container row; a container shared by several
+	// rows is listed once as equipment
+	seen := map[string]bool{}
+	for _, container := range data.ContainerDetails {
+		containerNo := normalizeContainerNo(container.ContainerNo)
+		td.ConsignmentItems = append(td.ConsignmentItems, dcsa.ConsignmentItem{
+			CarrierBookingReference: data.CarrierReference,
+			DescriptionOfGoods:      splitLines(container.DescriptionOfGoods),
+			HSCodes:                 nonEmpty(container.HSCode),
+			ShippingMarks:           splitLines(container.MarksAndNumbers),
+			CargoItems: []dcsa.CargoItem{{
+				EquipmentReference: containerNo,
+				CargoGrossWeight:   dcsaWeight(container.GrossWeight.Value, container.GrossWeight.Unit),
+				CargoNetWeight:     dcsaOptionalWeight(container.NetWeight.Value, container.NetWeight.Unit),
+				CargoGrossVolume:   dcsaOptionalVolume(container.Measurement.Value, container.Measurement.Unit),
+				OuterPackaging:     dcsaPackaging(container.PackageCount, container.PackageType),
+			}},
+		})
+
+		if containerNo == "" || seen[containerNo] {
+			continue
+		}
+		seen[containerNo] = true
+		td.UtilizedTransportEquipments = append(td.UtilizedTransportEquipments,
+			dcsaEquipment(containerNo, container.ContainerSize, splitList(container.SealNo)))
+	}
+	return td
+}
+
+// mblToDCSA maps a stored MBL to a DCSA transport document. MBLs carry their
+// cargo as a single line, so the whole cargo is booked against the first container.
+func mblToDCSA(doc *mbl_schema.MBLDocument) dcsa.TransportDocument {
+	data := doc.MBL
+	td := dcsa.TransportDocument{
+		TransportDocumentReference: data.BillOfLadingNo,
+		TransportDocumentTypeCode:  dcsa.TypeBillOfLading,
+		IsShippedOnBoardType:       strings.TrimSpace(data.ShipmentDates.ShippedOnBoardDate) != "",
+		FreightPaymentTermCode:     dcsaPaymentTerm(data.FreightPaymentType),
+		IssueDate:                  dcsaDate(data.ShipmentDates.DateOfIssue),
+		ShippedOnBoardDate:         dcsaDate(data.ShipmentDates.ShippedOnBoardDate),
+		PlaceOfIssue:               dcsaLocation(data.ShipmentDates.PlaceOfIssue),
+		CarrierBookingReference:    data.Carrier.ReferenceNo,
+		TermsOfSale:                data.TermsOfSale,
+		Transports: dcsa.Transports{
+			PlaceOfReceipt:  dcsaLocation(data.Routing.PlaceOfReceipt),
+			PortOfLoading:   dcsaLocationValue(data.Routing.PortOfLoading),
+			PortOfDischarge: dcsaLocationValue(data.Routing.PortOfDischarge),
+			PlaceOfDelivery: dcsaLocation(data.Routing.PlaceOfDelivery),
+			VesselVoyages:   []dcsa.VesselVoyage{},
+		},
+		DocumentParties: dcsa.DocumentParties{
+			Shipper: dcsaParty(data.Shipper.Name, data.Shipper.Address,
+				dcsa.PartyContact{Phone: data.Shipper.Phone, Fax: data.Shipper.Fax}),
+			IssuingParty: dcsaOptionalParty(data.Carrier.Name, "", dcsa.PartyContact{}),
+		},
+		ConsignmentItems:            []dcsa.ConsignmentItem{},
+		UtilizedTransportEquipments: []dcsa.UtilizedTransportEquipment{},
+	}
+	if isSeaWaybillType(data.BillType) {
+		td.TransportDocumentTypeCode = dcsa.TypeSeaWaybill
+	} else {
+		td.NumberOfOriginalsWithCharges = data.NumberOfOriginalBLs
+	}
+	if scac := strings.ToUpper(strings.TrimSpace(data.Carrier.SCACCode)); scac != "" {
+		td.CarrierCode = scac
+		td.CarrierCodeListProvider = dcsa.CodeListNMFTA
+	}
+	if mode := strings.ToUpper(doc.Mode); mode == "FCL" || mode == "LCL" {
+		td.CargoMovementTypeAtOrigin, td.CargoMovementTypeAtDestination = mode, mode
+	}
+
+	if consignee := dcsaOptionalParty(data.Consignee.Name, data.Consignee.Address,
+		dcsa.PartyContact{Phone: data.Consignee.Phone, Email: data.Consignee.Email}); consignee != nil {
+		td.DocumentParties.Consignee = consignee
+	} else {
+		td.IsToOrder = true
+	}
+	if notify := dcsaOptionalParty(data.NotifyParty.Name, data.NotifyParty.Address, dcsa.PartyContact{}); notify != nil {
+		td.DocumentParties.NotifyParties = []dcsa.Party{*notify}
+	}
+	if strings.TrimSpace(data.VesselDetails.VesselName) != "" {
+		td.Transports.VesselVoyages = append(td.Transports.VesselVoyages, dcsa.VesselVoyage{
+			VesselName:                data.VesselDetails.VesselName,
+			CarrierExportVoyageNumber: data.VesselDetails.VoyageNo,
+		})
+	}
+	if data.PackingListNo != "" {
+		td.References = append(td.References, dcsa.Reference{Type: dcsa.ReferencePackingList, Value: data.PackingListNo})
+	}
+
+	// Seals are matched to containers by position when the counts agree,
+	// otherwise they all go on the first container
+	containers := splitList(data.Cargo.ContainerNo)
+	seals := splitList(data.Cargo.SealNumber)
+	for i, containerNo := range containers {
+		var containerSeals []string
+		if len(seals) == len(containers) {
+			containerSeals = seals[i : i+1]
+		} else if i == 0 {
+			containerSeals = seals
+		}
+		td.UtilizedTransportEquipments = append(td.UtilizedTransportEquipments,
+			dcsaEquipment(normalizeContainerNo(containerNo), data.Cargo.ContainerType, containerSeals))
+	}
+
+	cargo := data.Cargo
+	item := dcsa.ConsignmentItem{
+		CarrierBookingReference: data.Carrier.ReferenceNo,
+		DescriptionOfGoods:      splitLines(cargo.DescriptionOfGoods),
+		HSCodes:                 nonEmpty(cargo.HSCode),
+		ShippingMarks:           splitLines(cargo.MarksAndNumbers),
+		CargoItems: []dcsa.CargoItem{{
+			CargoGrossWeight: dcsaWeight(cargo.GrossWeight.Value, cargo.GrossWeight.Unit),
+			CargoNetWeight:   dcsaOptionalWeight(cargo.NetWeight.Value, cargo.NetWeight.Unit),
+			CargoGrossVolume: dcsaOptionalVolume(cargo.Measurement.Value, cargo.Measurement.Unit),
+			OuterPackaging:   dcsaPackaging(cargo.NumberOfPackages, cargo.PackageType),
+		}},
+	}
+	if len(td.UtilizedTransportEquipments) > 0 {
+		item.CargoItems[0].EquipmentReference = td.UtilizedTransportEquipments[0].Equipment.EquipmentReference
+	}
+	td.ConsignmentItems = append(td.ConsignmentItems, item)
+
+	freight := data.FreightCharges.OceanFreight
+	if freight.PrepaidAmount > 0 {
+		td.Charges = append(td.Charges, dcsaOceanFreight(freight.PrepaidAmount, freight.Currency, dcsa.PaymentTermPrepaid))
+	}
+	if freight.CollectAmount > 0 {
+		td.Charges = append(td.Charges, dcsaOceanFreight(freight.CollectAmount, freight.Currency, dcsa.PaymentTermCollect))
+	}
+	return td
+}
+
+// dcsaToMBL maps a validated DCSA transport document to an MBL document. The
+// consignment items are folded into the single cargo line of an MBL: texts
+// are joined, packages, weights (kg) and volumes (cbm) summed.
+func dcsaToMBL(td dcsa.TransportDocument) *mbl_schema.MBLDocument {
+	parties := td.DocumentParties
+	shipperContact := firstContact(parties.Shipper)
+	data := mbl_schema.MBLData{
+		BillType:            "MBL",
+		BillOfLadingNo:      strings.TrimSpace(td.TransportDocumentReference),
+		NumberOfOriginalBLs: td.NumberOfOriginalsWithCharges + td.NumberOfOriginalsWithoutCharges,
+		TermsOfSale:         td.TermsOfSale,
+		FreightPaymentType:  paymentTypeFromDCSA(td.FreightPaymentTermCode),
+		Carrier: mbl_schema.Carrier{
+			ReferenceNo: td.CarrierBookingReference,
+		},
+		Shipper: mbl_schema.Party{
+			Name:    parties.Shipper.PartyName,
+			Address: addressText(parties.Shipper.Address),
+			Phone:   shipperContact.Phone,
+			Fax:     shipperContact.Fax,
+		},
+		Routing: mbl_schema.Routing{
+			PlaceOfReceipt:  locationText(td.Transports.PlaceOfReceipt),
+			PortOfLoading:   locationText(&td.Transports.PortOfLoading),
+			PortOfDischarge: locationText(&td.Transports.PortOfDischarge),
+			PlaceOfDelivery: locationText(td.Transports.PlaceOfDelivery),
+		},
+		ShipmentDates: mbl_schema.ShipmentDates{
+			DateOfIssue:        td.IssueDate,
+			PlaceOfIssue:       locationText(td.PlaceOfIssue),
+			ShippedOnBoardDate: td.ShippedOnBoardDate,
+		},
+	}
+	if td.TransportDocumentTypeCode == dcsa.TypeSeaWaybill {
+		data.BillType = hbl_schema.HBLBillTypeSeaWaybill
+	}
+	if parties.IssuingParty != nil {
+		data.Carrier.Name = parties.IssuingParty.PartyName
+	}
+	if td.CarrierCodeListProvider == dcsa.CodeListNMFTA {
+		data.Carrier.SCACCode = td.CarrierCode
+	}
+	if parties.Consignee != nil {
+		contact := firstContact(*parties.Consignee)
+		data.Consignee = mbl_schema.ConsigneeParty{
+			Name:    parties.Consignee.PartyName,
+			Address: addressText(parties.Consignee.Address),
+			Phone:   contact.Phone,
+			Email:   contact.Email,
+		}
+	}
+	if len(parties.NotifyParties) > 0 {
+		data.NotifyParty = mbl_schema.NotifyParty{
+			Name:    parties.NotifyParties[0].PartyName,
+			Address: addressText(parties.NotifyParties[0].Address),
+		}
+	}
+	if len(td.Transports.VesselVoyages) > 0 {
+		data.VesselDetails = mbl_schema.VesselDetails{
+			VesselName: td.Transports.VesselVoyages[0].VesselName,
+			VoyageNo:   td.Transports.VesselVoyages[0].CarrierExportVoyageNumber,
+		}
+	}
+	for _, reference := range td.References {
+		if reference.Type == dcsa.ReferencePackingList {
+			data.PackingListNo = reference.Value
+		}
+	}
+
+	var containers, containerTypes, seals []string
+	for _, equipment := range td.UtilizedTransportEquipments {
+		containers = append(containers, equipment.Equipment.EquipmentReference)
+		if code := equipment.Equipment.ISOEquipmentCode; code != "" && !containsString(containerTypes, code) {
+			containerTypes = append(containerTypes, code)
+		}
+		for _, seal := range equipment.Seals {
+			seals = append(seals, seal.Number)
+		}
+	}
+
+	cargo := mbl_schema.Cargo{
+		ContainerNo:   strings.Join(containers, ", "),
+		ContainerType: strings.Join(containerTypes, ", "),
+		SealNumber:    strings.Join(seals, ", "),
+		GrossWeight:   mbl_schema.WeightMeasurement{Unit: "KGS"},
+		NetWeight:     mbl_schema.WeightMeasurement{Unit: "KGS"},
+		Measurement:   mbl_schema.WeightMeasurement{Unit: "CBM"},
+	}
+	var descriptions, marks []string
+	for _, item := range td.ConsignmentItems {
+		descriptions = append(descriptions, item.DescriptionOfGoods...)
+		marks = append(marks, item.ShippingMarks...)
+		if cargo.HSCode == "" && len(item.HSCodes) > 0 {
+			cargo.HSCode = item.HSCodes[0]
+		}
+		if data.Carrier.ReferenceNo == "" {
+			data.Carrier.ReferenceNo = item.CarrierBookingReference
+		}
+		for _, cargoItem := range item.CargoItems {
+			cargo.NumberOfPackages += cargoItem.OuterPackaging.NumberOfPackages
+			if cargo.PackageType == "" {
+				cargo.PackageType = packageTypeFromDCSA(cargoItem.OuterPackaging)
+			}
+			cargo.GrossWeight.Value += weightFromDCSA(cargoItem.CargoGrossWeight)
+			if cargoItem.CargoNetWeight != nil {
+				cargo.NetWeight.Value += weightFromDCSA(*cargoItem.CargoNetWeight)
+			}
+			if cargoItem.CargoGrossVolume != nil {
+				cargo.Measurement.Value += volumeFromDCSA(*cargoItem.CargoGrossVolume)
+			}
+		}
+	}
+	cargo.DescriptionOfGoods = strings.Join(descriptions, "\n")
+	cargo.MarksAndNumbers = strings.Join(marks, "\n")
+	data.Cargo = cargo
+
+	for _, charge := range td.Charges {
+		if data.FreightCharges.OceanFreight.Currency == "" {
+			data.FreightCharges.OceanFreight.Currency = charge.CurrencyCode
+		}
+		switch charge.PaymentTermCode {
+		case dcsa.PaymentTermPrepaid:
+			data.FreightCharges.OceanFreight.PrepaidAmount += charge.CurrencyAmount
+		case dcsa.PaymentTermCollect:
+			data.FreightCharges.OceanFreight.CollectAmount += charge.CurrencyAmount
+		}
+	}
+
+	doc := &mbl_schema.MBLDocument{MBL: data}
+	if mode := td.CargoMovementTypeAtOrigin; mode == "FCL" || mode == "LCL" {
+		doc.Mode = mode
+	}
+	return doc
+}
+
+// validateDCSATransportDocument checks the parts of a transport document the
+// MBL import relies on. Field paths follow the DCSA JSON names.
+func validateDCSATransportDocument(td dcsa.TransportDocument) []HBLFieldError {
+	var errs []HBLFieldError
+	add := func(field, message string) {
+		errs = append(errs, HBLFieldError{Field: field, Message: message})
+	}
+
+	if strings.TrimSpace(td.TransportDocumentReference) == "" {
+		add("transportDocumentReference", "is required")
+	}
+	switch td.TransportDocumentTypeCode {
+	case dcsa.TypeBillOfLading, dcsa.TypeSeaWaybill:
+	default:
+		add("transportDocumentTypeCode", fmt.Sprintf("must be %s or %s", dcsa.TypeBillOfLading, dcsa.TypeSeaWaybill))
+	}
+	switch td.FreightPaymentTermCode {
+	case "", dcsa.PaymentTermPrepaid, dcsa.PaymentTermCollect:
+	default:
+		add("freightPaymentTermCode", fmt.Sprintf("must be %s or %s", dcsa.PaymentTermPrepaid, dcsa.PaymentTermCollect))
+	}
+	if td.NumberOfOriginalsWithCharges < 0 || td.NumberOfOriginalsWithoutCharges < 0 {
+		add("numberOfOriginals", "must not be negative")
+	}
+	if td.TransportDocumentTypeCode == dcsa.TypeSeaWaybill && td.NumberOfOriginalsWithCharges+td.NumberOfOriginalsWithoutCharges > 0 {
+		add("numberOfOriginals", "sea waybills have no originals")
+	}
+	for _, date := range []struct{ field, value string }{
+		{"issueDate", td.IssueDate},
+		{"shippedOnBoardDate", td.ShippedOnBoardDate},
+	} {
+		if date.value == "" {
+			continue
+		}
+		if _, err := time.Parse(dcsa.DateFormat, date.value); err != nil {
+			add(date.field, "must be a date in YYYY-MM-DD format")
+		}
+	}
+	if td.IsShippedOnBoardType && td.ShippedOnBoardDate == "" {
+		add("shippedOnBoardDate", "is required on a shipped on board document")
+	}
+	switch td.CarrierCodeListProvider {
+	case "":
+		if td.CarrierCode != "" {
+			add("carrierCodeListProvider", "is required with carrierCode")
+		}
+	case dcsa.CodeListSMDG, dcsa.CodeListNMFTA:
+		if strings.TrimSpace(td.CarrierCode) == "" {
+			add("carrierCode", "is required with carrierCodeListProvider")
+		} else if td.CarrierCodeListProvider == dcsa.CodeListNMFTA && validateSCACCode(normalizeSCACCode(td.CarrierCode)) != nil {
+			add("carrierCode", "must be a SCAC code of 2 to 4 letters")
+		}
+	default:
+		add("carrierCodeListProvider", fmt.Sprintf("must be %s or %s", dcsa.CodeListSMDG, dcsa.CodeListNMFTA))
+	}
+	for _, movement := range []struct{ field, value string }{
+		{"cargoMovementTypeAtOrigin", td.CargoMovementTypeAtOrigin},
+		{"cargoMovementTypeAtDestination", td.CargoMovementTypeAtDestination},
+	} {
+		if movement.value != "" && movement.value != "FCL" && movement.value != "LCL" {
+			add(movement.field, "must be FCL or LCL")
+		}
+	}
+
+	// Parties
+	if strings.TrimSpace(td.DocumentParties.Shipper.PartyName) == "" {
+		add("documentParties.shipper.partyName", "is required")
+	}
+	if td.IsToOrder {
+		if td.TransportDocumentTypeCode == dcsa.TypeSeaWaybill {
+			add("isToOrder", "a sea waybill cannot be to order")
+		}
+	} else if td.DocumentParties.Consignee == nil || strings.TrimSpace(td.DocumentParties.Consignee.PartyName) == "" {
+		add("documentParties.consignee.partyName", "is required unless the document is to order")
+	}
+	for i, notify := range td.DocumentParties.NotifyParties {
+		if strings.TrimSpace(notify.PartyName) == "" {
+			add(fmt.Sprintf("documentParties.notifyParties[%d].partyName", i), "is required")
+		}
+	}
+
+	// Transports
+	for _, location := range []struct {
+		field    string
+		location *dcsa.Location
+		required bool
+	}{
+		{"transports.placeOfReceipt", td.Transports.PlaceOfReceipt, false},
+		{"transports.portOfLoading", &td.Transports.PortOfLoading, true},
+		{"transports.portOfDischarge", &td.Transports.PortOfDischarge, true},
+		{"transports.placeOfDelivery", td.Transports.PlaceOfDelivery, false},
+	} {
+		if location.location == nil {
+			continue
+		}
+		code := location.location.UNLocationCode
+		if code != "" && !unLocationCodePattern.MatchString(code) {
+			add(location.field+".UNLocationCode", "must be a 5 character UN/LOCODE")
+		}
+		if location.required && code == "" && strings.TrimSpace(location.location.LocationName) == "" {
+			add(location.field, "requires a UNLocationCode or a locationName")
+		}
+	}
+	if len(td.Transports.VesselVoyages) == 0 {
+		add("transports.vesselVoyages", "at least one vessel voyage is required")
+	}
+	for i, voyage := range td.Transports.VesselVoyages {
+		if strings.TrimSpace(voyage.VesselName) == "" {
+			add(fmt.Sprintf("transports.vesselVoyages[%d].vesselName", i), "is required")
+		}
+	}
+
+	// Equipment
+	equipment := map[string]bool{}
+	for i, utilized := range td.UtilizedTransportEquipments {
+		field := fmt.Sprintf("utilizedTransportEquipments[%d].equipment.equipmentReference", i)
+		reference := utilized.Equipment.EquipmentReference
+		switch {
+		case strings.TrimSpace(reference) == "":
+			add(field, "is required")
+		case equipment[reference]:
+			add(field, fmt.Sprintf("container %s is listed twice", reference))
+		}
+		equipment[reference] = true
+		if code := utilized.Equipment.ISOEquipmentCode; code != "" {
+			if _, ok := models.LookupContainerCapacity(code); !ok {
+				add(fmt.Sprintf("utilizedTransportEquipments[%d].equipment.ISOEquipmentCode", i), fmt.Sprintf("unknown ISO equipment code %s", code))
+			}
+		}
+		for j, seal := range utilized.Seals {
+			if strings.TrimSpace(seal.Number) == "" {
+				add(fmt.Sprintf("utilizedTransportEquipments[%d].seals[%d].number", i, j), "is required")
+			}
+		}
+	}
+
+	// Consignment items
+	if len(td.ConsignmentItems) == 0 {
+		add("consignmentItems", "at least one consignment item is required")
+	}
+	for i, item := range td.ConsignmentItems {
+		prefix := fmt.Sprintf("consignmentItems[%d]", i)
+		if len(item.DescriptionOfGoods) == 0 || strings.TrimSpace(strings.Join(item.DescriptionOfGoods, "")) == "" {
+			add(prefix+".descriptionOfGoods", "is required")
+		}
+		if len(item.CargoItems) == 0 {
+			add(prefix+".cargoItems", "at least one cargo item is required")
+		}
+		for j, cargoItem := range item.CargoItems {
+			cargoPrefix := fmt.Sprintf("%s.cargoItems[%d]", prefix, j)
+			if !equipment[cargoItem.EquipmentReference] {
+				add(cargoPrefix+".equipmentReference", fmt.Sprintf("container %q is not in utilizedTransportEquipments", cargoItem.EquipmentReference))
+			}
+			if cargoItem.OuterPackaging.NumberOfPackages <= 0 {
+				add(cargoPrefix+".outerPackaging.numberOfPackages", "must be greater than zero")
+			}
+			validateDCSAMeasure(cargoPrefix+".cargoGrossWeight", &cargoItem.CargoGrossWeight, true, dcsa.UnitKilogram, dcsa.UnitPound, add)
+			validateDCSAMeasure(cargoPrefix+".cargoNetWeight", cargoItem.CargoNetWeight, false, dcsa.UnitKilogram, dcsa.UnitPound, add)
+			validateDCSAMeasure(cargoPrefix+".cargoGrossVolume", cargoItem.CargoGrossVolume, false, dcsa.UnitCubicMetre, dcsa.UnitCubicFoot, add)
+		}
+	}
+
+	for i, charge := range td.Charges {
+		prefix := fmt.Sprintf("charges[%d]", i)
+		if charge.PaymentTermCode != dcsa.PaymentTermPrepaid && charge.PaymentTermCode != dcsa.PaymentTermCollect {
+			add(prefix+".paymentTermCode", fmt.Sprintf("must be %s or %s", dcsa.PaymentTermPrepaid, dcsa.PaymentTermCollect))
+		}
+		if len(charge.CurrencyCode) != 3 {
+			add(prefix+".currencyCode", "must be an ISO 4217 currency code")
+		}
+	}
+	return errs
+}
+
+func validateDCSAMeasure(field string, measure *dcsa.Measure, required bool, unit, altUnit string, add func(field, message string)) {
+	if measure == nil {
+		if required {
+			add(field, "is required")
+		}
+		return
+	}
+	if measure.Value < 0 || (required && measure.Value == 0) {
+		add(field+".value", "must be greater than zero")
+	}
+	if measure.Unit != unit && measure.Unit != altUnit {
+		add(field+".unit", fmt.Sprintf("must be %s or %s", unit, altUnit))
+	}
+}
+
+// dcsaStatus maps an HBL status to the DCSA document status; telex released
+// bills count as surrendered
+func dcsaStatus(status string) string {
+	switch normalizeHBLStatus(status) {
+	case hbl_schema.HBLStatusApproved:
+		return dcsa.StatusApproved
+	case hbl_schema.HBLStatusIssued:
+		return dcsa.StatusIssued
+	case hbl_schema.HBLStatusSurrendered, hbl_schema.HBLStatusReleased:
+		return dcsa.StatusSurrendered
+	case hbl_schema.HBLStatusVoid:
+		return dcsa.StatusVoided
+	}
+	return dcsa.StatusDraft
+}
+
+func dcsaPaymentTerm(text string) string {
+	upper := strings.ToUpper(text)
+	switch {
+	case strings.Contains(upper, "PREPAID"):
+		return dcsa.PaymentTermPrepaid
+	case strings.Contains(upper, "COLLECT"):
+		return dcsa.PaymentTermCollect
+	}
+	return ""
+}
+
+func paymentTypeFromDCSA(code string) string {
+	switch code {
+	case dcsa.PaymentTermPrepaid:
+		return "PREPAID"
+	case dcsa.PaymentTermCollect:
+		return "COLLECT"
+	}
+	return ""
+}
+
+// dcsaMovementTypes splits a movement type such as "FCL/LCL" into the cargo
+// movement types at origin and destination
+func dcsaMovementTypes(movementType string) (string, string) {
+	var types []string
+	for _, part := range strings.Split(strings.ToUpper(movementType), "/") {
+		part = strings.TrimSpace(part)
+		if part != "FCL" && part != "LCL" {
+			return "", ""
+		}
+		types = append(types, part)
+	}
+	switch len(types) {
+	case 1:
+		return types[0], types[0]
+	case 2:
+		return types[0], types[1]
+	}
+	return "", ""
+}
+
+// dcsaDate formats a document date as YYYY-MM-DD; dates in an unknown layout are dropped
+func dcsaDate(value string) string {
+	value = strings.TrimSpace(value)
+	for _, layout := range documentDateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.Format(dcsa.DateFormat)
+		}
+	}
+	return ""
+}
+
+// dcsaPlaceAndDate splits an HBL "place, date" of issue
+func dcsaPlaceAndDate(placeAndDate string) (*dcsa.Location, string) {
+	if i := strings.LastIndex(placeAndDate, ","); i >= 0 {
+		if date := dcsaDate(placeAndDate[i+1:]); date != "" {
+			return dcsaLocation(placeAndDate[:i]), date
+		}
+	}
+	if date := dcsaDate(placeAndDate); date != "" {
+		return nil, date
+	}
+	return dcsaLocation(placeAndDate), ""
+}
+
+// dcsaLocation returns nil for an empty location; a value that is a UN/LOCODE
+// is sent as UNLocationCode, anything else as locationName
+func dcsaLocation(value string) *dcsa.Location {
+	if strings.TrimSpace(value) == "" {
+		return nil
+	}
+	location := dcsaLocationValue(value)
+	return &location
+}
+
+func dcsaLocationValue(value string) dcsa.Location {
+	value = strings.TrimSpace(value)
+	if unLocationCodePattern.MatchString(value) {
+		return dcsa.Location{UNLocationCode: value}
+	}
+	return dcsa.Location{LocationName: value}
+}
+
+func locationText(location *dcsa.Location) string {
+	if location == nil {
+		return ""
+	}
+	if name := strings.TrimSpace(location.LocationName); name != "" {
+		return name
+	}
+	return location.UNLocationCode
+}
+
+func dcsaParty(name, address string, contact dcsa.PartyContact) dcsa.Party {
+	party := dcsa.Party{PartyName: strings.TrimSpace(name)}
+	if address = strings.TrimSpace(address); address != "" {
+		party.Address = &dcsa.Address{Street: address}
+	}
+	if contact != (dcsa.PartyContact{}) {
+		party.PartyContactDetails = []dcsa.PartyContact{contact}
+	}
+	return party
+}
+
+func dcsaOptionalParty(name, address string, contact dcsa.PartyContact) *dcsa.Party {
+	if strings.TrimSpace(name) == "" && strings.TrimSpace(address) == "" {
+		return nil
+	}
+	party := dcsaParty(name, address, contact)
+	return &party
+}
+
+// addressText flattens a structured address into a single address line
+func addressText(address *dcsa.Address) string {
+	if address == nil {
+		return ""
+	}
+	var parts []string
+	for _, part := range []string{
+		strings.TrimSpace(address.Street + " " + address.StreetNumber),
+		address.Floor,
+		strings.TrimSpace(address.PostCode + " " + address.City),
+		address.StateRegion,
+		address.CountryCode,
+	} {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// firstContact merges the contact details of a party, first value wins
+func firstContact(party dcsa.Party) dcsa.PartyContact {
+	var contact dcsa.PartyContact
+	for _, detail := range party.PartyContactDetails {
+		if contact.Phone == "" {
+			contact.Phone = detail.Phone
+		}
+		if contact.Email == "" {
+			contact.Email = detail.Email
+		}
+		if contact.Fax == "" {
+			contact.Fax = detail.Fax
+		}
+	}
+	return contact
+}
+
+func dcsaEquipment(containerNo, containerType string, seals []string) dcsa.UtilizedTransportEquipment {
+	equipment := dcsa.UtilizedTransportEquipment{
+		Equipment: dcsa.Equipment{EquipmentReference: containerNo},
+	}
+	if capacity, ok := models.LookupContainerCapacity(containerType); ok {
+		equipment.Equipment.ISOEquipmentCode = capacity.ISOType
+	}
+	for _, seal := range seals {
+		equipment.Seals = append(equipment.Seals, dcsa.Seal{Number: seal})
+	}
+	return equipment
+}
+
+// dcsaWeight converts a weight to kilograms
+func dcsaWeight(value float64, unit string) dcsa.Measure {
+	return dcsa.Measure{Value: weightToKg(value, unit), Unit: dcsa.UnitKilogram}
+}
+
+func dcsaOptionalWeight(value float64, unit string) *dcsa.Measure {
+	if value <= 0 {
+		return nil
+	}
+	weight := dcsaWeight(value, unit)
+	return &weight
+}
+
+// dcsaOptionalVolume converts a volume to cubic metres
+func dcsaOptionalVolume(value float64, unit string) *dcsa.Measure {
+	if value <= 0 {
+		return nil
+	}
+	return &dcsa.Measure{Value: volumeToCbm(value, unit), Unit: dcsa.UnitCubicMetre}
+}
+
+func weightFromDCSA(measure dcsa.Measure) float64 {
+	if measure.Unit == dcsa.UnitPound {
+		return weightToKg(measure.Value, "LBS")
+	}
+	return measure.Value
+}
+
+func volumeFromDCSA(measure dcsa.Measure) float64 {
+	if measure.Unit == dcsa.UnitCubicFoot {
+		return volumeToCbm(measure.Value, "CFT")
+	}
+	return measure.Value
+}
+
+// dcsaPackaging sends a package type that already is a two character code as
+// packageCode and any other text as description
+func dcsaPackaging(count int, packageType string) dcsa.OuterPackaging {
+	packaging := dcsa.OuterPackaging{NumberOfPackages: count}
+	packageType = strings.TrimSpace(packageType)
+	if packageCodePattern.MatchString(packageType) {
+		packaging.PackageCode = packageType
+	} else {
+		packaging.Description = packageType
+	}
+	return packaging
+}
+
+func packageTypeFromDCSA(packaging dcsa.OuterPackaging) string {
+	if packaging.Description != "" {
+		return packaging.Description
+	}
+	return packaging.PackageCode
+}
+
+func isSeaWaybillType(billType string) bool {
+	upper := strings.ToUpper(billType)
+	return strings.Contains(upper, "WAYBILL") || upper == dcsa.TypeSeaWaybill
+}
+
+// splitLines splits a multi-line text into its non-empty lines
+func splitLines(text string) []string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// splitList splits a comma, semicolon or slash separated list such as
+// several container or seal numbers
+func splitList(text string) []string {
+	var items []string
+	for _, item := range strings.FieldsFunc(text, func(r rune) bool { return r == ',' || r == ';' || r == '/' }) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func nonEmpty(value string) []string {
+	if value = strings.TrimSpace(value); value == "" {
+		return nil
+	}
+	return []string{value}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func dcsaOceanFreight(amount float64, currency, paymentTerm string) dcsa.Charge {
+	return dcsa.Charge{
+		ChargeName:       "Ocean Freight",
+		CurrencyAmount:   amount,
+		CurrencyCode:     currency,
+		PaymentTermCode:  paymentTerm,
+		CalculationBasis: dcsa.CalculationBasisPerBill,
+		UnitPrice:        amount,
+		Quantity:         1,
+	}
+}
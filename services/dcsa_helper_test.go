@@ -0,0 +1,403 @@
+package services
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"fs-backend/models/dcsa"
+	"fs-backend/models/hbl_schema"
+	"fs-backend/models/mbl_schema"
+)
+
+// sampleMBL is an MBL whose every field survives an export to DCSA and back
+func sampleMBL() *mbl_schema.MBLDocument {
+	return &mbl_schema.MBLDocument{
+		Mode: "FCL",
+		MBL: mbl_schema.MBLData{
+			BillType:            "MBL",
+			BillOfLadingNo:      "MAEU123456789",
+			PackingListNo:       "PL-7781",
+			NumberOfOriginalBLs: 3,
+			TermsOfSale:         "FOB",
+			FreightPaymentType:  "PREPAID",
+			Carrier:             mbl_schema.Carrier{Name: "Maersk", SCACCode: "MAEU", ReferenceNo: "BKG-4411"},
+			Shipper:             mbl_schema.Party{Name: "Acme Exports", Address: "12 Harbour Road, Mumbai", Phone: "+91 22 1234", Fax: "+91 22 5678"},
+			Consignee:           mbl_schema.ConsigneeParty{Name: "Globex Imports", Address: "1 Dock Street, Rotterdam", Phone: "+31 10 1234", Email: "ops@globex.example"},
+			NotifyParty:         mbl_schema.NotifyParty{Name: "Globex Logistics", Address: "2 Dock Street, Rotterdam"},
+			Routing:             mbl_schema.Routing{PlaceOfReceipt: "Pune", PortOfLoading: "INNSA", PortOfDischarge: "NLRTM", PlaceOfDelivery: "Venlo"},
+			VesselDetails:       mbl_schema.VesselDetails{VesselName: "MAERSK ESSEN", VoyageNo: "412W"},
+			ShipmentDates:       mbl_schema.ShipmentDates{DateOfIssue: "2026-03-01", PlaceOfIssue: "Mumbai", ShippedOnBoardDate: "2026-03-02"},
+			Cargo: mbl_schema.Cargo{
+				ContainerNo:        "MSKU1234565, MSKU7654321",
+				ContainerType:      "22G1",
+				SealNumber:         "SL001, SL002",
+				MarksAndNumbers:    "ACME\nROTTERDAM",
+				NumberOfPackages:   240,
+				PackageType:        "CARTONS",
+				DescriptionOfGoods: "COTTON T-SHIRTS\nHS 6109.10",
+				HSCode:             "610910",
+				GrossWeight:        mbl_schema.WeightMeasurement{Value: 12000, Unit: "KGS"},
+				NetWeight:          mbl_schema.WeightMeasurement{Value: 11000, Unit: "KGS"},
+				Measurement:        mbl_schema.WeightMeasurement{Value: 54.5, Unit: "CBM"},
+			},
+			FreightCharges: mbl_schema.FreightCharges{
+				OceanFreight: mbl_schema.OceanFreight{PrepaidAmount: 1800, Currency: "USD"},
+			},
+		},
+	}
+}
+
+// viaJSON sends a transport document through its wire format, as the import endpoint receives it
+func viaJSON(t *testing.T, td dcsa.TransportDocument) dcsa.TransportDocument {
+	t.Helper()
+	body, err := json.Marshal(td)
+	if err != nil {
+		t.Fatalf("marshal transport document: %v", err)
+	}
+	var decoded dcsa.TransportDocument
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		t.Fatalf("unmarshal transport document: %v", err)
+	}
+	return decoded
+}
+
+func TestMBLDCSARoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(doc *mbl_schema.MBLDocument)
+	}{
+		{
+			name:   "bill of lading",
+			modify: func(doc *mbl_schema.MBLDocument) {},
+		},
+		{
+			name: "sea waybill with collect freight",
+			modify: func(doc *mbl_schema.MBLDocument) {
+				doc.MBL.BillType = hbl_schema.HBLBillTypeSeaWaybill
+				doc.MBL.NumberOfOriginalBLs = 0
+				doc.MBL.FreightPaymentType = "COLLECT"
+				doc.MBL.FreightCharges.OceanFreight = mbl_schema.OceanFreight{CollectAmount: 2150.5, Currency: "EUR"}
+			},
+		},
+		{
+			name: "LCL with more seals than containers",
+			modify: func(doc *mbl_schema.MBLDocument) {
+				doc.Mode = "LCL"
+				doc.MBL.Cargo.SealNumber = "SL001, SL002, SL003"
+			},
+		},
+		{
+			name: "single container without optional fields",
+			modify: func(doc *mbl_schema.MBLDocument) {
+				doc.MBL.PackingListNo = ""
+				doc.MBL.Routing.PlaceOfReceipt = ""
+				doc.MBL.Routing.PlaceOfDelivery = ""
+				doc.MBL.NotifyParty = mbl_schema.NotifyParty{}
+				doc.MBL.ShipmentDates.ShippedOnBoardDate = ""
+				doc.MBL.Cargo.ContainerNo = "MSKU1234565"
+				doc.MBL.Cargo.SealNumber = "SL001"
+				doc.MBL.FreightCharges = mbl_schema.FreightCharges{}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := sampleMBL()
+			tt.modify(want)
+
+			td := viaJSON(t, mblToDCSA(want))
+			if errs := validateDCSATransportDocument(td); len(errs) > 0 {
+				t.Fatalf("exported document is invalid: %+v", errs)
+			}
+			got := dcsaToMBL(td)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("round trip changed the MBL\n got: %+v\nwant: %+v", got.MBL, want.MBL)
+			}
+		})
+	}
+}
+
+func TestHBLDCSARoundTrip(t *testing.T) {
+	hbl := func() *hbl_schema.HBLDocument {
+		return &hbl_schema.HBLDocument{
+			HBLNumber: "FSHBL000123",
+			Status:    hbl_schema.HBLStatusIssued,
+			HBL: hbl_schema.HBLData{
+				BillType:         "HBL",
+				CarrierReference: "MAEU123456789",
+				ExportReference:  "EXP-88",
+				Shipper:          hbl_schema.HBLParty{Name: "Acme Exports", Address: "12 Harbour Road, Mumbai"},
+				Consignee:        hbl_schema.HBLParty{Name: "Globex Imports", Address: "1 Dock Street, Rotterdam"},
+				NotifyParty:      hbl_schema.HBLParty{Name: "Globex Logistics", Address: "2 Dock Street, Rotterdam"},
+				ForwardingAgent:  hbl_schema.HBLParty{Name: "FreightShip", Address: "5 Cargo Lane, Mumbai"},
+				MovementType:     "LCL/LCL",
+				Routing:          hbl_schema.HBLRouting{PlaceOfReceipt: "Pune", PortOfLoading: "INNSA", PortOfDischarge: "Rotterdam", PlaceOfDelivery: "Venlo"},
+				VesselDetails: []hbl_schema.HBLVessel{
+					{VesselName: "MAERSK ESSEN", VoyageNo: "412W"},
+					{VesselName: "FEEDER ONE", VoyageNo: "7E"},
+				},
+				ShipmentDates: hbl_schema.HBLShipmentDates{PlaceAndDateOfIssue: "Mumbai, 01-03-2026", FreightPayableAt: "Mumbai"},
+				ContainerDetails: []hbl_schema.HBLContainer{
+					{
+						ContainerNo:        "MSKU 123456-5",
+						ContainerSize:      "20GP",
+						SealNo:             "SL001",
+						PackageCount:       40,
+						PackageType:        "CARTONS",
+						MarksAndNumbers:    "ACME 1-40",
+						DescriptionOfGoods: "COTTON T-SHIRTS",
+						HSCode:             "610910",
+						GrossWeight:        hbl_schema.HBLWeightMeasurement{Value: 800, Unit: "KGS"},
+						NetWeight:          hbl_schema.HBLWeightMeasurement{Value: 750, Unit: "KGS"},
+						Measurement:        hbl_schema.HBLWeightMeasurement{Value: 4.5, Unit: "CBM"},
+					},
+					{
+						ContainerNo:        "MSKU7654321",
+						ContainerSize:      "40HC",
+						SealNo:             "SL002",
+						PackageCount:       10,
+						PackageType:        "PK",
+						MarksAndNumbers:    "ACME 41-50",
+						DescriptionOfGoods: "COTTON SOCKS",
+						HSCode:             "611595",
+						GrossWeight:        hbl_schema.HBLWeightMeasurement{Value: 1.5, Unit: "MT"},
+						Measurement:        hbl_schema.HBLWeightMeasurement{Value: 2, Unit: "CBM"},
+					},
+				},
+				FreightDetails: hbl_schema.HBLFreightDetails{FreightStatus: "FREIGHT PREPAID"},
+			},
+			Release: hbl_schema.HBLRelease{Originals: []hbl_schema.HBLOriginal{{Number: 1}, {Number: 2}, {Number: 3}}},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(doc *hbl_schema.HBLDocument)
+		want   func(data *mbl_schema.MBLData)
+	}{
+		{
+			name:   "bill of lading with two containers",
+			modify: func(doc *hbl_schema.HBLDocument) {},
+			want:   func(data *mbl_schema.MBLData) {},
+		},
+		{
+			name: "sea waybill",
+			modify: func(doc *hbl_schema.HBLDocument) {
+				doc.HBL.BillType = hbl_schema.HBLBillTypeSeaWaybill
+				doc.HBL.FreightDetails.FreightStatus = "FREIGHT COLLECT"
+			},
+			want: func(data *mbl_schema.MBLData) {
+				data.BillType = hbl_schema.HBLBillTypeSeaWaybill
+				data.NumberOfOriginalBLs = 0
+				data.FreightPaymentType = "COLLECT"
+			},
+		},
+		{
+			name: "two rows in one container",
+			modify: func(doc *hbl_schema.HBLDocument) {
+				doc.HBL.ContainerDetails[1].ContainerNo = "MSKU1234565"
+				doc.HBL.ContainerDetails[1].ContainerSize = "20GP"
+				doc.HBL.ContainerDetails[1].SealNo = "SL001"
+			},
+			want: func(data *mbl_schema.MBLData) {
+				data.Cargo.ContainerNo = "MSKU1234565"
+				data.Cargo.ContainerType = "22G1"
+				data.Cargo.SealNumber = "SL001"
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := hbl()
+			tt.modify(doc)
+			want := mbl_schema.MBLData{
+				BillType:            "MBL",
+				BillOfLadingNo:      "FSHBL000123",
+				NumberOfOriginalBLs: 3,
+				FreightPaymentType:  "PREPAID",
+				Carrier:             mbl_schema.Carrier{Name: "FreightShip", ReferenceNo: "MAEU123456789"},
+				Shipper:             mbl_schema.Party{Name: "Acme Exports", Address: "12 Harbour Road, Mumbai"},
+				Consignee:           mbl_schema.ConsigneeParty{Name: "Globex Imports", Address: "1 Dock Street, Rotterdam"},
+				NotifyParty:         mbl_schema.NotifyParty{Name: "Globex Logistics", Address: "2 Dock Street, Rotterdam"},
+				Routing:             mbl_schema.Routing{PlaceOfReceipt: "Pune", PortOfLoading: "INNSA", PortOfDischarge: "Rotterdam", PlaceOfDelivery: "Venlo"},
+				VesselDetails:       mbl_schema.VesselDetails{VesselName: "MAERSK ESSEN", VoyageNo: "412W"},
+				ShipmentDates:       mbl_schema.ShipmentDates{DateOfIssue: "2026-03-01", PlaceOfIssue: "Mumbai"},
+				Cargo: mbl_schema.Cargo{
+					ContainerNo:        "MSKU1234565, MSKU7654321",
+					ContainerType:      "22G1, 45G1",
+					SealNumber:         "SL001, SL002",
+					MarksAndNumbers:    "ACME 1-40\nACME 41-50",
+					NumberOfPackages:   50,
+					PackageType:        "CARTONS",
+					DescriptionOfGoods: "COTTON T-SHIRTS\nCOTTON SOCKS",
+					HSCode:             "610910",
+					GrossWeight:        mbl_schema.WeightMeasurement{Value: 2300, Unit: "KGS"},
+					NetWeight:          mbl_schema.WeightMeasurement{Value: 750, Unit: "KGS"},
+					Measurement:        mbl_schema.WeightMeasurement{Value: 6.5, Unit: "CBM"},
+				},
+			}
+			tt.want(&want)
+
+			td := viaJSON(t, hblToDCSA(doc))
+			if errs := validateDCSATransportDocument(td); len(errs) > 0 {
+				t.Fatalf("exported document is invalid: %+v", errs)
+			}
+			got := dcsaToMBL(td)
+			if got.Mode != "LCL" {
+				t.Errorf("mode = %q, want LCL", got.Mode)
+			}
+			if !reflect.DeepEqual(got.MBL, want) {
+				t.Errorf("round trip changed the HBL\n got: %+v\nwant: %+v", got.MBL, want)
+			}
+		})
+	}
+}
+
+func TestValidateDCSATransportDocument(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(td *dcsa.TransportDocument)
+		want   []HBLFieldError
+	}{
+		{
+			name:   "valid",
+			modify: func(td *dcsa.TransportDocument) {},
+		},
+		{
+			name: "missing reference and unknown type",
+			modify: func(td *dcsa.TransportDocument) {
+				td.TransportDocumentReference = " "
+				td.TransportDocumentTypeCode = "HBL"
+			},
+			want: []HBLFieldError{
+				{Field: "transportDocumentReference", Message: "is required"},
+				{Field: "transportDocumentTypeCode", Message: "must be BOL or SWB"},
+			},
+		},
+		{
+			name: "sea waybill with originals to order",
+			modify: func(td *dcsa.TransportDocument) {
+				td.TransportDocumentTypeCode = dcsa.TypeSeaWaybill
+				td.IsToOrder = true
+				td.DocumentParties.Consignee = nil
+			},
+			want: []HBLFieldError{
+				{Field: "numberOfOriginals", Message: "sea waybills have no originals"},
+				{Field: "isToOrder", Message: "a sea waybill cannot be to order"},
+			},
+		},
+		{
+			name: "dates not in YYYY-MM-DD format",
+			modify: func(td *dcsa.TransportDocument) {
+				td.IssueDate = "01/03/2026"
+				td.ShippedOnBoardDate = ""
+			},
+			want: []HBLFieldError{
+				{Field: "issueDate", Message: "must be a date in YYYY-MM-DD format"},
+				{Field: "shippedOnBoardDate", Message: "is required on a shipped on board document"},
+			},
+		},
+		{
+			name: "invalid SCAC",
+			modify: func(td *dcsa.TransportDocument) {
+				td.CarrierCode = "MAE1"
+			},
+			want: []HBLFieldError{
+				{Field: "carrierCode", Message: "must be a SCAC code of 2 to 4 letters"},
+			},
+		},
+		{
+			name: "carrier code without provider",
+			modify: func(td *dcsa.TransportDocument) {
+				td.CarrierCodeListProvider = ""
+			},
+			want: []HBLFieldError{
+				{Field: "carrierCodeListProvider", Message: "is required with carrierCode"},
+			},
+		},
+		{
+			name: "missing consignee and shipper",
+			modify: func(td *dcsa.TransportDocument) {
+				td.DocumentParties.Shipper.PartyName = ""
+				td.DocumentParties.Consignee = nil
+			},
+			want: []HBLFieldError{
+				{Field: "documentParties.shipper.partyName", Message: "is required"},
+				{Field: "documentParties.consignee.partyName", Message: "is required unless the document is to order"},
+			},
+		},
+		{
+			name: "bad locations and no vessel",
+			modify: func(td *dcsa.TransportDocument) {
+				td.Transports.PortOfLoading = dcsa.Location{UNLocationCode: "INNS"}
+				td.Transports.PortOfDischarge = dcsa.Location{}
+				td.Transports.VesselVoyages = nil
+			},
+			want: []HBLFieldError{
+				{Field: "transports.portOfLoading.UNLocationCode", Message: "must be a 5 character UN/LOCODE"},
+				{Field: "transports.portOfDischarge", Message: "requires a UNLocationCode or a locationName"},
+				{Field: "transports.vesselVoyages", Message: "at least one vessel voyage is required"},
+			},
+		},
+		{
+			name: "duplicate equipment and unknown ISO code",
+			modify: func(td *dcsa.TransportDocument) {
+				td.UtilizedTransportEquipments[1].Equipment.EquipmentReference = "MSKU1234565"
+				td.UtilizedTransportEquipments[1].Equipment.ISOEquipmentCode = "99X9"
+			},
+			want: []HBLFieldError{
+				{Field: "utilizedTransportEquipments[1].equipment.equipmentReference", Message: "container MSKU1234565 is listed twice"},
+				{Field: "utilizedTransportEquipments[1].equipment.ISOEquipmentCode", Message: "unknown ISO equipment code 99X9"},
+			},
+		},
+		{
+			name: "cargo item on unknown container without packages or weight",
+			modify: func(td *dcsa.TransportDocument) {
+				cargo := &td.ConsignmentItems[0].CargoItems[0]
+				cargo.EquipmentReference = "TGHU0000000"
+				cargo.OuterPackaging.NumberOfPackages = 0
+				cargo.CargoGrossWeight = dcsa.Measure{Value: 0, Unit: "KGS"}
+			},
+			want: []HBLFieldError{
+				{Field: "consignmentItems[0].cargoItems[0].equipmentReference", Message: `container "TGHU0000000" is not in utilizedTransportEquipments`},
+				{Field: "consignmentItems[0].cargoItems[0].outerPackaging.numberOfPackages", Message: "must be greater than zero"},
+				{Field: "consignmentItems[0].cargoItems[0].cargoGrossWeight.value", Message: "must be greater than zero"},
+				{Field: "consignmentItems[0].cargoItems[0].cargoGrossWeight.unit", Message: "must be KGM or LBR"},
+			},
+		},
+		{
+			name: "no consignment items",
+			modify: func(td *dcsa.TransportDocument) {
+				td.ConsignmentItems = nil
+			},
+			want: []HBLFieldError{
+				{Field: "consignmentItems", Message: "at least one consignment item is required"},
+			},
+		},
+		{
+			name: "bad charge",
+			modify: func(td *dcsa.TransportDocument) {
+				td.Charges[0].PaymentTermCode = "PPD"
+				td.Charges[0].CurrencyCode = "US"
+			},
+			want: []HBLFieldError{
+				{Field: "charges[0].paymentTermCode", Message: "must be PRE or COL"},
+				{Field: "charges[0].currencyCode", Message: "must be an ISO 4217 currency code"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			td := mblToDCSA(sampleMBL())
+			tt.modify(&td)
+			got := validateDCSATransportDocument(td)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("validateDCSATransportDocument()\n got: %+v\nwant: %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"fs-backend/models"
 	"fs-backend/models/mbl_schema"
 	"fs-backend/repository"
 	"log"
@@ -28,6 +29,9 @@ type documentConvertService struct {
 	bookingRepo       repository.BookingRepository
 	shipmentRepo      repository.ShipmentRepository
 	shipperRepo       repository.ShipperRepository
+	partyMatcher      PartyMatchingService
+	hsCodeService     HSCodeService
+	carrierRepo       repository.CarrierRepository
 }
 
 // NewDocumentConvertService creates a new DocumentConvertService with all dependencies
@@ -38,6 +42,9 @@ func NewDocumentConvertService(
 	bookingRepo repository.BookingRepository,
 	shipmentRepo repository.ShipmentRepository,
 	shipperRepo repository.ShipperRepository,
+	partyMatcher PartyMatchingService,
+	hsCodeService HSCodeService,
+	carrierRepo repository.CarrierRepository,
 ) DocumentConvertService {
 	return &documentConvertService{
 		extractionBaseURL: extractionBaseURL,
@@ -46,6 +53,9 @@ func NewDocumentConvertService(
 		bookingRepo:       bookingRepo,
 		shipmentRepo:      shipmentRepo,
 		shipperRepo:       shipperRepo,
+		partyMatcher:      partyMatcher,
+		hsCodeService:     hsCodeService,
+		carrierRepo:       carrierRepo,
 	}
 }
 
@@ -53,10 +63,12 @@ func NewDocumentConvertService(
 // 1. Hash file → check MBL_Cache → if hit, skip extraction
 // 2. Extract data from document via extraction server (on cache miss)
 // 3. Save extraction result to MBL_Cache
-// 4. Map extracted data to MBL schema
+// 4. Map extracted data to MBL schema, resolving the carrier against the master
 // 5. Check if MBL number already exists → skip insert if duplicate
 // 6. Lookup linked shippers via Booking → Shipment → Shipper chain
-// 7. Return response
+// 7. Suggest shipper master records for the extracted parties
+// 8. Validate the extracted HS code against the nomenclature
+// 9. Return response
 func (s *documentConvertService) ConvertMBL(ctx context.Context, fileBytes []byte, filename string, model string) (*mbl_schema.ConvertMBLResponse, error) {
 	extractionEngine, err := normalizeExtractionEngine(model)
 	if err != nil {
@@ -124,6 +136,10 @@ func (s *documentConvertService) ConvertMBL(ctx context.Context, fileBytes []byt
 
 	// Step 3: Map the flat extracted data to the structured MBL document
 	mblDoc := mapExtractionToMBLDocument(extractedData)
+	var warnings []string
+	if warning := applyCarrierMaster(ctx, s.carrierRepo, &mblDoc.MBL.Carrier); warning != "" {
+		warnings = append(warnings, warning)
+	}
 	mblNumber := mblDoc.MBL.BillOfLadingNo
 	log.Printf("MBL number extracted: %s", mblNumber)
 
@@ -146,10 +162,30 @@ func (s *documentConvertService) ConvertMBL(ctx context.Context, fileBytes []byt
 		shipmentsList = []mbl_schema.ShipmentListItem{}
 	}
 
-	// Step 6: Build and return response
+	// Step 6: Match extracted parties against the shipper master
+	partyMatches, err := s.partyMatcher.MatchParties(ctx, mblDoc.MBL)
+	if err != nil {
+		log.Printf("Warning: party matching failed for MBL %s: %v", mblNumber, err)
+		partyMatches = nil
+	}
+
+	// Step 7: Validate the extracted HS code
+	var hsCodeCheck *models.HSCodeValidation
+	if cargo := mblDoc.MBL.Cargo; strings.TrimSpace(cargo.HSCode) != "" {
+		check := s.hsCodeService.Validate(ctx, cargo.HSCode, cargo.DescriptionOfGoods)
+		if !check.Valid {
+			log.Printf("Warning: MBL %s carries invalid HS code %s: %v", mblNumber, cargo.HSCode, check.Errors)
+		}
+		hsCodeCheck = &check
+	}
+
+	// Step 8: Build and return response
 	return &mbl_schema.ConvertMBLResponse{
 		MBLNumber:       mblNumber,
 		ShipmentsList: shipmentsList,
+		PartyMatches:  partyMatches,
+		HSCodeCheck:   hsCodeCheck,
+		Warnings:      warnings,
 	}, nil
 }
 
@@ -0,0 +1,233 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"fs-backend/models"
+	"fs-backend/models/dcsa"
+	"fs-backend/models/mbl_schema"
+	"fs-backend/modules/edifact"
+	"fs-backend/repository"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var ErrMBLAlreadyExists = errors.New("MBL already exists")
+
+// DocumentExchangeService exchanges bills with partners in the DCSA
+// Transport Document format and ingests carrier EDIFACT messages
+type DocumentExchangeService interface {
+	ExportHBL(ctx context.Context, hblNumber string) (*dcsa.TransportDocument, error)
+	ExportMBL(ctx context.Context, mblNumber string) (*dcsa.TransportDocument, error)
+	ImportMBL(ctx context.Context, td dcsa.TransportDocument) (*mbl_schema.ConvertMBLResponse, error)
+	ImportEDIFACT(ctx context.Context, data []byte, forwarderID, mode string) (*models.EDIFACTImportResponse, error)
+}
+
+type documentExchangeService struct {
+	mblRepo       repository.MBLRepository
+	hblRepo       repository.HBLRepository
+	bookingRepo   repository.BookingRepository
+	shipmentRepo  repository.ShipmentRepository
+	partyMatcher  PartyMatchingService
+	hsCodeService HSCodeService
+	carrierRepo   repository.CarrierRepository
+	modeRuleRepo  repository.BookingModeRuleRepository
+	txRunner      repository.TxRunner
+}
+
+// NewDocumentExchangeService creates a new DocumentExchangeService
+func NewDocumentExchangeService(
+	mblRepo repository.MBLRepository,
+	hblRepo repository.HBLRepository,
+	bookingRepo repository.BookingRepository,
+	shipmentRepo repository.ShipmentRepository,
+	partyMatcher PartyMatchingService,
+	hsCodeService HSCodeService,
+	carrierRepo repository.CarrierRepository,
+	modeRuleRepo repository.BookingModeRuleRepository,
+	txRunner repository.TxRunner,
+) DocumentExchangeService {
+	return &documentExchangeService{
+		mblRepo:       mblRepo,
+		hblRepo:       hblRepo,
+		bookingRepo:   bookingRepo,
+		shipmentRepo:  shipmentRepo,
+		partyMatcher:  partyMatcher,
+		hsCodeService: hsCodeService,
+		carrierRepo:   carrierRepo,
+		modeRuleRepo:  modeRuleRepo,
+		txRunner:      txRunner,
+	}
+}
+
+func (s *documentExchangeService) ExportHBL(ctx context.Context, hblNumber string) (*dcsa.TransportDocument, error) {
+	doc, err := s.hblRepo.FindByHBLNumber(ctx, hblNumber)
+	if err != nil {
+		return nil, err
+	}
+	td := hblToDCSA(doc)
+	return &td, nil
+}
+
+func (s *documentExchangeService) ExportMBL(ctx context.Context, mblNumber string) (*dcsa.TransportDocument, error) {
+	doc, err := s.mblRepo.FindByMBLNumber(ctx, mblNumber)
+	if err != nil {
+		return nil, err
+	}
+	td := mblToDCSA(doc)
+	return &td, nil
+}
+
+// ImportMBL stores an MBL received as a DCSA transport document, skipping
+// AI extraction, and answers like POST /api/v1/convert/mbl so the HBL
+// preview flow can continue from it
+func (s *documentExchangeService) ImportMBL(ctx context.Context, td dcsa.TransportDocument) (*mbl_schema.ConvertMBLResponse, error) {
+	if fieldErrors := validateDCSATransportDocument(td); len(fieldErrors) > 0 {
+		return nil, &DCSAValidationError{Errors: fieldErrors}
+	}
+
+	mblDoc := dcsaToMBL(td)
+	var warnings []string
+	if warning := applyCarrierMaster(ctx, s.carrierRepo, &mblDoc.MBL.Carrier); warning != "" {
+		warnings = append(warnings, warning)
+	}
+	mblNumber := mblDoc.MBL.BillOfLadingNo
+
+	_, err := s.mblRepo.FindByMBLNumber(ctx, mblNumber)
+	if err == nil {
+		return nil, fmt.Errorf("%w: %s", ErrMBLAlreadyExists, mblNumber)
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, err
+	}
+	if err := s.mblRepo.InsertMBL(ctx, mblDoc); err != nil {
+		return nil, err
+	}
+	log.Printf("MBL %s imported from DCSA transport document", mblNumber)
+
+	shipmentsList, err := lookupShipmentsByMBLNumber(ctx, mblNumber, s.bookingRepo, s.shipmentRepo)
+	if err != nil {
+		log.Printf("Warning: shipment lookup failed for MBL %s: %v", mblNumber, err)
+		shipmentsList = []mbl_schema.ShipmentListItem{}
+	}
+
+	partyMatches, err := s.partyMatcher.MatchParties(ctx, mblDoc.MBL)
+	if err != nil {
+		log.Printf("Warning: party matching failed for MBL %s: %v", mblNumber, err)
+		partyMatches = nil
+	}
+
+	var hsCodeCheck *models.HSCodeValidation
+	if cargo := mblDoc.MBL.Cargo; strings.TrimSpace(cargo.HSCode) != "" {
+		check := s.hsCodeService.Validate(ctx, cargo.HSCode, cargo.DescriptionOfGoods)
+		hsCodeCheck = &check
+	}
+
+	return &mbl_schema.ConvertMBLResponse{
+		MBLNumber:     mblNumber,
+		ShipmentsList: shipmentsList,
+		PartyMatches:  partyMatches,
+		HSCodeCheck:   hsCodeCheck,
+		Warnings:      warnings,
+	}, nil
+}
+
+// ImportEDIFACT stores the MBLs and bookings of an IFTMCS/IFTMIN interchange
+// in one transaction: nothing is stored unless every message maps cleanly and
+// every write succeeds. Carrier messages do
+// not say whether we consolidate, so new bookings belong to the given
+// forwarder and get the given mode (FCL by default), which must be one of the
+// forwarder's booking modes; existing MBLs are kept and existing bookings get
+// the carrier's latest schedule.
+func (s *documentExchangeService) ImportEDIFACT(ctx context.Context, data []byte, forwarderID, mode string) (*models.EDIFACTImportResponse, error) {
+	forwarderID = strings.TrimSpace(forwarderID)
+	rules, err := loadBookingModeRules(ctx, s.modeRuleRepo, forwarderID)
+	if err != nil {
+		return nil, err
+	}
+	rule, err := findBookingModeRule(rules, firstNonEmpty(mode, models.BookingModeFCL))
+	if err != nil {
+		return nil, err
+	}
+	mode = rule.Mode
+
+	interchange, err := edifact.Parse(data)
+	var segmentErrs edifact.Errors
+	if errors.As(err, &segmentErrs) {
+		return nil, &EDIFACTError{Errors: segmentErrs}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	shipments := make([]*edifactShipment, 0, len(interchange.Messages))
+	for _, message := range interchange.Messages {
+		shipment, errs := mapEDIFACTMessage(message, mode)
+		segmentErrs = append(segmentErrs, errs...)
+		shipments = append(shipments, shipment)
+	}
+	if len(segmentErrs) > 0 {
+		return nil, &EDIFACTError{Errors: segmentErrs}
+	}
+
+	// SCAC codes were validated while mapping, so no warning can come back
+	for _, shipment := range shipments {
+		applyCarrierMaster(ctx, s.carrierRepo, &shipment.mbl.MBL.Carrier)
+		shipment.booking.CarrierName = shipment.mbl.MBL.Carrier.Name
+		shipment.booking.ForwarderID = forwarderID
+	}
+
+	var results []models.EDIFACTMessageResult
+	err = s.txRunner.RunInTransaction(ctx, func(ctx context.Context) error {
+		results = make([]models.EDIFACTMessageResult, 0, len(shipments))
+		for i, shipment := range shipments {
+			message := interchange.Messages[i]
+			result := models.EDIFACTMessageResult{
+				MessageType:      message.Type,
+				MessageReference: message.Reference,
+				MBLNumber:        shipment.mbl.MBL.BillOfLadingNo,
+			}
+
+			_, err := s.mblRepo.FindByMBLNumber(ctx, result.MBLNumber)
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				if err := s.mblRepo.InsertMBL(ctx, shipment.mbl); err != nil {
+					return err
+				}
+				result.MBLCreated = true
+			} else if err != nil {
+				return err
+			}
+
+			_, err = s.bookingRepo.FindByMBLNumber(ctx, result.MBLNumber)
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				if err := s.bookingRepo.CreateBooking(ctx, &shipment.booking); err != nil {
+					return err
+				}
+				result.BookingCreated = true
+			} else if err != nil {
+				return err
+			} else {
+				booking := shipment.booking
+				if err := s.bookingRepo.UpdateSchedule(ctx, result.MBLNumber, booking.CarrierName, booking.EstimatedDeparture, booking.EstimatedArrival); err != nil {
+					return err
+				}
+				result.BookingUpdated = true
+			}
+			results = append(results, result)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, result := range results {
+		log.Printf("%s %s from %s imported for MBL %s (mbl created: %t, booking created: %t)",
+			result.MessageType, result.MessageReference, interchange.Sender, result.MBLNumber, result.MBLCreated, result.BookingCreated)
+	}
+	return &models.EDIFACTImportResponse{Sender: interchange.Sender, Messages: results}, nil
+}
@@ -2,24 +2,44 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"fs-backend/models"
 	"fs-backend/models/hbl_schema"
+	"fs-backend/models/mbl_schema"
 	"fs-backend/repository"
 	"log"
+
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 // DocumentPreviewService defines the interface for document preview operations
 type DocumentPreviewService interface {
 	PreviewHBL(ctx context.Context, req hbl_schema.PreviewHBLRequest) (*hbl_schema.PreviewHBLResponse, error)
-	UpdateHBL(ctx context.Context, hblNumber string, data hbl_schema.HBLData) error
+	CreateHBLs(ctx context.Context, req hbl_schema.PreviewHBLRequest, idempotencyKey string) (*hbl_schema.CreateHBLResponse, error)
+	UpdateHBL(ctx context.Context, hblNumber string, data hbl_schema.HBLData, change hbl_schema.HBLChangeInfo, revision int64) ([]models.HSCodeValidation, error)
+	PatchHBL(ctx context.Context, hblNumber, contentType string, patch []byte, change hbl_schema.HBLChangeInfo, revision int64) (*hbl_schema.HBLDocument, []models.HSCodeValidation, error)
+	CreateStandaloneHBL(ctx context.Context, req hbl_schema.StandaloneHBLRequest) (*hbl_schema.HBLDocument, error)
+	LinkMBL(ctx context.Context, hblNumber string, req hbl_schema.LinkMBLRequest, change hbl_schema.HBLChangeInfo, revision int64) (*hbl_schema.LinkMBLResponse, error)
 }
 
+var (
+	ErrMBLNotFound      = errors.New("MBL not found")
+	ErrHBLAlreadyLinked = errors.New("HBL is already linked to an MBL")
+	ErrHBLNotStandalone = errors.New("only HBLs created without an MBL can be linked to one")
+)
+
 type documentPreviewService struct {
-	mblRepo      repository.MBLRepository
-	hblRepo      repository.HBLRepository
-	shipmentRepo repository.ShipmentRepository
-	shipperRepo  repository.ShipperRepository
-	mblCacheRepo repository.MBLCacheRepository
+	mblRepo          repository.MBLRepository
+	hblRepo          repository.HBLRepository
+	shipmentRepo     repository.ShipmentRepository
+	shipperRepo      repository.ShipperRepository
+	mblCacheRepo     repository.MBLCacheRepository
+	hsCodeService    HSCodeService
+	numberingService HBLNumberingService
+	idempotencyRepo  repository.IdempotencyRepository
+	versionService   HBLVersionService
+	stuffingPlanRepo repository.StuffingPlanRepository
 }
 
 // NewDocumentPreviewService creates a new DocumentPreviewService with all dependencies
@@ -29,124 +49,433 @@ func NewDocumentPreviewService(
 	shipmentRepo repository.ShipmentRepository,
 	shipperRepo repository.ShipperRepository,
 	mblCacheRepo repository.MBLCacheRepository,
+	hsCodeService HSCodeService,
+	numberingService HBLNumberingService,
+	idempotencyRepo repository.IdempotencyRepository,
+	versionService HBLVersionService,
+	stuffingPlanRepo repository.StuffingPlanRepository,
 ) DocumentPreviewService {
 	return &documentPreviewService{
-		mblRepo:      mblRepo,
-		hblRepo:      hblRepo,
-		shipmentRepo: shipmentRepo,
-		shipperRepo:  shipperRepo,
-		mblCacheRepo: mblCacheRepo,
+		mblRepo:          mblRepo,
+		hblRepo:          hblRepo,
+		shipmentRepo:     shipmentRepo,
+		shipperRepo:      shipperRepo,
+		mblCacheRepo:     mblCacheRepo,
+		hsCodeService:    hsCodeService,
+		numberingService: numberingService,
+		idempotencyRepo:  idempotencyRepo,
+		versionService:   versionService,
+		stuffingPlanRepo: stuffingPlanRepo,
 	}
 }
 
-// PreviewHBL generates multiple HBLs from an MBL and a list of shipment IDs.
-// Flow:
-// 1. Fetch MBL from DB
-// 2. Fetch shipments by shipment IDs
-// 3. Extract shipper IDs from shipments and fetch shipper details
-// 4. For each shipment: map MBL + shipment + shipper → HBL, generate HBL number, store in DB
-// 5. Return all generated HBLs
-func (s *documentPreviewService) PreviewHBL(ctx context.Context, req hbl_schema.PreviewHBLRequest) (*hbl_schema.PreviewHBLResponse, error) {
-	// Step 1: Fetch MBL from DB
+// hblSources holds everything needed to map shipments of an MBL into HBLs
+type hblSources struct {
+	mblDoc          *mbl_schema.MBLDocument
+	stuffingPlan    *models.StuffingPlan // nil when the MBL has no plan
+	validationScore float64
+	accuracyScore   float64
+	shipmentByID    map[string]repository.ShipmentDocument
+	shipperByID     map[string]repository.ShipperDocument
+}
+
+// loadHBLSources fetches the MBL, its extraction scores and stuffing plan, the
+// requested shipments and their shippers.
+func (s *documentPreviewService) loadHBLSources(ctx context.Context, req hbl_schema.PreviewHBLRequest) (*hblSources, error) {
+	// Fetch MBL from DB
 	mblDoc, err := s.mblRepo.FindByMBLNumber(ctx, req.MBLNumber)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, fmt.Errorf("%w for number %s", ErrMBLNotFound, req.MBLNumber)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("MBL not found for number %s: %w", req.MBLNumber, err)
+		return nil, fmt.Errorf("failed to fetch MBL %s: %w", req.MBLNumber, err)
 	}
 	log.Printf("Fetched MBL: %s", req.MBLNumber)
 
+	sources := &hblSources{
+		mblDoc:       mblDoc,
+		shipmentByID: make(map[string]repository.ShipmentDocument),
+		shipperByID:  make(map[string]repository.ShipperDocument),
+	}
+
 	// Fetch MBL Cache to get raw extracted data for accurate scores
 	mblCacheDoc, err := s.mblCacheRepo.FindByMBLNumber(ctx, req.MBLNumber)
-	var validationScore, accuracyScore float64
 	if err == nil && mblCacheDoc != nil {
-		validationScore, accuracyScore = CalculateScores(mblCacheDoc.ExtractedData)
+		sources.validationScore, sources.accuracyScore = CalculateScores(mblCacheDoc.ExtractedData)
 	} else {
 		log.Printf("Warning: MBL_Cache not found for %s, scores will be 0", req.MBLNumber)
 	}
 
-	// Step 2: Fetch shipments for the given shipment IDs
+	sources.stuffingPlan, err = s.stuffingPlanRepo.FindByMBLNumber(ctx, req.MBLNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch stuffing plan: %w", err)
+	}
+
+	// Fetch shipments for the given shipment IDs
 	shipments, err := s.shipmentRepo.FindByShipmentIDs(ctx, req.ShipmentList)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch shipments: %w", err)
 	}
 
 	// Build shipment_id → shipment map, and collect unique shipper IDs
-	shipmentByID := make(map[string]repository.ShipmentDocument)
 	shipperIDMap := make(map[string]bool)
 	var shipperIDs []string
-
 	for _, shipment := range shipments {
-		shipmentByID[shipment.ShipmentID] = shipment
+		sources.shipmentByID[shipment.ShipmentID] = shipment
 		if !shipperIDMap[shipment.ShipperID] {
 			shipperIDMap[shipment.ShipperID] = true
 			shipperIDs = append(shipperIDs, shipment.ShipperID)
 		}
 	}
 
-	// Step 3: Fetch shipper details
+	// Fetch shipper details and build shipper_id → shipper map
 	shippers, err := s.shipperRepo.FindByShipperIDs(ctx, shipperIDs)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch shippers: %w", err)
 	}
-
-	// Build shipper_id → shipper map
-	shipperByID := make(map[string]repository.ShipperDocument)
 	for _, shipper := range shippers {
-		shipperByID[shipper.ShipperID] = shipper
+		sources.shipperByID[shipper.ShipperID] = shipper
+	}
+
+	return sources, nil
+}
+
+// lookup returns the shipment and shipper for a shipment ID, logging why it
+// has to be skipped when either is missing.
+func (src *hblSources) lookup(shipmentID string) (repository.ShipmentDocument, repository.ShipperDocument, bool) {
+	shipment, shipmentFound := src.shipmentByID[shipmentID]
+	if !shipmentFound {
+		log.Printf("Warning: no shipment found for shipment_id %s, skipping", shipmentID)
+		return shipment, repository.ShipperDocument{}, false
 	}
 
-	// Step 4: Generate HBLs — one per shipment
-	var hblList []hbl_schema.HBLData
-	
-	totalCount, err := s.hblRepo.CountTotal(ctx)
+	shipper, shipperFound := src.shipperByID[shipment.ShipperID]
+	if !shipperFound {
+		log.Printf("Warning: no shipper details found for shipper_id %s (shipment %s), skipping", shipment.ShipperID, shipmentID)
+		return shipment, shipper, false
+	}
+	return shipment, shipper, true
+}
+
+// mapHBL maps the MBL, shipment and shipper into an HBL. When the stuffing plan
+// allocates the shipment, its containers replace the single MBL container.
+func (src *hblSources) mapHBL(shipment repository.ShipmentDocument, shipper repository.ShipperDocument, hblNumber string) hbl_schema.HBLData {
+	hbl := mapMBLToHBL(src.mblDoc.MBL, shipment, shipper, hblNumber, src.mblDoc.Mode, src.validationScore, src.accuracyScore)
+	if containers := containerDetailsFromPlan(src.stuffingPlan, shipment); len(containers) > 0 {
+		hbl.ContainerDetails = containers
+		hbl.ShipmentSummary.TotalContainersReceived = len(containers)
+	}
+	return hbl
+}
+
+// PreviewHBL computes the HBLs an MBL and a list of shipment IDs would produce
+// without storing anything. Shipments that already have an HBL return it as is;
+// the others are drafts whose HBL numbers are only indicative.
+// Flow:
+// 1. Fetch MBL, scores, shipments and shippers from DB
+// 2. For each shipment: return the existing HBL, or map MBL + shipment + shipper → draft HBL
+// 3. Return all HBLs
+func (s *documentPreviewService) PreviewHBL(ctx context.Context, req hbl_schema.PreviewHBLRequest) (*hbl_schema.PreviewHBLResponse, error) {
+	// Step 1: Fetch sources
+	sources, err := s.loadHBLSources(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	draftNumbers, err := s.numberingService.NextNumbers(ctx, req.ForwarderID, req.MBLNumber, len(req.ShipmentList))
 	if err != nil {
-		totalCount = 0
+		return nil, fmt.Errorf("failed to compute HBL numbers: %w", err)
 	}
-	hblIndex := int(totalCount) + 1
+
+	// Step 2: One HBL per shipment
+	hblList := []hbl_schema.HBLData{}
+	items := []hbl_schema.HBLPreviewItem{}
+	nextDraft := 0
 
 	for _, shipmentID := range req.ShipmentList {
-		shipment, shipmentFound := shipmentByID[shipmentID]
-		if !shipmentFound {
-			log.Printf("Warning: no shipment found for shipment_id %s, skipping", shipmentID)
-			continue
+		existing, err := s.hblRepo.FindByMBLAndShipment(ctx, req.MBLNumber, shipmentID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up HBL for shipment %s: %w", shipmentID, err)
 		}
-
-		shipper, shipperFound := shipperByID[shipment.ShipperID]
-		if !shipperFound {
-			log.Printf("Warning: no shipper details found for shipper_id %s (shipment %s), skipping", shipment.ShipperID, shipmentID)
+		if existing != nil {
+			hblList = append(hblList, existing.HBL)
+			items = append(items, hbl_schema.HBLPreviewItem{ShipmentID: shipmentID, HBLNumber: existing.HBLNumber, State: hbl_schema.HBLPreviewExisting, Revision: existing.Revision})
 			continue
 		}
 
-		// Generate HBL number based on global HBL collection count
-		hblNumber := generateHBLNumber(req.MBLNumber, hblIndex)
-
-		// Map MBL + shipment + shipper → HBL
-		hblData := mapMBLToHBL(mblDoc.MBL, shipment, shipper, hblNumber, mblDoc.Mode, validationScore, accuracyScore)
-
-		// Store HBL in DB
-		hblDoc := &hbl_schema.HBLDocument{
-			ShipmentID: shipment.ShipmentID,
-			HBLNumber:  hblNumber,
-			HBL:        hblData,
-		}
-		if err := s.hblRepo.InsertHBL(ctx, hblDoc); err != nil {
-			log.Printf("Warning: failed to store HBL %s: %v", hblNumber, err)
-		} else {
-			log.Printf("HBL stored in DB: %s (shipment: %s, shipper: %s)", hblNumber, shipmentID, shipment.ShipperID)
+		shipment, shipper, ok := sources.lookup(shipmentID)
+		if !ok {
+			continue
 		}
 
-		hblList = append(hblList, hblData)
-		hblIndex++
+		hblNumber := draftNumbers[nextDraft]
+		nextDraft++
+		hblList = append(hblList, sources.mapHBL(shipment, shipper, hblNumber))
+		items = append(items, hbl_schema.HBLPreviewItem{ShipmentID: shipmentID, HBLNumber: hblNumber, State: hbl_schema.HBLPreviewDraft})
 	}
 
-	// Step 5: Return response
+	// Step 3: Return response
 	return &hbl_schema.PreviewHBLResponse{
 		MBLNumber:  req.MBLNumber,
 		TotalCount: len(hblList),
 		HBLList:    hblList,
+		Items:      items,
 	}, nil
 }
 
-// UpdateHBL updates an existing HBL document
-func (s *documentPreviewService) UpdateHBL(ctx context.Context, hblNumber string, data hbl_schema.HBLData) error {
-	return s.hblRepo.UpdateHBL(ctx, hblNumber, data)
+// CreateHBLs stores one HBL per (MBL, shipment) pair. It is idempotent: pairs
+// that already have an HBL return it instead of creating another one. When an
+// idempotency key is given, a repeated request replays the first response.
+func (s *documentPreviewService) CreateHBLs(ctx context.Context, req hbl_schema.PreviewHBLRequest, idempotencyKey string) (*hbl_schema.CreateHBLResponse, error) {
+	if idempotencyKey == "" {
+		return s.createHBLs(ctx, req)
+	}
+
+	var resp hbl_schema.CreateHBLResponse
+	err := withIdempotency(ctx, s.idempotencyRepo, "hbl.create", idempotencyKey, req, &resp, func() (interface{}, error) {
+		return s.createHBLs(ctx, req)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (s *documentPreviewService) createHBLs(ctx context.Context, req hbl_schema.PreviewHBLRequest) (*hbl_schema.CreateHBLResponse, error) {
+	sources, err := s.loadHBLSources(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &hbl_schema.CreateHBLResponse{
+		MBLNumber: req.MBLNumber,
+		HBLList:   []hbl_schema.HBLData{},
+		Items:     []hbl_schema.HBLPreviewItem{},
+	}
+	addItem := func(doc *hbl_schema.HBLDocument, state string) {
+		resp.HBLList = append(resp.HBLList, doc.HBL)
+		resp.Items = append(resp.Items, hbl_schema.HBLPreviewItem{ShipmentID: doc.ShipmentID, HBLNumber: doc.HBLNumber, State: state, Revision: doc.Revision})
+	}
+
+	for _, shipmentID := range req.ShipmentList {
+		existing, err := s.hblRepo.FindByMBLAndShipment(ctx, req.MBLNumber, shipmentID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up HBL for shipment %s: %w", shipmentID, err)
+		}
+		if existing != nil {
+			addItem(existing, hbl_schema.HBLPreviewExisting)
+			continue
+		}
+
+		shipment, shipper, ok := sources.lookup(shipmentID)
+		if !ok {
+			continue
+		}
+
+		// Allocate an HBL number from the forwarder's sequence, map MBL + shipment + shipper → HBL and store it
+		hblDoc, err := s.numberingService.InsertWithNewNumber(ctx, req.ForwarderID, req.MBLNumber, func(hblNumber string) *hbl_schema.HBLDocument {
+			return &hbl_schema.HBLDocument{
+				ShipmentID: shipment.ShipmentID,
+				MBLNumber:  req.MBLNumber,
+				HBLNumber:  hblNumber,
+				HBL:        sources.mapHBL(shipment, shipper, hblNumber),
+			}
+		})
+		if errors.Is(err, ErrHBLAlreadyExists) {
+			// A concurrent request created it first
+			existing, err = s.hblRepo.FindByMBLAndShipment(ctx, req.MBLNumber, shipmentID)
+			if err != nil || existing == nil {
+				return nil, fmt.Errorf("failed to load concurrently created HBL for shipment %s: %w", shipmentID, err)
+			}
+			addItem(existing, hbl_schema.HBLPreviewExisting)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to store HBL for shipment %s: %w", shipmentID, err)
+		}
+		log.Printf("HBL stored in DB: %s (shipment: %s, shipper: %s)", hblDoc.HBLNumber, shipmentID, shipment.ShipperID)
+
+		addItem(hblDoc, hbl_schema.HBLPreviewCreated)
+		resp.CreatedCount++
+	}
+
+	resp.TotalCount = len(resp.HBLList)
+	return resp, nil
+}
+
+// UpdateHBL validates the HS codes on the HBL and updates the stored document.
+// The validation results are returned so warnings can be shown to the user.
+// Every update is recorded as a new version; issued HBLs are locked and return
+// ErrHBLLocked. The update only applies if the HBL is still at revision,
+// otherwise a *RevisionConflictError with the current document is returned.
+// Server-computed scores keep their stored values.
+func (s *documentPreviewService) UpdateHBL(ctx context.Context, hblNumber string, data hbl_schema.HBLData, change hbl_schema.HBLChangeInfo, revision int64) ([]models.HSCodeValidation, error) {
+	doc, err := s.loadEditableHBL(ctx, hblNumber, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	keepReadOnlyFields(&data, doc.HBL, doc.HBLNumber)
+	return s.saveHBL(ctx, doc, data, change, revision)
+}
+
+// PatchHBL applies a merge patch or JSON Patch (by content type) to the HBL,
+// validates the result and stores it like UpdateHBL
+func (s *documentPreviewService) PatchHBL(ctx context.Context, hblNumber, contentType string, patch []byte, change hbl_schema.HBLChangeInfo, revision int64) (*hbl_schema.HBLDocument, []models.HSCodeValidation, error) {
+	doc, err := s.loadEditableHBL(ctx, hblNumber, revision)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	data, err := patchHBLData(doc.HBL, doc.HBLNumber, contentType, patch)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := validateHBLData(data); err != nil {
+		return nil, nil, err
+	}
+
+	hsCodeChecks, err := s.saveHBL(ctx, doc, data, change, revision)
+	if err != nil {
+		return nil, hsCodeChecks, err
+	}
+	doc.HBL = data
+	doc.Revision++
+	return doc, hsCodeChecks, nil
+}
+
+// loadEditableHBL fetches an HBL that is at revision and not yet issued
+func (s *documentPreviewService) loadEditableHBL(ctx context.Context, hblNumber string, revision int64) (*hbl_schema.HBLDocument, error) {
+	doc, err := s.hblRepo.FindByHBLNumber(ctx, hblNumber)
+	if err != nil {
+		return nil, err
+	}
+	if doc.Revision != revision {
+		return nil, &RevisionConflictError{Revision: doc.Revision, Current: doc}
+	}
+	if !isHBLEditable(doc.Status) {
+		return nil, ErrHBLLocked
+	}
+	return doc, nil
+}
+
+// saveHBL validates HS codes and writes the data together with its new version
+func (s *documentPreviewService) saveHBL(ctx context.Context, doc *hbl_schema.HBLDocument, data hbl_schema.HBLData, change hbl_schema.HBLChangeInfo, revision int64) ([]models.HSCodeValidation, error) {
+	hsCodeChecks, err := s.hsCodeService.ValidateHBL(ctx, data)
+	if err != nil {
+		return hsCodeChecks, err
+	}
+
+	_, err = s.versionService.Record(ctx, doc, data, change, func(ctx context.Context, _ *hbl_schema.HBLVersion) error {
+		return s.hblRepo.UpdateHBL(ctx, doc.HBLNumber, data, revision)
+	})
+	if errors.Is(err, repository.ErrRevisionMismatch) {
+		if current, findErr := s.hblRepo.FindByHBLNumber(ctx, doc.HBLNumber); findErr == nil {
+			return hsCodeChecks, &RevisionConflictError{Revision: current.Revision, Current: current}
+		}
+	}
+	return hsCodeChecks, err
+}
+
+// CreateStandaloneHBL stores an HBL for a shipment that has no MBL yet, built
+// from the shipment, its shipper and the manually entered routing and vessel
+// data. A shipment has at most one standalone HBL at a time; ErrHBLAlreadyExists
+// is returned for a second one.
+func (s *documentPreviewService) CreateStandaloneHBL(ctx context.Context, req hbl_schema.StandaloneHBLRequest) (*hbl_schema.HBLDocument, error) {
+	if err := validateStandaloneHBLRequest(req); err != nil {
+		return nil, err
+	}
+
+	shipment, err := s.shipmentRepo.FindByShipmentID(ctx, req.ShipmentID)
+	if err != nil {
+		return nil, err
+	}
+	shippers, err := s.shipperRepo.FindByShipperIDs(ctx, []string{shipment.ShipperID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch shipper: %w", err)
+	}
+	if len(shippers) == 0 {
+		return nil, fmt.Errorf("no shipper details found for shipper_id %s: %w", shipment.ShipperID, mongo.ErrNoDocuments)
+	}
+
+	existing, err := s.hblRepo.FindByMBLAndShipment(ctx, "", req.ShipmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up HBL for shipment %s: %w", req.ShipmentID, err)
+	}
+	if existing != nil {
+		return nil, ErrHBLAlreadyExists
+	}
+
+	// Without an MBL the number is drawn from the forwarder-wide sequence
+	hblDoc, err := s.numberingService.InsertWithNewNumber(ctx, req.ForwarderID, "", func(hblNumber string) *hbl_schema.HBLDocument {
+		return &hbl_schema.HBLDocument{
+			ShipmentID: shipment.ShipmentID,
+			HBLNumber:  hblNumber,
+			Standalone: true,
+			HBL:        mapStandaloneHBL(req, *shipment, shippers[0], hblNumber),
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Standalone HBL stored in DB: %s (shipment: %s, shipper: %s)", hblDoc.HBLNumber, shipment.ShipmentID, shipment.ShipperID)
+	return hblDoc, nil
+}
+
+// LinkMBL attaches a standalone HBL to the MBL that has since arrived. The
+// MBL-derived fields are merged into the HBL (see mergeMBLFields) and the
+// differences are reported. Like an update it needs the HBL at revision and not
+// yet issued, and it is recorded as a new version.
+func (s *documentPreviewService) LinkMBL(ctx context.Context, hblNumber string, req hbl_schema.LinkMBLRequest, change hbl_schema.HBLChangeInfo, revision int64) (*hbl_schema.LinkMBLResponse, error) {
+	doc, err := s.loadEditableHBL(ctx, hblNumber, revision)
+	if err != nil {
+		return nil, err
+	}
+	if doc.MBLNumber != "" {
+		return nil, ErrHBLAlreadyLinked
+	}
+	if !doc.Standalone {
+		return nil, ErrHBLNotStandalone
+	}
+
+	sources, err := s.loadHBLSources(ctx, hbl_schema.PreviewHBLRequest{MBLNumber: req.MBLNumber, ShipmentList: []string{doc.ShipmentID}})
+	if err != nil {
+		return nil, err
+	}
+	existing, err := s.hblRepo.FindByMBLAndShipment(ctx, req.MBLNumber, doc.ShipmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up HBL for shipment %s: %w", doc.ShipmentID, err)
+	}
+	if existing != nil {
+		return nil, ErrHBLAlreadyExists
+	}
+
+	// Shipper and cargo are not merged, so a shipment deleted in the meantime does not matter
+	shipment, shipper, _ := sources.lookup(doc.ShipmentID)
+	data, merged, conflicts := mergeMBLFields(doc.HBL, sources.mapHBL(shipment, shipper, doc.HBLNumber), req.PreferMBL)
+	data.ValidationScore = sources.validationScore
+	data.AccuracyScore = sources.accuracyScore
+
+	if change.Reason == "" {
+		change.Reason = "Linked to MBL " + req.MBLNumber
+	}
+	_, err = s.versionService.Record(ctx, doc, data, change, func(ctx context.Context, _ *hbl_schema.HBLVersion) error {
+		return s.hblRepo.LinkMBL(ctx, hblNumber, req.MBLNumber, data, revision)
+	})
+	if repository.IsDuplicateKeyOnIndex(err, repository.HBLMBLShipmentIndex) {
+		return nil, ErrHBLAlreadyExists
+	}
+	if errors.Is(err, repository.ErrRevisionMismatch) {
+		if current, findErr := s.hblRepo.FindByHBLNumber(ctx, hblNumber); findErr == nil {
+			return nil, &RevisionConflictError{Revision: current.Revision, Current: current}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	doc.MBLNumber = req.MBLNumber
+	doc.HBL = data
+	doc.Revision++
+	return &hbl_schema.LinkMBLResponse{HBL: doc, Merged: merged, Conflicts: conflicts}, nil
 }
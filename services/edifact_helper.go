@@ -0,0 +1,365 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"fs-backend/models"
+	"fs-backend/models/mbl_schema"
+	"fs-backend/modules/edifact"
+	"fs-backend/repository"
+)
+
+// Supported EDIFACT message types
+const (
+	edifactBookingConfirmation = "IFTMCS"
+	edifactShippingInstruction = "IFTMIN"
+)
+
+// edifactDateFormats maps DTM format qualifiers (2379) to Go layouts
+var edifactDateFormats = map[string]string{
+	"102": "20060102",
+	"203": "200601021504",
+	"204": "20060102150405",
+}
+
+// EDIFACTError lists the segments of an interchange that could not be read or mapped
+type EDIFACTError struct {
+	Errors edifact.Errors
+}
+
+func (e *EDIFACTError) Error() string {
+	return "invalid EDIFACT interchange: " + e.Errors.Error()
+}
+
+// edifactShipment is what one IFTMCS/IFTMIN message maps to
+type edifactShipment struct {
+	mbl     *mbl_schema.MBLDocument
+	booking repository.BookingDocument
+}
+
+// edifactEquipment is an EQD segment with the seals that follow it
+type edifactEquipment struct {
+	containerNo string
+	sizeType    string
+	seals       []string
+}
+
+// mapEDIFACTMessage maps the NAD, LOC, TDT, EQD, GID and MEA segments (plus
+// BGM, RFF, DTM, FTX, PIA, PCI and SEL) of a booking confirmation or shipping
+// instruction. The bill is keyed by RFF+BM, or by the booking number (RFF+BN)
+// when the carrier has not assigned a bill number yet. GID quantities and
+// measures are summed into the single MBL cargo line.
+func mapEDIFACTMessage(message edifact.Message, mode string) (*edifactShipment, edifact.Errors) {
+	var errs edifact.Errors
+	fail := func(segment edifact.Segment, element int, format string, args ...interface{}) {
+		errs = append(errs, edifact.SegmentError{Position: segment.Position, Tag: segment.Tag, Element: element, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if message.Type != edifactBookingConfirmation && message.Type != edifactShippingInstruction {
+		return nil, edifact.Errors{{Position: message.Position, Tag: "UNH", Element: 2,
+			Message: fmt.Sprintf("unsupported message type %q, expected %s or %s", message.Type, edifactBookingConfirmation, edifactShippingInstruction)}}
+	}
+
+	data := mbl_schema.MBLData{
+		BillType: "MBL",
+		Cargo: mbl_schema.Cargo{
+			GrossWeight: mbl_schema.WeightMeasurement{Unit: "KGS"},
+			NetWeight:   mbl_schema.WeightMeasurement{Unit: "KGS"},
+			Measurement: mbl_schema.WeightMeasurement{Unit: "CBM"},
+		},
+	}
+	booking := repository.BookingDocument{
+		Mode:          mode,
+		ShipmentIDs:   []string{},
+		Status:        models.BookingStatusBooked,
+		StatusHistory: initialBookingStatus(models.BookingStatusSourceCarrier),
+	}
+
+	var (
+		billNumber, bookingNumber string
+		hasBGM                    bool
+		equipment                 []edifactEquipment
+		descriptions, marks       []string
+		inGoods                   bool // between a GID and the equipment groups
+	)
+	for _, segment := range message.Segments {
+		switch segment.Tag {
+		case "BGM":
+			hasBGM = true
+			if segment.Component(2, 0) == "1" {
+				fail(segment, 3, "cancellations are not supported")
+			}
+
+		case "RFF":
+			switch segment.Component(0, 0) {
+			case "BM":
+				billNumber = strings.TrimSpace(segment.Component(0, 1))
+			case "BN":
+				bookingNumber = strings.TrimSpace(segment.Component(0, 1))
+			}
+
+		case "DTM":
+			qualifier := segment.Component(0, 0)
+			if qualifier != "132" && qualifier != "133" && qualifier != "137" {
+				continue
+			}
+			if qualifier == "137" {
+				date, err := edifactDate(segment.Component(0, 1), segment.Component(0, 2))
+				if err != nil {
+					fail(segment, 1, "%v", err)
+					continue
+				}
+				data.ShipmentDates.DateOfIssue = date
+				continue
+			}
+			date, err := edifactDateTime(segment.Component(0, 1), segment.Component(0, 2))
+			if err != nil {
+				fail(segment, 1, "%v", err)
+				continue
+			}
+			date = date.UTC()
+			if qualifier == "132" {
+				booking.EstimatedArrival = &date
+			} else {
+				booking.EstimatedDeparture = &date
+			}
+			if err := validateSchedule(booking.EstimatedDeparture, booking.EstimatedArrival); err != nil {
+				fail(segment, 1, "%v", err)
+			}
+
+		case "TDT":
+			if segment.Component(0, 0) != "20" || data.VesselDetails.VesselName != "" {
+				continue // only the first main carriage leg
+			}
+			data.VesselDetails.VoyageNo = segment.Component(1, 0)
+			data.VesselDetails.VesselName = firstNonEmpty(segment.Component(7, 3), segment.Component(7, 0))
+			if data.Carrier.Name == "" {
+				data.Carrier.Name = segment.Component(4, 3)
+			}
+			if data.VesselDetails.VesselName == "" {
+				fail(segment, 8, "vessel is missing")
+			}
+
+		case "NAD":
+			name, address := edifactParty(segment)
+			switch segment.Component(0, 0) {
+			case "CZ":
+				data.Shipper = mbl_schema.Party{Name: name, Address: address}
+			case "CN":
+				data.Consignee = mbl_schema.ConsigneeParty{Name: name, Address: address}
+			case "N1":
+				data.NotifyParty = mbl_schema.NotifyParty{Name: name, Address: address}
+			case "CA":
+				if name != "" {
+					data.Carrier.Name = name
+				}
+				if segment.Component(1, 2) == "182" { // agency: SCAC
+					data.Carrier.SCACCode = normalizeSCACCode(segment.Component(1, 0))
+					if err := validateSCACCode(data.Carrier.SCACCode); err != nil {
+						fail(segment, 1, "%v", err)
+					}
+				}
+			default:
+				continue
+			}
+			if name == "" && segment.Component(0, 0) != "CA" {
+				fail(segment, 4, "party name is missing")
+			}
+
+		case "LOC":
+			location := firstNonEmpty(segment.Component(1, 3), segment.Component(1, 0))
+			switch segment.Component(0, 0) {
+			case "88":
+				data.Routing.PlaceOfReceipt = location
+			case "9":
+				data.Routing.PortOfLoading = location
+			case "11":
+				data.Routing.PortOfDischarge = location
+			case "7":
+				data.Routing.PlaceOfDelivery = location
+			case "91":
+				data.ShipmentDates.PlaceOfIssue = location
+			default:
+				continue
+			}
+			if location == "" {
+				fail(segment, 2, "location is missing")
+			}
+
+		case "EQD":
+			inGoods = false
+			if segment.Component(0, 0) != "CN" {
+				continue
+			}
+			containerNo := normalizeContainerNo(segment.Component(1, 0))
+			if containerNo == "" {
+				fail(segment, 2, "container number is missing")
+			}
+			equipment = append(equipment, edifactEquipment{containerNo: containerNo, sizeType: segment.Component(2, 0)})
+
+		case "SEL":
+			if len(equipment) == 0 {
+				fail(segment, 0, "seal is not preceded by an EQD segment")
+				continue
+			}
+			last := &equipment[len(equipment)-1]
+			last.seals = append(last.seals, segment.Component(0, 0))
+
+		case "GID":
+			inGoods = true
+			if count := segment.Component(1, 0); count != "" {
+				packages, err := strconv.Atoi(count)
+				if err != nil || packages < 0 {
+					fail(segment, 2, "number of packages %q is not a number", count)
+					continue
+				}
+				data.Cargo.NumberOfPackages += packages
+			}
+			if data.Cargo.PackageType == "" {
+				data.Cargo.PackageType = firstNonEmpty(segment.Component(1, 4), segment.Component(1, 1))
+			}
+
+		case "FTX":
+			if segment.Component(0, 0) == "AAA" {
+				descriptions = append(descriptions, strings.Join(nonEmptyStrings(segment.Element(3)), " "))
+			}
+
+		case "PIA":
+			if data.Cargo.HSCode == "" && segment.Component(1, 1) == "HS" {
+				data.Cargo.HSCode = segment.Component(1, 0)
+			}
+
+		case "PCI":
+			marks = append(marks, nonEmptyStrings(segment.Element(1))...)
+
+		case "MEA":
+			if !inGoods {
+				continue // equipment level weights would count the cargo twice
+			}
+			value, err := strconv.ParseFloat(strings.Replace(segment.Component(2, 1), ",", ".", 1), 64)
+			if err != nil {
+				fail(segment, 3, "measure %q is not a number", segment.Component(2, 1))
+				continue
+			}
+			unit := segment.Component(2, 0)
+			switch segment.Component(1, 0) {
+			case "G", "WT", "AAB":
+				data.Cargo.GrossWeight.Value += edifactWeight(value, unit)
+			case "N", "AAL":
+				data.Cargo.NetWeight.Value += edifactWeight(value, unit)
+			case "AAW", "ABJ":
+				data.Cargo.Measurement.Value += edifactVolume(value, unit)
+			}
+		}
+	}
+
+	if !hasBGM {
+		errs = append(errs, edifact.SegmentError{Position: message.Position, Tag: "UNH", Message: "message has no BGM segment"})
+	}
+	mblNumber := firstNonEmpty(billNumber, bookingNumber)
+	if mblNumber == "" {
+		errs = append(errs, edifact.SegmentError{Position: message.Position, Tag: "UNH",
+			Message: "message carries neither a bill of lading (RFF+BM) nor a booking (RFF+BN) reference"})
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	var containers, sizeTypes, seals []string
+	for _, eqd := range equipment {
+		containers = append(containers, eqd.containerNo)
+		if eqd.sizeType != "" && !containsString(sizeTypes, eqd.sizeType) {
+			sizeTypes = append(sizeTypes, eqd.sizeType)
+		}
+		seals = append(seals, eqd.seals...)
+	}
+	data.BillOfLadingNo = mblNumber
+	data.Carrier.ReferenceNo = bookingNumber
+	data.Cargo.ContainerNo = strings.Join(containers, ", ")
+	data.Cargo.ContainerType = strings.Join(sizeTypes, ", ")
+	data.Cargo.SealNumber = strings.Join(seals, ", ")
+	data.Cargo.DescriptionOfGoods = strings.Join(descriptions, "\n")
+	data.Cargo.MarksAndNumbers = strings.Join(marks, "\n")
+
+	booking.MBLNumber = mblNumber
+	booking.CarrierName = data.Carrier.Name
+	return &edifactShipment{
+		mbl:     &mbl_schema.MBLDocument{Mode: mode, MBL: data},
+		booking: booking,
+	}, nil
+}
+
+// edifactParty reads the name and a single line address of a NAD segment.
+// Without a structured party name (C080) the first name and address line is the name.
+func edifactParty(segment edifact.Segment) (string, string) {
+	lines := nonEmptyStrings(segment.Element(2))
+	name := strings.Join(nonEmptyStrings(segment.Element(3)), " ")
+	if name == "" && len(lines) > 0 {
+		name, lines = lines[0], lines[1:]
+	}
+
+	address := append(lines, nonEmptyStrings(segment.Element(4))...)
+	for _, element := range []int{5, 6, 7, 8} { // city, region, postcode, country
+		if value := strings.TrimSpace(segment.Component(element, 0)); value != "" {
+			address = append(address, value)
+		}
+	}
+	return strings.TrimSpace(name), strings.Join(address, ", ")
+}
+
+// edifactDate converts a DTM date to YYYY-MM-DD
+func edifactDate(value, format string) (string, error) {
+	if format == "" {
+		format = "102"
+	}
+	layout, ok := edifactDateFormats[format]
+	if !ok {
+		return "", fmt.Errorf("unsupported date format %s", format)
+	}
+	t, err := time.Parse(layout, value)
+	if err != nil {
+		return "", fmt.Errorf("date %q does not match format %s", value, format)
+	}
+	return t.Format("2006-01-02"), nil
+}
+
+// edifactWeight converts a weight to kilograms
+func edifactWeight(value float64, unit string) float64 {
+	switch unit {
+	case "LBR":
+		return weightToKg(value, "LBS")
+	case "TNE":
+		return weightToKg(value, "TNE")
+	}
+	return value
+}
+
+// edifactVolume converts a volume to cubic metres
+func edifactVolume(value float64, unit string) float64 {
+	if unit == "FTQ" {
+		return volumeToCbm(value, "CFT")
+	}
+	return value
+}
+
+func nonEmptyStrings(values []string) []string {
+	var result []string
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,52 @@
+package services
+
+import "fs-backend/models/hbl_schema"
+
+// hblTransitions lists the statuses each HBL status may move to through a
+// plain transition. Surrendered and released are left out: they are only set
+// by the release service, once the originals are surrendered and the telex
+// release is recorded. Void HBLs are final.
+var hblTransitions = map[string][]string{
+	hbl_schema.HBLStatusDraft:    {hbl_schema.HBLStatusApproved, hbl_schema.HBLStatusVoid},
+	hbl_schema.HBLStatusApproved: {hbl_schema.HBLStatusDraft, hbl_schema.HBLStatusIssued, hbl_schema.HBLStatusVoid},
+	hbl_schema.HBLStatusIssued:   {hbl_schema.HBLStatusVoid},
+}
+
+// hblReleaseStatuses can only be reached through the release endpoints
+var hblReleaseStatuses = []string{hbl_schema.HBLStatusSurrendered, hbl_schema.HBLStatusReleased}
+
+// normalizeHBLStatus treats the empty status of legacy HBLs as draft
+func normalizeHBLStatus(status string) string {
+	if status == "" {
+		return hbl_schema.HBLStatusDraft
+	}
+	return status
+}
+
+func isKnownHBLStatus(status string) bool {
+	switch status {
+	case hbl_schema.HBLStatusDraft, hbl_schema.HBLStatusApproved, hbl_schema.HBLStatusIssued,
+		hbl_schema.HBLStatusReleased, hbl_schema.HBLStatusSurrendered, hbl_schema.HBLStatusVoid:
+		return true
+	}
+	return false
+}
+
+func canTransitionHBL(from, to string) bool {
+	for _, allowed := range hblTransitions[normalizeHBLStatus(from)] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// isHBLEditable reports whether the HBL data may still be changed freely; once
+// issued only amendments are allowed
+func isHBLEditable(status string) bool {
+	switch normalizeHBLStatus(status) {
+	case hbl_schema.HBLStatusDraft, hbl_schema.HBLStatusApproved:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"fs-backend/models"
+	"fs-backend/models/hbl_schema"
+	"fs-backend/repository"
+)
+
+var (
+	ErrInvalidHBLStatus     = errors.New("invalid HBL status")
+	ErrIllegalHBLTransition = errors.New("illegal HBL status transition")
+	ErrHBLLocked            = errors.New("HBL has been issued and can only be changed through an amendment")
+	ErrHBLNotIssued         = errors.New("only issued HBLs can be amended")
+	ErrHBLStatusConflict    = errors.New("HBL status was changed by another request")
+)
+
+// HBLLifecycleService moves HBLs through draft → approved → issued →
+// released/surrendered (or void) and records amendments to issued HBLs
+type HBLLifecycleService interface {
+	GetStatus(ctx context.Context, hblNumber string) (*hbl_schema.HBLStatusResponse, error)
+	Transition(ctx context.Context, hblNumber string, req hbl_schema.HBLTransitionRequest) (*hbl_schema.HBLStatusResponse, error)
+	Amend(ctx context.Context, hblNumber string, req hbl_schema.HBLAmendmentRequest, revision int64) (*hbl_schema.HBLStatusResponse, []models.HSCodeValidation, error)
+}
+
+type hblLifecycleService struct {
+	hblRepo        repository.HBLRepository
+	hsCodeService  HSCodeService
+	versionService HBLVersionService
+}
+
+// NewHBLLifecycleService creates a new HBLLifecycleService
+func NewHBLLifecycleService(hblRepo repository.HBLRepository, hsCodeService HSCodeService, versionService HBLVersionService) HBLLifecycleService {
+	return &hblLifecycleService{
+		hblRepo:        hblRepo,
+		hsCodeService:  hsCodeService,
+		versionService: versionService,
+	}
+}
+
+func (s *hblLifecycleService) GetStatus(ctx context.Context, hblNumber string) (*hbl_schema.HBLStatusResponse, error) {
+	doc, err := s.hblRepo.FindByHBLNumber(ctx, hblNumber)
+	if err != nil {
+		return nil, err
+	}
+	return hblStatusResponse(doc), nil
+}
+
+// Transition applies a status change. The update only succeeds if the HBL is
+// still in the status it was read in, so concurrent transitions cannot both win.
+func (s *hblLifecycleService) Transition(ctx context.Context, hblNumber string, req hbl_schema.HBLTransitionRequest) (*hbl_schema.HBLStatusResponse, error) {
+	to := strings.ToLower(strings.TrimSpace(req.Status))
+	if !isKnownHBLStatus(to) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidHBLStatus, req.Status)
+	}
+
+	doc, err := s.hblRepo.FindByHBLNumber(ctx, hblNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	from := normalizeHBLStatus(doc.Status)
+	if containsString(hblReleaseStatuses, to) {
+		return nil, fmt.Errorf("%w: %s is set by surrendering the originals or the telex release", ErrIllegalHBLTransition, to)
+	}
+	if !canTransitionHBL(from, to) {
+		return nil, fmt.Errorf("%w: %s → %s", ErrIllegalHBLTransition, from, to)
+	}
+	if to == hbl_schema.HBLStatusVoid && strings.TrimSpace(req.Reason) == "" {
+		return nil, fmt.Errorf("%w: a reason is required to void an HBL", ErrIllegalHBLTransition)
+	}
+
+	change := hbl_schema.HBLStatusChange{
+		From:   from,
+		To:     to,
+		Actor:  req.Actor,
+		Reason: req.Reason,
+		At:     time.Now(),
+	}
+	ok, err := s.hblRepo.TransitionStatus(ctx, hblNumber, from, change)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrHBLStatusConflict
+	}
+	log.Printf("HBL %s: %s → %s by %s", hblNumber, from, to, req.Actor)
+
+	doc.Status = to
+	doc.StatusHistory = append(doc.StatusHistory, change)
+	doc.Revision++
+	return hblStatusResponse(doc), nil
+}
+
+// Amend replaces the data of an issued HBL and appends an amendment record
+func (s *hblLifecycleService) Amend(ctx context.Context, hblNumber string, req hbl_schema.HBLAmendmentRequest, revision int64) (*hbl_schema.HBLStatusResponse, []models.HSCodeValidation, error) {
+	doc, err := s.hblRepo.FindByHBLNumber(ctx, hblNumber)
+	if err != nil {
+		return nil, nil, err
+	}
+	if doc.Revision != revision {
+		return nil, nil, &RevisionConflictError{Revision: doc.Revision, Current: doc}
+	}
+	if normalizeHBLStatus(doc.Status) != hbl_schema.HBLStatusIssued {
+		return nil, nil, ErrHBLNotIssued
+	}
+
+	keepReadOnlyFields(&req.HBL, doc.HBL, doc.HBLNumber)
+	hsCodeChecks, err := s.hsCodeService.ValidateHBL(ctx, req.HBL)
+	if err != nil {
+		return nil, hsCodeChecks, err
+	}
+
+	var amendment hbl_schema.HBLAmendment
+	_, err = s.versionService.Record(ctx, doc, req.HBL, hbl_schema.HBLChangeInfo{Author: req.Actor, Reason: req.Reason}, func(ctx context.Context, version *hbl_schema.HBLVersion) error {
+		amendment = hbl_schema.HBLAmendment{
+			Number:  len(doc.Amendments) + 1,
+			Version: version.Version,
+			Actor:   req.Actor,
+			Reason:  req.Reason,
+			At:      time.Now(),
+		}
+		ok, err := s.hblRepo.AmendHBL(ctx, hblNumber, req.HBL, amendment, revision)
+		if err == nil && !ok {
+			return ErrHBLStatusConflict
+		}
+		return err
+	})
+	if errors.Is(err, ErrHBLStatusConflict) {
+		current, findErr := s.hblRepo.FindByHBLNumber(ctx, hblNumber)
+		if findErr == nil && current.Revision != revision {
+			return nil, hsCodeChecks, &RevisionConflictError{Revision: current.Revision, Current: current}
+		}
+	}
+	if err != nil {
+		return nil, hsCodeChecks, err
+	}
+	log.Printf("HBL %s amended (amendment %d) by %s", hblNumber, amendment.Number, req.Actor)
+
+	doc.HBL = req.HBL
+	doc.Amendments = append(doc.Amendments, amendment)
+	doc.Revision++
+	return hblStatusResponse(doc), hsCodeChecks, nil
+}
+
+func hblStatusResponse(doc *hbl_schema.HBLDocument) *hbl_schema.HBLStatusResponse {
+	resp := &hbl_schema.HBLStatusResponse{
+		HBLNumber:     doc.HBLNumber,
+		Status:        normalizeHBLStatus(doc.Status),
+		StatusHistory: doc.StatusHistory,
+		Amendments:    doc.Amendments,
+		Revision:      doc.Revision,
+	}
+	if resp.StatusHistory == nil {
+		resp.StatusHistory = []hbl_schema.HBLStatusChange{}
+	}
+	if resp.Amendments == nil {
+		resp.Amendments = []hbl_schema.HBLAmendment{}
+	}
+	return resp
+}
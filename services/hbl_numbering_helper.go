@@ -0,0 +1,95 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"fs-backend/models"
+)
+
+const checkCharacterAlphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+var hblPrefixPattern = regexp.MustCompile(`^[A-Z0-9\-]{0,10}$`)
+
+// validateHBLNumberFormat rejects formats that cannot produce unique numbers
+func validateHBLNumberFormat(format models.HBLNumberFormat) error {
+	if !hblPrefixPattern.MatchString(format.Prefix) {
+		return errors.New("prefix must be at most 10 upper-case letters, digits or dashes")
+	}
+	if format.Padding < 1 || format.Padding > 10 {
+		return errors.New("padding must be between 1 and 10")
+	}
+	switch format.Scope {
+	case models.HBLNumberScopeMBL:
+		if !format.IncludeMBLNumber {
+			return errors.New("per-MBL sequences require the MBL number in the format")
+		}
+	case models.HBLNumberScopeForwarder:
+	default:
+		return fmt.Errorf("scope must be %q or %q", models.HBLNumberScopeMBL, models.HBLNumberScopeForwarder)
+	}
+	return nil
+}
+
+// hblSequenceKey names the counter a number is drawn from. Forwarder-wide
+// sequences restart every year when the year is part of the number.
+func hblSequenceKey(format models.HBLNumberFormat, forwarderID, mblNumber string, year int) string {
+	if forwarderID == "" {
+		forwarderID = "default"
+	}
+	if format.Scope == models.HBLNumberScopeMBL {
+		return fmt.Sprintf("hbl:%s:mbl:%s", forwarderID, mblNumber)
+	}
+	if format.IncludeYear {
+		return fmt.Sprintf("hbl:%s:%d", forwarderID, year)
+	}
+	return fmt.Sprintf("hbl:%s", forwarderID)
+}
+
+// standaloneHBLNumberFormat adapts a format for HBLs created before their MBL:
+// per-MBL sequences have nothing to count on, so the forwarder-wide one is used
+func standaloneHBLNumberFormat(format models.HBLNumberFormat) models.HBLNumberFormat {
+	format.Scope = models.HBLNumberScopeForwarder
+	format.IncludeMBLNumber = false
+	return format
+}
+
+// formatHBLNumber assembles an HBL number from its format and sequence value
+func formatHBLNumber(format models.HBLNumberFormat, mblNumber string, year int, seq int64) string {
+	var b strings.Builder
+	b.WriteString(format.Prefix)
+	if format.IncludeMBLNumber {
+		b.WriteString(strings.ToUpper(strings.TrimSpace(mblNumber)))
+	}
+	if format.IncludeYear {
+		fmt.Fprintf(&b, "%04d", year)
+	}
+	fmt.Fprintf(&b, "%0*d", format.Padding, seq)
+
+	number := b.String()
+	if format.CheckCharacter {
+		number += string(computeCheckCharacter(number))
+	}
+	return number
+}
+
+// computeCheckCharacter implements ISO/IEC 7064 MOD 37,36 over the
+// alphanumeric characters of s; other characters are ignored.
+func computeCheckCharacter(s string) byte {
+	const m = 36
+	p := m
+	for _, r := range strings.ToUpper(s) {
+		value := strings.IndexRune(checkCharacterAlphabet, r)
+		if value < 0 {
+			continue
+		}
+		p = (p + value) % m
+		if p == 0 {
+			p = m
+		}
+		p = (p * 2) % (m + 1)
+	}
+	return checkCharacterAlphabet[(m+1-p)%m]
+}
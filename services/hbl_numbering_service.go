@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"fs-backend/models"
+	"fs-backend/models/hbl_schema"
+	"fs-backend/repository"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// maxHBLNumberAttempts bounds the retries when an allocated number already exists
+const maxHBLNumberAttempts = 5
+
+var (
+	ErrInvalidHBLNumberFormat = errors.New("invalid HBL number format")
+	ErrHBLNumberExhausted     = errors.New("could not allocate a free HBL number")
+	ErrHBLAlreadyExists       = errors.New("an HBL already exists for this shipment")
+)
+
+// HBLNumberingService allocates collision-free HBL numbers from atomic sequences
+type HBLNumberingService interface {
+	GetFormat(ctx context.Context, forwarderID string) (models.HBLNumberFormat, error)
+	SaveFormat(ctx context.Context, format models.HBLNumberFormat) error
+	PreviewFormat(format models.HBLNumberFormat, mblNumber string, count int) ([]string, error)
+	NextNumbers(ctx context.Context, forwarderID, mblNumber string, count int) ([]string, error)
+	InsertWithNewNumber(ctx context.Context, forwarderID, mblNumber string, build func(hblNumber string) *hbl_schema.HBLDocument) (*hbl_schema.HBLDocument, error)
+}
+
+type hblNumberingService struct {
+	formatRepo  repository.HBLNumberFormatRepository
+	counterRepo repository.CounterRepository
+	hblRepo     repository.HBLRepository
+}
+
+// NewHBLNumberingService creates a new HBLNumberingService
+func NewHBLNumberingService(
+	formatRepo repository.HBLNumberFormatRepository,
+	counterRepo repository.CounterRepository,
+	hblRepo repository.HBLRepository,
+) HBLNumberingService {
+	return &hblNumberingService{
+		formatRepo:  formatRepo,
+		counterRepo: counterRepo,
+		hblRepo:     hblRepo,
+	}
+}
+
+// GetFormat returns the forwarder's numbering format, or the default one
+func (s *hblNumberingService) GetFormat(ctx context.Context, forwarderID string) (models.HBLNumberFormat, error) {
+	format, err := s.formatRepo.FindByForwarderID(ctx, forwarderID)
+	if err != nil {
+		return models.HBLNumberFormat{}, err
+	}
+	if format == nil {
+		defaultFormat := models.DefaultHBLNumberFormat()
+		defaultFormat.ForwarderID = forwarderID
+		return defaultFormat, nil
+	}
+	return *format, nil
+}
+
+func (s *hblNumberingService) SaveFormat(ctx context.Context, format models.HBLNumberFormat) error {
+	if err := validateHBLNumberFormat(format); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidHBLNumberFormat, err)
+	}
+	return s.formatRepo.Upsert(ctx, &format)
+}
+
+// PreviewFormat renders the first count numbers a format would produce
+func (s *hblNumberingService) PreviewFormat(format models.HBLNumberFormat, mblNumber string, count int) ([]string, error) {
+	if err := validateHBLNumberFormat(format); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidHBLNumberFormat, err)
+	}
+
+	year := time.Now().Year()
+	numbers := make([]string, 0, count)
+	for i := 1; i <= count; i++ {
+		numbers = append(numbers, formatHBLNumber(format, mblNumber, year, int64(i)))
+	}
+	return numbers, nil
+}
+
+// NextNumbers predicts the next count numbers without consuming the sequence.
+// Another allocation may take them first, so they are only indicative.
+func (s *hblNumberingService) NextNumbers(ctx context.Context, forwarderID, mblNumber string, count int) ([]string, error) {
+	format, err := s.GetFormat(ctx, forwarderID)
+	if err != nil {
+		return nil, err
+	}
+
+	year := time.Now().Year()
+	current, err := s.counterRepo.Current(ctx, hblSequenceKey(format, forwarderID, mblNumber, year))
+	if err != nil {
+		return nil, err
+	}
+
+	numbers := make([]string, 0, count)
+	for i := 1; i <= count; i++ {
+		numbers = append(numbers, formatHBLNumber(format, mblNumber, year, current+int64(i)))
+	}
+	return numbers, nil
+}
+
+// InsertWithNewNumber draws a number from the sequence, builds the document and
+// inserts it. If the number is already taken (e.g. by HBLs numbered before the
+// sequence existed) the next value is drawn, up to maxHBLNumberAttempts times.
+// Standalone HBLs (no mblNumber) always use a forwarder-wide sequence.
+func (s *hblNumberingService) InsertWithNewNumber(
+	ctx context.Context,
+	forwarderID, mblNumber string,
+	build func(hblNumber string) *hbl_schema.HBLDocument,
+) (*hbl_schema.HBLDocument, error) {
+	format, err := s.GetFormat(ctx, forwarderID)
+	if err != nil {
+		return nil, err
+	}
+	if mblNumber == "" {
+		format = standaloneHBLNumberFormat(format)
+	}
+
+	year := time.Now().Year()
+	key := hblSequenceKey(format, forwarderID, mblNumber, year)
+
+	for attempt := 1; attempt <= maxHBLNumberAttempts; attempt++ {
+		seq, err := s.counterRepo.Next(ctx, key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to allocate HBL number: %w", err)
+		}
+
+		hblNumber := formatHBLNumber(format, mblNumber, year, seq)
+		doc := build(hblNumber)
+		err = s.hblRepo.InsertHBL(ctx, doc)
+		if err == nil {
+			return doc, nil
+		}
+		if repository.IsDuplicateKeyOnIndex(err, repository.HBLMBLShipmentIndex) || repository.IsDuplicateKeyOnIndex(err, repository.HBLStandaloneIndex) {
+			return nil, ErrHBLAlreadyExists
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return nil, err
+		}
+		log.Printf("HBL number %s already taken, retrying (attempt %d)", hblNumber, attempt)
+	}
+
+	return nil, ErrHBLNumberExhausted
+}
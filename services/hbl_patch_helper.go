@@ -0,0 +1,448 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"fs-backend/models/hbl_schema"
+)
+
+// Patch formats accepted by PatchHBL
+const (
+	MergePatchContentType = "application/merge-patch+json"
+	JSONPatchContentType  = "application/json-patch+json"
+)
+
+// hblReadOnlyFields are computed by the server and cannot be set by clients.
+// The bill type only changes through the sea waybill conversion. The sea
+// waybill number is the HBL number, by which PDF payloads find their HBL.
+var hblReadOnlyFields = []string{"validation_score", "accuracy_score", "bill_type", "sea_waybill_no"}
+
+var (
+	ErrInvalidPatch = errors.New("invalid patch document")
+	ErrPatchFailed  = errors.New("patch could not be applied")
+)
+
+var containerNumberPattern = regexp.MustCompile(`^[A-Z]{3}[UJZ][0-9]{7}$`)
+
+// HBLFieldError is a validation failure on one field of an HBL
+type HBLFieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// HBLValidationError lists everything wrong with an HBL
+type HBLValidationError struct {
+	Errors []HBLFieldError
+}
+
+func (e *HBLValidationError) Error() string {
+	var parts []string
+	for _, fieldErr := range e.Errors {
+		parts = append(parts, fieldErr.Field+": "+fieldErr.Message)
+	}
+	return "invalid HBL: " + strings.Join(parts, "; ")
+}
+
+// jsonPatchOperation is one operation of an RFC 6902 JSON Patch
+type jsonPatchOperation struct {
+	Op    string           `json:"op"`
+	Path  string           `json:"path"`
+	From  string           `json:"from"`
+	Value *json.RawMessage `json:"value"`
+}
+
+// patchHBLData applies an RFC 7396 merge patch or an RFC 6902 JSON Patch to
+// the HBL and decodes the result. Read-only fields keep their current values.
+func patchHBLData(current hbl_schema.HBLData, hblNumber, contentType string, patch []byte) (hbl_schema.HBLData, error) {
+	raw, err := json.Marshal(current)
+	if err != nil {
+		return current, err
+	}
+	var doc interface{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return current, err
+	}
+
+	switch contentType {
+	case JSONPatchContentType:
+		var ops []jsonPatchOperation
+		if err := json.Unmarshal(patch, &ops); err != nil {
+			return current, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+		}
+		for i, op := range ops {
+			if err := checkReadOnlyPointer(op); err != nil {
+				return current, err
+			}
+			if doc, err = applyJSONPatchOperation(doc, op); err != nil {
+				return current, fmt.Errorf("operation %d (%s %s): %w", i, op.Op, op.Path, err)
+			}
+		}
+	default:
+		var mergePatch interface{}
+		if err := json.Unmarshal(patch, &mergePatch); err != nil {
+			return current, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+		}
+		if fields, ok := mergePatch.(map[string]interface{}); ok {
+			for _, field := range hblReadOnlyFields {
+				if _, found := fields[field]; found {
+					return current, fmt.Errorf("%w: %s is read-only", ErrPatchFailed, field)
+				}
+			}
+		}
+		doc = applyMergePatch(doc, mergePatch)
+	}
+
+	merged, err := json.Marshal(doc)
+	if err != nil {
+		return current, err
+	}
+	var result hbl_schema.HBLData
+	decoder := json.NewDecoder(bytes.NewReader(merged))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&result); err != nil {
+		return current, fmt.Errorf("%w: %v", ErrPatchFailed, err)
+	}
+
+	keepReadOnlyFields(&result, current, hblNumber)
+	return result, nil
+}
+
+// keepReadOnlyFields copies the server-computed fields of current onto data,
+// sets the sea waybill number to the HBL number and drops the PDF-only markings
+func keepReadOnlyFields(data *hbl_schema.HBLData, current hbl_schema.HBLData, hblNumber string) {
+	data.ValidationScore = current.ValidationScore
+	data.AccuracyScore = current.AccuracyScore
+	data.BillType = current.BillType
+	data.SeaWaybillNo = hblNumber
+	data.Marking, data.OriginalSerial, data.NumberOfOriginals = "", "", 0
+	data.VerificationURL = ""
+}
+
+// checkReadOnlyPointer rejects operations that write a read-only field or
+// replace the whole document
+func checkReadOnlyPointer(op jsonPatchOperation) error {
+	if op.Op == "test" {
+		return nil
+	}
+	if op.Path == "" {
+		return fmt.Errorf("%w: the whole document cannot be replaced", ErrPatchFailed)
+	}
+	written := []string{op.Path}
+	if op.Op == "move" {
+		written = append(written, op.From)
+	}
+	for _, pointer := range written {
+		tokens := splitJSONPointer(pointer)
+		if len(tokens) == 0 {
+			continue
+		}
+		for _, field := range hblReadOnlyFields {
+			if tokens[0] == field {
+				return fmt.Errorf("%w: %s is read-only", ErrPatchFailed, field)
+			}
+		}
+	}
+	return nil
+}
+
+// applyMergePatch implements RFC 7396
+func applyMergePatch(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+			continue
+		}
+		targetObject[key] = applyMergePatch(targetObject[key], value)
+	}
+	return targetObject
+}
+
+// splitJSONPointer decodes an RFC 6901 pointer into reference tokens
+func splitJSONPointer(pointer string) []string {
+	if pointer == "" {
+		return nil
+	}
+	tokens := strings.Split(strings.TrimPrefix(pointer, "/"), "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens
+}
+
+func applyJSONPatchOperation(doc interface{}, op jsonPatchOperation) (interface{}, error) {
+	if !strings.HasPrefix(op.Path, "/") && op.Path != "" {
+		return nil, fmt.Errorf("%w: path must start with /", ErrInvalidPatch)
+	}
+	path := splitJSONPointer(op.Path)
+
+	value := func() (interface{}, error) {
+		if op.Value == nil {
+			return nil, fmt.Errorf("%w: value is required", ErrInvalidPatch)
+		}
+		var v interface{}
+		err := json.Unmarshal(*op.Value, &v)
+		return v, err
+	}
+
+	switch op.Op {
+	case "add":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		return jsonPointerAdd(doc, path, v)
+	case "remove":
+		doc, _, err := jsonPointerRemove(doc, path)
+		return doc, err
+	case "replace":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := jsonPointerGet(doc, path); err != nil {
+			return nil, err
+		}
+		doc, _, err = jsonPointerRemove(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		return jsonPointerAdd(doc, path, v)
+	case "move":
+		from := splitJSONPointer(op.From)
+		doc, v, err := jsonPointerRemove(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		return jsonPointerAdd(doc, path, v)
+	case "copy":
+		v, err := jsonPointerGet(doc, splitJSONPointer(op.From))
+		if err != nil {
+			return nil, err
+		}
+		return jsonPointerAdd(doc, path, deepCopyJSON(v))
+	case "test":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		actual, err := jsonPointerGet(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(actual, v) {
+			return nil, fmt.Errorf("%w: test failed", ErrPatchFailed)
+		}
+		return doc, nil
+	}
+	return nil, fmt.Errorf("%w: unknown op %q", ErrInvalidPatch, op.Op)
+}
+
+func deepCopyJSON(v interface{}) interface{} {
+	raw, _ := json.Marshal(v)
+	var c interface{}
+	_ = json.Unmarshal(raw, &c)
+	return c
+}
+
+func arrayIndex(token string, length int, allowEnd bool) (int, error) {
+	if allowEnd && token == "-" {
+		return length, nil
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || (token != "0" && strings.HasPrefix(token, "0")) {
+		return 0, fmt.Errorf("%w: invalid array index %q", ErrPatchFailed, token)
+	}
+	limit := length - 1
+	if allowEnd {
+		limit = length
+	}
+	if index > limit {
+		return 0, fmt.Errorf("%w: array index %d out of range", ErrPatchFailed, index)
+	}
+	return index, nil
+}
+
+func jsonPointerGet(doc interface{}, path []string) (interface{}, error) {
+	current := doc
+	for _, token := range path {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			child, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("%w: %q does not exist", ErrPatchFailed, token)
+			}
+			current = child
+		case []interface{}:
+			index, err := arrayIndex(token, len(node), false)
+			if err != nil {
+				return nil, err
+			}
+			current = node[index]
+		default:
+			return nil, fmt.Errorf("%w: %q does not exist", ErrPatchFailed, token)
+		}
+	}
+	return current, nil
+}
+
+// jsonPointerAdd returns doc with value added at path
+func jsonPointerAdd(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	parent, err := jsonPointerGet(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := path[len(path)-1]
+
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		node[last] = value
+		return doc, nil
+	case []interface{}:
+		index, err := arrayIndex(last, len(node), true)
+		if err != nil {
+			return nil, err
+		}
+		updated := append(node[:index:index], append([]interface{}{value}, node[index:]...)...)
+		return jsonPointerAdd(doc, path[:len(path)-1], updated)
+	}
+	return nil, fmt.Errorf("%w: cannot add to %q", ErrPatchFailed, last)
+}
+
+// jsonPointerRemove returns doc without the value at path, and that value
+func jsonPointerRemove(doc interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, nil, fmt.Errorf("%w: the whole document cannot be removed", ErrPatchFailed)
+	}
+	parent, err := jsonPointerGet(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, nil, err
+	}
+	last := path[len(path)-1]
+
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		value, ok := node[last]
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: %q does not exist", ErrPatchFailed, last)
+		}
+		delete(node, last)
+		return doc, value, nil
+	case []interface{}:
+		index, err := arrayIndex(last, len(node), false)
+		if err != nil {
+			return nil, nil, err
+		}
+		value := node[index]
+		updated := append(node[:index:index], node[index+1:]...)
+		doc, err = jsonPointerAdd(doc, path[:len(path)-1], updated)
+		return doc, value, err
+	}
+	return nil, nil, fmt.Errorf("%w: %q does not exist", ErrPatchFailed, last)
+}
+
+// validateHBLData checks the rules a stored HBL must satisfy
+func validateHBLData(data hbl_schema.HBLData) error {
+	var fieldErrors []HBLFieldError
+	add := func(field, message string) {
+		fieldErrors = append(fieldErrors, HBLFieldError{Field: field, Message: message})
+	}
+
+	if strings.TrimSpace(data.Shipper.Name) == "" {
+		add("shipper.name", "shipper is required")
+	}
+	if strings.TrimSpace(data.Consignee.Name) == "" {
+		add("consignee.name", "consignee is required")
+	}
+
+	for i, container := range data.ContainerDetails {
+		prefix := fmt.Sprintf("container_details[%d]", i)
+		if container.ContainerNo != "" {
+			if err := validateContainerNumber(container.ContainerNo); err != nil {
+				add(prefix+".container_no", err.Error())
+			}
+		}
+		if container.PackageCount < 0 {
+			add(prefix+".package_count", "must not be negative")
+		}
+		if container.GrossWeight.Value < 0 {
+			add(prefix+".gross_weight.value", "must not be negative")
+		}
+		if container.NetWeight.Value < 0 {
+			add(prefix+".net_weight.value", "must not be negative")
+		}
+		if container.Measurement.Value < 0 {
+			add(prefix+".measurement.value", "must not be negative")
+		}
+		if container.NetWeight.Value > container.GrossWeight.Value && container.GrossWeight.Value > 0 &&
+			strings.EqualFold(container.NetWeight.Unit, container.GrossWeight.Unit) {
+			add(prefix+".net_weight.value", "must not exceed the gross weight")
+		}
+	}
+	if data.ShipmentSummary.TotalContainersReceived < 0 {
+		add("shipment_summary.total_containers_received", "must not be negative")
+	}
+	if data.ShipmentSummary.PackagesReceived < 0 {
+		add("shipment_summary.packages_received", "must not be negative")
+	}
+
+	if len(fieldErrors) > 0 {
+		return &HBLValidationError{Errors: fieldErrors}
+	}
+	return nil
+}
+
+// validateContainerNumber checks the ISO 6346 format and check digit
+func validateContainerNumber(number string) error {
+	normalized := strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(number))
+	if !containerNumberPattern.MatchString(normalized) {
+		return errors.New("must be 4 letters (owner code and U, J or Z) followed by 7 digits")
+	}
+	if computeContainerCheckDigit(normalized[:10]) != int(normalized[10]-'0') {
+		return errors.New("check digit does not match (ISO 6346)")
+	}
+	return nil
+}
+
+// computeContainerCheckDigit implements the ISO 6346 check digit over the
+// owner code, category identifier and serial number
+func computeContainerCheckDigit(code string) int {
+	sum := 0
+	for i, r := range code {
+		value := int(r - '0')
+		if r >= 'A' && r <= 'Z' {
+			value = containerLetterValue(r)
+		}
+		sum += value << i
+	}
+	return sum % 11 % 10
+}
+
+// containerLetterValue maps A..Z to 10..38, skipping multiples of 11
+func containerLetterValue(letter rune) int {
+	value := 10
+	for r := 'A'; r < letter; r++ {
+		value++
+		if value%11 == 0 {
+			value++
+		}
+	}
+	return value
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"fs-backend/models/hbl_schema"
+)
+
+// maxHBLOriginals is the largest set of originals that can be issued; a full
+// set is conventionally three
+const maxHBLOriginals = 3
+
+// newOriginalSerial numbers an original of a set. The random part keeps serials
+// from being guessed from the HBL number.
+func newOriginalSerial(hblNumber string, number int) (string, error) {
+	random := make([]byte, 3)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s-O%d-%s", hblNumber, number, strings.ToUpper(hex.EncodeToString(random))), nil
+}
+
+// outstandingOriginals counts the originals that have not been surrendered
+func outstandingOriginals(release hbl_schema.HBLRelease) int {
+	count := 0
+	for _, original := range release.Originals {
+		if original.SurrenderedAt == nil {
+			count++
+		}
+	}
+	return count
+}
+
+// hblPDFMarking returns the marking printed on PDFs generated for an HBL.
+// Once originals exist every other print is a non-negotiable copy; originals
+// themselves are only printed through the originals endpoint. A sea waybill
+// is never negotiable.
+func hblPDFMarking(doc *hbl_schema.HBLDocument) string {
+	switch {
+	case doc.Release.SeaWaybill != nil || doc.HBL.BillType == hbl_schema.HBLBillTypeSeaWaybill:
+		return hbl_schema.HBLMarkingSeaWaybill
+	case doc.Release.TelexRelease != nil:
+		return hbl_schema.HBLMarkingTelexReleased
+	case len(doc.Release.Originals) > 0:
+		return hbl_schema.HBLMarkingCopy
+	}
+	return ""
+}
+
+// telexReleaseMessage renders the telex release sent to the destination agent
+func telexReleaseMessage(doc *hbl_schema.HBLDocument) string {
+	telex := doc.Release.TelexRelease
+	data := doc.HBL
+
+	var vessels []string
+	for _, vessel := range data.VesselDetails {
+		vessels = append(vessels, strings.TrimSpace(vessel.VesselName+" "+vessel.VoyageNo))
+	}
+	var containers []string
+	for _, container := range data.ContainerDetails {
+		if container.ContainerNo != "" {
+			containers = append(containers, container.ContainerNo)
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "TELEX RELEASE\n")
+	fmt.Fprintf(&b, "REF: %s\n", telex.Reference)
+	fmt.Fprintf(&b, "DATE: %s\n\n", telex.At.UTC().Format(time.RFC1123))
+	fmt.Fprintf(&b, "HBL NO: %s\n", doc.HBLNumber)
+	if doc.MBLNumber != "" {
+		fmt.Fprintf(&b, "MBL NO: %s\n", doc.MBLNumber)
+	}
+	fmt.Fprintf(&b, "SHIPPER: %s\n", data.Shipper.Name)
+	fmt.Fprintf(&b, "CONSIGNEE: %s\n", data.Consignee.Name)
+	fmt.Fprintf(&b, "VESSEL/VOYAGE: %s\n", strings.Join(vessels, ", "))
+	fmt.Fprintf(&b, "PORT OF LOADING: %s\n", data.Routing.PortOfLoading)
+	fmt.Fprintf(&b, "PORT OF DISCHARGE: %s\n", data.Routing.PortOfDischarge)
+	fmt.Fprintf(&b, "CONTAINER(S): %s\n\n", strings.Join(containers, ", "))
+	fmt.Fprintf(&b, "PLEASE NOTE THAT THE FULL SET OF %d ORIGINAL BILL(S) OF LADING HAS BEEN SURRENDERED AT ORIGIN. ", len(doc.Release.Originals))
+	fmt.Fprintf(&b, "PLEASE RELEASE THE CARGO TO THE CONSIGNEE WITHOUT PRESENTATION OF ORIGINAL BILLS OF LADING.\n")
+	if telex.Remarks != "" {
+		fmt.Fprintf(&b, "\nREMARKS: %s\n", telex.Remarks)
+	}
+	fmt.Fprintf(&b, "\nRELEASED BY: %s\n", telex.Actor)
+	return b.String()
+}
@@ -0,0 +1,337 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"fs-backend/models"
+	"fs-backend/models/hbl_schema"
+	"fs-backend/repository"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	ErrHBLReleaseState       = errors.New("HBL release state does not allow this operation")
+	ErrInvalidOriginalsCount = fmt.Errorf("the number of originals must be between 1 and %d", maxHBLOriginals)
+	ErrUnknownOriginal       = errors.New("unknown original bill serial number")
+)
+
+// HBLReleaseService manages original bills, their surrender, telex releases
+// and sea waybill conversion
+type HBLReleaseService interface {
+	GetRelease(ctx context.Context, hblNumber string) (*hbl_schema.HBLReleaseResponse, error)
+	IssueOriginals(ctx context.Context, hblNumber string, req hbl_schema.IssueOriginalsRequest, revision int64) (*hbl_schema.HBLReleaseResponse, error)
+	SurrenderOriginals(ctx context.Context, hblNumber string, req hbl_schema.SurrenderOriginalsRequest, revision int64) (*hbl_schema.HBLReleaseResponse, error)
+	TelexRelease(ctx context.Context, hblNumber string, req hbl_schema.TelexReleaseRequest, revision int64) (*hbl_schema.HBLReleaseResponse, error)
+	ConvertToSeaWaybill(ctx context.Context, hblNumber string, req hbl_schema.SeaWaybillRequest, revision int64) (*hbl_schema.HBLReleaseResponse, error)
+	TelexReleaseMessage(ctx context.Context, hblNumber string) (string, error)
+	OriginalsPdfRequest(ctx context.Context, hblNumber string) (*models.PdfGenerationRequest, error)
+	MarkPdfRequest(ctx context.Context, req *models.PdfGenerationRequest) error
+}
+
+type hblReleaseService struct {
+	hblRepo        repository.HBLRepository
+	versionService HBLVersionService
+}
+
+// NewHBLReleaseService creates a new HBLReleaseService
+func NewHBLReleaseService(hblRepo repository.HBLRepository, versionService HBLVersionService) HBLReleaseService {
+	return &hblReleaseService{
+		hblRepo:        hblRepo,
+		versionService: versionService,
+	}
+}
+
+func (s *hblReleaseService) GetRelease(ctx context.Context, hblNumber string) (*hbl_schema.HBLReleaseResponse, error) {
+	doc, err := s.hblRepo.FindByHBLNumber(ctx, hblNumber)
+	if err != nil {
+		return nil, err
+	}
+	return hblReleaseResponse(doc), nil
+}
+
+// IssueOriginals issues a set of count numbered originals for an issued HBL
+func (s *hblReleaseService) IssueOriginals(ctx context.Context, hblNumber string, req hbl_schema.IssueOriginalsRequest, revision int64) (*hbl_schema.HBLReleaseResponse, error) {
+	if req.Count < 1 || req.Count > maxHBLOriginals {
+		return nil, ErrInvalidOriginalsCount
+	}
+
+	doc, err := s.loadHBL(ctx, hblNumber, revision)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case normalizeHBLStatus(doc.Status) != hbl_schema.HBLStatusIssued:
+		return nil, fmt.Errorf("%w: originals can only be issued for issued HBLs", ErrHBLReleaseState)
+	case doc.Release.SeaWaybill != nil:
+		return nil, fmt.Errorf("%w: a sea waybill has no originals", ErrHBLReleaseState)
+	case len(doc.Release.Originals) > 0:
+		return nil, fmt.Errorf("%w: originals have already been issued", ErrHBLReleaseState)
+	}
+
+	release := doc.Release
+	now := time.Now()
+	for number := 1; number <= req.Count; number++ {
+		serial, err := newOriginalSerial(hblNumber, number)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate original serial: %w", err)
+		}
+		release.Originals = append(release.Originals, hbl_schema.HBLOriginal{
+			Number:   number,
+			Serial:   serial,
+			IssuedBy: req.Actor,
+			IssuedAt: now,
+		})
+	}
+
+	if err := s.saveRelease(ctx, doc, release, nil, revision); err != nil {
+		return nil, err
+	}
+	log.Printf("HBL %s: %d originals issued by %s", hblNumber, req.Count, req.Actor)
+	return hblReleaseResponse(doc), nil
+}
+
+// SurrenderOriginals records the surrender of originals by serial number. Once
+// the full set is surrendered the HBL moves to surrendered.
+func (s *hblReleaseService) SurrenderOriginals(ctx context.Context, hblNumber string, req hbl_schema.SurrenderOriginalsRequest, revision int64) (*hbl_schema.HBLReleaseResponse, error) {
+	doc, err := s.loadHBL(ctx, hblNumber, revision)
+	if err != nil {
+		return nil, err
+	}
+	if normalizeHBLStatus(doc.Status) != hbl_schema.HBLStatusIssued || len(doc.Release.Originals) == 0 {
+		return nil, fmt.Errorf("%w: no originals are outstanding", ErrHBLReleaseState)
+	}
+
+	release := doc.Release
+	release.Originals = append([]hbl_schema.HBLOriginal(nil), doc.Release.Originals...)
+	now := time.Now()
+	for _, serial := range req.Serials {
+		index := -1
+		for i, original := range release.Originals {
+			if strings.EqualFold(original.Serial, strings.TrimSpace(serial)) {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownOriginal, serial)
+		}
+		if release.Originals[index].SurrenderedAt != nil {
+			return nil, fmt.Errorf("%w: original %s has already been surrendered", ErrHBLReleaseState, release.Originals[index].Serial)
+		}
+		release.Originals[index].SurrenderedAt = &now
+		release.Originals[index].SurrenderedBy = req.Actor
+		release.Originals[index].SurrenderedIn = req.Place
+	}
+
+	var change *hbl_schema.HBLStatusChange
+	if outstandingOriginals(release) == 0 {
+		change = &hbl_schema.HBLStatusChange{
+			From:   hbl_schema.HBLStatusIssued,
+			To:     hbl_schema.HBLStatusSurrendered,
+			Actor:  req.Actor,
+			Reason: "Full set of originals surrendered",
+			At:     now,
+		}
+	}
+
+	if err := s.saveRelease(ctx, doc, release, change, revision); err != nil {
+		return nil, err
+	}
+	log.Printf("HBL %s: %d originals surrendered by %s", hblNumber, len(req.Serials), req.Actor)
+	return hblReleaseResponse(doc), nil
+}
+
+// TelexRelease releases the cargo at destination once the full set of
+// originals has been surrendered at origin
+func (s *hblReleaseService) TelexRelease(ctx context.Context, hblNumber string, req hbl_schema.TelexReleaseRequest, revision int64) (*hbl_schema.HBLReleaseResponse, error) {
+	doc, err := s.loadHBL(ctx, hblNumber, revision)
+	if err != nil {
+		return nil, err
+	}
+	if normalizeHBLStatus(doc.Status) != hbl_schema.HBLStatusSurrendered {
+		return nil, fmt.Errorf("%w: all originals must be surrendered before a telex release", ErrHBLReleaseState)
+	}
+
+	now := time.Now()
+	release := doc.Release
+	release.TelexRelease = &hbl_schema.HBLTelexRelease{
+		Reference: "TLX-" + hblNumber,
+		Actor:     req.Actor,
+		Remarks:   req.Remarks,
+		At:        now,
+	}
+	change := &hbl_schema.HBLStatusChange{
+		From:   hbl_schema.HBLStatusSurrendered,
+		To:     hbl_schema.HBLStatusReleased,
+		Actor:  req.Actor,
+		Reason: "Telex release " + release.TelexRelease.Reference,
+		At:     now,
+	}
+
+	if err := s.saveRelease(ctx, doc, release, change, revision); err != nil {
+		return nil, err
+	}
+	log.Printf("HBL %s telex released by %s", hblNumber, req.Actor)
+	return hblReleaseResponse(doc), nil
+}
+
+// ConvertToSeaWaybill turns a bill for which no originals were issued into a
+// sea waybill. The bill type change is recorded as a new HBL version.
+func (s *hblReleaseService) ConvertToSeaWaybill(ctx context.Context, hblNumber string, req hbl_schema.SeaWaybillRequest, revision int64) (*hbl_schema.HBLReleaseResponse, error) {
+	doc, err := s.loadHBL(ctx, hblNumber, revision)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case doc.Release.SeaWaybill != nil:
+		return nil, fmt.Errorf("%w: the HBL is already a sea waybill", ErrHBLReleaseState)
+	case len(doc.Release.Originals) > 0:
+		return nil, fmt.Errorf("%w: originals have been issued for this HBL", ErrHBLReleaseState)
+	case !isHBLEditable(doc.Status) && normalizeHBLStatus(doc.Status) != hbl_schema.HBLStatusIssued:
+		return nil, fmt.Errorf("%w: a %s HBL cannot be converted", ErrHBLReleaseState, normalizeHBLStatus(doc.Status))
+	}
+
+	release := doc.Release
+	release.SeaWaybill = &hbl_schema.HBLReleaseEvent{Actor: req.Actor, Reason: req.Reason, At: time.Now()}
+
+	data := doc.HBL
+	data.BillType = hbl_schema.HBLBillTypeSeaWaybill
+	reason := req.Reason
+	if reason == "" {
+		reason = "Converted to sea waybill"
+	}
+	_, err = s.versionService.Record(ctx, doc, data, hbl_schema.HBLChangeInfo{Author: req.Actor, Reason: reason}, func(ctx context.Context, _ *hbl_schema.HBLVersion) error {
+		return s.hblRepo.UpdateRelease(ctx, doc.HBLNumber, release, nil, revision)
+	})
+	if err := s.releaseError(ctx, doc, err); err != nil {
+		return nil, err
+	}
+	doc.Release = release
+	doc.Revision++
+	doc.HBL = data
+	doc.HBL.Marking = hblPDFMarking(doc)
+	log.Printf("HBL %s converted to sea waybill by %s", hblNumber, req.Actor)
+	return hblReleaseResponse(doc), nil
+}
+
+// TelexReleaseMessage renders the telex release message of a released HBL
+func (s *hblReleaseService) TelexReleaseMessage(ctx context.Context, hblNumber string) (string, error) {
+	doc, err := s.hblRepo.FindByHBLNumber(ctx, hblNumber)
+	if err != nil {
+		return "", err
+	}
+	if doc.Release.TelexRelease == nil {
+		return "", fmt.Errorf("%w: the HBL has not been telex released", ErrHBLReleaseState)
+	}
+	return telexReleaseMessage(doc), nil
+}
+
+// OriginalsPdfRequest builds a PDF generation payload with one entry per
+// outstanding original, each marked ORIGINAL with its serial number
+func (s *hblReleaseService) OriginalsPdfRequest(ctx context.Context, hblNumber string) (*models.PdfGenerationRequest, error) {
+	doc, err := s.hblRepo.FindByHBLNumber(ctx, hblNumber)
+	if err != nil {
+		return nil, err
+	}
+	if normalizeHBLStatus(doc.Status) != hbl_schema.HBLStatusIssued || outstandingOriginals(doc.Release) == 0 {
+		return nil, fmt.Errorf("%w: no originals are outstanding", ErrHBLReleaseState)
+	}
+
+	req := &models.PdfGenerationRequest{MBLNumber: doc.MBLNumber}
+	if req.MBLNumber == "" {
+		req.MBLNumber = doc.HBLNumber
+	}
+	for _, original := range doc.Release.Originals {
+		if original.SurrenderedAt != nil {
+			continue
+		}
+		data := doc.HBL
+		data.Marking = hbl_schema.HBLMarkingOriginal
+		data.OriginalSerial = original.Serial
+		data.NumberOfOriginals = len(doc.Release.Originals)
+		req.HBLList = append(req.HBLList, data)
+	}
+	req.TotalCount = len(req.HBLList)
+	return req, nil
+}
+
+// MarkPdfRequest sets the marking of every HBL in a PDF generation payload from
+// its stored release state, so originals cannot be printed through it. HBLs
+// that are not stored get no marking.
+func (s *hblReleaseService) MarkPdfRequest(ctx context.Context, req *models.PdfGenerationRequest) error {
+	for i := range req.HBLList {
+		data := &req.HBLList[i]
+		data.Marking, data.OriginalSerial, data.NumberOfOriginals = "", "", 0
+
+		doc, err := s.hblRepo.FindByHBLNumber(ctx, data.SeaWaybillNo)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to fetch HBL %s: %w", data.SeaWaybillNo, err)
+		}
+		data.Marking = hblPDFMarking(doc)
+		data.NumberOfOriginals = len(doc.Release.Originals)
+	}
+	return nil
+}
+
+// loadHBL fetches an HBL that is still at revision
+func (s *hblReleaseService) loadHBL(ctx context.Context, hblNumber string, revision int64) (*hbl_schema.HBLDocument, error) {
+	doc, err := s.hblRepo.FindByHBLNumber(ctx, hblNumber)
+	if err != nil {
+		return nil, err
+	}
+	if doc.Revision != revision {
+		return nil, &RevisionConflictError{Revision: doc.Revision, Current: doc}
+	}
+	return doc, nil
+}
+
+// saveRelease stores the release state and updates doc to match
+func (s *hblReleaseService) saveRelease(ctx context.Context, doc *hbl_schema.HBLDocument, release hbl_schema.HBLRelease, change *hbl_schema.HBLStatusChange, revision int64) error {
+	err := s.hblRepo.UpdateRelease(ctx, doc.HBLNumber, release, change, revision)
+	if err := s.releaseError(ctx, doc, err); err != nil {
+		return err
+	}
+
+	doc.Release = release
+	if change != nil {
+		doc.Status = change.To
+		doc.StatusHistory = append(doc.StatusHistory, *change)
+	}
+	doc.Revision++
+	return nil
+}
+
+// releaseError turns a revision mismatch on a release write into a
+// RevisionConflictError carrying the current HBL
+func (s *hblReleaseService) releaseError(ctx context.Context, doc *hbl_schema.HBLDocument, err error) error {
+	if errors.Is(err, repository.ErrRevisionMismatch) {
+		if current, findErr := s.hblRepo.FindByHBLNumber(ctx, doc.HBLNumber); findErr == nil {
+			return &RevisionConflictError{Revision: current.Revision, Current: current}
+		}
+	}
+	return err
+}
+
+func hblReleaseResponse(doc *hbl_schema.HBLDocument) *hbl_schema.HBLReleaseResponse {
+	billType := doc.HBL.BillType
+	if doc.Release.SeaWaybill != nil {
+		billType = hbl_schema.HBLBillTypeSeaWaybill
+	}
+	return &hbl_schema.HBLReleaseResponse{
+		HBLNumber: doc.HBLNumber,
+		Status:    normalizeHBLStatus(doc.Status),
+		BillType:  billType,
+		Marking:   hblPDFMarking(doc),
+		Release:   doc.Release,
+		Revision:  doc.Revision,
+	}
+}
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"fs-backend/models/hbl_schema"
+	"fs-backend/repository"
+)
+
+const (
+	defaultHBLPageSize = 20
+	maxHBLPageSize     = 100
+)
+
+// hblDeletableStatuses are the statuses in which an HBL has no legal effect yet
+var hblDeletableStatuses = []string{hbl_schema.HBLStatusDraft, hbl_schema.HBLStatusVoid}
+
+// HBLService reads, searches and deletes stored HBLs
+type HBLService interface {
+	GetHBL(ctx context.Context, hblNumber string) (*hbl_schema.HBLDocument, error)
+	ListHBLs(ctx context.Context, query hbl_schema.HBLSearchQuery) (*hbl_schema.HBLListResponse, error)
+	DeleteHBL(ctx context.Context, hblNumber string, revision int64) (int64, error)
+}
+
+type hblService struct {
+	hblRepo          repository.HBLRepository
+	hblDocRepo       repository.HBLDocRepository
+	versionRepo      repository.HBLVersionRepository
+	verificationRepo repository.HBLVerificationRepository
+	txRunner         repository.TxRunner
+}
+
+// NewHBLService creates a new HBLService
+func NewHBLService(
+	hblRepo repository.HBLRepository,
+	hblDocRepo repository.HBLDocRepository,
+	versionRepo repository.HBLVersionRepository,
+	verificationRepo repository.HBLVerificationRepository,
+	txRunner repository.TxRunner,
+) HBLService {
+	return &hblService{
+		hblRepo:          hblRepo,
+		hblDocRepo:       hblDocRepo,
+		versionRepo:      versionRepo,
+		verificationRepo: verificationRepo,
+		txRunner:         txRunner,
+	}
+}
+
+func (s *hblService) GetHBL(ctx context.Context, hblNumber string) (*hbl_schema.HBLDocument, error) {
+	return s.hblRepo.FindByHBLNumber(ctx, hblNumber)
+}
+
+// ListHBLs searches HBLs; paging defaults to the first 20 results, newest first
+func (s *hblService) ListHBLs(ctx context.Context, query hbl_schema.HBLSearchQuery) (*hbl_schema.HBLListResponse, error) {
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.PageSize < 1 {
+		query.PageSize = defaultHBLPageSize
+	}
+	query.PageSize = min(query.PageSize, maxHBLPageSize)
+	if query.SortBy == "" {
+		query.SortBy = "created_at"
+		query.SortDesc = true
+	}
+
+	docs, total, err := s.hblRepo.Search(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return &hbl_schema.HBLListResponse{
+		Items:    docs,
+		Total:    total,
+		Page:     query.Page,
+		PageSize: query.PageSize,
+	}, nil
+}
+
+// DeleteHBL deletes a draft or void HBL at revision together with its generated
+// documents and versions, and returns how many documents were removed. The
+// verifications of its PDFs are kept as tombstones. Everything is done in one
+// transaction, so the HBL is only gone when its cascade succeeded.
+func (s *hblService) DeleteHBL(ctx context.Context, hblNumber string, revision int64) (int64, error) {
+	var deletedDocs, deletedVersions int64
+	err := s.txRunner.RunInTransaction(ctx, func(ctx context.Context) error {
+		if err := s.hblRepo.DeleteHBL(ctx, hblNumber, hblDeletableStatuses, revision); err != nil {
+			return err
+		}
+		docs, err := s.hblDocRepo.DeleteByHBLNumber(ctx, hblNumber)
+		if err != nil {
+			return err
+		}
+		versions, err := s.versionRepo.DeleteByHBLNumber(ctx, hblNumber)
+		if err != nil {
+			return err
+		}
+		if _, err := s.verificationRepo.MarkHBLDeleted(ctx, hblNumber, time.Now()); err != nil {
+			return err
+		}
+		deletedDocs, deletedVersions = docs, versions
+		return nil
+	})
+	if errors.Is(err, repository.ErrRevisionMismatch) {
+		if current, findErr := s.hblRepo.FindByHBLNumber(ctx, hblNumber); findErr == nil {
+			return 0, &RevisionConflictError{Revision: current.Revision, Current: current}
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+	log.Printf("HBL %s deleted with %d document(s) and %d version(s)", hblNumber, deletedDocs, deletedVersions)
+	return deletedDocs, nil
+}
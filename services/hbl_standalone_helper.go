@@ -0,0 +1,223 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"fs-backend/models/hbl_schema"
+	"fs-backend/repository"
+)
+
+// validateStandaloneHBLRequest checks the manually entered data a standalone
+// HBL cannot do without
+func validateStandaloneHBLRequest(req hbl_schema.StandaloneHBLRequest) error {
+	var fieldErrors []HBLFieldError
+	add := func(field, message string) {
+		fieldErrors = append(fieldErrors, HBLFieldError{Field: field, Message: message})
+	}
+
+	if strings.TrimSpace(req.Consignee.Name) == "" {
+		add("consignee.name", "consignee is required")
+	}
+	if strings.TrimSpace(req.Routing.PortOfLoading) == "" {
+		add("routing.port_of_loading", "port of loading is required")
+	}
+	if strings.TrimSpace(req.Routing.PortOfDischarge) == "" {
+		add("routing.port_of_discharge", "port of discharge is required")
+	}
+	if len(req.VesselDetails) == 0 {
+		add("vessel_details", "at least one vessel is required")
+	}
+	for i, vessel := range req.VesselDetails {
+		if strings.TrimSpace(vessel.VesselName) == "" {
+			add(fmt.Sprintf("vessel_details[%d].vessel_name", i), "vessel name is required")
+		}
+	}
+	if req.ContainerNo != "" {
+		if err := validateContainerNumber(req.ContainerNo); err != nil {
+			add("container_no", err.Error())
+		}
+	}
+
+	if len(fieldErrors) > 0 {
+		return &HBLValidationError{Errors: fieldErrors}
+	}
+	return nil
+}
+
+// mapStandaloneHBL maps manually entered routing data + shipment cargo data +
+// shipper details into an HBLData struct. It follows mapMBLToHBL, with the
+// request standing in for the MBL.
+func mapStandaloneHBL(
+	req hbl_schema.StandaloneHBLRequest,
+	shipment repository.ShipmentDocument,
+	shipper repository.ShipperDocument,
+	hblNumber string,
+) hbl_schema.HBLData {
+	freightPayableAt := req.ShipmentDates.FreightPayableAt
+	if freightPayableAt == "" {
+		freightPayableAt = req.Routing.PortOfDischarge
+	}
+
+	return hbl_schema.HBLData{
+		BillType:        "HBL",
+		SeaWaybillNo:    hblNumber,
+		MovementType:    shipment.Mode,
+		Carrier:         req.Carrier,
+		Consignee:       req.Consignee,
+		NotifyParty:     req.NotifyParty,
+		ForwardingAgent: req.ForwardingAgent,
+		Routing:         req.Routing,
+		VesselDetails:   req.VesselDetails,
+
+		// Shipper from DB (actual shipper for this HBL)
+		Shipper: hbl_schema.HBLParty{
+			Name:    shipper.ShipperName,
+			Address: shipper.ShipperAddress,
+		},
+
+		ShipmentDates: hbl_schema.HBLShipmentDates{
+			PlaceAndDateOfIssue: req.ShipmentDates.PlaceAndDateOfIssue,
+			FreightPayableAt:    freightPayableAt,
+		},
+
+		// Container details: container info from the request, cargo details from DB shipment
+		ContainerDetails: []hbl_schema.HBLContainer{
+			{
+				ContainerNo:        req.ContainerNo,
+				ContainerSize:      req.ContainerSize,
+				SealNo:             req.SealNo,
+				PackageCount:       shipment.PackagesCount,
+				MarksAndNumbers:    shipment.MarksAndNumbers,
+				DescriptionOfGoods: shipment.GoodsDescription,
+				GrossWeight: hbl_schema.HBLWeightMeasurement{
+					Value: shipment.GrossWeight,
+					Unit:  "KGS",
+				},
+				NetWeight: hbl_schema.HBLWeightMeasurement{
+					Value: shipment.NetWeight,
+					Unit:  "KGS",
+				},
+				Measurement: hbl_schema.HBLWeightMeasurement{
+					Value: shipment.Volume,
+					Unit:  "CBM",
+				},
+			},
+		},
+
+		ShipmentSummary: hbl_schema.HBLShipmentSummary{
+			TotalContainersReceived: 1,
+			PackagesReceived:        shipment.PackagesCount,
+		},
+
+		FreightDetails: hbl_schema.HBLFreightDetails{
+			FreightStatus: req.FreightStatus,
+		},
+	}
+}
+
+// mergeMBLFields fills the MBL-derived fields of a standalone HBL from fromMBL,
+// the HBL mapMBLToHBL produces for the same shipment. Empty HBL fields take the
+// MBL value; fields filled in on both sides with different values are reported
+// as conflicts and keep the HBL value unless preferMBL is set. Shipper and
+// cargo fields come from the shipment and are left alone. Containers are
+// merged pairwise (see pairContainers); MBL containers the HBL lacks are appended.
+func mergeMBLFields(hbl, fromMBL hbl_schema.HBLData, preferMBL bool) (hbl_schema.HBLData, []string, []hbl_schema.HBLFieldConflict) {
+	merged := []string{}
+	conflicts := []hbl_schema.HBLFieldConflict{}
+
+	merge := func(path string, hblValue *string, mblValue string) {
+		switch {
+		case normalizeLocation(mblValue) == "":
+		case normalizeLocation(*hblValue) == "":
+			*hblValue = mblValue
+			merged = append(merged, path)
+		case normalizeLocation(*hblValue) != normalizeLocation(mblValue):
+			conflict := hbl_schema.HBLFieldConflict{Path: path, HBLValue: *hblValue, MBLValue: mblValue, Kept: "hbl"}
+			if preferMBL {
+				*hblValue = mblValue
+				conflict.Kept = "mbl"
+			}
+			conflicts = append(conflicts, conflict)
+		}
+	}
+	mergeParty := func(path string, party *hbl_schema.HBLParty, mblParty hbl_schema.HBLParty) {
+		merge(path+".name", &party.Name, mblParty.Name)
+		merge(path+".address", &party.Address, mblParty.Address)
+	}
+
+	merge("carrier_reference", &hbl.CarrierReference, fromMBL.CarrierReference)
+	merge("export_reference", &hbl.ExportReference, fromMBL.ExportReference)
+	merge("carrier.name", &hbl.Carrier.Name, fromMBL.Carrier.Name)
+	mergeParty("consignee", &hbl.Consignee, fromMBL.Consignee)
+	mergeParty("notify_party", &hbl.NotifyParty, fromMBL.NotifyParty)
+	mergeParty("forwarding_agent", &hbl.ForwardingAgent, fromMBL.ForwardingAgent)
+
+	merge("routing.place_of_receipt", &hbl.Routing.PlaceOfReceipt, fromMBL.Routing.PlaceOfReceipt)
+	merge("routing.port_of_loading", &hbl.Routing.PortOfLoading, fromMBL.Routing.PortOfLoading)
+	merge("routing.port_of_discharge", &hbl.Routing.PortOfDischarge, fromMBL.Routing.PortOfDischarge)
+	merge("routing.place_of_delivery", &hbl.Routing.PlaceOfDelivery, fromMBL.Routing.PlaceOfDelivery)
+
+	// The MBL has a single vessel; it is compared with the HBL's ocean leg (the first one)
+	if len(fromMBL.VesselDetails) > 0 {
+		if len(hbl.VesselDetails) == 0 {
+			hbl.VesselDetails = []hbl_schema.HBLVessel{{}}
+		}
+		merge("vessel_details[0].vessel_name", &hbl.VesselDetails[0].VesselName, fromMBL.VesselDetails[0].VesselName)
+		merge("vessel_details[0].voyage_no", &hbl.VesselDetails[0].VoyageNo, fromMBL.VesselDetails[0].VoyageNo)
+	}
+
+	merge("shipment_dates.place_and_date_of_issue", &hbl.ShipmentDates.PlaceAndDateOfIssue, fromMBL.ShipmentDates.PlaceAndDateOfIssue)
+	merge("shipment_dates.freight_payable_at", &hbl.ShipmentDates.FreightPayableAt, fromMBL.ShipmentDates.FreightPayableAt)
+
+	for _, pair := range pairContainers(hbl.ContainerDetails, fromMBL.ContainerDetails) {
+		i := pair[0]
+		if i < 0 {
+			i = len(hbl.ContainerDetails)
+			hbl.ContainerDetails = append(hbl.ContainerDetails, hbl_schema.HBLContainer{})
+		}
+		mblContainer := fromMBL.ContainerDetails[pair[1]]
+		prefix := fmt.Sprintf("container_details[%d]", i)
+		merge(prefix+".container_no", &hbl.ContainerDetails[i].ContainerNo, mblContainer.ContainerNo)
+		merge(prefix+".container_size", &hbl.ContainerDetails[i].ContainerSize, mblContainer.ContainerSize)
+		merge(prefix+".seal_no", &hbl.ContainerDetails[i].SealNo, mblContainer.SealNo)
+	}
+
+	merge("freight_details.freight_status", &hbl.FreightDetails.FreightStatus, fromMBL.FreightDetails.FreightStatus)
+	return hbl, merged, conflicts
+}
+
+// pairContainers pairs every MBL container with the HBL container it is merged
+// into, as {hbl index, mbl index}. Containers are matched by container number
+// first; the remaining MBL containers take the unmatched HBL container at the
+// same index, or -1 when the HBL has none and the container is to be appended.
+func pairContainers(hblContainers, mblContainers []hbl_schema.HBLContainer) [][2]int {
+	hblIndex := make([]int, len(mblContainers))
+	taken := make([]bool, len(hblContainers))
+	for j, mblContainer := range mblContainers {
+		hblIndex[j] = -1
+		number := normalizeContainerNo(mblContainer.ContainerNo)
+		if number == "" {
+			continue
+		}
+		for i, hblContainer := range hblContainers {
+			if !taken[i] && normalizeContainerNo(hblContainer.ContainerNo) == number {
+				hblIndex[j] = i
+				taken[i] = true
+				break
+			}
+		}
+	}
+	for j := range mblContainers {
+		if hblIndex[j] < 0 && j < len(hblContainers) && !taken[j] {
+			hblIndex[j] = j
+			taken[j] = true
+		}
+	}
+
+	pairs := make([][2]int, 0, len(mblContainers))
+	for j, i := range hblIndex {
+		pairs = append(pairs, [2]int{i, j})
+	}
+	return pairs
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"fs-backend/models/hbl_schema"
+)
+
+// verificationClaims is the signed part of a verification token
+type verificationClaims struct {
+	ID        string `json:"id"`
+	HBLNumber string `json:"hbl"`
+	IssuedAt  int64  `json:"iat"`
+}
+
+// ParseEd25519SigningKey decodes a base64 Ed25519 private key, given either as
+// its 32-byte seed or as the 64-byte private key
+func ParseEd25519SigningKey(encoded string) (ed25519.PrivateKey, error) {
+	encoded = strings.TrimSpace(encoded)
+	if encoded == "" {
+		return nil, errors.New("no signing key configured")
+	}
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("signing key is not valid base64: %w", err)
+	}
+	switch len(raw) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(raw), nil
+	case ed25519.PrivateKeySize:
+		return ed25519.PrivateKey(raw), nil
+	}
+	return nil, fmt.Errorf("signing key must be %d or %d bytes, got %d", ed25519.SeedSize, ed25519.PrivateKeySize, len(raw))
+}
+
+// newVerificationTokenID returns a random token identifier
+func newVerificationTokenID() (string, error) {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(random), nil
+}
+
+// signVerificationToken encodes the claims as base64url(JSON) "." base64url(Ed25519 signature)
+func signVerificationToken(key ed25519.PrivateKey, claims verificationClaims) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signature := ed25519.Sign(key, payload)
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// parseVerificationToken checks the signature of a token and returns its claims
+func parseVerificationToken(key ed25519.PublicKey, token string) (verificationClaims, error) {
+	var claims verificationClaims
+	encodedPayload, encodedSignature, found := strings.Cut(token, ".")
+	if !found {
+		return claims, errors.New("malformed token")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return claims, errors.New("malformed token payload")
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil {
+		return claims, errors.New("malformed token signature")
+	}
+	if !ed25519.Verify(key, payload, signature) {
+		return claims, errors.New("invalid token signature")
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return claims, errors.New("malformed token claims")
+	}
+	return claims, nil
+}
+
+// hblDataHash fingerprints the printed content of an HBL. It is computed over
+// the flattened fields, so empty and missing lists hash the same, and ignores
+// the PDF-only fields.
+func hblDataHash(data hbl_schema.HBLData) string {
+	keepReadOnlyFields(&data, data, data.SeaWaybillNo) // drops the PDF-only fields
+	fields := flattenHBLData(data)
+
+	paths := make([]string, 0, len(fields))
+	for path, value := range fields {
+		if value != nil {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	hash := sha256.New()
+	for _, path := range paths {
+		value, _ := json.Marshal(fields[path])
+		fmt.Fprintf(hash, "%s=%s\n", path, value)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,254 @@
+package services
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"fs-backend/models"
+	"fs-backend/models/hbl_schema"
+	"fs-backend/repository"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	// ErrInvalidVerificationToken is returned for tokens that are malformed,
+	// badly signed or unknown
+	ErrInvalidVerificationToken = errors.New("invalid verification token")
+	// ErrHBLPayloadMismatch is returned when a PDF payload would print other
+	// data than the stored HBL it is signed for
+	ErrHBLPayloadMismatch = errors.New("HBL payload does not match the stored HBL")
+)
+
+// HBLVerificationService signs generated HBL PDFs so their authenticity can be
+// checked through a public verification URL
+type HBLVerificationService interface {
+	Prepare(ctx context.Context, req *models.PdfGenerationRequest) ([]models.HBLVerification, error)
+	Record(ctx context.Context, pending []models.HBLVerification, files []models.PdfGeneratorUploadedFile) error
+	Verify(ctx context.Context, token string) (*models.HBLVerificationResult, error)
+}
+
+type hblVerificationService struct {
+	verificationRepo repository.HBLVerificationRepository
+	hblRepo          repository.HBLRepository
+	versionRepo      repository.HBLVersionRepository
+	signingKey       ed25519.PrivateKey
+	baseURL          string
+	issuer           string
+	client           *http.Client
+}
+
+// NewHBLVerificationService creates a new HBLVerificationService. Without a
+// signing key PDFs are generated without verification URLs.
+func NewHBLVerificationService(
+	verificationRepo repository.HBLVerificationRepository,
+	hblRepo repository.HBLRepository,
+	versionRepo repository.HBLVersionRepository,
+	signingKey ed25519.PrivateKey,
+	baseURL, issuer string,
+) HBLVerificationService {
+	return &hblVerificationService{
+		verificationRepo: verificationRepo,
+		hblRepo:          hblRepo,
+		versionRepo:      versionRepo,
+		signingKey:       signingKey,
+		baseURL:          strings.TrimRight(baseURL, "/"),
+		issuer:           issuer,
+		client:           &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// Prepare signs a verification token for every stored HBL of a PDF generation
+// payload and embeds its verification URL. The token vouches for stored
+// content only: the payload must print the HBL as stored or as one of its
+// recorded versions, otherwise ErrHBLPayloadMismatch is returned. The
+// returned verifications are stored by Record once the PDFs exist.
+func (s *hblVerificationService) Prepare(ctx context.Context, req *models.PdfGenerationRequest) ([]models.HBLVerification, error) {
+	if s.signingKey == nil {
+		return nil, nil
+	}
+
+	var pending []models.HBLVerification
+	now := time.Now()
+	for i := range req.HBLList {
+		data := &req.HBLList[i]
+		data.VerificationURL = ""
+
+		doc, err := s.hblRepo.FindByHBLNumber(ctx, data.SeaWaybillNo)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			continue // only stored HBLs can be verified
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch HBL %s: %w", data.SeaWaybillNo, err)
+		}
+
+		dataHash := hblDataHash(*data)
+		stored, err := s.isStoredContent(ctx, doc, dataHash)
+		if err != nil {
+			return nil, err
+		}
+		if !stored {
+			return nil, fmt.Errorf("%w: %s, save the changes before generating the PDF", ErrHBLPayloadMismatch, doc.HBLNumber)
+		}
+
+		tokenID, err := newVerificationTokenID()
+		if err != nil {
+			return nil, err
+		}
+		token, err := signVerificationToken(s.signingKey, verificationClaims{ID: tokenID, HBLNumber: doc.HBLNumber, IssuedAt: now.Unix()})
+		if err != nil {
+			return nil, err
+		}
+
+		issuer := s.issuer
+		if issuer == "" {
+			issuer = doc.HBL.ForwardingAgent.Name
+		}
+		data.VerificationURL = s.baseURL + "/verify/" + token
+		pending = append(pending, models.HBLVerification{
+			TokenID:        tokenID,
+			HBLNumber:      doc.HBLNumber,
+			Issuer:         issuer,
+			IssuedAt:       now,
+			DataHash:       dataHash,
+			Marking:        data.Marking,
+			OriginalSerial: data.OriginalSerial,
+			URL:            data.VerificationURL,
+		})
+	}
+	return pending, nil
+}
+
+// isStoredContent tells whether dataHash is the hash of the HBL as stored or
+// of one of its recorded versions
+func (s *hblVerificationService) isStoredContent(ctx context.Context, doc *hbl_schema.HBLDocument, dataHash string) (bool, error) {
+	if hblDataHash(doc.HBL) == dataHash {
+		return true, nil
+	}
+	versions, err := s.versionRepo.FindByHBLNumber(ctx, doc.HBLNumber)
+	if err != nil {
+		return false, fmt.Errorf("failed to fetch versions of HBL %s: %w", doc.HBLNumber, err)
+	}
+	for _, version := range versions {
+		if hblDataHash(version.HBL) == dataHash {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Record links the generated files to the prepared verifications, computes the
+// SHA-256 of each PDF and stores the verifications. A file that cannot be
+// downloaded is logged and its verification stored without a hash.
+func (s *hblVerificationService) Record(ctx context.Context, pending []models.HBLVerification, files []models.PdfGeneratorUploadedFile) error {
+	if len(pending) == 0 {
+		return nil
+	}
+
+	hblNumbers := make([]string, 0, len(pending))
+	for _, verification := range pending {
+		hblNumbers = append(hblNumbers, verification.HBLNumber)
+	}
+
+	// Files of the same HBL (e.g. a set of originals) are matched in payload order
+	for _, file := range files {
+		hblNumber := fileHBLNumber(file, hblNumbers)
+		if hblNumber == "" || file.URL == "" {
+			continue
+		}
+		for i := range pending {
+			if pending[i].HBLNumber != hblNumber || pending[i].Filename != "" {
+				continue
+			}
+			pending[i].Filename = file.Filename
+			sum, err := s.fileSHA256(ctx, file.URL)
+			if err != nil {
+				log.Printf("Warning: could not hash %s for verification: %v", file.Filename, err)
+			}
+			pending[i].SHA256 = sum
+			break
+		}
+	}
+
+	return s.verificationRepo.InsertMany(ctx, pending)
+}
+
+// Verify checks a token and reports whether the HBL it was issued for has
+// since been amended or voided. Valid means the document was issued by us.
+func (s *hblVerificationService) Verify(ctx context.Context, token string) (*models.HBLVerificationResult, error) {
+	if s.signingKey == nil {
+		return nil, ErrInvalidVerificationToken
+	}
+	claims, err := parseVerificationToken(s.signingKey.Public().(ed25519.PublicKey), token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidVerificationToken, err)
+	}
+
+	verification, err := s.verificationRepo.FindByTokenID(ctx, claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	if verification == nil || verification.HBLNumber != claims.HBLNumber {
+		return nil, ErrInvalidVerificationToken
+	}
+
+	result := &models.HBLVerificationResult{
+		Valid:          true,
+		HBLNumber:      verification.HBLNumber,
+		Issuer:         verification.Issuer,
+		IssuedAt:       verification.IssuedAt,
+		Marking:        verification.Marking,
+		OriginalSerial: verification.OriginalSerial,
+		SHA256:         verification.SHA256,
+	}
+
+	if verification.HBLDeletedAt != nil {
+		result.Status = "deleted"
+		result.Voided = true
+		return result, nil
+	}
+	doc, err := s.hblRepo.FindByHBLNumber(ctx, verification.HBLNumber)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		result.Status = "deleted"
+		result.Voided = true
+		return result, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result.Status = normalizeHBLStatus(doc.Status)
+	result.Voided = result.Status == hbl_schema.HBLStatusVoid
+	result.Amended = hblDataHash(doc.HBL) != verification.DataHash
+	return result, nil
+}
+
+func (s *hblVerificationService) fileSHA256(ctx context.Context, url string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("download returned status %d", resp.StatusCode)
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, resp.Body); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"fs-backend/models/hbl_schema"
+)
+
+// diffHBLData compares two HBLs field by field, using the JSON field names as
+// paths so the diff reads like the API payload
+func diffHBLData(from, to hbl_schema.HBLData) []hbl_schema.HBLFieldChange {
+	oldFields := flattenHBLData(from)
+	newFields := flattenHBLData(to)
+
+	paths := make(map[string]bool, len(oldFields)+len(newFields))
+	for path := range oldFields {
+		paths[path] = true
+	}
+	for path := range newFields {
+		paths[path] = true
+	}
+
+	changes := []hbl_schema.HBLFieldChange{}
+	for path := range paths {
+		oldValue, newValue := oldFields[path], newFields[path]
+		if reflect.DeepEqual(oldValue, newValue) {
+			continue
+		}
+		changes = append(changes, hbl_schema.HBLFieldChange{Path: path, OldValue: oldValue, NewValue: newValue})
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes
+}
+
+// flattenHBLData maps every leaf value of the HBL to its JSON path
+func flattenHBLData(data hbl_schema.HBLData) map[string]interface{} {
+	fields := make(map[string]interface{})
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return fields
+	}
+	var tree interface{}
+	if err := json.Unmarshal(raw, &tree); err != nil {
+		return fields
+	}
+	flattenJSON("", tree, fields)
+	return fields
+}
+
+func flattenJSON(prefix string, value interface{}, out map[string]interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			path := key
+			if prefix != "" {
+				path = prefix + "." + key
+			}
+			flattenJSON(path, child, out)
+		}
+	case []interface{}:
+		for i, child := range v {
+			flattenJSON(fmt.Sprintf("%s[%d]", prefix, i), child, out)
+		}
+	default:
+		out[prefix] = v
+	}
+}
@@ -0,0 +1,219 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"fs-backend/models"
+	"fs-backend/models/hbl_schema"
+	"fs-backend/repository"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// baselineVersionReason describes version 1, the content an HBL had before its first recorded change
+const baselineVersionReason = "initial version"
+
+var (
+	ErrHBLVersionNotFound = errors.New("HBL version not found")
+	ErrHBLVersionConflict = errors.New("HBL was changed by another request, reload and retry")
+)
+
+// HBLVersionService keeps an immutable version for every change made to an HBL
+type HBLVersionService interface {
+	Record(ctx context.Context, doc *hbl_schema.HBLDocument, data hbl_schema.HBLData, change hbl_schema.HBLChangeInfo, apply func(ctx context.Context, version *hbl_schema.HBLVersion) error) (*hbl_schema.HBLVersion, error)
+	ListVersions(ctx context.Context, hblNumber string) ([]hbl_schema.HBLVersion, error)
+	GetVersion(ctx context.Context, hblNumber string, version int) (*hbl_schema.HBLVersion, error)
+	Diff(ctx context.Context, hblNumber string, from, to int) (*hbl_schema.HBLVersionDiff, error)
+	PdfRequest(ctx context.Context, hblNumber string, version int) (*models.PdfGenerationRequest, error)
+}
+
+type hblVersionService struct {
+	hblRepo     repository.HBLRepository
+	versionRepo repository.HBLVersionRepository
+	txRunner    repository.TxRunner
+}
+
+// NewHBLVersionService creates a new HBLVersionService
+func NewHBLVersionService(hblRepo repository.HBLRepository, versionRepo repository.HBLVersionRepository, txRunner repository.TxRunner) HBLVersionService {
+	return &hblVersionService{
+		hblRepo:     hblRepo,
+		versionRepo: versionRepo,
+		txRunner:    txRunner,
+	}
+}
+
+// baselineVersion describes the current content of an HBL that has no stored
+// versions yet (created before versioning, or never changed)
+func baselineVersion(doc *hbl_schema.HBLDocument) hbl_schema.HBLVersion {
+	return hbl_schema.HBLVersion{
+		HBLNumber: doc.HBLNumber,
+		Version:   1,
+		Author:    "system",
+		Reason:    baselineVersionReason,
+		Changes:   []hbl_schema.HBLFieldChange{},
+		HBL:       doc.HBL,
+		CreatedAt: doc.CreatedAt,
+	}
+}
+
+// versionsOf returns the stored versions of doc, or its baseline when none are stored.
+// The boolean is true when the baseline is not stored yet.
+func (s *hblVersionService) versionsOf(ctx context.Context, doc *hbl_schema.HBLDocument) ([]hbl_schema.HBLVersion, bool, error) {
+	versions, err := s.versionRepo.FindByHBLNumber(ctx, doc.HBLNumber)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(versions) == 0 {
+		return []hbl_schema.HBLVersion{baselineVersion(doc)}, true, nil
+	}
+	return versions, false, nil
+}
+
+// Record stores the version that replaces doc.HBL with data and writes the
+// HBL itself through apply, in one transaction: when apply fails (revision
+// conflict, HBL gone, duplicate key) the version is not kept either, and its
+// error is returned as is. The unique version index makes a concurrent update
+// fail with ErrHBLVersionConflict.
+func (s *hblVersionService) Record(ctx context.Context, doc *hbl_schema.HBLDocument, data hbl_schema.HBLData, change hbl_schema.HBLChangeInfo, apply func(ctx context.Context, version *hbl_schema.HBLVersion) error) (*hbl_schema.HBLVersion, error) {
+	var recorded *hbl_schema.HBLVersion
+	err := s.txRunner.RunInTransaction(ctx, func(ctx context.Context) error {
+		version, err := s.insertVersion(ctx, doc, data, change)
+		if err != nil {
+			return err
+		}
+		if err := apply(ctx, version); err != nil {
+			return err
+		}
+		recorded = version
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("HBL %s version %d recorded by %s (%d changed fields)", doc.HBLNumber, recorded.Version, recorded.Author, len(recorded.Changes))
+	return recorded, nil
+}
+
+// insertVersion stores the baseline of an unversioned HBL and the version
+// that replaces its latest content with data
+func (s *hblVersionService) insertVersion(ctx context.Context, doc *hbl_schema.HBLDocument, data hbl_schema.HBLData, change hbl_schema.HBLChangeInfo) (*hbl_schema.HBLVersion, error) {
+	versions, unsaved, err := s.versionsOf(ctx, doc)
+	if err != nil {
+		return nil, err
+	}
+	if unsaved {
+		if err := s.versionRepo.Insert(ctx, &versions[0]); err != nil {
+			return nil, versionInsertError(err)
+		}
+	}
+
+	latest := versions[len(versions)-1]
+	author := change.Author
+	if author == "" {
+		author = "unknown"
+	}
+	version := &hbl_schema.HBLVersion{
+		HBLNumber: doc.HBLNumber,
+		Version:   latest.Version + 1,
+		Author:    author,
+		Reason:    change.Reason,
+		Changes:   diffHBLData(latest.HBL, data),
+		HBL:       data,
+		CreatedAt: time.Now(),
+	}
+	if err := s.versionRepo.Insert(ctx, version); err != nil {
+		return nil, versionInsertError(err)
+	}
+	return version, nil
+}
+
+func versionInsertError(err error) error {
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrHBLVersionConflict
+	}
+	return fmt.Errorf("failed to store HBL version: %w", err)
+}
+
+// ListVersions returns every version of an HBL, oldest first
+func (s *hblVersionService) ListVersions(ctx context.Context, hblNumber string) ([]hbl_schema.HBLVersion, error) {
+	doc, err := s.hblRepo.FindByHBLNumber(ctx, hblNumber)
+	if err != nil {
+		return nil, err
+	}
+	versions, _, err := s.versionsOf(ctx, doc)
+	return versions, err
+}
+
+func (s *hblVersionService) GetVersion(ctx context.Context, hblNumber string, version int) (*hbl_schema.HBLVersion, error) {
+	doc, err := s.hblRepo.FindByHBLNumber(ctx, hblNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	found, err := s.versionRepo.FindVersion(ctx, hblNumber, version)
+	if err != nil {
+		return nil, err
+	}
+	if found != nil {
+		return found, nil
+	}
+
+	// Unversioned HBLs only have their baseline
+	if version == 1 {
+		versions, unsaved, err := s.versionsOf(ctx, doc)
+		if err != nil {
+			return nil, err
+		}
+		if unsaved {
+			return &versions[0], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s version %d", ErrHBLVersionNotFound, hblNumber, version)
+}
+
+// Diff compares two versions of an HBL; from may be greater than to
+func (s *hblVersionService) Diff(ctx context.Context, hblNumber string, from, to int) (*hbl_schema.HBLVersionDiff, error) {
+	fromVersion, err := s.GetVersion(ctx, hblNumber, from)
+	if err != nil {
+		return nil, err
+	}
+	toVersion, err := s.GetVersion(ctx, hblNumber, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return &hbl_schema.HBLVersionDiff{
+		HBLNumber:   hblNumber,
+		FromVersion: from,
+		ToVersion:   to,
+		Changes:     diffHBLData(fromVersion.HBL, toVersion.HBL),
+	}, nil
+}
+
+// PdfRequest builds the PDF generation payload for a historical version
+func (s *hblVersionService) PdfRequest(ctx context.Context, hblNumber string, version int) (*models.PdfGenerationRequest, error) {
+	doc, err := s.hblRepo.FindByHBLNumber(ctx, hblNumber)
+	if err != nil {
+		return nil, err
+	}
+	found, err := s.GetVersion(ctx, hblNumber, version)
+	if err != nil {
+		return nil, err
+	}
+
+	mblNumber := doc.MBLNumber
+	if mblNumber == "" {
+		mblNumber = doc.HBLNumber
+	}
+	found.HBL.Marking = hblPDFMarking(doc)
+	found.HBL.NumberOfOriginals = len(doc.Release.Originals)
+	return &models.PdfGenerationRequest{
+		MBLNumber:  mblNumber,
+		TotalCount: 1,
+		HBLList:    []hbl_schema.HBLData{found.HBL},
+	}, nil
+}
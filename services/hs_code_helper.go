@@ -0,0 +1,262 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"fs-backend/models"
+	"fs-backend/modules/hscodes"
+)
+
+const (
+	// hsConflictMinScore is the suggestion score above which a description is
+	// considered specific enough to contradict the declared chapter
+	hsConflictMinScore = 0.5
+	hsConflictSample   = 5
+)
+
+// hsStopWords are ignored when matching goods descriptions to the nomenclature
+var hsStopWords = map[string]bool{
+	"and": true, "or": true, "of": true, "the": true, "for": true, "with": true,
+	"other": true, "not": true, "than": true, "thereof": true, "part": true,
+	"article": true, "kind": true, "whether": true, "used": true, "etc": true,
+	"elsewhere": true, "specified": true, "including": true, "such": true,
+	"made": true, "said": true, "contain": true, "containing": true, "stc": true,
+	"pkg": true, "pkgs": true, "ctns": true, "pcs": true, "piece": true,
+	"similar": true, "like": true, "more": true, "less": true, "exceeding": true,
+	"weighing": true, "form": true, "good": true, "new": true,
+}
+
+// hsTable is an in-memory, keyword-indexed copy of the nomenclature. A table
+// that is not complete (the bundled subset) only warns about unknown headings
+// and subheadings instead of rejecting them.
+type hsTable struct {
+	complete     bool
+	byCode       map[string]models.HSCode
+	keywords     map[string][]string
+	docFreq      map[string]int
+	hasChildren  map[string]bool
+	orderedCodes []string
+}
+
+func buildHSTable(codes []models.HSCode, complete bool) *hsTable {
+	t := &hsTable{
+		complete:    complete,
+		byCode:      make(map[string]models.HSCode, len(codes)),
+		keywords:    make(map[string][]string, len(codes)),
+		docFreq:     make(map[string]int),
+		hasChildren: make(map[string]bool),
+	}
+
+	for _, code := range codes {
+		t.byCode[code.Code] = code
+		t.orderedCodes = append(t.orderedCodes, code.Code)
+		if len(code.Code) > 2 {
+			t.hasChildren[code.Code[:len(code.Code)-2]] = true
+		}
+
+		words := hsKeywords(code.Description)
+		t.keywords[code.Code] = words
+		for _, word := range words {
+			t.docFreq[word]++
+		}
+	}
+	sort.Strings(t.orderedCodes)
+	return t
+}
+
+// hsCodesFromEntries converts parsed nomenclature rows to storable records
+func hsCodesFromEntries(entries []hscodes.Entry) []models.HSCode {
+	codes := make([]models.HSCode, 0, len(entries))
+	for _, entry := range entries {
+		codes = append(codes, models.HSCode{
+			Code:        entry.Code,
+			Level:       hscodes.Level(entry.Code),
+			Chapter:     entry.Code[:2],
+			Description: entry.Description,
+		})
+	}
+	return codes
+}
+
+// hsKeywords lower-cases text, drops stop words and folds simple plurals
+func hsKeywords(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+
+	seen := make(map[string]bool)
+	var words []string
+	for _, word := range fields {
+		word = hsStem(word)
+		if len(word) < 3 || hsStopWords[word] || seen[word] {
+			continue
+		}
+		seen[word] = true
+		words = append(words, word)
+	}
+	return words
+}
+
+func hsStem(word string) string {
+	switch {
+	case len(word) > 4 && strings.HasSuffix(word, "ies"):
+		return word[:len(word)-3] + "y"
+	case len(word) > 4 && (strings.HasSuffix(word, "ches") || strings.HasSuffix(word, "shes") ||
+		strings.HasSuffix(word, "sses") || strings.HasSuffix(word, "xes")):
+		return word[:len(word)-2]
+	case len(word) > 3 && strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss"):
+		return word[:len(word)-1]
+	}
+	return word
+}
+
+func (t *hsTable) idf(word string) float64 {
+	return math.Log(1 + float64(len(t.byCode))/float64(t.docFreq[word]))
+}
+
+// suggest ranks nomenclature entries by the IDF-weighted share of the
+// description's known keywords they contain.
+func (t *hsTable) suggest(description string, limit int) []models.HSCodeSuggestion {
+	var query []string
+	total := 0.0
+	for _, word := range hsKeywords(description) {
+		if t.docFreq[word] == 0 {
+			continue
+		}
+		query = append(query, word)
+		total += t.idf(word)
+	}
+
+	suggestions := []models.HSCodeSuggestion{}
+	if total == 0 {
+		return suggestions
+	}
+
+	levelWeight := map[string]float64{"subheading": 1, "heading": 0.95, "chapter": 0.85}
+	for _, code := range t.orderedCodes {
+		entryWords := make(map[string]bool)
+		for _, word := range t.keywords[code] {
+			entryWords[word] = true
+		}
+
+		matched := 0.0
+		for _, word := range query {
+			if entryWords[word] {
+				matched += t.idf(word)
+			}
+		}
+		if matched == 0 {
+			continue
+		}
+
+		entry := t.byCode[code]
+		suggestions = append(suggestions, models.HSCodeSuggestion{
+			Code:        entry.Code,
+			Level:       entry.Level,
+			Description: entry.Description,
+			Score:       roundScore(matched / total * levelWeight[entry.Level]),
+		})
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return suggestions[i].Score > suggestions[j].Score
+	})
+	if limit > 0 && len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions
+}
+
+// validate checks the code against the table level by level. Codes deeper than
+// the table knows about are accepted with a warning; on a complete table, codes
+// contradicting a level the table does cover are rejected.
+func (t *hsTable) validate(code, description string) models.HSCodeValidation {
+	normalized := hscodes.NormalizeCode(code)
+	result := models.HSCodeValidation{Code: code, NormalizedCode: normalized}
+
+	if normalized == "" {
+		result.Errors = append(result.Errors, "HS code is empty")
+		return result
+	}
+	for _, r := range normalized {
+		if r < '0' || r > '9' {
+			result.Errors = append(result.Errors, "HS code must contain digits only")
+			return result
+		}
+	}
+	switch len(normalized) {
+	case 4, 6, 8, 10:
+	default:
+		result.Errors = append(result.Errors, "HS code must be 4, 6, 8 or 10 digits")
+		return result
+	}
+
+	chapter, ok := t.byCode[normalized[:2]]
+	if !ok {
+		result.Errors = append(result.Errors, fmt.Sprintf("unknown HS chapter %s", normalized[:2]))
+		return result
+	}
+	if strings.HasPrefix(chapter.Description, "(Reserved") {
+		result.Errors = append(result.Errors, fmt.Sprintf("HS chapter %s is reserved", normalized[:2]))
+		return result
+	}
+	result.Level, result.Description = chapter.Level, chapter.Description
+
+	for _, size := range []int{4, 6} {
+		if len(normalized) < size {
+			break
+		}
+		prefix := normalized[:size]
+		if entry, found := t.byCode[prefix]; found {
+			result.Level, result.Description = entry.Level, entry.Description
+			continue
+		}
+		parent := prefix[:size-2]
+		if t.complete && t.hasChildren[parent] {
+			result.Errors = append(result.Errors, fmt.Sprintf("unknown HS %s %s", hscodes.Level(prefix), prefix))
+			return result
+		}
+		result.Warnings = append(result.Warnings, fmt.Sprintf("%s %s is not in the nomenclature table", hscodes.Level(prefix), prefix))
+		break
+	}
+	if len(normalized) > 6 {
+		result.Warnings = append(result.Warnings, "national tariff digits beyond the 6-digit subheading are not validated")
+	}
+
+	result.Valid = true
+	if warning := t.chapterConflict(normalized[:2], description); warning != "" {
+		result.Warnings = append(result.Warnings, warning)
+	}
+	return result
+}
+
+// chapterConflict returns a warning when the description clearly points to
+// other chapters than the one declared.
+func (t *hsTable) chapterConflict(chapter, description string) string {
+	if strings.TrimSpace(description) == "" {
+		return ""
+	}
+	suggestions := t.suggest(description, hsConflictSample)
+	if len(suggestions) == 0 || suggestions[0].Score < hsConflictMinScore {
+		return ""
+	}
+
+	var suggested []string
+	seen := make(map[string]bool)
+	for _, suggestion := range suggestions {
+		if suggestion.Code[:2] == chapter {
+			return ""
+		}
+		if !seen[suggestion.Code[:2]] {
+			seen[suggestion.Code[:2]] = true
+			suggested = append(suggested, suggestion.Code[:2])
+		}
+	}
+
+	return fmt.Sprintf("HS chapter %s (%s) does not match the goods description; suggested chapters: %s",
+		chapter, t.byCode[chapter].Description, strings.Join(suggested, ", "))
+}
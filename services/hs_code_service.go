@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"fs-backend/models"
+	"fs-backend/models/hbl_schema"
+	"fs-backend/modules/hscodes"
+	"fs-backend/repository"
+)
+
+// HSCodeValidationError is returned when an HBL carries HS codes that are not
+// in the nomenclature.
+type HSCodeValidationError struct {
+	Results []models.HSCodeValidation
+}
+
+func (e *HSCodeValidationError) Error() string {
+	var codes []string
+	for _, result := range e.Results {
+		codes = append(codes, result.Code)
+	}
+	return fmt.Sprintf("invalid HS code(s): %s", strings.Join(codes, ", "))
+}
+
+// HSCodeService validates HS codes and proposes codes for goods descriptions
+type HSCodeService interface {
+	Validate(ctx context.Context, code, description string) models.HSCodeValidation
+	ValidateHBL(ctx context.Context, data hbl_schema.HBLData) ([]models.HSCodeValidation, error)
+	Suggest(ctx context.Context, description string, limit int) []models.HSCodeSuggestion
+	Import(ctx context.Context, entries []hscodes.Entry, complete bool) (int64, error)
+}
+
+type hsCodeService struct {
+	repo repository.HSCodeRepository
+
+	mu         sync.Mutex
+	table      *hsTable
+	importedAt time.Time // updated_at of the import the table was read from
+}
+
+// NewHSCodeService creates a new HSCodeService. The nomenclature is read from
+// the "hs_codes" collection on first use and read again whenever an import
+// (e.g. by cmd/hs-import) has been recorded since, falling back to the bundled
+// table while the collection has not been imported. Only a table imported as
+// complete rejects codes it does not know.
+func NewHSCodeService(repo repository.HSCodeRepository) HSCodeService {
+	return &hsCodeService{repo: repo}
+}
+
+func (s *hsCodeService) loadTable(ctx context.Context) *hsTable {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	imported, err := s.repo.LastImport(ctx)
+	if err != nil && s.table != nil {
+		log.Printf("Warning: failed to check for HS code imports, using the loaded nomenclature: %v", err)
+		return s.table
+	}
+	var importedAt time.Time
+	complete := false
+	if imported != nil {
+		importedAt = imported.UpdatedAt
+		complete = imported.Complete
+	}
+	if s.table != nil && s.importedAt.Equal(importedAt) {
+		return s.table
+	}
+
+	var codes []models.HSCode
+	if err == nil {
+		codes, err = s.repo.FindAll(ctx)
+	}
+	if err != nil {
+		// Not cached, the collection is read again on the next call
+		log.Printf("Warning: failed to load hs_codes, using bundled nomenclature: %v", err)
+		return buildHSTable(bundledHSCodes(), false)
+	}
+	if len(codes) == 0 {
+		codes = bundledHSCodes()
+		complete = false
+	}
+
+	s.table = buildHSTable(codes, complete)
+	s.importedAt = importedAt
+	return s.table
+}
+
+func bundledHSCodes() []models.HSCode {
+	entries, err := hscodes.Bundled()
+	if err != nil {
+		log.Printf("Warning: bundled HS nomenclature is unreadable: %v", err)
+	}
+	return hsCodesFromEntries(entries)
+}
+
+// Validate checks a single code; description is optional and only used for
+// the chapter conflict warning.
+func (s *hsCodeService) Validate(ctx context.Context, code, description string) models.HSCodeValidation {
+	return s.loadTable(ctx).validate(code, description)
+}
+
+// ValidateHBL validates the HS code of every container that declares one. It
+// returns all results (including warnings) and an *HSCodeValidationError when
+// any code is invalid.
+func (s *hsCodeService) ValidateHBL(ctx context.Context, data hbl_schema.HBLData) ([]models.HSCodeValidation, error) {
+	table := s.loadTable(ctx)
+
+	results := []models.HSCodeValidation{}
+	var invalid []models.HSCodeValidation
+	for _, container := range data.ContainerDetails {
+		if strings.TrimSpace(container.HSCode) == "" {
+			continue
+		}
+		result := table.validate(container.HSCode, container.DescriptionOfGoods)
+		results = append(results, result)
+		if !result.Valid {
+			invalid = append(invalid, result)
+		}
+	}
+
+	if len(invalid) > 0 {
+		return results, &HSCodeValidationError{Results: invalid}
+	}
+	return results, nil
+}
+
+// Suggest proposes nomenclature entries for a goods description
+func (s *hsCodeService) Suggest(ctx context.Context, description string, limit int) []models.HSCodeSuggestion {
+	return s.loadTable(ctx).suggest(description, limit)
+}
+
+// Import upserts nomenclature entries and refreshes the in-memory table.
+// complete marks the imported table as the full nomenclature, so unknown
+// codes are rejected rather than only warned about, and removes the codes
+// that are not in it.
+func (s *hsCodeService) Import(ctx context.Context, entries []hscodes.Entry, complete bool) (int64, error) {
+	codes := hsCodesFromEntries(entries)
+	count, err := s.repo.UpsertMany(ctx, codes)
+	if err != nil {
+		return 0, err
+	}
+	if complete && len(codes) > 0 {
+		keep := make([]string, 0, len(codes))
+		for _, code := range codes {
+			keep = append(keep, code.Code)
+		}
+		deleted, err := s.repo.DeleteExcept(ctx, keep)
+		if err != nil {
+			return 0, err
+		}
+		if deleted > 0 {
+			log.Printf("Removed %d HS codes that are not in the complete nomenclature", deleted)
+		}
+	}
+	if err := s.repo.SetComplete(ctx, complete); err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	s.table = nil
+	s.mu.Unlock()
+	return count, nil
+}
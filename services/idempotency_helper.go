@@ -0,0 +1,77 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"fs-backend/repository"
+)
+
+var (
+	ErrIdempotencyKeyReused     = errors.New("idempotency key was already used with a different request")
+	ErrIdempotencyKeyInProgress = errors.New("a request with this idempotency key is still in progress")
+)
+
+// withIdempotency runs fn at most once per (scope, key). The response of the
+// first successful run is stored and decoded into result, so a retry with the
+// same key and body gets the same response. A failed run releases the key.
+func withIdempotency(
+	ctx context.Context,
+	repo repository.IdempotencyRepository,
+	scope, key string,
+	request interface{},
+	result interface{},
+	fn func() (interface{}, error),
+) error {
+	requestJSON, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(requestJSON)
+	requestHash := hex.EncodeToString(sum[:])
+
+	reserved, err := repo.Reserve(ctx, &repository.IdempotencyRecord{Key: key, Scope: scope, RequestHash: requestHash})
+	if err != nil {
+		return fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+	if !reserved {
+		record, err := repo.Find(ctx, scope, key)
+		if err != nil {
+			return fmt.Errorf("failed to load idempotency key: %w", err)
+		}
+		if record == nil {
+			// Expired between Reserve and Find
+			return ErrIdempotencyKeyInProgress
+		}
+		if record.RequestHash != requestHash {
+			return ErrIdempotencyKeyReused
+		}
+		if !record.Completed {
+			return ErrIdempotencyKeyInProgress
+		}
+		log.Printf("Replaying %s response for idempotency key %s", scope, key)
+		return json.Unmarshal([]byte(record.ResponseJSON), result)
+	}
+
+	response, err := fn()
+	if err != nil {
+		if releaseErr := repo.Release(ctx, scope, key); releaseErr != nil {
+			log.Printf("Warning: failed to release idempotency key %s: %v", key, releaseErr)
+		}
+		return err
+	}
+
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+	if err := repo.Complete(ctx, scope, key, string(responseJSON)); err != nil {
+		log.Printf("Warning: failed to store response for idempotency key %s: %v", key, err)
+	}
+	return json.Unmarshal(responseJSON, result)
+}
@@ -0,0 +1,217 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"fs-backend/models"
+	"fs-backend/repository"
+)
+
+// defaultLoadPlanTypes are the container types a plan uses when none are given
+var defaultLoadPlanTypes = []string{"22G1", "42G1", "45G1"}
+
+// loadTolerance absorbs floating point noise when summing weights and volumes
+const loadTolerance = 1e-9
+
+// loadCapacity is what one container of a type may take in a plan
+type loadCapacity struct {
+	isoType     string
+	description string
+	payloadKg   float64
+	volumeCbm   float64
+	packages    int // 0 is unlimited
+}
+
+// loadPlanCapacities resolves the requested container types, largest volume
+// first. Limits may lower the nominal capacity of a type but not raise it.
+func loadPlanCapacities(types []models.LoadPlanContainerType) ([]loadCapacity, error) {
+	if len(types) == 0 {
+		for _, isoType := range defaultLoadPlanTypes {
+			types = append(types, models.LoadPlanContainerType{Type: isoType})
+		}
+	}
+
+	capacities := make([]loadCapacity, 0, len(types))
+	for _, containerType := range types {
+		nominal, ok := models.LookupContainerCapacity(containerType.Type)
+		if !ok {
+			return nil, fmt.Errorf("unknown container type %q", containerType.Type)
+		}
+		if containerType.MaxPayloadKg < 0 || containerType.MaxVolumeCbm < 0 || containerType.MaxPackages < 0 {
+			return nil, fmt.Errorf("container type %s: limits cannot be negative", containerType.Type)
+		}
+		if containerType.MaxPayloadKg > nominal.MaxPayloadKg || containerType.MaxVolumeCbm > nominal.MaxVolumeCbm {
+			return nil, fmt.Errorf("container type %s takes at most %.0f kg and %.1f cbm", containerType.Type, nominal.MaxPayloadKg, nominal.MaxVolumeCbm)
+		}
+
+		capacity := loadCapacity{
+			isoType:     nominal.ISOType,
+			description: nominal.Description,
+			payloadKg:   nominal.MaxPayloadKg,
+			volumeCbm:   nominal.MaxVolumeCbm,
+			packages:    containerType.MaxPackages,
+		}
+		if containerType.MaxPayloadKg > 0 {
+			capacity.payloadKg = containerType.MaxPayloadKg
+		}
+		if containerType.MaxVolumeCbm > 0 {
+			capacity.volumeCbm = containerType.MaxVolumeCbm
+		}
+		capacities = append(capacities, capacity)
+	}
+
+	sort.SliceStable(capacities, func(i, j int) bool {
+		if capacities[i].volumeCbm != capacities[j].volumeCbm {
+			return capacities[i].volumeCbm > capacities[j].volumeCbm
+		}
+		return capacities[i].payloadKg > capacities[j].payloadKg
+	})
+	return capacities, nil
+}
+
+// containerLoad is a container being filled by the planner
+type containerLoad struct {
+	capacity  loadCapacity
+	shipments []repository.ShipmentDocument
+	packages  int
+	weight    float64
+	volume    float64
+}
+
+// fitsLoad tells whether a container of a type can take a load, both by its
+// capacity and by the limits of one booking of the plan's mode
+func fitsLoad(capacity loadCapacity, rule models.BookingModeRule, shipments, packages int, weight, volume float64) bool {
+	return weight <= capacity.payloadKg+loadTolerance &&
+		volume <= capacity.volumeCbm+loadTolerance &&
+		(capacity.packages == 0 || packages <= capacity.packages) &&
+		(rule.MaxShipments == 0 || shipments <= rule.MaxShipments) &&
+		(rule.MaxWeightKg == 0 || weight <= rule.MaxWeightKg+loadTolerance) &&
+		(rule.MaxVolumeCbm == 0 || volume <= rule.MaxVolumeCbm+loadTolerance)
+}
+
+func (l *containerLoad) fits(shipment repository.ShipmentDocument, capacity loadCapacity, rule models.BookingModeRule) bool {
+	return fitsLoad(capacity, rule, len(l.shipments)+1, l.packages+shipment.PackagesCount, l.weight+shipment.GrossWeight, l.volume+shipment.Volume)
+}
+
+func (l *containerLoad) add(shipment repository.ShipmentDocument) {
+	l.shipments = append(l.shipments, shipment)
+	l.packages += shipment.PackagesCount
+	l.weight += shipment.GrossWeight
+	l.volume += shipment.Volume
+}
+
+// planLoad packs shipments into as few containers as it can with first-fit
+// decreasing: the bulkiest shipment goes first into the first open container
+// with room, a new container of the largest type that takes it is opened when
+// none has. Each container is then swapped for the smallest type its load fits.
+func planLoad(shipments []repository.ShipmentDocument, capacities []loadCapacity, rule models.BookingModeRule) ([]containerLoad, []models.UnplannedShipment) {
+	sorted := append([]repository.ShipmentDocument(nil), shipments...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Volume != sorted[j].Volume {
+			return sorted[i].Volume > sorted[j].Volume
+		}
+		return sorted[i].GrossWeight > sorted[j].GrossWeight
+	})
+
+	var (
+		loads     []containerLoad
+		unplanned []models.UnplannedShipment
+	)
+	for _, shipment := range sorted {
+		if shipment.GrossWeight < 0 || shipment.Volume < 0 || shipment.PackagesCount < 0 {
+			unplanned = append(unplanned, models.UnplannedShipment{ShipmentID: shipment.ShipmentID, Reason: "weight, volume and packages cannot be negative"})
+			continue
+		}
+
+		placed := false
+		for i := range loads {
+			if loads[i].fits(shipment, loads[i].capacity, rule) {
+				loads[i].add(shipment)
+				placed = true
+				break
+			}
+		}
+		if placed {
+			continue
+		}
+
+		for _, capacity := range capacities {
+			load := containerLoad{capacity: capacity}
+			if load.fits(shipment, capacity, rule) {
+				load.add(shipment)
+				loads = append(loads, load)
+				placed = true
+				break
+			}
+		}
+		if !placed {
+			unplanned = append(unplanned, models.UnplannedShipment{ShipmentID: shipment.ShipmentID, Reason: oversizeReason(shipment, capacities, rule)})
+		}
+	}
+
+	for i := range loads {
+		for j := len(capacities) - 1; j >= 0; j-- {
+			load := loads[i]
+			if fitsLoad(capacities[j], rule, len(load.shipments), load.packages, load.weight, load.volume) {
+				loads[i].capacity = capacities[j]
+				break
+			}
+		}
+	}
+	return loads, unplanned
+}
+
+// oversizeReason explains why a shipment fits no container on its own
+func oversizeReason(shipment repository.ShipmentDocument, capacities []loadCapacity, rule models.BookingModeRule) string {
+	var maxWeight, maxVolume float64
+	for _, capacity := range capacities {
+		maxWeight = math.Max(maxWeight, capacity.payloadKg)
+		maxVolume = math.Max(maxVolume, capacity.volumeCbm)
+	}
+	if rule.MaxWeightKg > 0 {
+		maxWeight = math.Min(maxWeight, rule.MaxWeightKg)
+	}
+	if rule.MaxVolumeCbm > 0 {
+		maxVolume = math.Min(maxVolume, rule.MaxVolumeCbm)
+	}
+
+	switch {
+	case shipment.GrossWeight > maxWeight+loadTolerance:
+		return fmt.Sprintf("gross weight %.0f kg is more than the %.0f kg one container can take", shipment.GrossWeight, maxWeight)
+	case shipment.Volume > maxVolume+loadTolerance:
+		return fmt.Sprintf("volume %.2f cbm is more than the %.2f cbm one container can take", shipment.Volume, maxVolume)
+	default:
+		return fmt.Sprintf("no container type takes its %.0f kg, %.2f cbm and %d packages together", shipment.GrossWeight, shipment.Volume, shipment.PackagesCount)
+	}
+}
+
+// plannedContainers reports the loads of a plan with their utilisation
+func plannedContainers(loads []containerLoad) []models.PlannedContainer {
+	containers := make([]models.PlannedContainer, len(loads))
+	for i, load := range loads {
+		container := models.PlannedContainer{
+			Sequence:     i + 1,
+			ISOType:      load.capacity.isoType,
+			Description:  load.capacity.description,
+			ShipmentIDs:  make([]string, len(load.shipments)),
+			Packages:     load.packages,
+			GrossWeight:  load.weight,
+			Volume:       load.volume,
+			MaxPayloadKg: load.capacity.payloadKg,
+			MaxVolumeCbm: load.capacity.volumeCbm,
+		}
+		for j, shipment := range load.shipments {
+			container.ShipmentIDs[j] = shipment.ShipmentID
+		}
+		if container.MaxPayloadKg > 0 {
+			container.WeightUtilisation = math.Round(container.GrossWeight/container.MaxPayloadKg*1000) / 10
+		}
+		if container.MaxVolumeCbm > 0 {
+			container.VolumeUtilisation = math.Round(container.Volume/container.MaxVolumeCbm*1000) / 10
+		}
+		containers[i] = container
+	}
+	return containers
+}
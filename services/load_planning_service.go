@@ -0,0 +1,97 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"fs-backend/models"
+	"fs-backend/repository"
+)
+
+var ErrInvalidLoadPlan = errors.New("invalid load plan request")
+
+// LoadPlanningService proposes how candidate shipments of a consolidation are
+// spread over containers, so each container can be synced as one booking
+type LoadPlanningService interface {
+	PlanLoad(ctx context.Context, req models.LoadPlanRequest) (*models.LoadPlan, error)
+}
+
+type loadPlanningService struct {
+	shipmentRepo repository.ShipmentRepository
+	bookingRepo  repository.BookingRepository
+	modeRuleRepo repository.BookingModeRuleRepository
+}
+
+// NewLoadPlanningService creates a new LoadPlanningService
+func NewLoadPlanningService(
+	shipmentRepo repository.ShipmentRepository,
+	bookingRepo repository.BookingRepository,
+	modeRuleRepo repository.BookingModeRuleRepository,
+) LoadPlanningService {
+	return &loadPlanningService{
+		shipmentRepo: shipmentRepo,
+		bookingRepo:  bookingRepo,
+		modeRuleRepo: modeRuleRepo,
+	}
+}
+
+// PlanLoad packs the candidate shipments into the fewest containers of the
+// given types. Shipments that could not be synced (unknown, already booked,
+// of another mode) or that fit no container are listed as unplanned. Nothing
+// is stored.
+func (s *loadPlanningService) PlanLoad(ctx context.Context, req models.LoadPlanRequest) (*models.LoadPlan, error) {
+	shipmentIDs := uniqueShipmentIDs(req.ShipmentIDs)
+	if len(shipmentIDs) == 0 {
+		return nil, fmt.Errorf("%w: no shipments selected", ErrInvalidLoadPlan)
+	}
+	capacities, err := loadPlanCapacities(req.ContainerTypes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidLoadPlan, err)
+	}
+	rules, err := loadBookingModeRules(ctx, s.modeRuleRepo, req.ForwarderID)
+	if err != nil {
+		return nil, err
+	}
+	rule, err := findBookingModeRule(rules, firstNonEmpty(req.Mode, models.BookingModeLCL))
+	if err != nil {
+		return nil, err
+	}
+
+	shipments, err := s.shipmentRepo.FindByShipmentIDs(ctx, shipmentIDs)
+	if err != nil {
+		return nil, err
+	}
+	booked, err := s.bookingRepo.FindByShipmentIDs(ctx, shipmentIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &models.LoadPlan{
+		Mode:       rule.Mode,
+		Containers: []models.PlannedContainer{},
+		Unplanned:  []models.UnplannedShipment{},
+	}
+	rejected := make(map[string]bool)
+	for _, problem := range validateSyncShipments("", rule, shipmentIDs, shipments, booked, nil) {
+		if !rejected[problem.ShipmentID] {
+			plan.Unplanned = append(plan.Unplanned, models.UnplannedShipment{ShipmentID: problem.ShipmentID, Reason: problem.Message})
+		}
+		rejected[problem.ShipmentID] = true
+	}
+	var candidates []repository.ShipmentDocument
+	for _, shipment := range shipments {
+		if !rejected[shipment.ShipmentID] {
+			candidates = append(candidates, shipment)
+		}
+	}
+
+	loads, unplanned := planLoad(candidates, capacities, rule)
+	plan.Containers = append(plan.Containers, plannedContainers(loads)...)
+	plan.Unplanned = append(plan.Unplanned, unplanned...)
+	for _, container := range plan.Containers {
+		plan.GrossWeight += container.GrossWeight
+		plan.Volume += container.Volume
+	}
+	return plan, nil
+}
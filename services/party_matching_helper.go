@@ -0,0 +1,205 @@
+package services
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"fs-backend/models/mbl_schema"
+	"fs-backend/repository"
+)
+
+const (
+	// partyMatchThreshold is the minimum combined score for a shipper to be suggested
+	partyMatchThreshold = 0.55
+	// partyMatchLimit caps the number of candidates returned per party
+	partyMatchLimit = 3
+	// partyExactMatchScore is treated as "this shipper already exists"
+	partyExactMatchScore = 0.95
+)
+
+// legalEntityWords are dropped from party names before comparison so that
+// "ACME TRADING CO., LTD" and "Acme Trading Company Limited" compare equal.
+var legalEntityWords = map[string]bool{
+	"THE": true, "CO": true, "COMPANY": true, "CORP": true, "CORPORATION": true,
+	"LTD": true, "LIMITED": true, "PVT": true, "PRIVATE": true, "INC": true,
+	"INCORPORATED": true, "LLC": true, "LLP": true, "PLC": true, "GMBH": true,
+	"AG": true, "SA": true, "SRL": true, "BV": true, "NV": true, "PTE": true,
+	"SDN": true, "BHD": true, "KG": true, "SPA": true, "OY": true, "AB": true,
+}
+
+// addressAbbreviations folds common address words to a single spelling.
+var addressAbbreviations = map[string]string{
+	"ROAD": "RD", "STREET": "ST", "AVENUE": "AVE", "BUILDING": "BLDG",
+	"FLOOR": "FL", "SUITE": "STE", "DISTRICT": "DIST", "INDUSTRIAL": "IND",
+	"ESTATE": "EST", "NUMBER": "NO", "NAGAR": "NGR", "BOULEVARD": "BLVD",
+}
+
+// partyTokens upper-cases the text, strips punctuation and splits it into words.
+func partyTokens(text string) []string {
+	cleaned := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToUpper(r)
+		}
+		return ' '
+	}, text)
+	return strings.Fields(cleaned)
+}
+
+// normalizePartyName returns the comparable form of a company name.
+func normalizePartyName(name string) string {
+	var kept []string
+	for _, token := range partyTokens(name) {
+		if legalEntityWords[token] {
+			continue
+		}
+		kept = append(kept, token)
+	}
+	return strings.Join(kept, " ")
+}
+
+// addressTokens returns the distinct, abbreviated words of an address.
+func addressTokens(address string) []string {
+	seen := make(map[string]bool)
+	var tokens []string
+	for _, token := range partyTokens(address) {
+		if abbr, ok := addressAbbreviations[token]; ok {
+			token = abbr
+		}
+		if len(token) < 2 || seen[token] {
+			continue
+		}
+		seen[token] = true
+		tokens = append(tokens, token)
+	}
+	return tokens
+}
+
+// tokenOverlap returns the Sørensen–Dice coefficient of two token sets.
+func tokenOverlap(a, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	set := make(map[string]bool, len(a))
+	for _, token := range a {
+		set[token] = true
+	}
+	shared := 0
+	for _, token := range b {
+		if set[token] {
+			shared++
+			delete(set, token)
+		}
+	}
+	return 2 * float64(shared) / float64(len(a)+len(b))
+}
+
+// stringSimilarity returns 1 - normalized Levenshtein distance.
+func stringSimilarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) == 0 && len(rb) == 0 {
+		return 1
+	}
+	if len(ra) == 0 || len(rb) == 0 {
+		return 0
+	}
+
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return 1 - float64(prev[len(rb)])/float64(max(len(ra), len(rb)))
+}
+
+// scorePartyMatch compares an extracted party with a shipper master record.
+// The name dominates the score; the address only contributes when both sides have one.
+func scorePartyMatch(name, address string, shipper repository.ShipperDocument) (nameScore, addressScore, score float64) {
+	extracted := normalizePartyName(name)
+	master := normalizePartyName(shipper.ShipperName)
+	if extracted == "" || master == "" {
+		return 0, 0, 0
+	}
+
+	nameScore = max(
+		stringSimilarity(extracted, master),
+		tokenOverlap(strings.Fields(extracted), strings.Fields(master)),
+	)
+
+	extractedAddr := addressTokens(address)
+	masterAddr := addressTokens(shipper.ShipperAddress)
+	if len(extractedAddr) == 0 || len(masterAddr) == 0 {
+		return nameScore, 0, nameScore
+	}
+
+	addressScore = tokenOverlap(extractedAddr, masterAddr)
+	score = 0.7*nameScore + 0.3*addressScore
+	return nameScore, addressScore, score
+}
+
+// rankShipperCandidates scores every shipper against the party and returns the
+// best matches above partyMatchThreshold.
+func rankShipperCandidates(name, address string, shippers []repository.ShipperDocument) []mbl_schema.ShipperCandidate {
+	candidates := []mbl_schema.ShipperCandidate{}
+	if strings.TrimSpace(name) == "" {
+		return candidates
+	}
+
+	for _, shipper := range shippers {
+		nameScore, addressScore, score := scorePartyMatch(name, address, shipper)
+		if score < partyMatchThreshold {
+			continue
+		}
+		candidates = append(candidates, mbl_schema.ShipperCandidate{
+			ShipperID:      shipper.ShipperID,
+			ShipperName:    shipper.ShipperName,
+			ShipperAddress: shipper.ShipperAddress,
+			Score:          roundScore(score),
+			NameScore:      roundScore(nameScore),
+			AddressScore:   roundScore(addressScore),
+		})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Score > candidates[j].Score
+	})
+	if len(candidates) > partyMatchLimit {
+		candidates = candidates[:partyMatchLimit]
+	}
+	return candidates
+}
+
+func roundScore(v float64) float64 {
+	return float64(int(v*1000+0.5)) / 1000
+}
+
+// mblPartyDetails returns the name, address and contact of the requested MBL party.
+// party is one of "shipper", "consignee" or "notify_party".
+func mblPartyDetails(mbl mbl_schema.MBLData, party string) (name, address, contact string, ok bool) {
+	switch party {
+	case "shipper":
+		return mbl.Shipper.Name, mbl.Shipper.Address, mbl.Shipper.Phone, true
+	case "consignee":
+		contact = mbl.Consignee.Phone
+		if contact == "" {
+			contact = mbl.Consignee.Email
+		}
+		return mbl.Consignee.Name, mbl.Consignee.Address, contact, true
+	case "notify_party":
+		return mbl.NotifyParty.Name, mbl.NotifyParty.Address, "", true
+	default:
+		return "", "", "", false
+	}
+}
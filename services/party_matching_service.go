@@ -0,0 +1,104 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"fs-backend/models/mbl_schema"
+	"fs-backend/repository"
+)
+
+var (
+	ErrUnknownParty         = errors.New("party must be one of shipper, consignee or notify_party")
+	ErrPartyNameMissing     = errors.New("party name was not extracted from the MBL")
+	ErrShipperAlreadyExists = errors.New("a matching shipper already exists")
+)
+
+// PartyMatchingService suggests shipper master records for the parties found on an MBL
+type PartyMatchingService interface {
+	MatchParties(ctx context.Context, mbl mbl_schema.MBLData) (*mbl_schema.PartyMatchResult, error)
+	MatchMBLParties(ctx context.Context, mblNumber string) (*mbl_schema.PartyMatchResult, error)
+	CreateShipperFromParty(ctx context.Context, mblNumber, party string, force bool) (*repository.ShipperDocument, *mbl_schema.ShipperCandidate, error)
+}
+
+type partyMatchingService struct {
+	mblRepo     repository.MBLRepository
+	shipperRepo repository.ShipperRepository
+	shipperIDs  *shipperIDAllocator
+}
+
+// NewPartyMatchingService creates a new PartyMatchingService
+func NewPartyMatchingService(mblRepo repository.MBLRepository, shipperRepo repository.ShipperRepository, counterRepo repository.CounterRepository) PartyMatchingService {
+	return &partyMatchingService{
+		mblRepo:     mblRepo,
+		shipperRepo: shipperRepo,
+		shipperIDs:  newShipperIDAllocator(shipperRepo, counterRepo),
+	}
+}
+
+// MatchParties scores the MBL shipper, consignee and notify party against the
+// shippers collection and returns the best candidates for each.
+func (s *partyMatchingService) MatchParties(ctx context.Context, mbl mbl_schema.MBLData) (*mbl_schema.PartyMatchResult, error) {
+	shippers, err := s.shipperRepo.FindAllShippers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch shippers: %w", err)
+	}
+
+	return &mbl_schema.PartyMatchResult{
+		Shipper:     rankShipperCandidates(mbl.Shipper.Name, mbl.Shipper.Address, shippers),
+		Consignee:   rankShipperCandidates(mbl.Consignee.Name, mbl.Consignee.Address, shippers),
+		NotifyParty: rankShipperCandidates(mbl.NotifyParty.Name, mbl.NotifyParty.Address, shippers),
+	}, nil
+}
+
+// MatchMBLParties runs MatchParties for an MBL already stored in the DB
+func (s *partyMatchingService) MatchMBLParties(ctx context.Context, mblNumber string) (*mbl_schema.PartyMatchResult, error) {
+	mblDoc, err := s.mblRepo.FindByMBLNumber(ctx, mblNumber)
+	if err != nil {
+		return nil, fmt.Errorf("MBL not found for number %s: %w", mblNumber, err)
+	}
+	return s.MatchParties(ctx, mblDoc.MBL)
+}
+
+// CreateShipperFromParty creates a shipper master record from an extracted MBL party.
+// Unless force is set, creation is refused when a near-identical shipper already
+// exists; that shipper is returned as the second value.
+func (s *partyMatchingService) CreateShipperFromParty(ctx context.Context, mblNumber, party string, force bool) (*repository.ShipperDocument, *mbl_schema.ShipperCandidate, error) {
+	mblDoc, err := s.mblRepo.FindByMBLNumber(ctx, mblNumber)
+	if err != nil {
+		return nil, nil, fmt.Errorf("MBL not found for number %s: %w", mblNumber, err)
+	}
+
+	name, address, contact, ok := mblPartyDetails(mblDoc.MBL, party)
+	if !ok {
+		return nil, nil, ErrUnknownParty
+	}
+	if strings.TrimSpace(name) == "" {
+		return nil, nil, ErrPartyNameMissing
+	}
+
+	if !force {
+		shippers, err := s.shipperRepo.FindAllShippers(ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to fetch shippers: %w", err)
+		}
+		candidates := rankShipperCandidates(name, address, shippers)
+		if len(candidates) > 0 && candidates[0].Score >= partyExactMatchScore {
+			return nil, &candidates[0], ErrShipperAlreadyExists
+		}
+	}
+
+	doc := repository.ShipperDocument{
+		ShipperName:    strings.TrimSpace(name),
+		ShipperAddress: strings.TrimSpace(address),
+		ShipperContact: strings.TrimSpace(contact),
+	}
+	oid, err := s.shipperIDs.Insert(ctx, &doc)
+	if err != nil {
+		return nil, nil, err
+	}
+	doc.ID = oid
+	return &doc, nil, nil
+}
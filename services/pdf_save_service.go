@@ -3,6 +3,7 @@ package services
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"fs-backend/models"
 	"fs-backend/repository"
@@ -28,9 +29,10 @@ func (s *pdfSaveService) Save(ctx context.Context, req models.PdfSaveRequest) (*
 		}
 
 		docs = append(docs, models.HBLDoc{
-			Filename: file.Filename,
-			Type:     file.Type,
-			URL:      file.URL,
+			HBLNumber: fileHBLNumber(file, req.HBLNumbers),
+			Filename:  file.Filename,
+			Type:      file.Type,
+			URL:       file.URL,
 		})
 	}
 
@@ -44,3 +46,47 @@ func (s *pdfSaveService) Save(ctx context.Context, req models.PdfSaveRequest) (*
 		SavedCount: len(docs),
 	}, nil
 }
+
+// fileHBLNumber returns the HBL a generated file belongs to: the one the
+// generator reported, when it is one of hblNumbers, or else the one named in
+// the filename
+func fileHBLNumber(file models.PdfGeneratorUploadedFile, hblNumbers []string) string {
+	for _, hblNumber := range hblNumbers {
+		if hblNumber != "" && strings.EqualFold(strings.TrimSpace(file.HBLNumber), hblNumber) {
+			return hblNumber
+		}
+	}
+	return matchHBLNumber(file.Filename, hblNumbers)
+}
+
+// matchHBLNumber returns the longest HBL number the filename contains as a
+// whole, i.e. not followed or preceded by another letter or digit, so that
+// HBL001 does not match HBL0010.pdf
+func matchHBLNumber(filename string, hblNumbers []string) string {
+	upper := strings.ToUpper(filename)
+	match := ""
+	for _, hblNumber := range hblNumbers {
+		if hblNumber != "" && len(hblNumber) > len(match) && containsWholeToken(upper, strings.ToUpper(hblNumber)) {
+			match = hblNumber
+		}
+	}
+	return match
+}
+
+func containsWholeToken(s, token string) bool {
+	for offset := 0; ; {
+		i := strings.Index(s[offset:], token)
+		if i < 0 {
+			return false
+		}
+		start, end := offset+i, offset+i+len(token)
+		if (start == 0 || !isAlphanumeric(s[start-1])) && (end == len(s) || !isAlphanumeric(s[end])) {
+			return true
+		}
+		offset = start + 1
+	}
+}
+
+func isAlphanumeric(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z'
+}
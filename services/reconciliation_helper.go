@@ -0,0 +1,152 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"fs-backend/models"
+	"fs-backend/models/hbl_schema"
+	"fs-backend/models/mbl_schema"
+)
+
+// weightToKg converts a weight to kilograms; unknown units are taken as kilograms
+func weightToKg(value float64, unit string) float64 {
+	switch strings.ToUpper(strings.TrimSuffix(strings.TrimSpace(unit), ".")) {
+	case "LB", "LBS":
+		return value * 0.45359237
+	case "MT", "T", "TON", "TONS", "TNE":
+		return value * 1000
+	}
+	return value
+}
+
+// volumeToCbm converts a volume to cubic metres; unknown units are taken as cubic metres
+func volumeToCbm(value float64, unit string) float64 {
+	switch strings.ToUpper(strings.TrimSuffix(strings.TrimSpace(unit), ".")) {
+	case "CFT", "CUFT", "FT3":
+		return value * 0.0283168466
+	}
+	return value
+}
+
+func mblTotals(cargo mbl_schema.Cargo) models.ReconciliationTotals {
+	return models.ReconciliationTotals{
+		Packages:    cargo.NumberOfPackages,
+		GrossWeight: weightToKg(cargo.GrossWeight.Value, cargo.GrossWeight.Unit),
+		Volume:      volumeToCbm(cargo.Measurement.Value, cargo.Measurement.Unit),
+	}
+}
+
+func hblTotals(data hbl_schema.HBLData) models.ReconciliationTotals {
+	var totals models.ReconciliationTotals
+	for _, container := range data.ContainerDetails {
+		totals.Packages += container.PackageCount
+		totals.GrossWeight += weightToKg(container.GrossWeight.Value, container.GrossWeight.Unit)
+		totals.Volume += volumeToCbm(container.Measurement.Value, container.Measurement.Unit)
+	}
+	return totals
+}
+
+func addTotals(a, b models.ReconciliationTotals) models.ReconciliationTotals {
+	return models.ReconciliationTotals{
+		Packages:    a.Packages + b.Packages,
+		GrossWeight: a.GrossWeight + b.GrossWeight,
+		Volume:      a.Volume + b.Volume,
+	}
+}
+
+// compareTotals reports the totals that differ by more than the tolerances.
+// Totals the MBL does not state (zero) are not compared.
+func compareTotals(expected, actual models.ReconciliationTotals, tolerances models.ReconciliationTolerances) []models.ReconciliationMismatch {
+	var mismatches []models.ReconciliationMismatch
+
+	if expected.Packages > 0 && absInt(expected.Packages-actual.Packages) > tolerances.Packages {
+		mismatches = append(mismatches, models.ReconciliationMismatch{
+			Field:     "packages",
+			Expected:  fmt.Sprintf("%d", expected.Packages),
+			Actual:    fmt.Sprintf("%d", actual.Packages),
+			Tolerance: float64(tolerances.Packages),
+			Severity:  models.ReconciliationError,
+		})
+	}
+	if expected.GrossWeight > 0 && percentDiff(expected.GrossWeight, actual.GrossWeight) > tolerances.WeightPercent {
+		mismatches = append(mismatches, models.ReconciliationMismatch{
+			Field:     "gross_weight_kg",
+			Expected:  fmt.Sprintf("%.3f", expected.GrossWeight),
+			Actual:    fmt.Sprintf("%.3f", actual.GrossWeight),
+			Tolerance: tolerances.WeightPercent,
+			Severity:  models.ReconciliationError,
+		})
+	}
+	if expected.Volume > 0 && percentDiff(expected.Volume, actual.Volume) > tolerances.VolumePercent {
+		mismatches = append(mismatches, models.ReconciliationMismatch{
+			Field:     "volume_cbm",
+			Expected:  fmt.Sprintf("%.3f", expected.Volume),
+			Actual:    fmt.Sprintf("%.3f", actual.Volume),
+			Tolerance: tolerances.VolumePercent,
+			Severity:  models.ReconciliationError,
+		})
+	}
+	return mismatches
+}
+
+// compareRouting reports ports and vessels on the HBL that disagree with the
+// MBL. Place of receipt and delivery may legitimately differ on house bills.
+func compareRouting(mbl mbl_schema.MBLData, hblNumber string, data hbl_schema.HBLData) []models.ReconciliationMismatch {
+	var mismatches []models.ReconciliationMismatch
+	compare := func(field, expected, actual string) {
+		if normalizeLocation(expected) == "" || normalizeLocation(expected) == normalizeLocation(actual) {
+			return
+		}
+		mismatches = append(mismatches, models.ReconciliationMismatch{
+			Field:     field,
+			HBLNumber: hblNumber,
+			Expected:  expected,
+			Actual:    actual,
+			Severity:  models.ReconciliationError,
+		})
+	}
+
+	compare("routing.port_of_loading", mbl.Routing.PortOfLoading, data.Routing.PortOfLoading)
+	compare("routing.port_of_discharge", mbl.Routing.PortOfDischarge, data.Routing.PortOfDischarge)
+
+	if normalizeLocation(mbl.VesselDetails.VesselName) == "" {
+		return mismatches
+	}
+	var actual []string
+	for _, vessel := range data.VesselDetails {
+		if normalizeLocation(vessel.VesselName) == normalizeLocation(mbl.VesselDetails.VesselName) &&
+			(mbl.VesselDetails.VoyageNo == "" || normalizeLocation(vessel.VoyageNo) == normalizeLocation(mbl.VesselDetails.VoyageNo)) {
+			return mismatches
+		}
+		actual = append(actual, strings.TrimSpace(vessel.VesselName+" "+vessel.VoyageNo))
+	}
+	mismatches = append(mismatches, models.ReconciliationMismatch{
+		Field:     "vessel_details",
+		HBLNumber: hblNumber,
+		Expected:  strings.TrimSpace(mbl.VesselDetails.VesselName + " " + mbl.VesselDetails.VoyageNo),
+		Actual:    strings.Join(actual, ", "),
+		Severity:  models.ReconciliationError,
+	})
+	return mismatches
+}
+
+// normalizeLocation folds case, punctuation and spacing so "Shanghai, CN" and "SHANGHAI CN" compare equal
+func normalizeLocation(value string) string {
+	fields := strings.FieldsFunc(strings.ToUpper(value), func(r rune) bool {
+		return !(r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+	})
+	return strings.Join(fields, " ")
+}
+
+func percentDiff(expected, actual float64) float64 {
+	return math.Abs(expected-actual) / expected * 100
+}
+
+func absInt(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"fs-backend/models"
+	"fs-backend/models/hbl_schema"
+	"fs-backend/repository"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ErrReconciliationBlocked is returned when PDF generation is refused because
+// the HBLs do not reconcile with their MBL
+var ErrReconciliationBlocked = errors.New("HBLs do not reconcile with the MBL")
+
+// ReconciliationService checks that the HBLs under an MBL add up to it
+type ReconciliationService interface {
+	Reconcile(ctx context.Context, mblNumber string) (*models.ReconciliationReport, error)
+	CheckPDFGeneration(ctx context.Context, mblNumber string) (*models.ReconciliationReport, error)
+}
+
+type reconciliationService struct {
+	mblRepo     repository.MBLRepository
+	hblRepo     repository.HBLRepository
+	hblDocRepo  repository.HBLDocRepository
+	bookingRepo repository.BookingRepository
+	tolerances  models.ReconciliationTolerances
+}
+
+// NewReconciliationService creates a new ReconciliationService
+func NewReconciliationService(
+	mblRepo repository.MBLRepository,
+	hblRepo repository.HBLRepository,
+	hblDocRepo repository.HBLDocRepository,
+	bookingRepo repository.BookingRepository,
+	tolerances models.ReconciliationTolerances,
+) ReconciliationService {
+	return &reconciliationService{
+		mblRepo:     mblRepo,
+		hblRepo:     hblRepo,
+		hblDocRepo:  hblDocRepo,
+		bookingRepo: bookingRepo,
+		tolerances:  tolerances,
+	}
+}
+
+// Reconcile builds the report for an MBL. Void HBLs are ignored.
+func (s *reconciliationService) Reconcile(ctx context.Context, mblNumber string) (*models.ReconciliationReport, error) {
+	mblDoc, err := s.mblRepo.FindByMBLNumber(ctx, mblNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	docs, err := s.hblRepo.FindByMBLNumber(ctx, mblNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch HBLs: %w", err)
+	}
+	var hbls []hbl_schema.HBLDocument
+	for _, doc := range docs {
+		if doc.Status != hbl_schema.HBLStatusVoid {
+			hbls = append(hbls, doc)
+		}
+	}
+
+	report := &models.ReconciliationReport{
+		MBLNumber:        mblNumber,
+		GeneratedAt:      time.Now(),
+		HBLCount:         len(hbls),
+		MBLTotals:        mblTotals(mblDoc.MBL.Cargo),
+		Tolerances:       s.tolerances,
+		Mismatches:       []models.ReconciliationMismatch{},
+		MissingShipments: []string{},
+		UnbookedHBLs:     []string{},
+		HBLsWithoutPDF:   []string{},
+	}
+
+	// Totals and per-HBL routing
+	hblNumbers := make([]string, 0, len(hbls))
+	hblShipments := make(map[string]bool, len(hbls))
+	for _, doc := range hbls {
+		report.HBLTotals = addTotals(report.HBLTotals, hblTotals(doc.HBL))
+		report.Mismatches = append(report.Mismatches, compareRouting(mblDoc.MBL, doc.HBLNumber, doc.HBL)...)
+		hblNumbers = append(hblNumbers, doc.HBLNumber)
+		hblShipments[doc.ShipmentID] = true
+	}
+	report.HBLTotals.GrossWeight = math.Round(report.HBLTotals.GrossWeight*1000) / 1000
+	report.HBLTotals.Volume = math.Round(report.HBLTotals.Volume*1000) / 1000
+	if len(hbls) > 0 {
+		report.Mismatches = append(compareTotals(report.MBLTotals, report.HBLTotals, s.tolerances), report.Mismatches...)
+	}
+
+	// Booking coverage
+	booking, err := s.bookingRepo.FindByMBLNumber(ctx, mblNumber)
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		report.Mismatches = append(report.Mismatches, models.ReconciliationMismatch{
+			Field:    "booking",
+			Expected: "booking for " + mblNumber,
+			Actual:   "none",
+			Severity: models.ReconciliationWarning,
+		})
+	case err != nil:
+		return nil, fmt.Errorf("failed to fetch booking: %w", err)
+	default:
+		booked := make(map[string]bool, len(booking.ShipmentIDs))
+		for _, shipmentID := range booking.ShipmentIDs {
+			booked[shipmentID] = true
+			if !hblShipments[shipmentID] {
+				report.MissingShipments = append(report.MissingShipments, shipmentID)
+			}
+		}
+		for _, doc := range hbls {
+			if !booked[doc.ShipmentID] {
+				report.UnbookedHBLs = append(report.UnbookedHBLs, doc.HBLNumber)
+			}
+		}
+	}
+
+	// Generated documents
+	if len(hblNumbers) > 0 {
+		withDocs, err := s.hblDocRepo.HBLNumbersWithDocs(ctx, hblNumbers)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch HBL documents: %w", err)
+		}
+		hasDoc := make(map[string]bool, len(withDocs))
+		for _, hblNumber := range withDocs {
+			hasDoc[hblNumber] = true
+		}
+		for _, hblNumber := range hblNumbers {
+			if !hasDoc[hblNumber] {
+				report.HBLsWithoutPDF = append(report.HBLsWithoutPDF, hblNumber)
+			}
+		}
+	}
+
+	hasErrors := false
+	for _, mismatch := range report.Mismatches {
+		if mismatch.Severity == models.ReconciliationError {
+			hasErrors = true
+			break
+		}
+	}
+	report.Consistent = !hasErrors && len(report.MissingShipments) == 0 && len(report.UnbookedHBLs) == 0
+	report.BlocksPDF = hasErrors && s.tolerances.BlockPDFOnMismatches
+	return report, nil
+}
+
+// CheckPDFGeneration returns ErrReconciliationBlocked (with the report) when
+// blocking is enabled and the MBL's HBLs are out of tolerance. MBLs that are
+// not stored are not checked.
+func (s *reconciliationService) CheckPDFGeneration(ctx context.Context, mblNumber string) (*models.ReconciliationReport, error) {
+	if !s.tolerances.BlockPDFOnMismatches {
+		return nil, nil
+	}
+
+	report, err := s.Reconcile(ctx, mblNumber)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if report.BlocksPDF {
+		return report, ErrReconciliationBlocked
+	}
+	return report, nil
+}
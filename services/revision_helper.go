@@ -0,0 +1,14 @@
+package services
+
+import "fmt"
+
+// RevisionConflictError is returned when an update was based on an outdated
+// revision. Current holds the stored document so the client can merge.
+type RevisionConflictError struct {
+	Revision int64
+	Current  interface{}
+}
+
+func (e *RevisionConflictError) Error() string {
+	return fmt.Sprintf("document was modified by someone else (current revision %d)", e.Revision)
+}
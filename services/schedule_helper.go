@@ -0,0 +1,100 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"fs-backend/repository"
+)
+
+var ErrInvalidSchedule = errors.New("invalid schedule")
+
+// scheduleDateLayouts are the formats accepted for ETD, ETA and desired
+// delivery dates, most specific first
+var scheduleDateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	bookingDateLayout,
+}
+
+// ParseScheduleDate parses an ETD, ETA or delivery date. Offsets in RFC 3339
+// timestamps are honoured; timestamps without one are taken as UTC and plain
+// dates as midnight UTC. The result is always in UTC, and nil for an empty value.
+func ParseScheduleDate(value string) (*time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	for _, layout := range scheduleDateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			t = t.UTC()
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("%q is not a date (YYYY-MM-DD) or an RFC 3339 timestamp", value)
+}
+
+// parseLegacyScheduleDate also accepts the day-first and written-out dates
+// found in documents stored before dates were typed
+func parseLegacyScheduleDate(value string) (*time.Time, error) {
+	t, err := ParseScheduleDate(value)
+	if err == nil {
+		return t, nil
+	}
+	for _, layout := range documentDateLayouts {
+		if parsed, parseErr := time.Parse(layout, strings.TrimSpace(value)); parseErr == nil {
+			parsed = parsed.UTC()
+			return &parsed, nil
+		}
+	}
+	return nil, err
+}
+
+// ParseDateRange builds a list filter from from/to query values. A plain "to"
+// date includes the whole of that day.
+func ParseDateRange(from, to string) (repository.DateRange, error) {
+	var (
+		dateRange repository.DateRange
+		err       error
+	)
+	if dateRange.From, err = ParseScheduleDate(from); err != nil {
+		return dateRange, err
+	}
+	if dateRange.To, err = ParseScheduleDate(to); err != nil {
+		return dateRange, err
+	}
+	if dateRange.To != nil {
+		if _, dateOnly := time.Parse(bookingDateLayout, strings.TrimSpace(to)); dateOnly == nil {
+			end := dateRange.To.AddDate(0, 0, 1)
+			dateRange.To = &end
+		}
+	}
+	if dateRange.From != nil && dateRange.To != nil && !dateRange.From.Before(*dateRange.To) {
+		return dateRange, fmt.Errorf("range start %s is not before its end %s", strings.TrimSpace(from), strings.TrimSpace(to))
+	}
+	return dateRange, nil
+}
+
+// validateSchedule checks that a booking arrives after it departs
+func validateSchedule(departure, arrival *time.Time) error {
+	if departure != nil && arrival != nil && !arrival.After(*departure) {
+		return fmt.Errorf("%w: estimated arrival %s must be after estimated departure %s",
+			ErrInvalidSchedule, arrival.Format(time.RFC3339), departure.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// validateDeliveryDate checks that a shipment can be delivered on the date its
+// customer wants, i.e. not before the day its booking arrives
+func validateDeliveryDate(shipmentID string, desired, arrival *time.Time) error {
+	if desired == nil || arrival == nil || daysBetween(*arrival, *desired) >= 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: shipment %s cannot be delivered by %s, the booking only arrives on %s",
+		ErrInvalidSchedule, shipmentID, desired.Format(bookingDateLayout), arrival.Format(bookingDateLayout))
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"fs-backend/repository"
+)
+
+// ScheduleMigrationResult summarises the conversion of one collection
+type ScheduleMigrationResult struct {
+	Collection string
+	Converted  int
+	Cleared    int      // empty strings
+	Unparsed   []string // "<id> <field>: <value>", moved to <field>_unparsed
+}
+
+// MigrateScheduleDates converts the booking ETD/ETA and shipment desired
+// delivery dates still stored as strings into dates. Values that are not
+// dates are kept in <field>_unparsed for manual review. With dryRun nothing
+// is written. Running it again only touches documents not converted yet.
+func MigrateScheduleDates(ctx context.Context, repo repository.DateMigrationRepository, dryRun bool) ([]ScheduleMigrationResult, error) {
+	collections := make([]string, 0, len(repository.ScheduleDateFields))
+	for collection := range repository.ScheduleDateFields {
+		collections = append(collections, collection)
+	}
+	sort.Strings(collections)
+
+	var results []ScheduleMigrationResult
+	for _, collection := range collections {
+		dates, err := repo.FindStringDates(ctx, collection, repository.ScheduleDateFields[collection])
+		if err != nil {
+			return results, fmt.Errorf("%s: %w", collection, err)
+		}
+
+		result := ScheduleMigrationResult{Collection: collection}
+		for _, date := range dates {
+			value, parseErr := parseLegacyScheduleDate(date.Value)
+			switch {
+			case parseErr != nil:
+				result.Unparsed = append(result.Unparsed, fmt.Sprintf("%v %s: %q", date.ID, date.Field, date.Value))
+				if !dryRun {
+					err = repo.SetUnparsed(ctx, collection, date)
+				}
+			case value == nil:
+				result.Cleared++
+				if !dryRun {
+					err = repo.SetDate(ctx, collection, date, nil)
+				}
+			default:
+				result.Converted++
+				if !dryRun {
+					err = repo.SetDate(ctx, collection, date, value)
+				}
+			}
+			if err != nil {
+				return append(results, result), fmt.Errorf("%s %v: %w", collection, date.ID, err)
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
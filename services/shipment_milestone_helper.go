@@ -0,0 +1,216 @@
+package services
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"fs-backend/models"
+	"fs-backend/repository"
+)
+
+// milestoneDelayGrace is how late a milestone may be against its planned time
+// before it counts as delayed
+const milestoneDelayGrace = time.Hour
+
+// bookingDateLayout is the format of the booking's estimated dates
+const bookingDateLayout = "2006-01-02"
+
+// MilestoneValidationError lists the problems of a milestone request
+type MilestoneValidationError struct {
+	Errors []HBLFieldError
+}
+
+func (e *MilestoneValidationError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, fieldErr := range e.Errors {
+		messages[i] = fieldErr.Field + ": " + fieldErr.Message
+	}
+	return "invalid milestone: " + strings.Join(messages, "; ")
+}
+
+// milestoneRank returns the position of a milestone in models.MilestoneOrder
+func milestoneRank(milestone string) (int, bool) {
+	for i, known := range models.MilestoneOrder {
+		if known == milestone {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// validateMilestoneRequest normalises the request and checks it; an actual
+// time in the future is most likely a planned time entered in the wrong field
+func validateMilestoneRequest(req *models.RecordMilestoneRequest, now time.Time) []HBLFieldError {
+	var errs []HBLFieldError
+	req.Milestone = strings.ToLower(strings.TrimSpace(req.Milestone))
+	req.PlannedLocation = strings.TrimSpace(req.PlannedLocation)
+	req.ActualLocation = strings.TrimSpace(req.ActualLocation)
+	req.Remarks = strings.TrimSpace(req.Remarks)
+
+	if _, ok := milestoneRank(req.Milestone); !ok {
+		errs = append(errs, HBLFieldError{Field: "milestone", Message: "must be one of " + strings.Join(models.MilestoneOrder, ", ")})
+	}
+	if req.Sequence == 0 {
+		req.Sequence = 1
+	}
+	switch {
+	case req.Sequence < 1:
+		errs = append(errs, HBLFieldError{Field: "sequence", Message: "must be at least 1"})
+	case req.Sequence > 1 && req.Milestone != models.MilestoneTranshipped:
+		errs = append(errs, HBLFieldError{Field: "sequence", Message: "only transhipments can occur more than once"})
+	}
+	if req.PlannedAt == nil && req.ActualAt == nil && req.PlannedLocation == "" && req.ActualLocation == "" && req.Remarks == "" {
+		errs = append(errs, HBLFieldError{Field: "milestone", Message: "nothing to record, give a planned or actual time or location"})
+	}
+	if req.ActualAt != nil && req.ActualAt.After(now.Add(milestoneDelayGrace)) {
+		errs = append(errs, HBLFieldError{Field: "actual_at", Message: "must not be in the future"})
+	}
+	return errs
+}
+
+// sortMilestones puts milestones in shipment order, transhipments by number
+func sortMilestones(milestones []models.ShipmentMilestone) {
+	sort.SliceStable(milestones, func(i, j int) bool {
+		ri, _ := milestoneRank(milestones[i].Milestone)
+		rj, _ := milestoneRank(milestones[j].Milestone)
+		if ri != rj {
+			return ri < rj
+		}
+		return milestones[i].Sequence < milestones[j].Sequence
+	})
+}
+
+// currentMilestone returns the furthest milestone the shipment has actually
+// reached, or "" when it has reached none
+func currentMilestone(milestones []models.ShipmentMilestone) string {
+	current, best := "", -1
+	for _, milestone := range milestones {
+		if milestone.ActualAt == nil {
+			continue
+		}
+		if rank, ok := milestoneRank(milestone.Milestone); ok && rank > best {
+			current, best = milestone.Milestone, rank
+		}
+	}
+	return current
+}
+
+// milestoneDelays compares reached milestones with their planned times, and
+// departure and arrival with the booking's ETD and ETA. Milestones not reached
+// yet are overdue once their expected time has passed, unless a later
+// milestone has been reached already. Booking dates have no time of day, so
+// they are compared by day.
+func milestoneDelays(milestones []models.ShipmentMilestone, booking *repository.BookingDocument, now time.Time) []models.MilestoneDelay {
+	delays := []models.MilestoneDelay{}
+	reachedRank := -1
+	if current := currentMilestone(milestones); current != "" {
+		reachedRank, _ = milestoneRank(current)
+	}
+	pending := func(milestone string) bool {
+		rank, _ := milestoneRank(milestone)
+		return rank > reachedRank
+	}
+
+	for _, milestone := range milestones {
+		if milestone.PlannedAt == nil {
+			continue
+		}
+		delay := models.MilestoneDelay{
+			Milestone: milestone.Milestone,
+			Sequence:  milestone.Sequence,
+			Basis:     "planned",
+			Expected:  *milestone.PlannedAt,
+			ActualAt:  milestone.ActualAt,
+		}
+		switch {
+		case milestone.ActualAt != nil:
+			late := milestone.ActualAt.Sub(*milestone.PlannedAt)
+			if late <= milestoneDelayGrace {
+				continue
+			}
+			delay.DelayHours = roundHours(late)
+		case pending(milestone.Milestone) && now.Sub(*milestone.PlannedAt) > milestoneDelayGrace:
+			delay.DelayHours = roundHours(now.Sub(*milestone.PlannedAt))
+			delay.Overdue = true
+		default:
+			continue
+		}
+		delays = append(delays, delay)
+	}
+
+	if booking == nil {
+		return delays
+	}
+	for _, check := range []struct {
+		milestone, basis string
+		date             *time.Time
+	}{
+		{models.MilestoneDeparted, "booking_etd", booking.EstimatedDeparture},
+		{models.MilestoneArrived, "booking_eta", booking.EstimatedArrival},
+	} {
+		if check.date == nil {
+			continue // no estimate
+		}
+		expected := *check.date
+		delay := models.MilestoneDelay{Milestone: check.milestone, Sequence: 1, Basis: check.basis, Expected: expected}
+
+		var actual *time.Time
+		for _, milestone := range milestones {
+			if milestone.Milestone == check.milestone && milestone.ActualAt != nil {
+				actual = milestone.ActualAt
+			}
+		}
+		switch {
+		case actual != nil:
+			days := daysBetween(expected, *actual)
+			if days <= 0 {
+				continue
+			}
+			delay.ActualAt = actual
+			delay.DelayHours = float64(days * 24)
+		case pending(check.milestone):
+			days := daysBetween(expected, now)
+			if days <= 0 {
+				continue
+			}
+			delay.DelayHours = float64(days * 24)
+			delay.Overdue = true
+		default:
+			continue
+		}
+		delays = append(delays, delay)
+	}
+	return delays
+}
+
+// daysBetween counts the calendar days from the date of a to the date of b
+func daysBetween(a, b time.Time) int {
+	da := time.Date(a.Year(), a.Month(), a.Day(), 0, 0, 0, 0, time.UTC)
+	db := time.Date(b.Year(), b.Month(), b.Day(), 0, 0, 0, 0, time.UTC)
+	return int(db.Sub(da).Hours() / 24)
+}
+
+func roundHours(d time.Duration) float64 {
+	return float64(d.Round(6*time.Minute)) / float64(time.Hour)
+}
+
+// milestoneStatus is the shipment status label of a milestone
+func milestoneStatus(milestone string) string {
+	if status, ok := models.MilestoneStatus[milestone]; ok {
+		return status
+	}
+	return milestone
+}
+
+// shipmentStatus derives the status shown for a shipment: the furthest
+// milestone reached, otherwise whether it has been synced to a booking
+func shipmentStatus(booking *repository.BookingDocument, milestones []models.ShipmentMilestone) string {
+	if current := currentMilestone(milestones); current != "" {
+		return milestoneStatus(current)
+	}
+	if booking != nil {
+		return "MBL number Sycned"
+	}
+	return "Yet to sync"
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"fs-backend/models"
+	"fs-backend/repository"
+)
+
+// ShipmentMilestoneService records the milestones of shipments and derives
+// their status and delays
+type ShipmentMilestoneService interface {
+	RecordMilestone(ctx context.Context, shipmentID string, req models.RecordMilestoneRequest, user string) (*models.ShipmentMilestone, error)
+	GetTimeline(ctx context.Context, shipmentID string) (*models.ShipmentTimeline, error)
+}
+
+type shipmentMilestoneService struct {
+	milestoneRepo repository.ShipmentMilestoneRepository
+	shipmentRepo  repository.ShipmentRepository
+	bookingRepo   repository.BookingRepository
+}
+
+// NewShipmentMilestoneService creates a new ShipmentMilestoneService
+func NewShipmentMilestoneService(
+	milestoneRepo repository.ShipmentMilestoneRepository,
+	shipmentRepo repository.ShipmentRepository,
+	bookingRepo repository.BookingRepository,
+) ShipmentMilestoneService {
+	return &shipmentMilestoneService{
+		milestoneRepo: milestoneRepo,
+		shipmentRepo:  shipmentRepo,
+		bookingRepo:   bookingRepo,
+	}
+}
+
+// RecordMilestone records the planned and/or actual time and place of a
+// milestone, or mongo.ErrNoDocuments when the shipment does not exist
+func (s *shipmentMilestoneService) RecordMilestone(ctx context.Context, shipmentID string, req models.RecordMilestoneRequest, user string) (*models.ShipmentMilestone, error) {
+	if errs := validateMilestoneRequest(&req, time.Now()); len(errs) > 0 {
+		return nil, &MilestoneValidationError{Errors: errs}
+	}
+	if _, err := s.shipmentRepo.FindByShipmentID(ctx, shipmentID); err != nil {
+		return nil, err
+	}
+
+	return s.milestoneRepo.Upsert(ctx, &models.ShipmentMilestone{
+		ShipmentID:      shipmentID,
+		Milestone:       req.Milestone,
+		Sequence:        req.Sequence,
+		PlannedAt:       req.PlannedAt,
+		ActualAt:        req.ActualAt,
+		PlannedLocation: req.PlannedLocation,
+		ActualLocation:  req.ActualLocation,
+		Remarks:         req.Remarks,
+		RecordedBy:      user,
+	})
+}
+
+// GetTimeline returns the milestones of a shipment in shipment order with
+// its current status and delays, or mongo.ErrNoDocuments when the shipment
+// does not exist
+func (s *shipmentMilestoneService) GetTimeline(ctx context.Context, shipmentID string) (*models.ShipmentTimeline, error) {
+	if _, err := s.shipmentRepo.FindByShipmentID(ctx, shipmentID); err != nil {
+		return nil, err
+	}
+	booking, err := s.bookingRepo.FindByShipmentID(ctx, shipmentID)
+	if err != nil {
+		return nil, err
+	}
+	milestones, err := s.milestoneRepo.FindByShipmentID(ctx, shipmentID)
+	if err != nil {
+		return nil, err
+	}
+	sortMilestones(milestones)
+
+	timeline := &models.ShipmentTimeline{
+		ShipmentID: shipmentID,
+		Status:     shipmentStatus(booking, milestones),
+		Milestones: milestones,
+		Delays:     milestoneDelays(milestones, booking, time.Now()),
+	}
+	if booking != nil {
+		timeline.MBLNumber = booking.MBLNumber
+	}
+	return timeline, nil
+}
@@ -3,27 +3,33 @@ package services
 import (
 	"context"
 	"errors"
+	"fs-backend/models"
 	"fs-backend/repository"
+	"strings"
 )
 
 type ShipmentService interface {
-	GetAllShipments(ctx context.Context) ([]ShipmentWithStatusDTO, error)
+	GetAllShipments(ctx context.Context, filter repository.ShipmentFilter) ([]ShipmentWithStatusDTO, error)
 	InsertShipment(ctx context.Context, doc *repository.ShipmentDocument) (string, error)
-	UpdateShipment(ctx context.Context, id string, doc *repository.ShipmentDocument) error
+	UpdateShipment(ctx context.Context, id string, doc *repository.ShipmentDocument, revision int64) error
 	DeleteShipment(ctx context.Context, id string) error
 }
 
 type shipmentService struct {
-	shipmentRepo repository.ShipmentRepository
-	bookingRepo  repository.BookingRepository
-	shipperRepo  repository.ShipperRepository
+	shipmentRepo  repository.ShipmentRepository
+	bookingRepo   repository.BookingRepository
+	shipperRepo   repository.ShipperRepository
+	milestoneRepo repository.ShipmentMilestoneRepository
+	modeRuleRepo  repository.BookingModeRuleRepository
 }
 
-func NewShipmentService(shipmentRepo repository.ShipmentRepository, bookingRepo repository.BookingRepository, shipperRepo repository.ShipperRepository) ShipmentService {
+func NewShipmentService(shipmentRepo repository.ShipmentRepository, bookingRepo repository.BookingRepository, shipperRepo repository.ShipperRepository, milestoneRepo repository.ShipmentMilestoneRepository, modeRuleRepo repository.BookingModeRuleRepository) ShipmentService {
 	return &shipmentService{
-		shipmentRepo: shipmentRepo,
-		bookingRepo:  bookingRepo,
-		shipperRepo:  shipperRepo,
+		shipmentRepo:  shipmentRepo,
+		bookingRepo:   bookingRepo,
+		shipperRepo:   shipperRepo,
+		milestoneRepo: milestoneRepo,
+		modeRuleRepo:  modeRuleRepo,
 	}
 }
 
@@ -34,25 +40,43 @@ type ShipmentWithStatusDTO struct {
 	Status                      string `json:"status"`
 }
 
-func (s *shipmentService) GetAllShipments(ctx context.Context) ([]ShipmentWithStatusDTO, error) {
-	shipments, err := s.shipmentRepo.GetAllShipments(ctx)
+func (s *shipmentService) GetAllShipments(ctx context.Context, filter repository.ShipmentFilter) ([]ShipmentWithStatusDTO, error) {
+	shipments, err := s.shipmentRepo.GetAllShipments(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
+
+	// Milestones of all shipments in one query, the status is the furthest one reached
+	shipmentIDs := make([]string, 0, len(shipments))
+	for _, shipment := range shipments {
+		shipmentIDs = append(shipmentIDs, shipment.ShipmentID)
+	}
+	milestonesByShipment := make(map[string][]models.ShipmentMilestone)
+	if len(shipmentIDs) > 0 {
+		milestones, err := s.milestoneRepo.FindByShipmentIDs(ctx, shipmentIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, milestone := range milestones {
+			milestonesByShipment[milestone.ShipmentID] = append(milestonesByShipment[milestone.ShipmentID], milestone)
+		}
+	}
 	
 	var dtos []ShipmentWithStatusDTO
 	for _, shipment := range shipments {
 		dto := ShipmentWithStatusDTO{
 			ShipmentDocument: shipment,
 			MBLNumber:        "-",
-			Status:           "Yet to sync",
 		}
 		
 		booking, err := s.bookingRepo.FindByShipmentID(ctx, shipment.ShipmentID)
-		if err == nil && booking != nil {
+		if err != nil {
+			booking = nil // list the shipment as not synced, as before
+		}
+		if booking != nil {
 			dto.MBLNumber = booking.MBLNumber
-			dto.Status = "MBL number Sycned"
 		}
+		dto.Status = shipmentStatus(booking, milestonesByShipment[shipment.ShipmentID])
 		dtos = append(dtos, dto)
 	}
 	
@@ -71,6 +95,9 @@ func (s *shipmentService) InsertShipment(ctx context.Context, doc *repository.Sh
 	if len(shippers) == 0 {
 		return "", errors.New("Invalid shipper id")
 	}
+	if err := s.validateMode(ctx, doc); err != nil {
+		return "", err
+	}
 
 	// Auto ID logic
 	newID, err := s.shipmentRepo.GetNextShipmentID(ctx)
@@ -86,8 +113,29 @@ func (s *shipmentService) InsertShipment(ctx context.Context, doc *repository.Sh
 	return newID, nil
 }
 
-func (s *shipmentService) UpdateShipment(ctx context.Context, id string, doc *repository.ShipmentDocument) error {
-	return s.shipmentRepo.UpdateShipment(ctx, id, doc)
+// UpdateShipment applies the update only if the shipment is still at revision.
+// A booked shipment cannot be given a delivery date before its booking arrives.
+func (s *shipmentService) UpdateShipment(ctx context.Context, id string, doc *repository.ShipmentDocument, revision int64) error {
+	if err := s.validateMode(ctx, doc); err != nil {
+		return err
+	}
+	booking, err := s.bookingRepo.FindByShipmentID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if booking != nil {
+		if err := validateDeliveryDate(id, doc.DesiredDeliveryDate, booking.EstimatedArrival); err != nil {
+			return err
+		}
+	}
+
+	err = s.shipmentRepo.UpdateShipment(ctx, id, doc, revision)
+	if errors.Is(err, repository.ErrRevisionMismatch) {
+		if current, findErr := s.shipmentRepo.FindByShipmentID(ctx, id); findErr == nil {
+			return &RevisionConflictError{Revision: current.Revision, Current: current}
+		}
+	}
+	return err
 }
 
 func (s *shipmentService) DeleteShipment(ctx context.Context, id string) error {
@@ -99,3 +147,21 @@ func (s *shipmentService) DeleteShipment(ctx context.Context, id string) error {
 	// Ensure it is also removed from any Booking that might contain it
 	return s.bookingRepo.RemoveShipmentFromBooking(ctx, id)
 }
+
+// validateMode checks that the shipment's mode, when set, is one its
+// forwarder books in, and stores it in its canonical form
+func (s *shipmentService) validateMode(ctx context.Context, doc *repository.ShipmentDocument) error {
+	if strings.TrimSpace(doc.Mode) == "" {
+		return nil // set before syncing
+	}
+	rules, err := loadBookingModeRules(ctx, s.modeRuleRepo, doc.ForwarderID)
+	if err != nil {
+		return err
+	}
+	rule, err := findBookingModeRule(rules, doc.Mode)
+	if err != nil {
+		return err
+	}
+	doc.Mode = rule.Mode
+	return nil
+}
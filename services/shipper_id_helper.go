@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"fs-backend/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// shipperSequenceKey is the "counters" sequence shipper IDs are drawn from
+const shipperSequenceKey = "shipper_id"
+
+// shipperIDAttempts bounds the IDs drawn for one shipper when the drawn ones
+// turn out to be taken already
+const shipperIDAttempts = 5
+
+var ErrShipperIDTaken = errors.New("shipper ID is already in use")
+
+// shipperIDAllocator issues SHPR<n> shipper IDs from the "counters" sequence
+type shipperIDAllocator struct {
+	shipperRepo repository.ShipperRepository
+	counterRepo repository.CounterRepository
+
+	mu     sync.Mutex
+	seeded bool
+}
+
+func newShipperIDAllocator(shipperRepo repository.ShipperRepository, counterRepo repository.CounterRepository) *shipperIDAllocator {
+	return &shipperIDAllocator{shipperRepo: shipperRepo, counterRepo: counterRepo}
+}
+
+// shipperNumber returns n of a SHPR<n> shipper ID
+func shipperNumber(shipperID string) (int64, bool) {
+	var n int64
+	var rest string
+	if count, _ := fmt.Sscanf(shipperID, "SHPR%d%s", &n, &rest); count != 1 {
+		return 0, false
+	}
+	return n, true
+}
+
+// seed raises the sequence past the IDs in use. It runs on the first draw of
+// the process, as shippers were numbered from the collection before the
+// sequence existed, and again whenever a drawn ID is taken, e.g. by a shipper
+// added with its own ID.
+func (a *shipperIDAllocator) seed(ctx context.Context) error {
+	max, err := a.shipperRepo.MaxShipperNumber(ctx)
+	if err == nil {
+		err = a.counterRepo.SeedAtLeast(ctx, shipperSequenceKey, max)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to seed shipper ID sequence: %w", err)
+	}
+	return nil
+}
+
+// Insert stores doc under a newly drawn shipper ID
+func (a *shipperIDAllocator) Insert(ctx context.Context, doc *repository.ShipperDocument) (primitive.ObjectID, error) {
+	a.mu.Lock()
+	if !a.seeded {
+		if err := a.seed(ctx); err != nil {
+			a.mu.Unlock()
+			return primitive.NilObjectID, err
+		}
+		a.seeded = true
+	}
+	a.mu.Unlock()
+
+	for attempt := 0; attempt < shipperIDAttempts; attempt++ {
+		seq, err := a.counterRepo.Next(ctx, shipperSequenceKey)
+		if err != nil {
+			return primitive.NilObjectID, fmt.Errorf("failed to allocate shipper ID: %w", err)
+		}
+		doc.ShipperID = fmt.Sprintf("SHPR%03d", seq)
+		res, err := a.shipperRepo.CreateShipper(ctx, *doc)
+		if repository.IsDuplicateKeyOnIndex(err, repository.ShipperIDIndex) {
+			if err := a.seed(ctx); err != nil {
+				return primitive.NilObjectID, err
+			}
+			continue
+		}
+		if err != nil {
+			return primitive.NilObjectID, err
+		}
+		oid, _ := res.InsertedID.(primitive.ObjectID)
+		return oid, nil
+	}
+	return primitive.NilObjectID, fmt.Errorf("%w: no free shipper ID after %d attempts", ErrShipperIDTaken, shipperIDAttempts)
+}
+
+// Reserve keeps the sequence from handing out a SHPR<n> ID given by a client
+func (a *shipperIDAllocator) Reserve(ctx context.Context, shipperID string) error {
+	n, ok := shipperNumber(shipperID)
+	if !ok {
+		return nil
+	}
+	return a.counterRepo.SeedAtLeast(ctx, shipperSequenceKey, n)
+}
@@ -0,0 +1,223 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"fs-backend/models"
+	"fs-backend/models/hbl_schema"
+	"fs-backend/repository"
+)
+
+// StuffingPlanValidationError lists everything wrong with a stuffing plan
+type StuffingPlanValidationError struct {
+	Errors []HBLFieldError
+}
+
+func (e *StuffingPlanValidationError) Error() string {
+	var parts []string
+	for _, fieldErr := range e.Errors {
+		parts = append(parts, fieldErr.Field+": "+fieldErr.Message)
+	}
+	return "invalid stuffing plan: " + strings.Join(parts, "; ")
+}
+
+// normalizeContainerNo upper-cases a container number and drops spaces and dashes
+func normalizeContainerNo(number string) string {
+	return strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(number)))
+}
+
+// normalizeStuffingPlan canonicalises container numbers and ISO types so
+// allocations can be matched to containers
+func normalizeStuffingPlan(plan *models.StuffingPlan) {
+	for i := range plan.Containers {
+		plan.Containers[i].ContainerNo = normalizeContainerNo(plan.Containers[i].ContainerNo)
+		if capacity, ok := models.LookupContainerCapacity(plan.Containers[i].ISOType); ok {
+			plan.Containers[i].ISOType = capacity.ISOType
+		}
+	}
+	for i := range plan.Allocations {
+		plan.Allocations[i].ShipmentID = strings.TrimSpace(plan.Allocations[i].ShipmentID)
+		plan.Allocations[i].ContainerNo = normalizeContainerNo(plan.Allocations[i].ContainerNo)
+	}
+}
+
+// validateStuffingPlan checks the containers, that every allocation refers to a
+// container of the plan and a shipment booked on the MBL, that no container is
+// loaded beyond the capacity of its ISO type and that each shipment's packages
+// are allocated exactly once, with its weight and volume within tolerances.
+// Containers must be listed on the MBL unless it lists none; booked holds the
+// shipment IDs of the MBL's booking.
+func validateStuffingPlan(plan models.StuffingPlan, mblContainers []string, booked map[string]bool, shipmentByID map[string]repository.ShipmentDocument, tolerances models.ReconciliationTolerances) error {
+	var fieldErrors []HBLFieldError
+	add := func(field, message string) {
+		fieldErrors = append(fieldErrors, HBLFieldError{Field: field, Message: message})
+	}
+
+	if len(plan.Containers) == 0 {
+		add("containers", "at least one container is required")
+	}
+	onMBL := make(map[string]bool, len(mblContainers))
+	for _, containerNo := range mblContainers {
+		onMBL[normalizeContainerNo(containerNo)] = true
+	}
+	containers := make(map[string]models.StuffedContainer, len(plan.Containers))
+	for i, container := range plan.Containers {
+		prefix := fmt.Sprintf("containers[%d]", i)
+		if err := validateContainerNumber(container.ContainerNo); err != nil {
+			add(prefix+".container_no", err.Error())
+		}
+		if _, duplicate := containers[container.ContainerNo]; duplicate {
+			add(prefix+".container_no", "container is listed twice")
+		}
+		if _, ok := models.LookupContainerCapacity(container.ISOType); !ok {
+			add(prefix+".iso_type", fmt.Sprintf("unknown ISO container type %q", container.ISOType))
+		}
+		if len(onMBL) > 0 && !onMBL[container.ContainerNo] {
+			add(prefix+".container_no", fmt.Sprintf("container %s is not on MBL %s", container.ContainerNo, plan.MBLNumber))
+		}
+		containers[container.ContainerNo] = container
+	}
+
+	allocatedPackages := make(map[string]int)
+	allocatedWeight := make(map[string]float64)
+	allocatedVolume := make(map[string]float64)
+	for i, allocation := range plan.Allocations {
+		prefix := fmt.Sprintf("allocations[%d]", i)
+		if _, ok := shipmentByID[allocation.ShipmentID]; !ok {
+			add(prefix+".shipment_id", fmt.Sprintf("shipment %q not found", allocation.ShipmentID))
+		} else if !booked[allocation.ShipmentID] {
+			add(prefix+".shipment_id", fmt.Sprintf("shipment %s is not booked on MBL %s", allocation.ShipmentID, plan.MBLNumber))
+		}
+		if _, ok := containers[allocation.ContainerNo]; !ok {
+			add(prefix+".container_no", fmt.Sprintf("container %q is not part of the plan", allocation.ContainerNo))
+		}
+		if allocation.Packages <= 0 {
+			add(prefix+".packages", "must be positive")
+		}
+		if allocation.GrossWeight < 0 {
+			add(prefix+".gross_weight", "must not be negative")
+		}
+		if allocation.Volume < 0 {
+			add(prefix+".volume", "must not be negative")
+		}
+		allocatedPackages[allocation.ShipmentID] += allocation.Packages
+		allocatedWeight[allocation.ShipmentID] += allocation.GrossWeight
+		allocatedVolume[allocation.ShipmentID] += allocation.Volume
+	}
+
+	checked := make(map[string]bool)
+	for _, allocation := range plan.Allocations {
+		shipment, ok := shipmentByID[allocation.ShipmentID]
+		if !ok || checked[allocation.ShipmentID] {
+			continue
+		}
+		checked[allocation.ShipmentID] = true
+		if packages := allocatedPackages[allocation.ShipmentID]; shipment.PackagesCount > 0 && packages != shipment.PackagesCount {
+			add("allocations", fmt.Sprintf("shipment %s has %d packages but %d are allocated", allocation.ShipmentID, shipment.PackagesCount, packages))
+		}
+		if weight := allocatedWeight[allocation.ShipmentID]; shipment.GrossWeight > 0 && percentDiff(shipment.GrossWeight, weight) > tolerances.WeightPercent {
+			add("allocations", fmt.Sprintf("shipment %s weighs %.3f kg but %.3f kg are allocated", allocation.ShipmentID, shipment.GrossWeight, weight))
+		}
+		if volume := allocatedVolume[allocation.ShipmentID]; shipment.Volume > 0 && percentDiff(shipment.Volume, volume) > tolerances.VolumePercent {
+			add("allocations", fmt.Sprintf("shipment %s measures %.3f cbm but %.3f cbm are allocated", allocation.ShipmentID, shipment.Volume, volume))
+		}
+	}
+
+	for _, usage := range stuffingUtilisation(plan) {
+		if usage.MaxPayloadKg > 0 && usage.GrossWeight > usage.MaxPayloadKg {
+			add("containers", fmt.Sprintf("container %s carries %.0f kg, more than the %.0f kg payload of a %s", usage.ContainerNo, usage.GrossWeight, usage.MaxPayloadKg, usage.ISOType))
+		}
+		if usage.MaxVolumeCbm > 0 && usage.Volume > usage.MaxVolumeCbm {
+			add("containers", fmt.Sprintf("container %s holds %.2f cbm, more than the %.1f cbm of a %s", usage.ContainerNo, usage.Volume, usage.MaxVolumeCbm, usage.ISOType))
+		}
+	}
+
+	if len(fieldErrors) > 0 {
+		return &StuffingPlanValidationError{Errors: fieldErrors}
+	}
+	return nil
+}
+
+// stuffingUtilisation sums the allocations per container, in plan order
+func stuffingUtilisation(plan models.StuffingPlan) []models.ContainerUtilisation {
+	usage := make([]models.ContainerUtilisation, len(plan.Containers))
+	index := make(map[string]int, len(plan.Containers))
+	for i, container := range plan.Containers {
+		usage[i] = models.ContainerUtilisation{ContainerNo: container.ContainerNo, ISOType: container.ISOType}
+		if capacity, ok := models.LookupContainerCapacity(container.ISOType); ok {
+			usage[i].MaxPayloadKg = capacity.MaxPayloadKg
+			usage[i].MaxVolumeCbm = capacity.MaxVolumeCbm
+		}
+		index[container.ContainerNo] = i
+	}
+
+	for _, allocation := range plan.Allocations {
+		i, ok := index[allocation.ContainerNo]
+		if !ok {
+			continue
+		}
+		usage[i].Packages += allocation.Packages
+		usage[i].GrossWeight += allocation.GrossWeight
+		usage[i].Volume += allocation.Volume
+	}
+
+	for i := range usage {
+		if usage[i].MaxPayloadKg > 0 {
+			usage[i].WeightUtilisation = math.Round(usage[i].GrossWeight/usage[i].MaxPayloadKg*1000) / 10
+		}
+		if usage[i].MaxVolumeCbm > 0 {
+			usage[i].VolumeUtilisation = math.Round(usage[i].Volume/usage[i].MaxVolumeCbm*1000) / 10
+		}
+	}
+	return usage
+}
+
+// containerDetailsFromPlan builds one HBL container entry per container the
+// shipment is loaded into, with the shipment's share of the cargo. Net weight
+// is split in proportion to gross weight. It returns nil when the plan has no
+// allocation for the shipment.
+func containerDetailsFromPlan(plan *models.StuffingPlan, shipment repository.ShipmentDocument) []hbl_schema.HBLContainer {
+	if plan == nil {
+		return nil
+	}
+	containers := make(map[string]models.StuffedContainer, len(plan.Containers))
+	for _, container := range plan.Containers {
+		containers[container.ContainerNo] = container
+	}
+
+	var details []hbl_schema.HBLContainer
+	for _, allocation := range plan.Allocations {
+		if allocation.ShipmentID != shipment.ShipmentID {
+			continue
+		}
+		container := containers[allocation.ContainerNo]
+
+		netWeight := 0.0
+		if shipment.GrossWeight > 0 {
+			netWeight = math.Round(shipment.NetWeight*allocation.GrossWeight/shipment.GrossWeight*1000) / 1000
+		}
+		details = append(details, hbl_schema.HBLContainer{
+			ContainerNo:        allocation.ContainerNo,
+			ContainerSize:      container.ISOType,
+			SealNo:             container.SealNo,
+			PackageCount:       allocation.Packages,
+			MarksAndNumbers:    shipment.MarksAndNumbers,
+			DescriptionOfGoods: shipment.GoodsDescription,
+			GrossWeight: hbl_schema.HBLWeightMeasurement{
+				Value: allocation.GrossWeight,
+				Unit:  "KGS",
+			},
+			NetWeight: hbl_schema.HBLWeightMeasurement{
+				Value: netWeight,
+				Unit:  "KGS",
+			},
+			Measurement: hbl_schema.HBLWeightMeasurement{
+				Value: allocation.Volume,
+				Unit:  "CBM",
+			},
+		})
+	}
+	return details
+}
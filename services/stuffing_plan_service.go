@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"fs-backend/models"
+	"fs-backend/repository"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// StuffingPlanService manages the stuffing plans of LCL consolidations
+type StuffingPlanService interface {
+	GetPlan(ctx context.Context, mblNumber string) (*models.StuffingPlanResponse, error)
+	SavePlan(ctx context.Context, plan models.StuffingPlan) (*models.StuffingPlanResponse, error)
+	DeletePlan(ctx context.Context, mblNumber string) error
+}
+
+type stuffingPlanService struct {
+	planRepo     repository.StuffingPlanRepository
+	mblRepo      repository.MBLRepository
+	shipmentRepo repository.ShipmentRepository
+	bookingRepo  repository.BookingRepository
+	tolerances   models.ReconciliationTolerances
+}
+
+// NewStuffingPlanService creates a new StuffingPlanService
+func NewStuffingPlanService(
+	planRepo repository.StuffingPlanRepository,
+	mblRepo repository.MBLRepository,
+	shipmentRepo repository.ShipmentRepository,
+	bookingRepo repository.BookingRepository,
+	tolerances models.ReconciliationTolerances,
+) StuffingPlanService {
+	return &stuffingPlanService{
+		planRepo:     planRepo,
+		mblRepo:      mblRepo,
+		shipmentRepo: shipmentRepo,
+		bookingRepo:  bookingRepo,
+		tolerances:   tolerances,
+	}
+}
+
+// GetPlan returns the MBL's plan with its container utilisation, or
+// mongo.ErrNoDocuments when it has none
+func (s *stuffingPlanService) GetPlan(ctx context.Context, mblNumber string) (*models.StuffingPlanResponse, error) {
+	plan, err := s.planRepo.FindByMBLNumber(ctx, mblNumber)
+	if err != nil {
+		return nil, err
+	}
+	if plan == nil {
+		return nil, mongo.ErrNoDocuments
+	}
+	return &models.StuffingPlanResponse{Plan: plan, Utilisation: stuffingUtilisation(*plan)}, nil
+}
+
+// SavePlan validates and stores the plan of an MBL, replacing the previous one.
+// HBLs created from the MBL afterwards take their container details from it;
+// existing HBLs are not changed.
+func (s *stuffingPlanService) SavePlan(ctx context.Context, plan models.StuffingPlan) (*models.StuffingPlanResponse, error) {
+	mblDoc, err := s.mblRepo.FindByMBLNumber(ctx, plan.MBLNumber)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, fmt.Errorf("%w for number %s", ErrMBLNotFound, plan.MBLNumber)
+		}
+		return nil, err
+	}
+
+	// Without a booking no shipment is booked on the MBL, so every allocation is rejected
+	booked := make(map[string]bool)
+	booking, err := s.bookingRepo.FindByMBLNumber(ctx, plan.MBLNumber)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, fmt.Errorf("failed to fetch booking: %w", err)
+	}
+	if booking != nil {
+		for _, shipmentID := range booking.ShipmentIDs {
+			booked[shipmentID] = true
+		}
+	}
+
+	normalizeStuffingPlan(&plan)
+
+	var shipmentIDs []string
+	for _, allocation := range plan.Allocations {
+		shipmentIDs = append(shipmentIDs, allocation.ShipmentID)
+	}
+	shipmentByID := make(map[string]repository.ShipmentDocument)
+	if len(shipmentIDs) > 0 {
+		shipments, err := s.shipmentRepo.FindByShipmentIDs(ctx, shipmentIDs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch shipments: %w", err)
+		}
+		for _, shipment := range shipments {
+			shipmentByID[shipment.ShipmentID] = shipment
+		}
+	}
+
+	if err := validateStuffingPlan(plan, splitList(mblDoc.MBL.Cargo.ContainerNo), booked, shipmentByID, s.tolerances); err != nil {
+		return nil, err
+	}
+	if err := s.planRepo.Upsert(ctx, &plan); err != nil {
+		return nil, err
+	}
+	return &models.StuffingPlanResponse{Plan: &plan, Utilisation: stuffingUtilisation(plan)}, nil
+}
+
+func (s *stuffingPlanService) DeletePlan(ctx context.Context, mblNumber string) error {
+	return s.planRepo.DeleteByMBLNumber(ctx, mblNumber)
+}